@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"log"
+	"os"
 	"os/signal"
 	"sync"
 	"syscall"
@@ -11,13 +12,23 @@ import (
 
 	_ "github.com/jackc/pgx/stdlib"
 	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/auth"
+	internalclock "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
 	internalconfig "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/config"
+	internalhealth "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/health"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/limits"
 	internallogger "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/logger"
 	internalgrpc "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc"
 	internalhttp "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/http"
+	cachestorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/cache"
 	factorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/factory"
+	sqlstorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/sql"
+	internaltracing "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/tracing"
 )
 
+// healthInterval is how often the grpc health status is refreshed.
+const healthInterval = 5 * time.Second
+
 var configPath string
 
 func init() {
@@ -38,27 +49,118 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if flag.Arg(0) == "migrate" {
+		exitOnError(runMigrate(context.Background(), config, flag.Args()[1:], os.Stdout))
+		return
+	}
+
+	if flag.Arg(0) == "token" {
+		exitOnError(runToken(context.Background(), config, flag.Args()[1:], os.Stdout))
+		return
+	}
+
 	// Logger initialization.
 	logger := internallogger.New(config)
 
-	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGHUP)
+	// Tracing initialization.
+	tracer, err := internaltracing.NewProvider(context.Background(), config, "calendar")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Second*3)
+		defer shutdownCancel()
+		if err := tracer.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
+	// Clock initialization.
+	clk := internalclock.New(config)
+	if !config.GetClockTravelTo().IsZero() {
+		logger.Warn("travelled in time to " + config.GetClockTravelTo().Format(time.RFC3339))
+	}
+
 	// Storage initialization.
-	storage, err := factorystorage.GetStorage(ctx, config)
+	storage, err := factorystorage.GetStorage(ctx, config, clk)
 	if err != nil {
 		logger.Error(err.Error())
-		cancel()
+		os.Exit(1)
+	}
+
+	// Reporting failures of the change listener feeding event watchers.
+	if sqlStorage, ok := storage.(*sqlstorage.Storage); ok {
+		sqlStorage.OnListenError = func(err error) {
+			logger.Error(err.Error())
+		}
+	}
+
+	// Caching events in front of the storage, invalidated by the changes of other instances too.
+	var appStorage app.Storage = storage
+	if size := config.GetStorageCacheSize(); size > 0 {
+		cache := cachestorage.New(storage, clk, size, config.GetStorageCacheTTL())
+		go cache.Watch(ctx)
+		appStorage = cache
 	}
 
 	// Application initialization.
-	calendar := app.New(logger, storage)
+	calendar := app.New(logger, appStorage, clk)
+	calendar.MaxFreeBusyRange = config.GetFreeBusyMaxRange()
+
+	// REST API gateway initialization, served by the grpc service in-process.
+	service := internalgrpc.NewService(calendar, logger)
+	gateway, err := internalgrpc.NewGatewayHandler(ctx, service)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	// Readiness checks of the dependencies.
+	checker := internalhealth.New()
+	if pinger, ok := storage.(internalhealth.Pinger); ok {
+		checker.Add("storage", pinger.Ping)
+	}
+
+	// Authentication initialization, nil interfaces leave the servers open.
+	var (
+		httpAuthenticator internalhttp.Authenticator
+		grpcAuthenticator internalgrpc.Authenticator
+	)
+	if config.GetAuthEnabled() {
+		tokenStore, _ := storage.(auth.TokenStore)
+		authenticator, err := auth.New(config, tokenStore)
+		if err != nil {
+			// Serving without authentication would open the API to anybody.
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		httpAuthenticator = authenticator
+		grpcAuthenticator = authenticator
+	}
+
+	// Per client rate and request size limits.
+	limiter := limits.New(config, clk)
 
 	// HTTP Server initialization.
-	httpServer := internalhttp.NewServer(config, calendar, logger)
+	httpServer := internalhttp.NewServer(config, calendar, service, logger, gateway, checker, httpAuthenticator, limiter)
 
 	// GRPC Server initialization.
-	grpcServer := internalgrpc.NewServer(config, calendar, logger)
+	grpcServer := internalgrpc.NewServer(config, calendar, logger, grpcAuthenticator, limiter)
+
+	// Reloading the log level and the limits on SIGHUP.
+	go internalconfig.WatchReload(ctx, configPath, func(config *internalconfig.Config) {
+		logger.SetLevel(config.GetLoggerLevel())
+		limiter.Update(config)
+		logger.Info("config reloaded")
+	}, func(err error) {
+		logger.Error(err.Error())
+	})
+
+	// Keeping the grpc health service in line with the readiness checks.
+	go checker.Watch(ctx, healthInterval, grpcServer.SetServing)
 
 	var wg sync.WaitGroup
 
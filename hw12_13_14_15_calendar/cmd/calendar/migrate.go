@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	internalclock "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	internalconfig "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/config"
+	sqlstorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/sql"
+)
+
+var (
+	ErrMigrateCommand = errors.New("usage: calendar [-config path] migrate up|down|status|redo")
+	ErrMigrateStorage = errors.New("migrations apply to the sql storage only")
+)
+
+// runMigrate applies the embedded migrations to the configured sql storage.
+func runMigrate(ctx context.Context, config *internalconfig.Config, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return ErrMigrateCommand
+	}
+
+	if config.GetStorageImplementation() != sqlstorage.Alias {
+		return ErrMigrateStorage
+	}
+
+	// Migrations don't depend on the current time.
+	storage := sqlstorage.New(config, internalclock.Real{})
+	if err := storage.Connect(ctx); err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	err := storage.Migrate(ctx, args[0], out)
+	if errors.Is(err, sqlstorage.ErrUnknownMigrateCommand) {
+		return ErrMigrateCommand
+	}
+
+	return err
+}
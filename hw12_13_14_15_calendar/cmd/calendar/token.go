@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/auth"
+	internalclock "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	internalconfig "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	factorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/factory"
+)
+
+var (
+	ErrTokenCommand = errors.New("usage: calendar [-config path] token issue -owner id [-name name] [-ttl duration] | revoke -id id | list")
+	ErrTokenStore   = errors.New("storage does not keep api tokens")
+)
+
+// tokenStore is the storage side of the token admin commands.
+type tokenStore interface {
+	auth.TokenStore
+	ListAPITokens(ctx context.Context) ([]storage.APIToken, error)
+}
+
+// runToken manages api tokens: issues, revokes and lists them.
+func runToken(ctx context.Context, config *internalconfig.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return ErrTokenCommand
+	}
+
+	// Token expiry is checked against the real time.
+	storage, err := factorystorage.GetStorage(ctx, config, internalclock.Real{})
+	if err != nil {
+		return err
+	}
+	if closer, ok := storage.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	store, ok := storage.(tokenStore)
+	if !ok {
+		return ErrTokenStore
+	}
+
+	switch args[0] {
+	case "issue":
+		return issueToken(ctx, store, args[1:], out)
+	case "revoke":
+		return revokeToken(ctx, store, args[1:], out)
+	case "list":
+		return listTokens(ctx, store, out)
+	default:
+		return ErrTokenCommand
+	}
+}
+
+func issueToken(ctx context.Context, store tokenStore, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("token issue", flag.ContinueOnError)
+	ownerID := flags.Int64("owner", 0, "Owner the token acts on behalf of")
+	name := flags.String("name", "", "Token description")
+	ttl := flags.Duration("ttl", 0, "Token lifetime, zero never expires")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	token, stored, err := auth.IssueAPIToken(ctx, store, *ownerID, *name, *ttl)
+	if err != nil {
+		return err
+	}
+
+	// The token is only shown once, the storage keeps its hash.
+	fmt.Fprintf(out, "id: %d\nowner: %d\ntoken: %s\n", stored.ID, stored.OwnerID, token)
+
+	return nil
+}
+
+func revokeToken(ctx context.Context, store tokenStore, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("token revoke", flag.ContinueOnError)
+	id := flags.Int64("id", 0, "Token id")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if err := store.RevokeAPIToken(ctx, *id); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "token %d revoked\n", *id)
+
+	return nil
+}
+
+func listTokens(ctx context.Context, store tokenStore, out io.Writer) error {
+	tokens, err := store.ListAPITokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tOWNER\tNAME\tCREATED\tEXPIRES\tREVOKED")
+	for _, token := range tokens {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\n",
+			token.ID,
+			token.OwnerID,
+			token.Name,
+			token.CreatedAt.Format(time.RFC3339),
+			formatOptionalTime(token.ExpiresAt),
+			formatOptionalTime(token.RevokedAt),
+		)
+	}
+
+	return w.Flush()
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+// exitOnError prints the error and exits, used by the one-shot admin commands.
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
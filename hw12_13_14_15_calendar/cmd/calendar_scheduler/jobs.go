@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	internalconfig "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/jobs"
+	internallogger "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/logger"
+	internalmetrics "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/metrics"
+	internalrabbitmq "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/rabbitmq"
+	internalstorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	internaltracing "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Names of the scheduler jobs, as configured under [scheduler.jobs.<name>].
+const (
+	jobNotify  = "notify"
+	jobCleanup = "cleanup"
+	jobDigest  = "digest"
+)
+
+// defaultSchedule returns the schedule of a job missing in the config.
+func defaultSchedule(config *internalconfig.Config, name string) string {
+	switch name {
+	case jobNotify:
+		// Older configs only have the polling interval.
+		return config.GetSchedulerInterval().String()
+	case jobCleanup:
+		return "@hourly"
+	case jobDigest:
+		// Digests are due at whole minutes of the owners' local time.
+		return "* * * * *"
+	default:
+		return jobs.Off
+	}
+}
+
+// jobSchedule parses the configured schedule of a job, falling back to the default one.
+func jobSchedule(config *internalconfig.Config, name string) (jobs.Schedule, error) {
+	spec := config.GetSchedulerJobSchedule(name)
+	if spec == "" {
+		spec = defaultSchedule(config, name)
+	}
+
+	schedule, err := jobs.ParseSchedule(spec)
+	if err != nil {
+		return nil, fmt.Errorf("job %s: %w", name, err)
+	}
+
+	return schedule, nil
+}
+
+// addJobs registers the scheduler jobs.
+func addJobs(
+	scheduler *jobs.Scheduler,
+	config *internalconfig.Config,
+	calendar *app.App,
+	storage app.Storage,
+	rabbitClient *internalrabbitmq.Client,
+	logger *internallogger.Logger,
+	retention func() time.Duration,
+) error {
+	runs := map[string]func(ctx context.Context) error{
+		jobNotify: func(ctx context.Context) error {
+			return notify(ctx, calendar, storage, rabbitClient, logger)
+		},
+		jobCleanup: func(ctx context.Context) error {
+			// The retention is read at every run, so that a reload applies to the next one.
+			return storage.RemoveExpiredEvents(ctx, retention())
+		},
+		jobDigest: func(ctx context.Context) error {
+			return sendDigests(ctx, calendar, rabbitClient, logger)
+		},
+	}
+
+	for _, name := range []string{jobNotify, jobCleanup, jobDigest} {
+		schedule, err := jobSchedule(config, name)
+		if err != nil {
+			return err
+		}
+
+		err = scheduler.Add(jobs.Job{
+			Name:     name,
+			Schedule: schedule,
+			Jitter:   config.GetSchedulerJobJitter(name),
+			Timeout:  config.GetSchedulerJobTimeout(name),
+			Run:      runs[name],
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rescheduleJobs applies the job settings of a reloaded config.
+func rescheduleJobs(scheduler *jobs.Scheduler, config *internalconfig.Config) error {
+	for _, status := range scheduler.Status() {
+		schedule, err := jobSchedule(config, status.Name)
+		if err != nil {
+			return err
+		}
+
+		err = scheduler.Reschedule(
+			status.Name,
+			schedule,
+			config.GetSchedulerJobJitter(status.Name),
+			config.GetSchedulerJobTimeout(status.Name),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// notify publishes notifications of the due reminders, tracking each one in a notification record.
+// A failed reminder does not stop the others, the last error is returned.
+func notify(
+	ctx context.Context,
+	calendar *app.App,
+	storage app.Storage,
+	rabbitClient *internalrabbitmq.Client,
+	logger *internallogger.Logger,
+) error {
+	reminders, err := storage.GetDueReminders(ctx)
+	if err != nil {
+		return err
+	}
+
+	internalmetrics.SchedulerDueEvents.Add(float64(len(reminders)))
+
+	var lastErr error
+	for _, reminder := range reminders {
+		// Each notification starts its own trace, continued by the sender.
+		reminderCtx, span := internaltracing.Start(
+			ctx,
+			"scheduler.NotifyEvent",
+			trace.WithAttributes(
+				attribute.Int64("calendar.event_id", reminder.EventID),
+				attribute.Int64("calendar.reminder_id", reminder.ID),
+			),
+		)
+
+		if err := notifyReminder(reminderCtx, calendar, rabbitClient, reminder); err != nil {
+			logger.Error(err.Error())
+			lastErr = err
+		}
+
+		span.End()
+	}
+
+	return lastErr
+}
+
+// notifyReminder publishes the notification of the reminder's event and records the outcome.
+func notifyReminder(
+	ctx context.Context,
+	calendar *app.App,
+	rabbitClient *internalrabbitmq.Client,
+	reminder internalstorage.Reminder,
+) error {
+	event, err := calendar.GetEventByID(ctx, reminder.EventID)
+	if err != nil {
+		return err
+	}
+
+	notification, err := calendar.BeginNotification(ctx, reminder, internalstorage.ChannelConsole)
+	if err != nil {
+		return err
+	}
+
+	// Sending event into a queue broker.
+	if err := rabbitClient.SendEventNotification(ctx, event, notification); err != nil {
+		internalmetrics.SchedulerPublishes.WithLabelValues(internalmetrics.ResultFailure).Inc()
+		if _, failErr := calendar.NotificationFailed(ctx, notification.ID, err.Error()); failErr != nil {
+			return failErr
+		}
+
+		return err
+	}
+
+	internalmetrics.SchedulerPublishes.WithLabelValues(internalmetrics.ResultSuccess).Inc()
+
+	_, err = calendar.NotificationPublished(ctx, notification.ID, reminder)
+
+	return err
+}
+
+// sendDigests publishes the due agenda digests and marks them as sent.
+// A failed digest does not stop the others, the last error is returned.
+func sendDigests(
+	ctx context.Context,
+	calendar *app.App,
+	rabbitClient *internalrabbitmq.Client,
+	logger *internallogger.Logger,
+) error {
+	// Digests of the valid subscriptions are built even when some are broken.
+	digests, lastErr := calendar.DueDigests(ctx)
+	if lastErr != nil {
+		logger.Error(lastErr.Error())
+	}
+
+	for _, digest := range digests {
+		err := rabbitClient.SendDigestNotification(ctx, digestNotification(digest))
+		if err != nil {
+			internalmetrics.SchedulerPublishes.WithLabelValues(internalmetrics.ResultFailure).Inc()
+			logger.Error(err.Error())
+			lastErr = err
+			continue
+		}
+
+		internalmetrics.SchedulerPublishes.WithLabelValues(internalmetrics.ResultSuccess).Inc()
+
+		if err := calendar.MarkDigestSent(ctx, digest); err != nil {
+			logger.Error(err.Error())
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+func digestNotification(digest app.Digest) internalrabbitmq.DigestNotification {
+	notification := internalrabbitmq.DigestNotification{
+		OwnerID:  digest.Subscription.OwnerID,
+		Period:   digest.Subscription.Period,
+		TimeZone: digest.Subscription.TimeZone,
+		Begin:    digest.Begin,
+		End:      digest.End,
+		Events:   make([]internalrabbitmq.DigestEvent, 0, len(digest.Events)),
+	}
+
+	for _, event := range digest.Events {
+		notification.Events = append(notification.Events, internalrabbitmq.DigestEvent{
+			ID:    event.ID,
+			Title: event.Title,
+			Begin: event.BeginDate,
+			End:   event.EndDate,
+		})
+	}
+
+	return notification
+}
@@ -4,15 +4,24 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"log"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	_ "github.com/jackc/pgx/stdlib"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	internalclock "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
 	internalconfig "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/config"
+	internalhealth "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/health"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/jobs"
 	internallogger "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/logger"
+	internalmetrics "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/metrics"
 	internalrabbitmq "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/rabbitmq"
 	factorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/factory"
+	sqlstorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/sql"
+	internaltracing "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/tracing"
 )
 
 var configPath string
@@ -29,26 +38,51 @@ func main() {
 		return
 	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGHUP)
-	defer cancel()
-
 	// Config initialization.
 	config, err := internalconfig.NewConfig(configPath)
 	if err != nil {
-		fmt.Println(err)
-		return
+		log.Fatal(err)
 	}
 
 	// Logger initialization.
 	logger := internallogger.New(config)
 
+	// Tracing initialization.
+	tracer, err := internaltracing.NewProvider(context.Background(), config, "calendar_scheduler")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Second*3)
+		defer shutdownCancel()
+		if err := tracer.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// Clock initialization.
+	clk := internalclock.New(config)
+	if !config.GetClockTravelTo().IsZero() {
+		logger.Warn("travelled in time to " + config.GetClockTravelTo().Format(time.RFC3339))
+	}
+
 	// Storage initialization.
-	storage, err := factorystorage.GetStorage(ctx, config)
+	storage, err := factorystorage.GetStorage(ctx, config, clk)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
+	// Reporting failures of the listener of reminder changes.
+	if sqlStorage, ok := storage.(*sqlstorage.Storage); ok {
+		sqlStorage.OnListenError = func(err error) {
+			logger.Error(err.Error())
+		}
+	}
+
 	// RabbitMQ client initialization.
 	rabbitClient, err := internalrabbitmq.NewClient(config, logger)
 	if err != nil {
@@ -64,45 +98,105 @@ func main() {
 		return
 	}
 
-	ticker := time.NewTicker(time.Second)
-	defer ticker.Stop()
+	// Delivery receipts queue initialization, the sender replies to it.
+	receiptsQueue, err := rabbitClient.DeclareReceiptsQueue()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 
+	receipts, err := rabbitClient.ConsumeReceipts(receiptsQueue)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Health server initialization, readiness covers the storage and the broker.
+	checker := internalhealth.New()
+	if pinger, ok := storage.(internalhealth.Pinger); ok {
+		checker.Add("storage", pinger.Ping)
+	}
+	checker.Add("rabbitmq", rabbitClient.Ping)
+
+	healthServer := internalhealth.NewServer(config, checker)
 	go func() {
-		for range ticker.C {
-			// Getting suitable events to be notified.
-			events, err := storage.GetComingEvents(ctx)
-			if err != nil {
-				logger.Error(err.Error())
-			}
+		if err := healthServer.Start(); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
+	defer func() {
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Second*3)
+		defer stopCancel()
+		if err := healthServer.Stop(stopCtx); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
 
-			if len(events) == 0 {
-				continue
-			}
+	// Metrics server initialization.
+	metricsServer := internalmetrics.NewServer(config)
+	go func() {
+		if err := metricsServer.Start(); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
+	defer func() {
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Second*3)
+		defer stopCancel()
+		if err := metricsServer.Stop(stopCtx); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
 
-			for _, event := range events {
-				// Sending event into a queue broker.
-				err = rabbitClient.SendEventNotification(event)
-				if err != nil {
-					logger.Error(err.Error())
-				} else {
-					// If notification has been successfully sent, setting NotificationSent flag.
-					event.NotificationSent = true
-					event, err = storage.UpdateEvent(ctx, event)
-					if err != nil {
-						logger.Error(err.Error())
-					}
-				}
-
-				// Removing expired events.
-				err = storage.RemoveExpiredEvents(ctx)
-				if err != nil {
-					logger.Error(err.Error())
-				}
+	// Jobs initialization.
+	var retention int64
+	atomic.StoreInt64(&retention, int64(config.GetSchedulerRetention()))
+
+	calendar := app.New(logger, storage, clk)
+	scheduler := jobs.New(clk, logger)
+	err = addJobs(scheduler, config, calendar, storage, rabbitClient, logger, func() time.Duration {
+		return time.Duration(atomic.LoadInt64(&retention))
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	healthServer.Handle(jobs.StatusPath, scheduler.StatusHandler())
+
+	// Waking the notify job at the reminder times rather than polling for them, when configured.
+	if config.GetSchedulerWatch() {
+		go calendar.WatchReminderTimes(ctx, config.GetSchedulerHorizon(), func(times []time.Time) {
+			if err := scheduler.WakeAt(jobNotify, times...); err != nil {
+				logger.Error(err.Error())
 			}
+		}, func(err error) {
+			logger.Error(err.Error())
+		})
+	}
+
+	// Reloading the log level, the job schedules and the retention on SIGHUP.
+	go internalconfig.WatchReload(ctx, configPath, func(config *internalconfig.Config) {
+		logger.SetLevel(config.GetLoggerLevel())
+		atomic.StoreInt64(&retention, int64(config.GetSchedulerRetention()))
+		if err := rescheduleJobs(scheduler, config); err != nil {
+			logger.Error(err.Error())
+			return
 		}
+		logger.Info("config reloaded")
+	}, func(err error) {
+		logger.Error(err.Error())
+	})
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.Run(ctx)
+		close(done)
 	}()
 
+	// Recording the delivery receipts, the deliveries are closed along with the broker connection.
+	go consumeReceipts(ctx, calendar, receipts, logger)
+
 	logger.Info("calendar scheduler is running...")
 
 	<-ctx.Done()
+	<-done
 }
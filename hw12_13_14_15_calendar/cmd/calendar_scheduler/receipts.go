@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	internallogger "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/logger"
+	internalmetrics "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/metrics"
+	internalrabbitmq "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/rabbitmq"
+	internaltracing "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ErrMalformedReceipt = errors.New("malformed delivery receipt")
+
+// consumeReceipts records the delivery receipts replied by the sender until the deliveries are closed.
+// A receipt failing on the storage is requeued, the ones which can never be recorded are dropped.
+func consumeReceipts(
+	ctx context.Context,
+	calendar *app.App,
+	deliveries <-chan amqp.Delivery,
+	logger *internallogger.Logger,
+) {
+	for d := range deliveries {
+		// Continuing the trace of the notification.
+		deliveryCtx := internaltracing.Extract(ctx, internalrabbitmq.HeadersCarrier(d.Headers))
+		deliveryCtx, span := internaltracing.Start(
+			deliveryCtx,
+			"scheduler.RecordReceipt",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+		)
+
+		err := recordReceipt(deliveryCtx, calendar, d.Body)
+		switch {
+		case err == nil:
+			err = d.Ack(false)
+		case errors.Is(err, ErrMalformedReceipt),
+			errors.Is(err, app.ErrNotificationNotFound),
+			errors.Is(err, app.ErrNotificationState):
+			logger.Error(err.Error())
+			err = d.Ack(false)
+		default:
+			logger.Error(err.Error())
+			err = d.Nack(false, true)
+		}
+		if err != nil {
+			logger.Error(err.Error())
+		}
+
+		span.End()
+	}
+}
+
+func recordReceipt(ctx context.Context, calendar *app.App, body []byte) error {
+	receipt := internalrabbitmq.Receipt{}
+	if err := json.Unmarshal(body, &receipt); err != nil {
+		internalmetrics.SchedulerReceipts.WithLabelValues(internalmetrics.OutcomeMalformed).Inc()
+		return fmt.Errorf("%w: %s", ErrMalformedReceipt, err.Error())
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("calendar.notification_id", receipt.NotificationID))
+
+	if !receipt.Delivered {
+		internalmetrics.SchedulerReceipts.WithLabelValues(internalmetrics.OutcomeFailed).Inc()
+		_, err := calendar.NotificationFailed(ctx, receipt.NotificationID, receipt.Error)
+
+		return err
+	}
+
+	internalmetrics.SchedulerReceipts.WithLabelValues(internalmetrics.OutcomeDelivered).Inc()
+	_, err := calendar.NotificationDelivered(ctx, receipt.NotificationID, receipt.At)
+
+	return err
+}
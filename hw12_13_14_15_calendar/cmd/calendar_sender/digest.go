@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	internalmetrics "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/metrics"
+	internalrabbitmq "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/rabbitmq"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// SendDigest writes the rendered digest out to a fake recipient.
+func SendDigest(console io.Writer, digest internalrabbitmq.DigestNotification) error {
+	_, err := fmt.Fprintf(console, "Digest for owner %d has been successfully sent:\n%s",
+		digest.OwnerID, RenderDigest(digest))
+	if err != nil {
+		internalmetrics.SenderDeliveries.WithLabelValues(internalmetrics.OutcomeFailed).Inc()
+		return fmt.Errorf("%w: digest for owner %d: %s", ErrNotificationNotSent, digest.OwnerID, err.Error())
+	}
+
+	internalmetrics.SenderDeliveries.WithLabelValues(internalmetrics.OutcomeDelivered).Inc()
+
+	return nil
+}
+
+// RenderDigest renders the digest as plain text in the owner's time zone, grouping the events by day.
+func RenderDigest(digest internalrabbitmq.DigestNotification) string {
+	location, err := time.LoadLocation(digest.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	begin := digest.Begin.In(location)
+	b := &strings.Builder{}
+
+	if digest.Period == storage.DigestWeekly {
+		fmt.Fprintf(b, "Your agenda for the week of %s:\n", begin.Format("Monday, 2 January 2006"))
+	} else {
+		fmt.Fprintf(b, "Your agenda for %s:\n", begin.Format("Monday, 2 January 2006"))
+	}
+
+	if len(digest.Events) == 0 {
+		b.WriteString("  Nothing planned.\n")
+		return b.String()
+	}
+
+	day := ""
+	for _, event := range digest.Events {
+		eventBegin := event.Begin.In(location)
+
+		if digest.Period == storage.DigestWeekly {
+			if eventDay := eventBegin.Format("Monday, 2 January"); eventDay != day {
+				day = eventDay
+				fmt.Fprintf(b, "%s\n", day)
+			}
+		}
+
+		when := eventBegin.Format("15:04")
+		if event.End.After(event.Begin) {
+			when += "-" + event.End.In(location).Format("15:04")
+		}
+
+		fmt.Fprintf(b, "  %-11s %s\n", when, event.Title)
+	}
+
+	return b.String()
+}
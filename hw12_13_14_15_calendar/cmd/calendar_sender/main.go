@@ -2,18 +2,21 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
+	"log"
+	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	_ "github.com/jackc/pgx/stdlib"
-	internalapp "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	internalclock "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
 	internalconfig "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/config"
+	internalhealth "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/health"
 	internallogger "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/logger"
+	internalmetrics "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/metrics"
 	internalrabbitmq "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/rabbitmq"
-	factorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/factory"
+	internaltracing "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/tracing"
 )
 
 var configPath string
@@ -30,25 +33,33 @@ func main() {
 		return
 	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGHUP)
-	defer cancel()
-
 	// Config initialization.
 	config, err := internalconfig.NewConfig(configPath)
 	if err != nil {
-		fmt.Println(err)
-		return
+		log.Fatal(err)
 	}
 
 	// Logger initialization.
 	logger := internallogger.New(config)
 
-	// Storage initialization.
-	storage, err := factorystorage.GetStorage(ctx, config)
+	// Tracing initialization.
+	tracer, err := internaltracing.NewProvider(context.Background(), config, "calendar_sender")
 	if err != nil {
-		fmt.Println(err)
-		return
+		log.Fatal(err)
 	}
+	defer func() {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Second*3)
+		defer shutdownCancel()
+		if err := tracer.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// Clock initialization.
+	clk := internalclock.New(config)
 
 	// RabbitMQ client initialization.
 	rabbitClient, err := internalrabbitmq.NewClient(config, logger)
@@ -86,38 +97,51 @@ func main() {
 		return
 	}
 
-	// Getting notifications.
+	// Health server initialization, readiness covers the broker.
+	checker := internalhealth.New()
+	checker.Add("rabbitmq", rabbitClient.Ping)
+
+	healthServer := internalhealth.NewServer(config, checker)
 	go func() {
-		for d := range messages {
-			notification := internalrabbitmq.Notification{}
-			err := json.Unmarshal(d.Body, &notification)
-			if err != nil {
-				logger.Error(err.Error())
-			} else {
-				// Fake sending for receiving notification.
-				SendNotification(ctx, notification, storage, logger)
-			}
+		if err := healthServer.Start(); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
+	defer func() {
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Second*3)
+		defer stopCancel()
+		if err := healthServer.Stop(stopCtx); err != nil {
+			logger.Error(err.Error())
 		}
 	}()
 
-	logger.Info("calendar sender is running...")
-
-	<-ctx.Done()
-}
+	// Metrics server initialization.
+	metricsServer := internalmetrics.NewServer(config)
+	go func() {
+		if err := metricsServer.Start(); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
+	defer func() {
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Second*3)
+		defer stopCancel()
+		if err := metricsServer.Stop(stopCtx); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
 
-// SendNotification sends notification to a fake recipient.
-func SendNotification(ctx context.Context, notification internalrabbitmq.Notification, storage internalapp.Storage, logger *internallogger.Logger) {
-	fmt.Printf("Notification %v has been successfully sent\n", notification)
+	// Getting notifications.
+	go consumeNotifications(ctx, messages, config.GetConsumeAutoAck(), os.Stdout, rabbitClient, clk, logger)
 
-	// If notification has been successfully received, setting NotificationReceived flag.
-	event, err := storage.GetEventByID(ctx, notification.ID)
-	if err != nil {
+	// Reloading the log level on SIGHUP.
+	go internalconfig.WatchReload(ctx, configPath, func(config *internalconfig.Config) {
+		logger.SetLevel(config.GetLoggerLevel())
+		logger.Info("config reloaded")
+	}, func(err error) {
 		logger.Error(err.Error())
-	} else {
-		event.NotificationReceived = true
-		event, err = storage.UpdateEvent(ctx, event)
-		if err != nil {
-			logger.Error(err.Error())
-		}
-	}
+	})
+
+	logger.Info("calendar sender is running...")
+
+	<-ctx.Done()
 }
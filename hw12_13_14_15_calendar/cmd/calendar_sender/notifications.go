@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	internalclock "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	internallogger "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/logger"
+	internalmetrics "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/metrics"
+	internalrabbitmq "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/rabbitmq"
+	internaltracing "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrMalformedNotification = errors.New("malformed notification")
+	ErrNotificationNotSent   = errors.New("notification not sent")
+	ErrSendReceipt           = errors.New("unable to send delivery receipt")
+)
+
+// ReceiptSender replies the delivery receipts to the scheduler.
+type ReceiptSender interface {
+	SendReceipt(ctx context.Context, replyTo string, receipt internalrabbitmq.Receipt) error
+}
+
+// consumeNotifications sends the notifications and digests to the console until the deliveries are closed.
+// Unless the broker acknowledges them on delivery, a message is acknowledged once handled: requeued when
+// its receipt could not be replied, so that it is sent again, and dropped when malformed.
+func consumeNotifications(
+	ctx context.Context,
+	deliveries <-chan amqp.Delivery,
+	autoAck bool,
+	console io.Writer,
+	receipts ReceiptSender,
+	clk internalclock.Clock,
+	logger *internallogger.Logger,
+) {
+	for d := range deliveries {
+		if !d.Timestamp.IsZero() {
+			internalmetrics.SenderConsumeLag.Observe(time.Since(d.Timestamp).Seconds())
+		}
+
+		// Continuing the trace started by the scheduler.
+		deliveryCtx := internaltracing.Extract(ctx, internalrabbitmq.HeadersCarrier(d.Headers))
+		deliveryCtx, span := internaltracing.Start(
+			deliveryCtx,
+			"sender.SendNotification",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+		)
+
+		err := sendDelivery(deliveryCtx, d, console, receipts, clk)
+		if err != nil {
+			logger.Error(err.Error())
+		}
+
+		if !autoAck {
+			if err := acknowledge(d, err); err != nil {
+				logger.Error(err.Error())
+			}
+		}
+
+		span.End()
+	}
+}
+
+// acknowledge settles the message by the outcome of sending it.
+func acknowledge(d amqp.Delivery, err error) error {
+	switch {
+	case err == nil, errors.Is(err, ErrNotificationNotSent):
+		// A failed delivery is reported by its receipt, the scheduler sends the reminder again.
+		return d.Ack(false)
+	case errors.Is(err, ErrMalformedNotification):
+		return d.Reject(false)
+	default:
+		return d.Nack(false, true)
+	}
+}
+
+// sendDelivery sends the digest or the notification the message holds.
+func sendDelivery(
+	ctx context.Context,
+	d amqp.Delivery,
+	console io.Writer,
+	receipts ReceiptSender,
+	clk internalclock.Clock,
+) error {
+	span := trace.SpanFromContext(ctx)
+
+	switch d.Type {
+	case internalrabbitmq.TypeDigest:
+		digest := internalrabbitmq.DigestNotification{}
+		if err := json.Unmarshal(d.Body, &digest); err != nil {
+			internalmetrics.SenderDeliveries.WithLabelValues(internalmetrics.OutcomeMalformed).Inc()
+			return fmt.Errorf("%w: %s", ErrMalformedNotification, err.Error())
+		}
+
+		span.SetAttributes(attribute.Int64("calendar.owner_id", digest.OwnerID))
+
+		return SendDigest(console, digest)
+	default:
+		notification := internalrabbitmq.Notification{}
+		if err := json.Unmarshal(d.Body, &notification); err != nil {
+			internalmetrics.SenderDeliveries.WithLabelValues(internalmetrics.OutcomeMalformed).Inc()
+			return fmt.Errorf("%w: %s", ErrMalformedNotification, err.Error())
+		}
+
+		span.SetAttributes(
+			attribute.Int64("calendar.event_id", notification.ID),
+			attribute.Int64("calendar.reminder_id", notification.ReminderID),
+			attribute.Int64("calendar.notification_id", notification.NotificationID),
+		)
+
+		return SendNotification(ctx, console, notification, d.ReplyTo, receipts, clk)
+	}
+}
+
+// SendNotification writes the notification out to a fake recipient and replies with a delivery receipt,
+// telling whether the write succeeded. Notifications published without a record to report to get no receipt.
+func SendNotification(
+	ctx context.Context,
+	console io.Writer,
+	notification internalrabbitmq.Notification,
+	replyTo string,
+	receipts ReceiptSender,
+	clk internalclock.Clock,
+) error {
+	var sendErr error
+	if _, err := fmt.Fprintf(console, "Notification %v has been successfully sent\n", notification); err != nil {
+		sendErr = fmt.Errorf("%w: %d: %s", ErrNotificationNotSent, notification.NotificationID, err.Error())
+	}
+
+	receipt := internalrabbitmq.Receipt{
+		NotificationID: notification.NotificationID,
+		Delivered:      sendErr == nil,
+		At:             clk.Now(),
+	}
+	if sendErr != nil {
+		internalmetrics.SenderDeliveries.WithLabelValues(internalmetrics.OutcomeFailed).Inc()
+		receipt.Error = sendErr.Error()
+	} else {
+		internalmetrics.SenderDeliveries.WithLabelValues(internalmetrics.OutcomeDelivered).Inc()
+	}
+
+	if replyTo == "" || notification.NotificationID == 0 {
+		return sendErr
+	}
+
+	if err := receipts.SendReceipt(ctx, replyTo, receipt); err != nil {
+		return fmt.Errorf("%w: %d: %s", ErrSendReceipt, notification.NotificationID, err.Error())
+	}
+
+	return sendErr
+}
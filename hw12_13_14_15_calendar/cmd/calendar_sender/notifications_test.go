@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	internalrabbitmq "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/rabbitmq"
+	"github.com/stretchr/testify/require"
+)
+
+var errBroken = errors.New("broken")
+
+type brokenWriter struct{}
+
+func (brokenWriter) Write([]byte) (int, error) {
+	return 0, errBroken
+}
+
+// receiptsRecorder keeps the receipts it is sent, failing them all if told so.
+type receiptsRecorder struct {
+	receipts []internalrabbitmq.Receipt
+	err      error
+}
+
+func (r *receiptsRecorder) SendReceipt(_ context.Context, _ string, receipt internalrabbitmq.Receipt) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.receipts = append(r.receipts, receipt)
+
+	return nil
+}
+
+// settlement records how a message is settled.
+type settlement struct {
+	acked, rejected, requeued bool
+}
+
+func (s *settlement) Ack(uint64, bool) error {
+	s.acked = true
+	return nil
+}
+
+func (s *settlement) Nack(_ uint64, _ bool, requeue bool) error {
+	s.requeued = requeue
+	return nil
+}
+
+func (s *settlement) Reject(_ uint64, requeue bool) error {
+	s.rejected = !requeue
+	return nil
+}
+
+func TestSendNotification(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2021, time.November, 2, 9, 0, 0, 0, time.UTC))
+	notification := internalrabbitmq.Notification{NotificationID: 7}
+
+	t.Run("delivered", func(t *testing.T) {
+		console := &bytes.Buffer{}
+		receipts := &receiptsRecorder{}
+
+		require.NoError(t, SendNotification(ctx, console, notification, "receipts", receipts, clk))
+		require.Contains(t, console.String(), "has been successfully sent")
+		require.Equal(t, []internalrabbitmq.Receipt{{NotificationID: 7, Delivered: true, At: clk.Now()}}, receipts.receipts)
+	})
+
+	t.Run("failed write", func(t *testing.T) {
+		receipts := &receiptsRecorder{}
+
+		err := SendNotification(ctx, brokenWriter{}, notification, "receipts", receipts, clk)
+		require.ErrorIs(t, err, ErrNotificationNotSent)
+		require.Len(t, receipts.receipts, 1)
+		require.False(t, receipts.receipts[0].Delivered)
+		require.Contains(t, receipts.receipts[0].Error, errBroken.Error())
+	})
+
+	t.Run("failed receipt", func(t *testing.T) {
+		err := SendNotification(ctx, &bytes.Buffer{}, notification, "receipts", &receiptsRecorder{err: errBroken}, clk)
+		require.ErrorIs(t, err, ErrSendReceipt)
+	})
+
+	t.Run("settlement", func(t *testing.T) {
+		for _, tc := range []struct {
+			name     string
+			err      error
+			expected settlement
+		}{
+			{name: "sent", expected: settlement{acked: true}},
+			{name: "reported failure", err: ErrNotificationNotSent, expected: settlement{acked: true}},
+			{name: "malformed", err: ErrMalformedNotification, expected: settlement{rejected: true}},
+			{name: "lost receipt", err: ErrSendReceipt, expected: settlement{requeued: true}},
+		} {
+			settled := &settlement{}
+			require.NoError(t, acknowledge(amqp.Delivery{Acknowledger: settled}, tc.err), tc.name)
+			require.Equal(t, tc.expected, *settled, tc.name)
+		}
+	})
+}
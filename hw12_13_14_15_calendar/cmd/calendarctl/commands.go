@@ -0,0 +1,583 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/ical"
+	internalgrpc "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc"
+	pb "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc/eventpb"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// listPageSize is the page size of listing events of a range.
+const listPageSize = 100
+
+var (
+	ErrUsage        = errors.New("invalid arguments")
+	ErrUnknownInput = errors.New("unknown file format, use .ics or .json")
+	ErrImport       = errors.New("import failed")
+)
+
+// session is what the commands run with.
+type session struct {
+	client pb.CalendarClient
+	out    io.Writer
+	format string
+}
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, s *session, args []string) error
+}
+
+// commands are set up on init, as their flag sets refer back to the usages.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"create": {"create -title T -begin TIME -end TIME [-description D] [-owner ID] [-calendar ID] [-category ID] [-tags T1,T2]", runCreate},
+		"update": {"update -id ID [-title T] [-begin TIME] [-end TIME] [-description D] [-owner ID] [-calendar ID] [-category ID] [-tags T1,T2]", runUpdate},
+		"delete": {"delete -id ID", runDelete},
+		"show":   {"show -id ID", runShow},
+		"list":   {"list day|week|month [FILTERS] | list -begin TIME -end TIME [FILTERS], FILTERS being [-calendars ID1,ID2] [-category ID] [-tags T1,T2]", runList},
+		"export": {"export -begin TIME -end TIME [-file PATH]", runExport},
+		"import": {"import -file PATH [-owner ID] [-best-effort]", runImport},
+		"watch":  {"watch [-owner ID] [-since REVISION]", runWatch},
+	}
+}
+
+// eventFlags are the event fields settable from the command line.
+type eventFlags struct {
+	title       string
+	begin       string
+	end         string
+	description string
+	owner       int64
+	calendar    int64
+	category    int64
+	tags        string
+}
+
+func (f *eventFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.title, "title", "", "Event title")
+	flags.StringVar(&f.begin, "begin", "", "Event start, RFC 3339 or \"2006-01-02 15:04\" local time")
+	flags.StringVar(&f.end, "end", "", "Event end, RFC 3339 or \"2006-01-02 15:04\" local time")
+	flags.StringVar(&f.description, "description", "", "Event description")
+	flags.Int64Var(&f.owner, "owner", 0, "Owner id, the authenticated one by default")
+	flags.Int64Var(&f.calendar, "calendar", 0, "Calendar id, the default one of the owner by default")
+	flags.Int64Var(&f.category, "category", 0, "Category id, 0 for none")
+	flags.StringVar(&f.tags, "tags", "", "Comma separated tags, empty for none")
+}
+
+func (f *eventFlags) event() (*pb.Event, error) {
+	event := &pb.Event{
+		Title:       f.title,
+		Description: f.description,
+		OwnerId:     f.owner,
+		CalendarId:  f.calendar,
+		CategoryId:  f.category,
+		Tags:        splitTags(f.tags),
+	}
+
+	if f.begin != "" {
+		begin, err := parseTime(f.begin)
+		if err != nil {
+			return nil, err
+		}
+		event.BeginDate = timestamppb.New(begin)
+	}
+
+	if f.end != "" {
+		end, err := parseTime(f.end)
+		if err != nil {
+			return nil, err
+		}
+		event.EndDate = timestamppb.New(end)
+	}
+
+	return event, nil
+}
+
+// flagPaths maps the command line flags onto the event field mask paths.
+var flagPaths = map[string]string{
+	"title":       "title",
+	"begin":       "begin_date",
+	"end":         "end_date",
+	"description": "description",
+	"owner":       "owner_id",
+	"calendar":    "calendar_id",
+	"category":    "category_id",
+	"tags":        "tags",
+}
+
+// listFilter is the calendars, category and tags filter of the commands listing, exporting and watching events.
+type listFilter struct {
+	calendars string
+	category  int64
+	tags      string
+}
+
+func (f *listFilter) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.calendars, "calendars", "", "Only the events of the comma separated calendar ids")
+	flags.Int64Var(&f.category, "category", 0, "Only the events of the category id")
+	flags.StringVar(&f.tags, "tags", "", "Only the events having all of the comma separated tags")
+}
+
+// calendarIDs parses the comma separated calendar ids.
+func (f *listFilter) calendarIDs() ([]int64, error) {
+	var ids []int64
+	for _, value := range strings.Split(f.calendars, ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: -calendars: %s", ErrUsage, err.Error())
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// splitTags parses a comma separated list of tags.
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: calendarctl %s\n", commands[name].usage)
+		flags.PrintDefaults()
+	}
+
+	return flags
+}
+
+func runCreate(ctx context.Context, s *session, args []string) error {
+	flags := newFlagSet("create")
+	fields := &eventFlags{}
+	fields.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if fields.title == "" || fields.begin == "" || fields.end == "" {
+		return fmt.Errorf("%w: -title, -begin and -end are required", ErrUsage)
+	}
+
+	event, err := fields.event()
+	if err != nil {
+		return err
+	}
+
+	response, err := s.client.CreateEvent(ctx, &pb.CreateEventRequest{Event: event})
+	if err != nil {
+		return err
+	}
+
+	return printEvent(s.out, s.format, response.Event)
+}
+
+func runUpdate(ctx context.Context, s *session, args []string) error {
+	flags := newFlagSet("update")
+	id := flags.Int64("id", 0, "Event id")
+	fields := &eventFlags{}
+	fields.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// Only the given flags are changed.
+	mask := &fieldmaskpb.FieldMask{}
+	flags.Visit(func(f *flag.Flag) {
+		if path, ok := flagPaths[f.Name]; ok {
+			mask.Paths = append(mask.Paths, path)
+		}
+	})
+
+	if *id <= 0 || len(mask.Paths) == 0 {
+		return fmt.Errorf("%w: -id and at least a field to change are required", ErrUsage)
+	}
+
+	event, err := fields.event()
+	if err != nil {
+		return err
+	}
+	event.Id = *id
+
+	response, err := s.client.UpdateEvent(ctx, &pb.UpdateEventRequest{Event: event, UpdateMask: mask})
+	if err != nil {
+		return err
+	}
+
+	return printEvent(s.out, s.format, response.Event)
+}
+
+func runDelete(ctx context.Context, s *session, args []string) error {
+	flags := newFlagSet("delete")
+	id := flags.Int64("id", 0, "Event id")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *id <= 0 {
+		return fmt.Errorf("%w: -id is required", ErrUsage)
+	}
+
+	if _, err := s.client.RemoveEvent(ctx, &pb.RemoveEventRequest{Id: *id}); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(s.out, "event %d deleted\n", *id)
+
+	return nil
+}
+
+func runShow(ctx context.Context, s *session, args []string) error {
+	flags := newFlagSet("show")
+	id := flags.Int64("id", 0, "Event id")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *id <= 0 {
+		return fmt.Errorf("%w: -id is required", ErrUsage)
+	}
+
+	response, err := s.client.GetEvent(ctx, &pb.GetEventRequest{Id: *id})
+	if err != nil {
+		return err
+	}
+
+	return printEvent(s.out, s.format, response.Event)
+}
+
+// parseRange reads the -begin and -end flags of the range commands.
+func parseRange(flags *flag.FlagSet, args []string) (time.Time, time.Time, error) {
+	beginFlag := flags.String("begin", "", "Range start, RFC 3339 or \"2006-01-02 15:04\" local time")
+	endFlag := flags.String("end", "", "Range end, RFC 3339 or \"2006-01-02 15:04\" local time")
+	if err := flags.Parse(args); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if *beginFlag == "" || *endFlag == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: -begin and -end are required", ErrUsage)
+	}
+
+	begin, err := parseTime(*beginFlag)
+	if err != nil {
+		return begin, time.Time{}, err
+	}
+
+	end, err := parseTime(*endFlag)
+
+	return begin, end, err
+}
+
+// listRange fetches all the pages of the events in the range.
+func listRange(ctx context.Context, client pb.CalendarClient, begin, end time.Time, filter listFilter) ([]storage.Event, error) {
+	calendarIDs, err := filter.calendarIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	request := &pb.ListEventsRequest{
+		BeginDate:   timestamppb.New(begin),
+		EndDate:     timestamppb.New(end),
+		PageSize:    listPageSize,
+		CategoryId:  filter.category,
+		Tags:        splitTags(filter.tags),
+		CalendarIds: calendarIDs,
+	}
+
+	var events []storage.Event
+	for {
+		response, err := client.ListEvents(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		page, err := eventsFromPb(response.Items)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, page...)
+
+		if response.NextPageToken == "" {
+			return events, nil
+		}
+		request.PageToken = response.NextPageToken
+	}
+}
+
+func runList(ctx context.Context, s *session, args []string) error {
+	var items []*pb.Event
+
+	flags := newFlagSet("list")
+	filter := listFilter{}
+	filter.register(flags)
+
+	period := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		period, args = args[0], args[1:]
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if flags.NArg() > 0 {
+			return fmt.Errorf("%w: unexpected %q", ErrUsage, flags.Arg(0))
+		}
+	}
+	calendarIDs, err := filter.calendarIDs()
+	if err != nil {
+		return err
+	}
+	categoryID, tags := filter.category, splitTags(filter.tags)
+
+	switch period {
+	case "day":
+		response, err := s.client.GetDayAheadEvents(ctx, &pb.GetDayAheadEventsRequest{
+			CalendarIds: calendarIDs, CategoryId: categoryID, Tags: tags,
+		})
+		if err != nil {
+			return err
+		}
+		items = response.Items
+	case "week":
+		response, err := s.client.GetWeekAheadEvents(ctx, &pb.GetWeekAheadEventsRequest{
+			CalendarIds: calendarIDs, CategoryId: categoryID, Tags: tags,
+		})
+		if err != nil {
+			return err
+		}
+		items = response.Items
+	case "month":
+		response, err := s.client.GetMonthAheadEvents(ctx, &pb.GetMonthAheadEventsRequest{
+			CalendarIds: calendarIDs, CategoryId: categoryID, Tags: tags,
+		})
+		if err != nil {
+			return err
+		}
+		items = response.Items
+	case "":
+		begin, end, err := parseRange(flags, args)
+		if err != nil {
+			return err
+		}
+
+		events, err := listRange(ctx, s.client, begin, end, filter)
+		if err != nil {
+			return err
+		}
+
+		return printEvents(s.out, s.format, events)
+	default:
+		return fmt.Errorf("%w: unknown period %q", ErrUsage, period)
+	}
+
+	events, err := eventsFromPb(items)
+	if err != nil {
+		return err
+	}
+
+	return printEvents(s.out, s.format, events)
+}
+
+// fileFormat picks the format of a file by its extension.
+func fileFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ics", ".ical":
+		return FormatICS, nil
+	case ".json":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnknownInput, path)
+	}
+}
+
+func runExport(ctx context.Context, s *session, args []string) error {
+	flags := newFlagSet("export")
+	file := flags.String("file", "", "Output file, .ics or .json, the standard output by default")
+	filter := listFilter{}
+	filter.register(flags)
+	begin, end, err := parseRange(flags, args)
+	if err != nil {
+		return err
+	}
+
+	out, format := s.out, s.format
+	if format == FormatTable {
+		format = FormatICS
+	}
+
+	if *file != "" {
+		if format, err = fileFormat(*file); err != nil {
+			return err
+		}
+
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	events, err := listRange(ctx, s.client, begin, end, filter)
+	if err != nil {
+		return err
+	}
+
+	return printEvents(out, format, events)
+}
+
+func runImport(ctx context.Context, s *session, args []string) error {
+	flags := newFlagSet("import")
+	file := flags.String("file", "", "Input file, .ics or .json")
+	owner := flags.Int64("owner", 0, "Owner of the imported events, the one of the file or the authenticated one by default")
+	bestEffort := flags.Bool("best-effort", false, "Import the acceptable events and skip the others, all or nothing by default")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return fmt.Errorf("%w: -file is required", ErrUsage)
+	}
+
+	format, err := fileFormat(*file)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var events []storage.Event
+	if format == FormatICS {
+		events, err = ical.Decode(f)
+	} else {
+		err = json.NewDecoder(f).Decode(&events)
+	}
+	if err != nil {
+		return err
+	}
+
+	mode := pb.BatchMode_ALL_OR_NOTHING
+	if *bestEffort {
+		mode = pb.BatchMode_BEST_EFFORT
+	}
+
+	stream, err := s.client.ImportEvents(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Imported events get new ids. A failed send means the server has ended the call,
+	// its status is returned by CloseAndRecv.
+	for _, event := range events {
+		event.ID = 0
+		if *owner != 0 {
+			event.OwnerID = *owner
+		}
+
+		if err := stream.Send(&pb.ImportEventsRequest{Mode: mode, Event: internalgrpc.EventToPb(event)}); err != nil {
+			break
+		}
+	}
+
+	response, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+
+	created := make([]*pb.Event, 0, len(response.Results))
+	var failures []string
+	for _, result := range response.Results {
+		if result.Ok {
+			created = append(created, result.Event)
+		} else if result.Code != int32(codes.Aborted) {
+			failures = append(failures, fmt.Sprintf("%q: %s", events[result.Index].Title, result.Message))
+		}
+	}
+
+	if len(created) > 0 {
+		events, err := eventsFromPb(created)
+		if err != nil {
+			return err
+		}
+		if err := printEvents(s.out, s.format, events); err != nil {
+			return err
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%w: %s", ErrImport, strings.Join(failures, "; "))
+	}
+
+	return nil
+}
+
+func runWatch(ctx context.Context, s *session, args []string) error {
+	flags := newFlagSet("watch")
+	owner := flags.Int64("owner", 0, "Owner id, the authenticated one by default")
+	since := flags.Int64("since", 0, "Revision to resume after, changes from now on by default")
+	filter := listFilter{}
+	filter.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	calendarIDs, err := filter.calendarIDs()
+	if err != nil {
+		return err
+	}
+
+	stream, err := s.client.WatchEvents(ctx, &pb.WatchEventsRequest{
+		OwnerId:     *owner,
+		Revision:    *since,
+		CategoryId:  filter.category,
+		Tags:        splitTags(filter.tags),
+		CalendarIds: calendarIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		change, err := stream.Recv()
+		if errors.Is(err, io.EOF) || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := printChange(s.out, s.format, change); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	pb "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc/eventpb"
+)
+
+// dialTimeout bounds connecting to the server.
+const dialTimeout = 5 * time.Second
+
+var (
+	profileFile string
+	profileName string
+	address     string
+	token       string
+	format      string
+)
+
+func init() {
+	flag.StringVar(&profileFile, "profile-file", defaultProfileFile(), "Path to the profiles file")
+	flag.StringVar(&profileName, "profile", "default", "Profile to connect with")
+	flag.StringVar(&address, "address", "", "Server address, overrides the profile one")
+	flag.StringVar(&token, "token", "", "API token or JWT, overrides the profile one")
+	flag.StringVar(&format, "o", FormatTable, "Output format: table, json or ics")
+
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintln(out, "usage: calendarctl [flags] <command> [command flags]")
+		fmt.Fprintln(out, "\ncommands:")
+
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(out, "  %s\n", commands[name].usage)
+		}
+		fmt.Fprintln(out, "  version")
+
+		fmt.Fprintln(out, "\nflags:")
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	if flag.Arg(0) == "version" {
+		printVersion()
+		return
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(cmd, flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(cmd command, args []string) error {
+	profile, err := loadProfile(profileFile, profileName)
+	if err != nil {
+		return err
+	}
+	if address != "" {
+		profile.Address = address
+	}
+	if token != "" {
+		profile.Token = token
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	dialCtx, dialCancel := context.WithTimeout(ctx, dialTimeout)
+	defer dialCancel()
+
+	conn, err := dial(dialCtx, profile)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return cmd.run(ctx, &session{
+		client: pb.NewCalendarClient(conn),
+		out:    os.Stdout,
+		format: format,
+	}, args)
+}
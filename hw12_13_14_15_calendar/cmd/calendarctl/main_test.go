@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	internalgrpc "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc"
+	pb "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc/eventpb"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestSession serves the calendar over an in-memory connection, the output is collected in the buffer.
+func newTestSession(t *testing.T, format string) (*session, *bytes.Buffer) {
+	t.Helper()
+
+	calendar := app.New(nil, memorystorage.New(clock.Real{}), clock.Real{})
+	listener := bufconn.Listen(1024 * 1024)
+	// Calls are internal, as to a server without authentication.
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(
+		ctx context.Context,
+		request interface{},
+		_ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return handler(app.ContextInternal(ctx), request)
+	}))
+	pb.RegisterCalendarServer(server, internalgrpc.NewService(calendar, nil))
+
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	out := &bytes.Buffer{}
+
+	return &session{client: pb.NewCalendarClient(conn), out: out, format: format}, out
+}
+
+func TestCommands(t *testing.T) {
+	ctx := context.Background()
+	s, out := newTestSession(t, FormatJSON)
+	begin := time.Now().Add(time.Hour).UTC().Truncate(time.Minute)
+
+	err := runCreate(ctx, s, []string{
+		"-title", "Planning",
+		"-begin", begin.Format(time.RFC3339),
+		"-end", begin.Add(time.Hour).Format(time.RFC3339),
+		"-owner", "7",
+		"-tags", "work, weekly",
+	})
+	require.NoError(t, err)
+
+	created := storage.Event{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &created))
+	require.Equal(t, "Planning", created.Title)
+	require.ElementsMatch(t, []string{"work", "weekly"}, created.Tags)
+	require.True(t, begin.Equal(created.BeginDate))
+
+	t.Run("update", func(t *testing.T) {
+		out.Reset()
+		require.NoError(t, runUpdate(ctx, s, []string{"-id", "1", "-description", "agenda"}))
+
+		updated := storage.Event{}
+		require.NoError(t, json.Unmarshal(out.Bytes(), &updated))
+		require.Equal(t, "Planning", updated.Title)
+		require.Equal(t, "agenda", updated.Description)
+
+		require.ErrorIs(t, runUpdate(ctx, s, []string{"-id", "1"}), ErrUsage)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		out.Reset()
+		require.NoError(t, runList(ctx, s, []string{"day", "-tags", "work"}))
+
+		events := []storage.Event{}
+		require.NoError(t, json.Unmarshal(out.Bytes(), &events))
+		require.Len(t, events, 1)
+
+		out.Reset()
+		require.NoError(t, runList(ctx, s, []string{"day", "-tags", "other"}))
+		require.NoError(t, json.Unmarshal(out.Bytes(), &events))
+		require.Empty(t, events)
+
+		require.ErrorIs(t, runList(ctx, s, []string{"year"}), ErrUsage)
+	})
+
+	t.Run("export and import", func(t *testing.T) {
+		out.Reset()
+		err := runExport(ctx, s, []string{
+			"-begin", begin.Add(-time.Hour).Format(time.RFC3339),
+			"-end", begin.Add(24 * time.Hour).Format(time.RFC3339),
+			"-tags", "other",
+		})
+		require.NoError(t, err)
+		require.NotContains(t, out.String(), "Planning")
+
+		file := filepath.Join(t.TempDir(), "events.ics")
+		err = runExport(ctx, s, []string{
+			"-begin", begin.Add(-time.Hour).Format(time.RFC3339),
+			"-end", begin.Add(24 * time.Hour).Format(time.RFC3339),
+			"-file", file,
+		})
+		require.NoError(t, err)
+
+		b, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Contains(t, string(b), "SUMMARY:Planning")
+
+		require.NoError(t, runDelete(ctx, s, []string{"-id", "1"}))
+		out.Reset()
+		require.NoError(t, runImport(ctx, s, []string{"-file", file, "-owner", "8"}))
+
+		imported := []storage.Event{}
+		require.NoError(t, json.Unmarshal(out.Bytes(), &imported))
+		require.Len(t, imported, 1)
+		require.Equal(t, int64(8), imported[0].OwnerID)
+	})
+}
+
+func TestPrintChange(t *testing.T) {
+	begin := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+	change := &pb.EventChange{
+		Revision: 5,
+		Type:     pb.ChangeType_DELETED,
+		Event:    internalgrpc.EventToPb(storage.Event{ID: 3, Title: "Planning", BeginDate: begin, EndDate: begin}),
+	}
+
+	out := &bytes.Buffer{}
+	require.NoError(t, printChange(out, FormatICS, change))
+	require.True(t, strings.HasPrefix(out.String(), "BEGIN:VCALENDAR\r\n"))
+	require.Contains(t, out.String(), "METHOD:CANCEL\r\n")
+	require.Contains(t, out.String(), "SEQUENCE:5\r\n")
+
+	out.Reset()
+	require.NoError(t, printChange(out, FormatTable, change))
+	require.True(t, strings.HasPrefix(out.String(), "5\tDELETED\t3\t"))
+
+	require.ErrorIs(t, printChange(out, "yaml", change), ErrUnknownFormat)
+}
+
+func TestParseTime(t *testing.T) {
+	parsed, err := parseTime("2021-12-01T10:00:00Z")
+	require.NoError(t, err)
+	require.True(t, time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC).Equal(parsed))
+
+	parsed, err = parseTime("2021-12-01 10:00")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2021, 12, 1, 10, 0, 0, 0, time.Local), parsed)
+
+	_, err = parseTime("tomorrow")
+	require.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/ical"
+	internalgrpc "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc"
+	pb "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc/eventpb"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Output formats.
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatICS   = "ics"
+)
+
+// timeLayout is how the table shows event dates, in local time.
+const timeLayout = "2006-01-02 15:04"
+
+var ErrUnknownFormat = errors.New("unknown output format, use table, json or ics")
+
+// eventsFromPb converts the events of a response, with the converter of the server.
+func eventsFromPb(pbEvents []*pb.Event) ([]storage.Event, error) {
+	events := make([]storage.Event, 0, len(pbEvents))
+	for _, pbEvent := range pbEvents {
+		event, err := internalgrpc.EventFromPb(pbEvent)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// printEvents writes the events in the given format.
+func printEvents(w io.Writer, format string, events []storage.Event) error {
+	switch format {
+	case FormatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tBEGIN\tEND\tOWNER\tTITLE\tDESCRIPTION")
+		for _, event := range events {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\n",
+				event.ID,
+				event.BeginDate.Local().Format(timeLayout),
+				event.EndDate.Local().Format(timeLayout),
+				event.OwnerID,
+				event.Title,
+				oneLine(event.Description),
+			)
+		}
+		return tw.Flush()
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(events)
+	case FormatICS:
+		return ical.Encode(w, events)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// printEvent writes a single event, as an object rather than a list in json.
+func printEvent(w io.Writer, format string, pbEvent *pb.Event) error {
+	event, err := internalgrpc.EventFromPb(pbEvent)
+	if err != nil {
+		return err
+	}
+
+	if format == FormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(event)
+	}
+
+	return printEvents(w, format, []storage.Event{event})
+}
+
+// printChange writes a single change of a watched stream: a line each in the table and json formats,
+// an iCalendar object each in the ics one.
+func printChange(w io.Writer, format string, pbChange *pb.EventChange) error {
+	change, err := internalgrpc.ChangeFromPb(pbChange)
+	if err != nil {
+		return err
+	}
+	event := change.Event
+
+	switch format {
+	case FormatJSON:
+		return json.NewEncoder(w).Encode(struct {
+			Revision int64         `json:"revision"`
+			Type     string        `json:"type"`
+			Event    storage.Event `json:"event"`
+		}{change.Revision, pbChange.Type.String(), event})
+	case FormatTable:
+		_, err := fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\n",
+			change.Revision,
+			pbChange.Type,
+			event.ID,
+			event.BeginDate.Local().Format(timeLayout),
+			event.EndDate.Local().Format(timeLayout),
+			event.Title,
+		)
+		return err
+	case FormatICS:
+		return ical.EncodeChange(w, change)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// parseTime reads RFC 3339 timestamps, as well as local "2006-01-02 15:04" and "2006-01-02" ones.
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, timeLayout, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q, use RFC 3339 or %q", value, timeLayout)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+var (
+	ErrProfileRead = errors.New("unable to read profile file")
+	ErrNoProfile   = errors.New("profile not found")
+	ErrDial        = errors.New("unable to connect to calendar")
+)
+
+// Profile tells how to reach a calendar server and on whose behalf.
+type Profile struct {
+	Address  string
+	Token    string
+	Insecure bool
+	CAFile   string
+}
+
+// defaultProfileFile returns the per-user profile file path.
+func defaultProfileFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "calendarctl.toml"
+	}
+
+	return filepath.Join(dir, "calendarctl", "profiles.toml")
+}
+
+// loadProfile reads the named profile of the file. A missing file gives a local plaintext profile.
+// Profiles are toml tables:
+//
+//	[default]
+//	address = "calendar.example.com:50051"
+//	token = "cal_..."
+//	insecure = false
+//	caFile = "/etc/ssl/calendar-ca.pem"
+func loadProfile(path, name string) (Profile, error) {
+	profile := Profile{Address: "localhost:50051", Insecure: true}
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return profile, nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("toml")
+	if err := v.ReadInConfig(); err != nil {
+		return profile, fmt.Errorf("%w: %s: %s", ErrProfileRead, path, err.Error())
+	}
+
+	if !v.IsSet(name) {
+		return profile, fmt.Errorf("%w: %q in %s", ErrNoProfile, name, path)
+	}
+
+	profile.Address = v.GetString(name + ".address")
+	profile.Token = v.GetString(name + ".token")
+	profile.Insecure = v.GetBool(name + ".insecure")
+	profile.CAFile = v.GetString(name + ".caFile")
+
+	return profile, nil
+}
+
+// tokenCredentials sends the profile token as a bearer authorization header.
+type tokenCredentials struct {
+	token    string
+	insecure bool
+}
+
+func (c tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + c.token}, nil
+}
+
+// RequireTransportSecurity lets plaintext profiles send their tokens, meant for local servers.
+func (c tokenCredentials) RequireTransportSecurity() bool {
+	return !c.insecure
+}
+
+// dial connects to the server of the profile.
+func dial(ctx context.Context, profile Profile) (*grpc.ClientConn, error) {
+	options := []grpc.DialOption{grpc.WithBlock()}
+
+	if profile.Insecure {
+		options = append(options, grpc.WithInsecure())
+	} else {
+		config := &tls.Config{MinVersion: tls.VersionTLS12}
+		if profile.CAFile != "" {
+			pem, err := ioutil.ReadFile(profile.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrDial, err.Error())
+			}
+			config.RootCAs = x509.NewCertPool()
+			if !config.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("%w: no certificates in %s", ErrDial, profile.CAFile)
+			}
+		}
+		options = append(options, grpc.WithTransportCredentials(credentials.NewTLS(config)))
+	}
+
+	if profile.Token != "" {
+		options = append(options, grpc.WithPerRPCCredentials(tokenCredentials{profile.Token, profile.Insecure}))
+	}
+
+	conn, err := grpc.DialContext(ctx, profile.Address, options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrDial, profile.Address, err.Error())
+	}
+
+	return conn, nil
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
 	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -15,11 +19,18 @@ var (
 	ErrGetDayAheadEvents   = errors.New("getting day events error")
 	ErrGetWeekAheadEvents  = errors.New("getting week events error")
 	ErrGetMonthAheadEvents = errors.New("getting month events error")
+	ErrGetEventsInRange    = errors.New("getting events in range error")
+	ErrGetEvent            = errors.New("getting event error")
+	ErrEventNotFound       = errors.New("event not found")
+	ErrInvalidEvent        = errors.New("invalid event")
 )
 
 type App struct {
 	Logger  Logger
 	Storage Storage
+	Clock   clock.Clock
+	// MaxFreeBusyRange bounds the periods of the free/busy queries, zero leaves them unbounded.
+	MaxFreeBusyRange time.Duration
 }
 
 type Logger interface {
@@ -36,77 +47,273 @@ type Storage interface {
 	GetDayAheadEvents(ctx context.Context) ([]storage.Event, error)
 	GetWeekAheadEvents(ctx context.Context) ([]storage.Event, error)
 	GetMonthAheadEvents(ctx context.Context) ([]storage.Event, error)
-	GetComingEvents(ctx context.Context) ([]storage.Event, error)
-	RemoveExpiredEvents(ctx context.Context) error
+	GetEventsInRange(ctx context.Context, begin, end time.Time) ([]storage.Event, error)
+	GetOwnerEventsInRange(ctx context.Context, begin, end time.Time, ownerIDs ...int64) ([]storage.Event, error)
+	RemoveExpiredEvents(ctx context.Context, retention time.Duration) error
 	GetEventByID(ctx context.Context, id int64) (storage.Event, error)
+	GetChanges(ctx context.Context, since int64) ([]storage.Change, error)
+	SubscribeChanges() (<-chan storage.Change, func())
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	SetDigestSubscription(ctx context.Context, subscription storage.DigestSubscription) (storage.DigestSubscription, error)
+	GetDigestSubscription(ctx context.Context, ownerID int64) (storage.DigestSubscription, error)
+	RemoveDigestSubscription(ctx context.Context, ownerID int64) error
+	ListDigestSubscriptions(ctx context.Context) ([]storage.DigestSubscription, error)
+	MarkDigestSent(ctx context.Context, ownerID int64, at time.Time) error
+	CreateReminder(ctx context.Context, reminder storage.Reminder) (storage.Reminder, error)
+	UpdateReminder(ctx context.Context, reminder storage.Reminder) (storage.Reminder, error)
+	UpdateReminderState(ctx context.Context, read, reminder storage.Reminder) (storage.Reminder, error)
+	RemoveReminder(ctx context.Context, id int64) error
+	GetReminder(ctx context.Context, id int64) (storage.Reminder, error)
+	GetReminders(ctx context.Context, eventIDs ...int64) ([]storage.Reminder, error)
+	GetDueReminders(ctx context.Context) ([]storage.Reminder, error)
+	GetUpcomingReminders(ctx context.Context, until time.Time) ([]storage.Reminder, error)
+	SubscribeReminderChanges() (<-chan struct{}, func())
+	CreateNotification(ctx context.Context, notification storage.Notification) (storage.Notification, error)
+	UpdateNotification(ctx context.Context, notification storage.Notification) (storage.Notification, error)
+	GetNotification(ctx context.Context, id int64) (storage.Notification, error)
+	FindNotification(ctx context.Context, eventID, reminderID int64, channel string) (storage.Notification, error)
+	ListNotifications(ctx context.Context, filter storage.NotificationFilter) ([]storage.Notification, error)
+	CreateCategory(ctx context.Context, category storage.Category) (storage.Category, error)
+	UpdateCategory(ctx context.Context, category storage.Category) (storage.Category, error)
+	RemoveCategory(ctx context.Context, id int64) error
+	GetCategory(ctx context.Context, id int64) (storage.Category, error)
+	ListCategories(ctx context.Context, ownerID int64) ([]storage.Category, error)
+	SetEventTags(ctx context.Context, eventID int64, tags []string) error
+	GetEventTags(ctx context.Context, eventIDs ...int64) (map[int64][]string, error)
+	CreateCalendar(ctx context.Context, calendar storage.Calendar) (storage.Calendar, error)
+	UpdateCalendar(ctx context.Context, calendar storage.Calendar) (storage.Calendar, error)
+	RemoveCalendar(ctx context.Context, id int64) error
+	GetCalendar(ctx context.Context, id int64) (storage.Calendar, error)
+	GetDefaultCalendar(ctx context.Context, ownerID int64) (storage.Calendar, error)
+	ListCalendars(ctx context.Context, ownerID int64) ([]storage.Calendar, error)
+	SetGrant(ctx context.Context, grant storage.Grant) (storage.Grant, error)
+	RemoveGrant(ctx context.Context, calendarID, granteeID int64) error
+	GetGrant(ctx context.Context, calendarID, granteeID int64) (storage.Grant, error)
+	ListGrants(ctx context.Context, filter storage.GrantFilter) ([]storage.Grant, error)
 }
 
-func New(logger Logger, storage Storage) *App {
+func New(logger Logger, storage Storage, clock clock.Clock) *App {
 	return &App{
 		logger,
 		storage,
+		clock,
+		DefaultMaxFreeBusyRange,
 	}
 }
 
+// CreateEvent saves the event along with its tags and reminders into a calendar the request may write to,
+// the default calendar of the owner unless given. An event without reminders gets the default ones
+// of its category, or the calendar ones.
 func (a *App) CreateEvent(ctx context.Context, event storage.Event) (storage.Event, error) {
-	event, err := a.Storage.CreateEvent(ctx, event)
+	if ownerID, ok := OwnerFromContext(ctx); ok && event.CalendarID == 0 {
+		event.OwnerID = ownerID
+	}
+
+	err := a.Storage.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := a.placeEvent(ctx, &event, nil); err != nil {
+			return err
+		}
+
+		if err := a.checkEvent(ctx, &event); err != nil {
+			return err
+		}
+
+		offsets := event.Reminders
+		if offsets == nil {
+			var err error
+			if offsets, err = a.defaultReminders(ctx, event.CategoryID); err != nil {
+				return err
+			}
+		}
+
+		created, err := a.Storage.CreateEvent(ctx, event)
+		if err != nil {
+			return wrapStorageError(ErrCreateEvent, err)
+		}
+
+		if created.Tags, err = a.syncTags(ctx, created, event.Tags); err != nil {
+			return err
+		}
+
+		created.Reminders, err = a.syncReminders(ctx, created, offsets)
+		event = created
+
+		return err
+	})
 	if err != nil {
-		err = fmt.Errorf("%w: %s", ErrCreateEvent, err.Error())
+		return event, err
 	}
 
-	return event, err
+	// Lets the request trace be found by the event the scheduler later notifies about.
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("calendar.event_id", event.ID))
+
+	return event, nil
+}
+
+// WithinTransaction runs fn in a transaction of the storage, letting callers combine operations of the app.
+func (a *App) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return a.Storage.WithinTransaction(ctx, fn)
 }
 
+// UpdateEvent replaces the event, nil tags and reminders keeping the current ones.
+// Both the current and the new calendar of the event must be writable by the request,
+// zero calendar keeping the current one.
 func (a *App) UpdateEvent(ctx context.Context, event storage.Event) (storage.Event, error) {
-	event, err := a.Storage.UpdateEvent(ctx, event)
-	if err != nil {
-		err = fmt.Errorf("%w: %s", ErrUpdateEvent, err.Error())
-	}
+	err := a.Storage.WithinTransaction(ctx, func(ctx context.Context) error {
+		current, err := a.requireEvent(ctx, event.ID, storage.AccessWrite)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := OwnerFromContext(ctx); ok && event.CalendarID == 0 {
+			event.OwnerID = current.OwnerID
+		}
+
+		if err := a.placeEvent(ctx, &event, &current); err != nil {
+			return err
+		}
+
+		if err := a.checkEvent(ctx, &event); err != nil {
+			return err
+		}
+
+		updated, err := a.Storage.UpdateEvent(ctx, event)
+		if err != nil {
+			return wrapStorageError(ErrUpdateEvent, err)
+		}
+
+		if updated.Tags, err = a.syncTags(ctx, updated, event.Tags); err != nil {
+			return err
+		}
+
+		updated.Reminders, err = a.syncReminders(ctx, updated, event.Reminders)
+		event = updated
+
+		return err
+	})
 
 	return event, err
 }
 
+// RemoveEvent removes the event of a calendar the request may write to.
+// Requests without an owner have to be internal ones.
 func (a *App) RemoveEvent(ctx context.Context, event storage.Event) error {
-	err := a.Storage.RemoveEvent(ctx, event)
+	if _, ok := OwnerFromContext(ctx); !ok && !IsInternal(ctx) {
+		return fmt.Errorf("%w: removing event %d on behalf of no owner", ErrPermissionDenied, event.ID)
+	}
+
+	return a.Storage.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := a.requireEvent(ctx, event.ID, storage.AccessWrite); err != nil {
+			return err
+		}
+
+		if err := a.Storage.RemoveEvent(ctx, event); err != nil {
+			return wrapStorageError(ErrRemoveEvent, err)
+		}
+
+		return nil
+	})
+}
+
+// GetEventByID returns the event of a calendar the request may read.
+func (a *App) GetEventByID(ctx context.Context, id int64) (storage.Event, error) {
+	event, err := a.requireEvent(ctx, id, storage.AccessRead)
+	if err != nil {
+		return event, err
+	}
+
+	events := []storage.Event{event}
+	if err := a.withDetails(ctx, events); err != nil {
+		return event, fmt.Errorf("%w: %s", ErrGetEvent, err.Error())
+	}
+
+	return events[0], nil
+}
+
+// GetEventsInRange returns events of the [begin, end) period passing the filter, ordered by their begin dates.
+func (a *App) GetEventsInRange(ctx context.Context, begin, end time.Time, filter EventFilter) ([]storage.Event, error) {
+	if !begin.Before(end) {
+		return nil, ErrInvalidPeriod
+	}
+
+	events, err := a.eventsInRange(ctx, begin, end)
 	if err != nil {
-		err = fmt.Errorf("%w: %s", ErrRemoveEvent, err.Error())
+		return nil, fmt.Errorf("%w: %s", ErrGetEventsInRange, err.Error())
 	}
 
-	return err
+	return a.selectEvents(ctx, events, filter)
 }
 
-func (a *App) GetDayAheadEvents(ctx context.Context) ([]storage.Event, error) {
-	events, err := a.Storage.GetDayAheadEvents(ctx)
+// GetDayAheadEvents returns events of the next 24 hours by the app clock passing the filter.
+// The views return the events overlapping their period, so an event going on now is included;
+// before free/busy they only returned events beginning within it. The month is a calendar one
+// in every storage, the memory one used to look 30 weeks ahead.
+func (a *App) GetDayAheadEvents(ctx context.Context, filter EventFilter) ([]storage.Event, error) {
+	now := a.Clock.Now()
+	events, err := a.eventsInRange(ctx, now, now.AddDate(0, 0, 1))
 	if err != nil {
-		err = fmt.Errorf("%w: %s", ErrGetDayAheadEvents, err.Error())
+		return nil, fmt.Errorf("%w: %s", ErrGetDayAheadEvents, err.Error())
 	}
 
-	return events, err
+	return a.selectEvents(ctx, events, filter)
 }
 
-func (a *App) GetWeekAheadEvents(ctx context.Context) ([]storage.Event, error) {
-	events, err := a.Storage.GetWeekAheadEvents(ctx)
+// GetWeekAheadEvents returns events of the next 7 days by the app clock passing the filter,
+// overlapping ones included like GetDayAheadEvents.
+func (a *App) GetWeekAheadEvents(ctx context.Context, filter EventFilter) ([]storage.Event, error) {
+	now := a.Clock.Now()
+	events, err := a.eventsInRange(ctx, now, now.AddDate(0, 0, 7))
 	if err != nil {
-		err = fmt.Errorf("%w: %s", ErrGetWeekAheadEvents, err.Error())
+		return nil, fmt.Errorf("%w: %s", ErrGetWeekAheadEvents, err.Error())
 	}
 
-	return events, err
+	return a.selectEvents(ctx, events, filter)
 }
 
-func (a *App) GetMonthAheadEvents(ctx context.Context) ([]storage.Event, error) {
-	events, err := a.Storage.GetMonthAheadEvents(ctx)
+// GetMonthAheadEvents returns events of the next calendar month by the app clock passing the filter,
+// overlapping ones included like GetDayAheadEvents.
+func (a *App) GetMonthAheadEvents(ctx context.Context, filter EventFilter) ([]storage.Event, error) {
+	now := a.Clock.Now()
+	events, err := a.eventsInRange(ctx, now, now.AddDate(0, 1, 0))
 	if err != nil {
-		err = fmt.Errorf("%w: %s", ErrGetMonthAheadEvents, err.Error())
+		return nil, fmt.Errorf("%w: %s", ErrGetMonthAheadEvents, err.Error())
 	}
 
-	return events, err
+	return a.selectEvents(ctx, events, filter)
 }
 
-func (a *App) GetComingEvents(ctx context.Context) ([]storage.Event, error) {
-	events, err := a.Storage.GetComingEvents(ctx)
+// checkEvent validates the event, normalizing its tags. Events of an owner may overlap.
+func (a *App) checkEvent(ctx context.Context, event *storage.Event) error {
+	if event.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidEvent)
+	}
+
+	if event.BeginDate.IsZero() {
+		return fmt.Errorf("%w: begin date is required", ErrInvalidEvent)
+	}
+
+	if event.EndDate.Before(event.BeginDate) {
+		return fmt.Errorf("%w: end date is before begin date", ErrInvalidEvent)
+	}
+
+	if err := validateReminders(event.Reminders); err != nil {
+		return err
+	}
+
+	tags, err := normalizeTags(event.Tags)
 	if err != nil {
-		err = fmt.Errorf("%w: %s", ErrGetMonthAheadEvents, err.Error())
+		return fmt.Errorf("%w: %s", ErrInvalidEvent, err.Error())
+	}
+	event.Tags = tags
+
+	return a.checkCategory(ctx, *event)
+}
+
+// wrapStorageError keeps missing events distinguishable from other storage failures.
+func wrapStorageError(kind error, err error) error {
+	if errors.Is(err, storage.ErrEventNotFound) {
+		return fmt.Errorf("%w: %s", ErrEventNotFound, err.Error())
 	}
 
-	return events, err
+	return fmt.Errorf("%w: %s", kind, err.Error())
 }
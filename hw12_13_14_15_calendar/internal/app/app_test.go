@@ -0,0 +1,38 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAheadEvents(t *testing.T) {
+	ctx := context.Background()
+	monday := time.Date(2021, time.November, 1, 9, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(monday)
+	app := New(nil, memorystorage.New(clk), clk)
+
+	nextTuesday := monday.AddDate(0, 0, 8)
+	_, err := app.CreateEvent(ctx, storage.Event{
+		Title:     "next tuesday",
+		BeginDate: nextTuesday,
+		EndDate:   nextTuesday.Add(time.Hour),
+		OwnerID:   1,
+	})
+	require.NoError(t, err)
+
+	events, err := app.GetWeekAheadEvents(ctx, EventFilter{})
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	clk.Set(nextTuesday.Add(-time.Hour))
+	events, err = app.GetDayAheadEvents(ctx, EventFilter{})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, "next tuesday", events[0].Title)
+}
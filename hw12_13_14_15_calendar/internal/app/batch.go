@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// MaxBatchSize is the maximum number of operations in a batch.
+const MaxBatchSize = 1000
+
+var (
+	ErrEmptyBatch       = errors.New("batch is empty")
+	ErrBatchTooLarge    = errors.New("batch is too large")
+	ErrBatchAborted     = errors.New("batch aborted")
+	ErrInvalidOperation = errors.New("invalid batch operation")
+)
+
+type OperationType int
+
+const (
+	OperationCreate OperationType = iota + 1
+	OperationUpdate
+	OperationRemove
+)
+
+// BatchOperation creates, replaces or removes the event, only the id is used for removal.
+type BatchOperation struct {
+	Type  OperationType
+	Event storage.Event
+}
+
+// BatchResult is an outcome of a batch operation, Event is the created or updated event.
+type BatchResult struct {
+	Event storage.Event
+	Err   error
+}
+
+// ApplyBatch applies the operations in order and returns a result per operation.
+// An atomic batch runs in a single transaction and is rolled back when any operation fails:
+// the failed operation keeps its error, the others get ErrBatchAborted and so does the returned error.
+// Otherwise every operation is applied on its own and failures are only reported in the results.
+func (a *App) ApplyBatch(ctx context.Context, operations []BatchOperation, atomic bool) ([]BatchResult, error) {
+	if len(operations) == 0 {
+		return nil, ErrEmptyBatch
+	}
+
+	if len(operations) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: %d operations, %d at most", ErrBatchTooLarge, len(operations), MaxBatchSize)
+	}
+
+	results := make([]BatchResult, len(operations))
+
+	if !atomic {
+		for i, operation := range operations {
+			results[i] = a.applyInTransaction(ctx, operation)
+		}
+
+		return results, nil
+	}
+
+	failed := -1
+	err := a.Storage.WithinTransaction(ctx, func(ctx context.Context) error {
+		for i, operation := range operations {
+			results[i] = a.applyOperation(ctx, operation)
+			if results[i].Err != nil {
+				failed = i
+				return results[i].Err
+			}
+		}
+
+		return nil
+	})
+	if err == nil {
+		return results, nil
+	}
+
+	for i, operation := range operations {
+		if i != failed {
+			results[i] = BatchResult{Event: operation.Event, Err: ErrBatchAborted}
+		}
+	}
+
+	if failed < 0 {
+		return results, fmt.Errorf("%w: %s", ErrBatchAborted, err.Error())
+	}
+
+	return results, fmt.Errorf("%w: operation %d: %s", ErrBatchAborted, failed, err.Error())
+}
+
+// applyInTransaction applies a single operation, so its checks and write are consistent.
+func (a *App) applyInTransaction(ctx context.Context, operation BatchOperation) BatchResult {
+	var result BatchResult
+	err := a.Storage.WithinTransaction(ctx, func(ctx context.Context) error {
+		result = a.applyOperation(ctx, operation)
+		return result.Err
+	})
+	if err != nil && result.Err == nil {
+		result.Err = err
+	}
+
+	return result
+}
+
+func (a *App) applyOperation(ctx context.Context, operation BatchOperation) BatchResult {
+	switch operation.Type {
+	case OperationCreate:
+		event, err := a.CreateEvent(ctx, operation.Event)
+		return BatchResult{Event: event, Err: err}
+	case OperationUpdate:
+		event, err := a.UpdateEvent(ctx, operation.Event)
+		return BatchResult{Event: event, Err: err}
+	case OperationRemove:
+		return BatchResult{Event: operation.Event, Err: a.RemoveEvent(ctx, operation.Event)}
+	default:
+		return BatchResult{Event: operation.Event, Err: fmt.Errorf("%w: %d", ErrInvalidOperation, operation.Type)}
+	}
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyBatch(t *testing.T) {
+	ctx := ContextInternal(context.Background())
+	begin := time.Now().Add(time.Hour).Truncate(time.Second)
+	newEvent := func(title string, hour int) storage.Event {
+		return storage.Event{
+			Title:     title,
+			OwnerID:   1,
+			BeginDate: begin.Add(time.Duration(hour) * time.Hour),
+			EndDate:   begin.Add(time.Duration(hour)*time.Hour + 30*time.Minute),
+		}
+	}
+
+	t.Run("atomic", func(t *testing.T) {
+		app := New(nil, memorystorage.New(clock.Real{}), clock.Real{})
+		existing, err := app.CreateEvent(ctx, newEvent("existing", 0))
+		require.NoError(t, err)
+
+		existing.Title = "renamed"
+		results, err := app.ApplyBatch(ctx, []BatchOperation{
+			{Type: OperationCreate, Event: newEvent("first", 1)},
+			{Type: OperationUpdate, Event: existing},
+			{Type: OperationCreate, Event: newEvent("second", 2)},
+		}, true)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		for _, result := range results {
+			require.NoError(t, result.Err)
+		}
+		require.NotZero(t, results[2].Event.ID)
+
+		stored, err := app.GetEventByID(ctx, existing.ID)
+		require.NoError(t, err)
+		require.Equal(t, "renamed", stored.Title)
+	})
+
+	t.Run("atomic rollback", func(t *testing.T) {
+		memory := memorystorage.New(clock.Real{})
+		app := New(nil, memory, clock.Real{})
+		changes, release := memory.SubscribeChanges()
+		defer release()
+
+		// The second event of the batch is invalid.
+		results, err := app.ApplyBatch(ctx, []BatchOperation{
+			{Type: OperationCreate, Event: newEvent("first", 1)},
+			{Type: OperationCreate, Event: newEvent("", 1)},
+			{Type: OperationCreate, Event: newEvent("third", 3)},
+		}, true)
+		require.ErrorIs(t, err, ErrBatchAborted)
+		require.ErrorIs(t, results[0].Err, ErrBatchAborted)
+		require.ErrorIs(t, results[1].Err, ErrInvalidEvent)
+		require.ErrorIs(t, results[2].Err, ErrBatchAborted)
+
+		events, err := app.GetEventsInRange(ctx, begin, begin.Add(24*time.Hour), EventFilter{})
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		// No change of the aborted batch is published, the first one is made after it.
+		created, err := app.CreateEvent(ctx, newEvent("after rollback", 1))
+		require.NoError(t, err)
+
+		change := receive(t, changes)
+		require.Equal(t, created.ID, change.Event.ID)
+		require.Equal(t, "after rollback", change.Event.Title)
+	})
+
+	t.Run("best effort", func(t *testing.T) {
+		app := New(nil, memorystorage.New(clock.Real{}), clock.Real{})
+
+		results, err := app.ApplyBatch(ctx, []BatchOperation{
+			{Type: OperationCreate, Event: newEvent("first", 1)},
+			{Type: OperationRemove, Event: storage.Event{ID: 100500}},
+			{Type: OperationCreate, Event: storage.Event{Title: "no dates"}},
+			{Type: OperationCreate, Event: newEvent("second", 2)},
+		}, false)
+		require.NoError(t, err)
+		require.NoError(t, results[0].Err)
+		require.ErrorIs(t, results[1].Err, ErrEventNotFound)
+		require.ErrorIs(t, results[2].Err, ErrInvalidEvent)
+		require.NoError(t, results[3].Err)
+
+		events, err := app.GetEventsInRange(ctx, begin, begin.Add(24*time.Hour), EventFilter{})
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+	})
+
+	t.Run("owner scoped", func(t *testing.T) {
+		app := New(nil, memorystorage.New(clock.Real{}), clock.Real{})
+		foreign, err := app.CreateEvent(ctx, newEvent("foreign", 1))
+		require.NoError(t, err)
+
+		results, err := app.ApplyBatch(ContextWithOwner(ctx, 2), []BatchOperation{
+			{Type: OperationRemove, Event: foreign},
+		}, false)
+		require.NoError(t, err)
+		require.ErrorIs(t, results[0].Err, ErrEventNotFound)
+	})
+
+	t.Run("invalid batches", func(t *testing.T) {
+		app := New(nil, memorystorage.New(clock.Real{}), clock.Real{})
+
+		_, err := app.ApplyBatch(ctx, nil, true)
+		require.ErrorIs(t, err, ErrEmptyBatch)
+
+		_, err = app.ApplyBatch(ctx, make([]BatchOperation, MaxBatchSize+1), true)
+		require.ErrorIs(t, err, ErrBatchTooLarge)
+
+		results, err := app.ApplyBatch(ctx, []BatchOperation{{Event: newEvent("typeless", 1)}}, true)
+		require.ErrorIs(t, err, ErrBatchAborted)
+		require.ErrorIs(t, results[0].Err, ErrInvalidOperation)
+	})
+}
+
+// BenchmarkApplyBatch applies batches next to many stored events, a best-effort batch running
+// a transaction per operation should not pay for the stored events in every one of them.
+func BenchmarkApplyBatch(b *testing.B) {
+	const (
+		stored    = 100000
+		batchSize = 100
+	)
+
+	ctx := context.Background()
+	begin := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	for _, atomic := range []bool{true, false} {
+		name := "best effort"
+		if atomic {
+			name = "atomic"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			memory := memorystorage.New(clock.Real{})
+			for i := 0; i < stored; i++ {
+				at := begin.Add(time.Duration(i) * time.Minute)
+				if _, err := memory.CreateEvent(ctx, storage.Event{Title: "stored", OwnerID: 2, BeginDate: at, EndDate: at}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			app := New(nil, memory, clock.Real{})
+
+			operations := make([]BatchOperation, batchSize)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := range operations {
+					at := begin.Add(time.Duration(i*batchSize+j) * time.Hour)
+					operations[j] = BatchOperation{
+						Type:  OperationCreate,
+						Event: storage.Event{Title: "batch", OwnerID: 1, BeginDate: at, EndDate: at.Add(time.Minute)},
+					}
+				}
+
+				if _, err := app.ApplyBatch(ctx, operations, atomic); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
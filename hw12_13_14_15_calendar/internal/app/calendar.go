@@ -0,0 +1,442 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// DefaultCalendarName is the name of the calendar created for the events of an owner not having any.
+const DefaultCalendarName = "Default"
+
+const maxCalendarNameLength = 64
+
+var (
+	ErrCalendarNotFound = errors.New("calendar not found")
+	ErrCalendarNotEmpty = errors.New("calendar still has events, move or remove them first")
+	ErrGrantNotFound    = errors.New("grant not found")
+	ErrInvalidCalendar  = errors.New("invalid calendar")
+	ErrInvalidGrant     = errors.New("invalid grant")
+	ErrGetCalendars     = errors.New("getting calendars error")
+	ErrSaveCalendar     = errors.New("saving calendar error")
+	ErrRemoveCalendar   = errors.New("removing calendar error")
+	ErrShareCalendar    = errors.New("sharing calendar error")
+)
+
+// GetCalendar returns the calendar along with the access of the request to it.
+// Calendars neither owned by nor shared with an authenticated request are hidden.
+func (a *App) GetCalendar(ctx context.Context, id int64) (storage.Calendar, error) {
+	return a.calendarAccess(ctx, id)
+}
+
+// ListCalendars returns the calendars of the owner ordered by name, followed by the ones shared with it.
+// Authenticated requests may only list their own ones, zero owner standing for them.
+func (a *App) ListCalendars(ctx context.Context, ownerID int64) ([]storage.Calendar, error) {
+	ownerID, err := resolveOwner(ctx, ownerID, "calendars")
+	if err != nil {
+		return nil, err
+	}
+
+	calendars, err := a.Storage.ListCalendars(ctx, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrGetCalendars, err.Error())
+	}
+
+	for i := range calendars {
+		calendars[i].Access = storage.AccessOwner
+	}
+
+	grants, err := a.Storage.ListGrants(ctx, storage.GrantFilter{GranteeID: ownerID})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrGetCalendars, err.Error())
+	}
+
+	for _, grant := range grants {
+		calendar, err := a.Storage.GetCalendar(ctx, grant.CalendarID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrGetCalendars, err.Error())
+		}
+		calendar.Access = grant.Access
+		calendars = append(calendars, calendar)
+	}
+
+	return calendars, nil
+}
+
+// CreateCalendar saves a new calendar of the owner.
+func (a *App) CreateCalendar(ctx context.Context, calendar storage.Calendar) (storage.Calendar, error) {
+	ownerID, err := resolveOwner(ctx, calendar.OwnerID, "calendar")
+	if err != nil {
+		return calendar, err
+	}
+	calendar.OwnerID = ownerID
+	calendar.Default = false
+
+	if calendar, err = normalizeCalendar(calendar); err != nil {
+		return calendar, err
+	}
+
+	calendar, err = a.Storage.CreateCalendar(ctx, calendar)
+	if err != nil {
+		return calendar, wrapCalendarError(ErrSaveCalendar, err)
+	}
+	calendar.Access = storage.AccessOwner
+
+	return calendar, nil
+}
+
+// UpdateCalendar renames the calendar, only its owner may do so.
+func (a *App) UpdateCalendar(ctx context.Context, calendar storage.Calendar) (storage.Calendar, error) {
+	current, err := a.requireCalendar(ctx, calendar.ID, storage.AccessOwner)
+	if err != nil {
+		return calendar, err
+	}
+	calendar.OwnerID = current.OwnerID
+	calendar.Default = current.Default
+
+	if calendar, err = normalizeCalendar(calendar); err != nil {
+		return calendar, err
+	}
+
+	calendar, err = a.Storage.UpdateCalendar(ctx, calendar)
+	if err != nil {
+		return calendar, wrapCalendarError(ErrSaveCalendar, err)
+	}
+	calendar.Access = storage.AccessOwner
+
+	return calendar, nil
+}
+
+// RemoveCalendar removes an empty calendar along with its grants, only its owner may do so.
+// The default calendar of the owner is kept.
+func (a *App) RemoveCalendar(ctx context.Context, id int64) error {
+	return a.Storage.WithinTransaction(ctx, func(ctx context.Context) error {
+		calendar, err := a.requireCalendar(ctx, id, storage.AccessOwner)
+		if err != nil {
+			return err
+		}
+
+		if calendar.Default {
+			return fmt.Errorf("%w: the default calendar can't be removed", ErrInvalidCalendar)
+		}
+
+		if err := a.Storage.RemoveCalendar(ctx, id); err != nil {
+			return wrapCalendarError(ErrRemoveCalendar, err)
+		}
+
+		return nil
+	})
+}
+
+// ShareCalendar grants the access to the calendar to another user, replacing the former one.
+// Only the owner of the calendar may share it.
+func (a *App) ShareCalendar(ctx context.Context, grant storage.Grant) (storage.Grant, error) {
+	calendar, err := a.requireCalendar(ctx, grant.CalendarID, storage.AccessOwner)
+	if err != nil {
+		return grant, err
+	}
+	grant.OwnerID = calendar.OwnerID
+
+	if grant.GranteeID <= 0 {
+		return grant, fmt.Errorf("%w: grantee id must be positive", ErrInvalidGrant)
+	}
+
+	if grant.GranteeID == calendar.OwnerID {
+		return grant, fmt.Errorf("%w: the owner can't be granted its own calendar", ErrInvalidGrant)
+	}
+
+	if !grant.Access.Valid() || grant.Access == storage.AccessOwner {
+		return grant, fmt.Errorf("%w: unknown access %q", ErrInvalidGrant, grant.Access)
+	}
+
+	grant, err = a.Storage.SetGrant(ctx, grant)
+	if err != nil {
+		return grant, wrapCalendarError(ErrShareCalendar, err)
+	}
+
+	return grant, nil
+}
+
+// UnshareCalendar revokes the access of the grantee to the calendar, only its owner may do so.
+func (a *App) UnshareCalendar(ctx context.Context, calendarID, granteeID int64) error {
+	if _, err := a.requireCalendar(ctx, calendarID, storage.AccessOwner); err != nil {
+		return err
+	}
+
+	if err := a.Storage.RemoveGrant(ctx, calendarID, granteeID); err != nil {
+		return wrapCalendarError(ErrShareCalendar, err)
+	}
+
+	return nil
+}
+
+// ListGrants returns the grants of the calendar ordered by grantee, only its owner may list them.
+func (a *App) ListGrants(ctx context.Context, calendarID int64) ([]storage.Grant, error) {
+	if _, err := a.requireCalendar(ctx, calendarID, storage.AccessOwner); err != nil {
+		return nil, err
+	}
+
+	grants, err := a.Storage.ListGrants(ctx, storage.GrantFilter{CalendarID: calendarID})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrGetCalendars, err.Error())
+	}
+
+	return grants, nil
+}
+
+// calendarAccess returns the calendar along with the access of the request to it.
+// Requests without an owner are trusted with everything.
+func (a *App) calendarAccess(ctx context.Context, id int64) (storage.Calendar, error) {
+	calendar, err := a.Storage.GetCalendar(ctx, id)
+	if err != nil {
+		return calendar, wrapCalendarError(ErrGetCalendars, err)
+	}
+
+	ownerID, ok := OwnerFromContext(ctx)
+	if !ok || calendar.OwnerID == ownerID {
+		calendar.Access = storage.AccessOwner
+		return calendar, nil
+	}
+
+	grant, err := a.Storage.GetGrant(ctx, id, ownerID)
+	if errors.Is(err, storage.ErrGrantNotFound) {
+		return storage.Calendar{}, fmt.Errorf("%w: %d", ErrCalendarNotFound, id)
+	}
+	if err != nil {
+		return storage.Calendar{}, fmt.Errorf("%w: %s", ErrGetCalendars, err.Error())
+	}
+	calendar.Access = grant.Access
+
+	return calendar, nil
+}
+
+// requireCalendar returns the calendar the request has the required access to.
+func (a *App) requireCalendar(ctx context.Context, id int64, required storage.Access) (storage.Calendar, error) {
+	calendar, err := a.calendarAccess(ctx, id)
+	if err != nil {
+		return calendar, err
+	}
+
+	if !calendar.Access.Allows(required) {
+		return calendar, fmt.Errorf("%w: %s access to calendar %d", ErrPermissionDenied, required, id)
+	}
+
+	return calendar, nil
+}
+
+// defaultCalendar returns the default calendar of the owner, creating it on first use.
+func (a *App) defaultCalendar(ctx context.Context, ownerID int64) (storage.Calendar, error) {
+	calendar, err := a.Storage.GetDefaultCalendar(ctx, ownerID)
+	if err == nil || !errors.Is(err, storage.ErrCalendarNotFound) {
+		return calendar, wrapCalendarError(ErrGetCalendars, err)
+	}
+
+	calendar, err = a.Storage.CreateCalendar(ctx, storage.Calendar{
+		OwnerID: ownerID,
+		Name:    DefaultCalendarName,
+		Default: true,
+	})
+	// Another request could have created it meanwhile, the storages keep the transaction going then.
+	if errors.Is(err, storage.ErrCalendarExists) {
+		calendar, err = a.Storage.GetDefaultCalendar(ctx, ownerID)
+	}
+
+	return calendar, wrapCalendarError(ErrSaveCalendar, err)
+}
+
+// placeEvent puts the event into its calendar, which the request must be able to write to,
+// making the owner of the calendar the owner of the event. Events without a calendar stay
+// in the current one of the update, unless changing owners, or go to the default calendar
+// of the owner.
+func (a *App) placeEvent(ctx context.Context, event *storage.Event, current *storage.Event) error {
+	if event.CalendarID == 0 && current != nil && current.OwnerID == event.OwnerID {
+		event.CalendarID = current.CalendarID
+	}
+
+	if event.CalendarID == 0 {
+		if event.OwnerID <= 0 {
+			return fmt.Errorf("%w: owner id must be positive", ErrInvalidEvent)
+		}
+
+		calendar, err := a.defaultCalendar(ctx, event.OwnerID)
+		if err != nil {
+			return err
+		}
+		event.CalendarID = calendar.ID
+	}
+
+	calendar, err := a.calendarAccess(ctx, event.CalendarID)
+	if errors.Is(err, ErrCalendarNotFound) {
+		return fmt.Errorf("%w: unknown calendar %d", ErrInvalidEvent, event.CalendarID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !calendar.Access.Allows(storage.AccessWrite) {
+		return fmt.Errorf("%w: events of calendar %d are read only", ErrPermissionDenied, calendar.ID)
+	}
+	event.OwnerID = calendar.OwnerID
+
+	return nil
+}
+
+// requireEvent returns the stored event the request has the required access to.
+// Events the request can't read are hidden.
+func (a *App) requireEvent(ctx context.Context, id int64, required storage.Access) (storage.Event, error) {
+	event, err := a.Storage.GetEventByID(ctx, id)
+	if err != nil {
+		return event, wrapStorageError(ErrGetEvent, err)
+	}
+
+	access, err := a.eventAccess(ctx, event)
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	if !access.Allows(storage.AccessRead) {
+		return storage.Event{}, fmt.Errorf("%w: %d", ErrEventNotFound, id)
+	}
+
+	if !access.Allows(required) {
+		return storage.Event{}, fmt.Errorf("%w: %s access to event %d", ErrPermissionDenied, required, id)
+	}
+
+	return event, nil
+}
+
+// eventAccess returns the access of the request to the event, the owners having full access.
+func (a *App) eventAccess(ctx context.Context, event storage.Event) (storage.Access, error) {
+	ownerID, ok := OwnerFromContext(ctx)
+	if !ok || event.OwnerID == ownerID {
+		return storage.AccessOwner, nil
+	}
+
+	if event.CalendarID == 0 {
+		return storage.AccessNone, nil
+	}
+
+	grant, err := a.Storage.GetGrant(ctx, event.CalendarID, ownerID)
+	if errors.Is(err, storage.ErrGrantNotFound) {
+		return storage.AccessNone, nil
+	}
+	if err != nil {
+		return storage.AccessNone, fmt.Errorf("%w: %s", ErrGetCalendars, err.Error())
+	}
+
+	return grant.Access, nil
+}
+
+// sharedGrants returns the grants of the calendars shared with the grantee by calendar.
+func (a *App) sharedGrants(ctx context.Context, granteeID int64) (map[int64]storage.Grant, error) {
+	grants, err := a.Storage.ListGrants(ctx, storage.GrantFilter{GranteeID: granteeID})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrGetCalendars, err.Error())
+	}
+
+	shared := make(map[int64]storage.Grant, len(grants))
+	for _, grant := range grants {
+		shared[grant.CalendarID] = grant
+	}
+
+	return shared, nil
+}
+
+// eventsInRange reads the events of the period, of the owners sharing their calendars
+// with the request only when it is authenticated.
+func (a *App) eventsInRange(ctx context.Context, begin, end time.Time) ([]storage.Event, error) {
+	ownerID, ok := OwnerFromContext(ctx)
+	if !ok {
+		return a.Storage.GetEventsInRange(ctx, begin, end)
+	}
+
+	shared, err := a.sharedGrants(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerIDs := []int64{ownerID}
+	seen := map[int64]bool{ownerID: true}
+	for _, grant := range shared {
+		if grant.Access.Allows(storage.AccessRead) && !seen[grant.OwnerID] {
+			ownerIDs = append(ownerIDs, grant.OwnerID)
+			seen[grant.OwnerID] = true
+		}
+	}
+
+	return a.Storage.GetOwnerEventsInRange(ctx, begin, end, ownerIDs...)
+}
+
+// filterVisible keeps the events the request may read, of the given calendars only unless there are none.
+func (a *App) filterVisible(ctx context.Context, events []storage.Event, calendarIDs []int64) ([]storage.Event, error) {
+	var selected map[int64]bool
+	if len(calendarIDs) > 0 {
+		selected = make(map[int64]bool, len(calendarIDs))
+		for _, id := range calendarIDs {
+			if _, err := a.requireCalendar(ctx, id, storage.AccessRead); err != nil {
+				return nil, err
+			}
+			selected[id] = true
+		}
+	}
+
+	ownerID, authenticated := OwnerFromContext(ctx)
+
+	var shared map[int64]storage.Grant
+	if authenticated {
+		var err error
+		if shared, err = a.sharedGrants(ctx, ownerID); err != nil {
+			return nil, err
+		}
+	}
+
+	visible := events[:0]
+	for _, event := range events {
+		if selected != nil && !selected[event.CalendarID] {
+			continue
+		}
+
+		if authenticated && event.OwnerID != ownerID && !shared[event.CalendarID].Access.Allows(storage.AccessRead) {
+			continue
+		}
+
+		visible = append(visible, event)
+	}
+
+	return visible, nil
+}
+
+// normalizeCalendar trims the name of the calendar, validating it.
+func normalizeCalendar(calendar storage.Calendar) (storage.Calendar, error) {
+	calendar.Name = strings.TrimSpace(calendar.Name)
+
+	if calendar.Name == "" {
+		return calendar, fmt.Errorf("%w: name is required", ErrInvalidCalendar)
+	}
+
+	if len(calendar.Name) > maxCalendarNameLength {
+		return calendar, fmt.Errorf("%w: name is longer than %d bytes", ErrInvalidCalendar, maxCalendarNameLength)
+	}
+
+	return calendar, nil
+}
+
+// wrapCalendarError keeps missing calendars and grants distinguishable from other failures, nil staying nil.
+func wrapCalendarError(kind error, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, storage.ErrCalendarNotFound):
+		return fmt.Errorf("%w: %s", ErrCalendarNotFound, err.Error())
+	case errors.Is(err, storage.ErrCalendarNotEmpty):
+		return fmt.Errorf("%w: %s", ErrCalendarNotEmpty, err.Error())
+	case errors.Is(err, storage.ErrGrantNotFound):
+		return fmt.Errorf("%w: %s", ErrGrantNotFound, err.Error())
+	default:
+		return fmt.Errorf("%w: %s", kind, err.Error())
+	}
+}
@@ -0,0 +1,264 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendars(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, time.November, 2, 9, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	app := New(nil, memorystorage.New(clk), clk)
+	alice, bob, carol := ContextWithOwner(ctx, 1), ContextWithOwner(ctx, 2), ContextWithOwner(ctx, 3)
+
+	// The first event of an owner creates its default calendar.
+	personal, err := app.CreateEvent(alice, storage.Event{
+		Title: "dentist", BeginDate: start.Add(time.Hour), EndDate: start.Add(2 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.NotZero(t, personal.CalendarID)
+
+	defaultCalendar, err := app.GetCalendar(alice, personal.CalendarID)
+	require.NoError(t, err)
+	require.True(t, defaultCalendar.Default)
+	require.Equal(t, DefaultCalendarName, defaultCalendar.Name)
+	require.Equal(t, storage.AccessOwner, defaultCalendar.Access)
+
+	work, err := app.CreateCalendar(alice, storage.Calendar{Name: " work ", Default: true})
+	require.NoError(t, err)
+	require.Equal(t, "work", work.Name)
+	require.False(t, work.Default)
+
+	meeting, err := app.CreateEvent(alice, storage.Event{
+		Title: "meeting", BeginDate: start.Add(3 * time.Hour), EndDate: start.Add(4 * time.Hour), CalendarID: work.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), meeting.OwnerID)
+
+	ids := func(events []storage.Event) []int64 {
+		result := make([]int64, 0, len(events))
+		for _, event := range events {
+			result = append(result, event.ID)
+		}
+
+		return result
+	}
+
+	t.Run("private", func(t *testing.T) {
+		_, err := app.GetCalendar(bob, work.ID)
+		require.ErrorIs(t, err, ErrCalendarNotFound)
+
+		_, err = app.GetEventByID(bob, meeting.ID)
+		require.ErrorIs(t, err, ErrEventNotFound)
+
+		_, err = app.CreateEvent(bob, storage.Event{
+			Title: "intrusion", BeginDate: start.Add(5 * time.Hour), EndDate: start.Add(6 * time.Hour), CalendarID: work.ID,
+		})
+		require.ErrorIs(t, err, ErrInvalidEvent)
+
+		events, err := app.GetDayAheadEvents(bob, EventFilter{})
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		_, err = app.GetDayAheadEvents(bob, EventFilter{CalendarIDs: []int64{work.ID}})
+		require.ErrorIs(t, err, ErrCalendarNotFound)
+
+		_, err = app.GetFreeBusy(bob, []int64{1}, start, start.AddDate(0, 0, 1), WorkingHours{}, EventFilter{})
+		require.ErrorIs(t, err, ErrPermissionDenied)
+	})
+
+	t.Run("sharing", func(t *testing.T) {
+		_, err := app.ShareCalendar(bob, storage.Grant{CalendarID: work.ID, GranteeID: 2, Access: storage.AccessRead})
+		require.ErrorIs(t, err, ErrCalendarNotFound)
+
+		for _, invalid := range []storage.Grant{
+			{CalendarID: work.ID, GranteeID: 0, Access: storage.AccessRead},
+			{CalendarID: work.ID, GranteeID: 1, Access: storage.AccessRead},
+			{CalendarID: work.ID, GranteeID: 2, Access: storage.AccessOwner},
+			{CalendarID: work.ID, GranteeID: 2, Access: "admin"},
+		} {
+			_, err := app.ShareCalendar(alice, invalid)
+			require.ErrorIs(t, err, ErrInvalidGrant)
+		}
+
+		grant, err := app.ShareCalendar(alice, storage.Grant{CalendarID: work.ID, GranteeID: 2, Access: storage.AccessFreeBusy})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), grant.OwnerID)
+
+		// Free/busy only shows the busy time of the shared calendar.
+		busy, err := app.GetFreeBusy(bob, []int64{1}, start, start.AddDate(0, 0, 1), WorkingHours{}, EventFilter{})
+		require.NoError(t, err)
+		require.Equal(t, []Interval{{meeting.BeginDate, meeting.EndDate}}, busy[1])
+
+		// Tags of the events hidden from the grantee don't narrow their busy time down.
+		busy, err = app.GetFreeBusy(
+			bob, []int64{1}, start, start.AddDate(0, 0, 1), WorkingHours{}, EventFilter{Tags: []string{"absent"}},
+		)
+		require.NoError(t, err)
+		require.Equal(t, []Interval{{meeting.BeginDate, meeting.EndDate}}, busy[1])
+
+		_, err = app.GetEventByID(bob, meeting.ID)
+		require.ErrorIs(t, err, ErrEventNotFound)
+
+		_, err = app.GetDayAheadEvents(bob, EventFilter{CalendarIDs: []int64{work.ID}})
+		require.ErrorIs(t, err, ErrPermissionDenied)
+
+		_, err = app.ShareCalendar(alice, storage.Grant{CalendarID: work.ID, GranteeID: 2, Access: storage.AccessRead})
+		require.NoError(t, err)
+
+		stored, err := app.GetEventByID(bob, meeting.ID)
+		require.NoError(t, err)
+		require.Equal(t, meeting.Title, stored.Title)
+
+		stored.Title = "renamed"
+		_, err = app.UpdateEvent(bob, stored)
+		require.ErrorIs(t, err, ErrPermissionDenied)
+		require.ErrorIs(t, app.RemoveEvent(bob, stored), ErrPermissionDenied)
+
+		// Requests without an owner only remove events when internal.
+		require.ErrorIs(t, app.RemoveEvent(context.Background(), stored), ErrPermissionDenied)
+
+		calendars, err := app.ListCalendars(bob, 0)
+		require.NoError(t, err)
+		require.Len(t, calendars, 1)
+		require.Equal(t, work.ID, calendars[0].ID)
+		require.Equal(t, storage.AccessRead, calendars[0].Access)
+
+		_, err = app.ListGrants(bob, work.ID)
+		require.ErrorIs(t, err, ErrPermissionDenied)
+
+		_, err = app.UpdateCalendar(bob, storage.Calendar{ID: work.ID, Name: "mine"})
+		require.ErrorIs(t, err, ErrPermissionDenied)
+
+		grants, err := app.ListGrants(alice, work.ID)
+		require.NoError(t, err)
+		require.Len(t, grants, 1)
+		require.Equal(t, storage.AccessRead, grants[0].Access)
+	})
+
+	t.Run("merging", func(t *testing.T) {
+		own, err := app.CreateEvent(bob, storage.Event{
+			Title: "gym", BeginDate: start.Add(5 * time.Hour), EndDate: start.Add(6 * time.Hour),
+		})
+		require.NoError(t, err)
+
+		events, err := app.GetDayAheadEvents(bob, EventFilter{})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []int64{meeting.ID, own.ID}, ids(events))
+
+		events, err = app.GetDayAheadEvents(bob, EventFilter{CalendarIDs: []int64{work.ID}})
+		require.NoError(t, err)
+		require.Equal(t, []int64{meeting.ID}, ids(events))
+
+		events, err = app.GetEventsInRange(bob, start, start.AddDate(0, 0, 1), EventFilter{
+			CalendarIDs: []int64{work.ID, own.CalendarID},
+		})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []int64{meeting.ID, own.ID}, ids(events))
+
+		// Alice's personal calendar stays private.
+		events, err = app.GetDayAheadEvents(alice, EventFilter{})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []int64{personal.ID, meeting.ID}, ids(events))
+	})
+
+	t.Run("writing", func(t *testing.T) {
+		_, err := app.ShareCalendar(alice, storage.Grant{CalendarID: work.ID, GranteeID: 3, Access: storage.AccessWrite})
+		require.NoError(t, err)
+
+		// Events written by a grantee belong to the owner of the calendar.
+		standup, err := app.CreateEvent(carol, storage.Event{
+			Title: "standup", BeginDate: start.Add(7 * time.Hour), EndDate: start.Add(8 * time.Hour), CalendarID: work.ID,
+		})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), standup.OwnerID)
+
+		standup.Title = "retro"
+		standup, err = app.UpdateEvent(carol, standup)
+		require.NoError(t, err)
+		require.Equal(t, work.ID, standup.CalendarID)
+
+		// Zero calendar keeps the current one along with its owner.
+		standup.CalendarID = 0
+		standup.OwnerID = 3
+		standup, err = app.UpdateEvent(carol, standup)
+		require.NoError(t, err)
+		require.Equal(t, work.ID, standup.CalendarID)
+
+		// Moving the event needs the write access to the new calendar too.
+		_, err = app.UpdateEvent(carol, storage.Event{
+			ID: standup.ID, Title: "moved", BeginDate: standup.BeginDate, EndDate: standup.EndDate,
+			CalendarID: defaultCalendar.ID,
+		})
+		require.ErrorIs(t, err, ErrInvalidEvent)
+
+		// Reminders of the shared events are dismissed by the writers only.
+		reminders, err := app.ListReminders(bob, standup.ID)
+		require.NoError(t, err)
+		require.NotEmpty(t, reminders)
+
+		_, err = app.DismissReminder(bob, reminders[0].ID)
+		require.ErrorIs(t, err, ErrPermissionDenied)
+
+		dismissed, err := app.DismissReminder(carol, reminders[0].ID)
+		require.NoError(t, err)
+		require.Equal(t, storage.ReminderDismissed, dismissed.State)
+
+		require.NoError(t, app.UnshareCalendar(alice, work.ID, 3))
+		require.ErrorIs(t, app.UnshareCalendar(alice, work.ID, 3), ErrGrantNotFound)
+		require.ErrorIs(t, app.RemoveEvent(carol, standup), ErrEventNotFound)
+
+		require.NoError(t, app.RemoveEvent(alice, standup))
+	})
+
+	t.Run("watching", func(t *testing.T) {
+		watchCtx, cancel := context.WithCancel(bob)
+		defer cancel()
+
+		changes, err := app.WatchEvents(watchCtx, 0, 0, EventFilter{})
+		require.NoError(t, err)
+
+		// Alice's personal calendar stays private, the shared one is watched.
+		personal.Title = "orthodontist"
+		_, err = app.UpdateEvent(alice, personal)
+		require.NoError(t, err)
+		review, err := app.CreateEvent(alice, storage.Event{
+			Title: "review", BeginDate: start.Add(9 * time.Hour), EndDate: start.Add(10 * time.Hour), CalendarID: work.ID,
+		})
+		require.NoError(t, err)
+		require.NoError(t, app.RemoveEvent(alice, review))
+
+		created := receive(t, changes)
+		require.Equal(t, storage.EventCreated, created.Type)
+		require.Equal(t, review.ID, created.Event.ID)
+
+		for {
+			change := receive(t, changes)
+			require.Equal(t, review.ID, change.Event.ID)
+			if change.Type == storage.EventDeleted {
+				break
+			}
+		}
+	})
+
+	t.Run("removing", func(t *testing.T) {
+		require.ErrorIs(t, app.RemoveCalendar(alice, defaultCalendar.ID), ErrInvalidCalendar)
+		require.ErrorIs(t, app.RemoveCalendar(bob, work.ID), ErrPermissionDenied)
+		require.ErrorIs(t, app.RemoveCalendar(alice, work.ID), ErrCalendarNotEmpty)
+
+		require.NoError(t, app.RemoveEvent(alice, meeting))
+		require.NoError(t, app.RemoveCalendar(alice, work.ID))
+
+		calendars, err := app.ListCalendars(bob, 0)
+		require.NoError(t, err)
+		require.Len(t, calendars, 1)
+		require.True(t, calendars[0].Default)
+	})
+}
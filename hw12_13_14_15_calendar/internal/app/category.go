@@ -0,0 +1,389 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	// MaxTags is the number of tags an event may have.
+	MaxTags = 20
+
+	maxTagLength          = 64
+	maxCategoryNameLength = 64
+)
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrCategoryExists   = errors.New("category with that name already exists")
+	ErrInvalidCategory  = errors.New("invalid category")
+	ErrInvalidFilter    = errors.New("invalid event filter")
+	ErrGetCategories    = errors.New("getting categories error")
+	ErrSaveCategory     = errors.New("saving category error")
+	ErrRemoveCategory   = errors.New("removing category error")
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// EventFilter selects events by their calendars, category and tags, the zero filter selects all of them.
+type EventFilter struct {
+	// CalendarIDs merge the events of several calendars, none standing for all the readable ones.
+	CalendarIDs []int64
+	CategoryID  int64
+	// Tags are all required to be on an event.
+	Tags []string
+}
+
+// Match reports whether the event passes the filter, tags are expected to be normalized.
+func (f EventFilter) Match(event storage.Event) bool {
+	if len(f.CalendarIDs) > 0 {
+		found := false
+		for _, id := range f.CalendarIDs {
+			if event.CalendarID == id {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if f.CategoryID != 0 && event.CategoryID != f.CategoryID {
+		return false
+	}
+
+	for _, tag := range f.Tags {
+		found := false
+		for _, eventTag := range event.Tags {
+			if eventTag == tag {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetCategory returns the category, hiding categories of other owners from an authenticated request.
+func (a *App) GetCategory(ctx context.Context, id int64) (storage.Category, error) {
+	category, err := a.Storage.GetCategory(ctx, id)
+	if err != nil {
+		return category, wrapCategoryError(ErrGetCategories, err)
+	}
+
+	if ownerID, ok := OwnerFromContext(ctx); ok && category.OwnerID != ownerID {
+		return storage.Category{}, fmt.Errorf("%w: %d", ErrCategoryNotFound, id)
+	}
+
+	return category, nil
+}
+
+// ListCategories returns the categories of the owner ordered by name.
+// Authenticated requests may only list their own ones, zero owner standing for them.
+func (a *App) ListCategories(ctx context.Context, ownerID int64) ([]storage.Category, error) {
+	ownerID, err := resolveOwner(ctx, ownerID, "categories")
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := a.Storage.ListCategories(ctx, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrGetCategories, err.Error())
+	}
+
+	return categories, nil
+}
+
+// CreateCategory saves a new category of the owner.
+func (a *App) CreateCategory(ctx context.Context, category storage.Category) (storage.Category, error) {
+	ownerID, err := resolveOwner(ctx, category.OwnerID, "category")
+	if err != nil {
+		return category, err
+	}
+	category.OwnerID = ownerID
+
+	if category, err = normalizeCategory(category); err != nil {
+		return category, err
+	}
+
+	category, err = a.Storage.CreateCategory(ctx, category)
+	if err != nil {
+		return category, wrapCategoryError(ErrSaveCategory, err)
+	}
+
+	return category, nil
+}
+
+// UpdateCategory replaces the name, the color and the reminders of the category, its owner stays the same.
+// New reminders apply to the events created from then on.
+func (a *App) UpdateCategory(ctx context.Context, category storage.Category) (storage.Category, error) {
+	current, err := a.GetCategory(ctx, category.ID)
+	if err != nil {
+		return category, err
+	}
+	category.OwnerID = current.OwnerID
+
+	if category, err = normalizeCategory(category); err != nil {
+		return category, err
+	}
+
+	category, err = a.Storage.UpdateCategory(ctx, category)
+	if err != nil {
+		return category, wrapCategoryError(ErrSaveCategory, err)
+	}
+
+	return category, nil
+}
+
+// RemoveCategory removes the category, its events are left without a category.
+func (a *App) RemoveCategory(ctx context.Context, id int64) error {
+	return a.Storage.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := a.GetCategory(ctx, id); err != nil {
+			return err
+		}
+
+		if err := a.Storage.RemoveCategory(ctx, id); err != nil {
+			return wrapCategoryError(ErrRemoveCategory, err)
+		}
+
+		return nil
+	})
+}
+
+// checkCategory makes sure the category of the event is one of its owner's.
+func (a *App) checkCategory(ctx context.Context, event storage.Event) error {
+	if event.CategoryID == 0 {
+		return nil
+	}
+
+	category, err := a.Storage.GetCategory(ctx, event.CategoryID)
+	if errors.Is(err, storage.ErrCategoryNotFound) || err == nil && category.OwnerID != event.OwnerID {
+		return fmt.Errorf("%w: unknown category %d", ErrInvalidEvent, event.CategoryID)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrGetCategories, err.Error())
+	}
+
+	return nil
+}
+
+// defaultReminders returns the reminder offsets of a new event of the category, the calendar ones
+// unless the category has its own.
+func (a *App) defaultReminders(ctx context.Context, categoryID int64) ([]time.Duration, error) {
+	if categoryID == 0 {
+		return DefaultReminders, nil
+	}
+
+	category, err := a.Storage.GetCategory(ctx, categoryID)
+	if err != nil {
+		return nil, wrapCategoryError(ErrGetCategories, err)
+	}
+
+	if len(category.Reminders) == 0 {
+		return DefaultReminders, nil
+	}
+
+	return category.Reminders, nil
+}
+
+// syncTags replaces the tags of the event with the normalized ones, nil tags keeping the current ones.
+// The tags of the event are returned.
+func (a *App) syncTags(ctx context.Context, event storage.Event, tags []string) ([]string, error) {
+	if tags == nil {
+		current, err := a.Storage.GetEventTags(ctx, event.ID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrGetEvent, err.Error())
+		}
+
+		return current[event.ID], nil
+	}
+
+	if err := a.Storage.SetEventTags(ctx, event.ID, tags); err != nil {
+		return nil, wrapStorageError(ErrUpdateEvent, err)
+	}
+
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	return tags, nil
+}
+
+// withTags fills the tags of the events in place.
+func (a *App) withTags(ctx context.Context, events []storage.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+
+	tags, err := a.Storage.GetEventTags(ctx, ids...)
+	if err != nil {
+		return err
+	}
+
+	for i := range events {
+		events[i].Tags = tags[events[i].ID]
+	}
+
+	return nil
+}
+
+// withDetails fills the tags and the reminders of the events in place.
+func (a *App) withDetails(ctx context.Context, events []storage.Event) error {
+	if err := a.withTags(ctx, events); err != nil {
+		return err
+	}
+
+	return a.withReminders(ctx, events)
+}
+
+// normalized returns the filter with normalized tags.
+func (f EventFilter) normalized() (EventFilter, error) {
+	tags, err := normalizeTags(f.Tags)
+	if err != nil {
+		return f, fmt.Errorf("%w: %s", ErrInvalidFilter, err.Error())
+	}
+	f.Tags = tags
+
+	return f, nil
+}
+
+// filterEvents keeps the events passing the normalized filter, filling their tags when it has any.
+func (a *App) filterEvents(ctx context.Context, events []storage.Event, filter EventFilter) ([]storage.Event, error) {
+	if len(filter.Tags) > 0 {
+		if err := a.withTags(ctx, events); err != nil {
+			return nil, err
+		}
+	}
+
+	selected := events[:0]
+	for _, event := range events {
+		if filter.Match(event) {
+			selected = append(selected, event)
+		}
+	}
+
+	return selected, nil
+}
+
+// selectEvents keeps the events visible to the request and passing the filter, with their details filled.
+func (a *App) selectEvents(ctx context.Context, events []storage.Event, filter EventFilter) ([]storage.Event, error) {
+	filter, err := filter.normalized()
+	if err != nil {
+		return nil, err
+	}
+
+	if events, err = a.filterVisible(ctx, events, filter.CalendarIDs); err != nil {
+		return nil, err
+	}
+
+	// Categories are known before the details are loaded, tags only after.
+	if filter.CategoryID != 0 {
+		selected := events[:0]
+		for _, event := range events {
+			if event.CategoryID == filter.CategoryID {
+				selected = append(selected, event)
+			}
+		}
+		events = selected
+	}
+
+	if err := a.withDetails(ctx, events); err != nil {
+		return nil, err
+	}
+
+	selected := events[:0]
+	for _, event := range events {
+		if filter.Match(event) {
+			selected = append(selected, event)
+		}
+	}
+
+	return selected, nil
+}
+
+// normalizeTags trims and lowercases the tags, sorting them and dropping duplicates.
+// Nil tags stay nil.
+func normalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, errors.New("tags must not be empty")
+		}
+
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d bytes", tag, maxTagLength)
+		}
+
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	if len(normalized) > MaxTags {
+		return nil, fmt.Errorf("%d tags, %d at most", len(normalized), MaxTags)
+	}
+	sort.Strings(normalized)
+
+	return normalized, nil
+}
+
+// normalizeCategory trims the name and lowercases the color of the category, validating it.
+func normalizeCategory(category storage.Category) (storage.Category, error) {
+	category.Name = strings.TrimSpace(category.Name)
+	category.Color = strings.ToLower(strings.TrimSpace(category.Color))
+
+	if category.Name == "" {
+		return category, fmt.Errorf("%w: name is required", ErrInvalidCategory)
+	}
+
+	if len(category.Name) > maxCategoryNameLength {
+		return category, fmt.Errorf("%w: name is longer than %d bytes", ErrInvalidCategory, maxCategoryNameLength)
+	}
+
+	if category.Color != "" && !colorPattern.MatchString(category.Color) {
+		return category, fmt.Errorf("%w: color must be formatted as #rrggbb", ErrInvalidCategory)
+	}
+
+	if err := validateReminders(category.Reminders); err != nil {
+		return category, fmt.Errorf("%w: %s", ErrInvalidCategory, err.Error())
+	}
+
+	return category, nil
+}
+
+func wrapCategoryError(kind error, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrCategoryNotFound):
+		return fmt.Errorf("%w: %s", ErrCategoryNotFound, err.Error())
+	case errors.Is(err, storage.ErrCategoryExists):
+		return fmt.Errorf("%w: %s", ErrCategoryExists, err.Error())
+	default:
+		return fmt.Errorf("%w: %s", kind, err.Error())
+	}
+}
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategories(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, time.November, 2, 9, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	app := New(nil, memorystorage.New(clk), clk)
+	first, second := ContextWithOwner(ctx, 1), ContextWithOwner(ctx, 2)
+
+	work, err := app.CreateCategory(first, storage.Category{
+		Name: " work ", Color: "#FF0000", Reminders: storage.Offsets{time.Hour, 10 * time.Minute},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "work", work.Name)
+	require.Equal(t, "#ff0000", work.Color)
+	require.Equal(t, int64(1), work.OwnerID)
+
+	t.Run("crud", func(t *testing.T) {
+		_, err := app.CreateCategory(first, storage.Category{Name: "work"})
+		require.ErrorIs(t, err, ErrCategoryExists)
+
+		for _, invalid := range []storage.Category{
+			{Name: " "},
+			{Name: "colored", Color: "red"},
+			{Name: "late", Reminders: storage.Offsets{-time.Minute}},
+		} {
+			_, err := app.CreateCategory(first, invalid)
+			require.ErrorIs(t, err, ErrInvalidCategory)
+		}
+
+		_, err = app.CreateCategory(second, storage.Category{Name: "other", OwnerID: 1})
+		require.ErrorIs(t, err, ErrPermissionDenied)
+
+		// Categories of other owners are hidden.
+		_, err = app.GetCategory(second, work.ID)
+		require.ErrorIs(t, err, ErrCategoryNotFound)
+
+		_, err = app.UpdateCategory(second, storage.Category{ID: work.ID, Name: "taken"})
+		require.ErrorIs(t, err, ErrCategoryNotFound)
+
+		require.ErrorIs(t, app.RemoveCategory(second, work.ID), ErrCategoryNotFound)
+
+		home, err := app.CreateCategory(first, storage.Category{Name: "home"})
+		require.NoError(t, err)
+
+		home, err = app.UpdateCategory(first, storage.Category{ID: home.ID, Name: "family", OwnerID: 2})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), home.OwnerID)
+
+		categories, err := app.ListCategories(first, 0)
+		require.NoError(t, err)
+		require.Equal(t, []storage.Category{home, work}, categories)
+
+		categories, err = app.ListCategories(second, 0)
+		require.NoError(t, err)
+		require.Empty(t, categories)
+
+		require.NoError(t, app.RemoveCategory(first, home.ID))
+		_, err = app.GetCategory(first, home.ID)
+		require.ErrorIs(t, err, ErrCategoryNotFound)
+	})
+
+	t.Run("events", func(t *testing.T) {
+		// Events of the category get its reminders unless given their own.
+		event, err := app.CreateEvent(first, storage.Event{
+			Title: "meeting", BeginDate: start.Add(2 * time.Hour), EndDate: start.Add(3 * time.Hour),
+			CategoryID: work.ID, Tags: []string{" Client ", "urgent", "client"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []time.Duration{time.Hour, 10 * time.Minute}, event.Reminders)
+		require.Equal(t, []string{"client", "urgent"}, event.Tags)
+
+		stored, err := app.GetEventByID(first, event.ID)
+		require.NoError(t, err)
+		require.Equal(t, work.ID, stored.CategoryID)
+		require.Equal(t, []string{"client", "urgent"}, stored.Tags)
+
+		_, err = app.CreateEvent(second, storage.Event{
+			Title: "foreign", BeginDate: start.Add(4 * time.Hour), EndDate: start.Add(5 * time.Hour),
+			CategoryID: work.ID,
+		})
+		require.ErrorIs(t, err, ErrInvalidEvent)
+
+		_, err = app.CreateEvent(first, storage.Event{
+			Title: "tagged", BeginDate: start.Add(4 * time.Hour), EndDate: start.Add(5 * time.Hour),
+			Tags: []string{""},
+		})
+		require.ErrorIs(t, err, ErrInvalidEvent)
+
+		// Nil tags keep the current ones, empty ones remove them.
+		stored.Title = "renamed"
+		stored.Tags = nil
+		updated, err := app.UpdateEvent(first, stored)
+		require.NoError(t, err)
+		require.Equal(t, []string{"client", "urgent"}, updated.Tags)
+
+		lunch, err := app.CreateEvent(first, storage.Event{
+			Title: "lunch", BeginDate: start.Add(4 * time.Hour), EndDate: start.Add(5 * time.Hour),
+			Tags: []string{"client"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, DefaultReminders, lunch.Reminders)
+
+		selected := func(filter EventFilter) []int64 {
+			t.Helper()
+
+			events, err := app.GetDayAheadEvents(first, filter)
+			require.NoError(t, err)
+
+			result := make([]int64, 0, len(events))
+			for _, event := range events {
+				result = append(result, event.ID)
+			}
+
+			return result
+		}
+
+		require.ElementsMatch(t, []int64{event.ID, lunch.ID}, selected(EventFilter{}))
+		require.Equal(t, []int64{event.ID}, selected(EventFilter{CategoryID: work.ID}))
+		require.ElementsMatch(t, []int64{event.ID, lunch.ID}, selected(EventFilter{Tags: []string{"CLIENT"}}))
+		require.Equal(t, []int64{event.ID}, selected(EventFilter{Tags: []string{"client", "urgent"}}))
+		require.Empty(t, selected(EventFilter{CategoryID: work.ID, Tags: []string{"food"}}))
+
+		_, err = app.GetEventsInRange(first, start, start.Add(time.Hour), EventFilter{Tags: []string{" "}})
+		require.ErrorIs(t, err, ErrInvalidFilter)
+
+		updated.Tags = []string{}
+		updated, err = app.UpdateEvent(first, updated)
+		require.NoError(t, err)
+		require.Empty(t, updated.Tags)
+		require.Equal(t, []int64{lunch.ID}, selected(EventFilter{Tags: []string{"client"}}))
+
+		// Removing the category keeps its events.
+		require.NoError(t, app.RemoveCategory(first, work.ID))
+		stored, err = app.GetEventByID(first, event.ID)
+		require.NoError(t, err)
+		require.Zero(t, stored.CategoryID)
+	})
+}
@@ -0,0 +1,214 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	// Owner time zones resolve without the system zoneinfo, e.g. in minimal images.
+	_ "time/tzdata"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+const digestTimeLayout = "15:04"
+
+var (
+	ErrInvalidDigest  = errors.New("invalid digest subscription")
+	ErrDigestNotFound = errors.New("digest subscription not found")
+	ErrGetDigest      = errors.New("getting digest subscription error")
+	ErrSetDigest      = errors.New("setting digest subscription error")
+	ErrRemoveDigest   = errors.New("removing digest subscription error")
+	ErrBuildDigests   = errors.New("building digests error")
+)
+
+// Digest is the agenda of an owner's upcoming day or week.
+type Digest struct {
+	Subscription storage.DigestSubscription
+	// DueAt is the moment the digest was scheduled for.
+	DueAt time.Time
+	// Begin and End bound the covered local days.
+	Begin  time.Time
+	End    time.Time
+	Events []storage.Event
+}
+
+// GetDigestSubscription returns the digest subscription of the owner.
+// Authenticated requests may only read their own one, zero owner standing for it.
+func (a *App) GetDigestSubscription(ctx context.Context, ownerID int64) (storage.DigestSubscription, error) {
+	ownerID, err := resolveOwner(ctx, ownerID, "digest")
+	if err != nil {
+		return storage.DigestSubscription{}, err
+	}
+
+	subscription, err := a.Storage.GetDigestSubscription(ctx, ownerID)
+	if err != nil {
+		return subscription, wrapDigestError(ErrGetDigest, err)
+	}
+
+	return subscription, nil
+}
+
+// SetDigestSubscription creates or replaces the digest subscription of the owner.
+// A new subscription gets its first digest at the next due time rather than at once.
+func (a *App) SetDigestSubscription(
+	ctx context.Context,
+	subscription storage.DigestSubscription,
+) (storage.DigestSubscription, error) {
+	ownerID, err := resolveOwner(ctx, subscription.OwnerID, "digest")
+	if err != nil {
+		return subscription, err
+	}
+	subscription.OwnerID = ownerID
+
+	if err := validateDigest(subscription); err != nil {
+		return subscription, err
+	}
+
+	now := a.Clock.Now()
+	subscription.LastSentAt = &now
+
+	subscription, err = a.Storage.SetDigestSubscription(ctx, subscription)
+	if err != nil {
+		return subscription, fmt.Errorf("%w: %s", ErrSetDigest, err.Error())
+	}
+
+	return subscription, nil
+}
+
+// RemoveDigestSubscription stops the digests of the owner.
+func (a *App) RemoveDigestSubscription(ctx context.Context, ownerID int64) error {
+	ownerID, err := resolveOwner(ctx, ownerID, "digest")
+	if err != nil {
+		return err
+	}
+
+	if err := a.Storage.RemoveDigestSubscription(ctx, ownerID); err != nil {
+		return wrapDigestError(ErrRemoveDigest, err)
+	}
+
+	return nil
+}
+
+// DueDigests builds the digests due by now, which have not been sent yet.
+// Digests missed while nobody was asking collapse into the latest one.
+// A broken subscription does not hold the others back, the digests built are returned
+// along with the error about it.
+func (a *App) DueDigests(ctx context.Context) ([]Digest, error) {
+	subscriptions, err := a.Storage.ListDigestSubscriptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBuildDigests, err.Error())
+	}
+
+	now := a.Clock.Now()
+
+	var (
+		digests []Digest
+		skipped error
+	)
+	for _, subscription := range subscriptions {
+		due, err := LastDigestDue(subscription, now)
+		if err != nil {
+			skipped = fmt.Errorf("%w: owner %d: %s", ErrBuildDigests, subscription.OwnerID, err.Error())
+			continue
+		}
+
+		if subscription.LastSentAt != nil && !subscription.LastSentAt.Before(due) {
+			continue
+		}
+
+		digest := Digest{Subscription: subscription, DueAt: due}
+		digest.Begin, digest.End = digestPeriod(subscription, due)
+
+		// The agenda reads chronologically, the way the events come from the storage.
+		events, err := a.GetEventsInRange(ContextWithOwner(ctx, subscription.OwnerID), digest.Begin, digest.End, EventFilter{})
+		if err != nil {
+			skipped = fmt.Errorf("%w: owner %d: %s", ErrBuildDigests, subscription.OwnerID, err.Error())
+			continue
+		}
+		digest.Events = events
+
+		digests = append(digests, digest)
+	}
+
+	return digests, skipped
+}
+
+// MarkDigestSent keeps the digest from being sent again.
+func (a *App) MarkDigestSent(ctx context.Context, digest Digest) error {
+	if err := a.Storage.MarkDigestSent(ctx, digest.Subscription.OwnerID, digest.DueAt); err != nil {
+		return wrapDigestError(ErrSetDigest, err)
+	}
+
+	return nil
+}
+
+// LastDigestDue returns the latest moment not after now the digest is scheduled for, in the owner's time zone.
+func LastDigestDue(subscription storage.DigestSubscription, now time.Time) (time.Time, error) {
+	location, err := time.LoadLocation(subscription.TimeZone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidDigest, err.Error())
+	}
+
+	at, err := time.Parse(digestTimeLayout, subscription.Time)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidDigest, err.Error())
+	}
+
+	local := now.In(location)
+	due := time.Date(local.Year(), local.Month(), local.Day(), at.Hour(), at.Minute(), 0, 0, location)
+
+	step := 1
+	if subscription.Period == storage.DigestWeekly {
+		step = 7
+		back := (int(local.Weekday()) - subscription.Weekday + 7) % 7
+		due = time.Date(due.Year(), due.Month(), due.Day()-back, at.Hour(), at.Minute(), 0, 0, location)
+	}
+
+	if due.After(now) {
+		due = time.Date(due.Year(), due.Month(), due.Day()-step, at.Hour(), at.Minute(), 0, 0, location)
+	}
+
+	return due, nil
+}
+
+// digestPeriod returns the local days covered by the digest due at the given moment.
+func digestPeriod(subscription storage.DigestSubscription, due time.Time) (time.Time, time.Time) {
+	days := 1
+	if subscription.Period == storage.DigestWeekly {
+		days = 7
+	}
+
+	begin := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, due.Location())
+
+	return begin, begin.AddDate(0, 0, days)
+}
+
+func validateDigest(subscription storage.DigestSubscription) error {
+	if subscription.Period != storage.DigestDaily && subscription.Period != storage.DigestWeekly {
+		return fmt.Errorf("%w: period must be %q or %q", ErrInvalidDigest, storage.DigestDaily, storage.DigestWeekly)
+	}
+
+	if subscription.Weekday < 0 || subscription.Weekday > 6 {
+		return fmt.Errorf("%w: weekday must be from 0 (Sunday) to 6", ErrInvalidDigest)
+	}
+
+	if _, err := time.Parse(digestTimeLayout, subscription.Time); err != nil {
+		return fmt.Errorf("%w: time must be formatted as %s", ErrInvalidDigest, digestTimeLayout)
+	}
+
+	if _, err := time.LoadLocation(subscription.TimeZone); err != nil {
+		return fmt.Errorf("%w: unknown time zone %q", ErrInvalidDigest, subscription.TimeZone)
+	}
+
+	return nil
+}
+
+func wrapDigestError(kind error, err error) error {
+	if errors.Is(err, storage.ErrDigestNotFound) {
+		return fmt.Errorf("%w: %s", ErrDigestNotFound, err.Error())
+	}
+
+	return fmt.Errorf("%w: %s", kind, err.Error())
+}
@@ -0,0 +1,208 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastDigestDue(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	// Tuesday, 12:00 in Moscow.
+	now := time.Date(2021, time.November, 2, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		subscription storage.DigestSubscription
+		due          time.Time
+	}{
+		{
+			name:         "daily passed today",
+			subscription: storage.DigestSubscription{Period: storage.DigestDaily, Time: "08:00", TimeZone: "Europe/Moscow"},
+			due:          time.Date(2021, time.November, 2, 8, 0, 0, 0, moscow),
+		},
+		{
+			name:         "daily later today",
+			subscription: storage.DigestSubscription{Period: storage.DigestDaily, Time: "18:00", TimeZone: "Europe/Moscow"},
+			due:          time.Date(2021, time.November, 1, 18, 0, 0, 0, moscow),
+		},
+		{
+			name:         "daily in utc",
+			subscription: storage.DigestSubscription{Period: storage.DigestDaily, Time: "09:00"},
+			due:          time.Date(2021, time.November, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "weekly on monday",
+			subscription: storage.DigestSubscription{Period: storage.DigestWeekly, Time: "08:00", TimeZone: "Europe/Moscow", Weekday: 1},
+			due:          time.Date(2021, time.November, 1, 8, 0, 0, 0, moscow),
+		},
+		{
+			name:         "weekly later today",
+			subscription: storage.DigestSubscription{Period: storage.DigestWeekly, Time: "18:00", TimeZone: "Europe/Moscow", Weekday: 2},
+			due:          time.Date(2021, time.October, 26, 18, 0, 0, 0, moscow),
+		},
+		{
+			name:         "weekly on sunday",
+			subscription: storage.DigestSubscription{Period: storage.DigestWeekly, Time: "20:00", TimeZone: "Europe/Moscow"},
+			due:          time.Date(2021, time.October, 31, 20, 0, 0, 0, moscow),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			due, err := LastDigestDue(tt.subscription, now)
+			require.NoError(t, err)
+			require.True(t, tt.due.Equal(due), "due %s, expected %s", due, tt.due)
+		})
+	}
+}
+
+func TestDigests(t *testing.T) {
+	ctx := context.Background()
+	// Tuesday, 07:00 in Moscow.
+	start := time.Date(2021, time.November, 2, 4, 0, 0, 0, time.UTC)
+
+	t.Run("subscriptions", func(t *testing.T) {
+		app := New(nil, memorystorage.New(clock.Real{}), clock.Real{})
+		daily := storage.DigestSubscription{Period: storage.DigestDaily, Time: "08:00", TimeZone: "Europe/Moscow"}
+
+		_, err := app.SetDigestSubscription(ctx, daily)
+		require.ErrorIs(t, err, ErrInvalidOwner)
+
+		stored, err := app.SetDigestSubscription(ContextWithOwner(ctx, 1), daily)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), stored.OwnerID)
+		require.NotNil(t, stored.LastSentAt)
+
+		_, err = app.GetDigestSubscription(ContextWithOwner(ctx, 1), 2)
+		require.ErrorIs(t, err, ErrPermissionDenied)
+
+		_, err = app.GetDigestSubscription(ctx, 2)
+		require.ErrorIs(t, err, ErrDigestNotFound)
+
+		for _, invalid := range []storage.DigestSubscription{
+			{OwnerID: 1, Period: "month", Time: "08:00"},
+			{OwnerID: 1, Period: storage.DigestDaily, Time: "8am"},
+			{OwnerID: 1, Period: storage.DigestDaily, Time: "08:00", TimeZone: "Mars/Olympus"},
+			{OwnerID: 1, Period: storage.DigestWeekly, Time: "08:00", Weekday: 7},
+		} {
+			_, err := app.SetDigestSubscription(ctx, invalid)
+			require.ErrorIs(t, err, ErrInvalidDigest, invalid)
+		}
+
+		require.NoError(t, app.RemoveDigestSubscription(ContextWithOwner(ctx, 1), 0))
+		require.ErrorIs(t, app.RemoveDigestSubscription(ctx, 1), ErrDigestNotFound)
+	})
+
+	t.Run("due digests", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		app := New(nil, memorystorage.New(clk), clk)
+
+		moscow, err := time.LoadLocation("Europe/Moscow")
+		require.NoError(t, err)
+		today := time.Date(2021, time.November, 2, 0, 0, 0, 0, moscow)
+
+		for _, event := range []storage.Event{
+			{Title: "standup", OwnerID: 1, BeginDate: today.Add(10 * time.Hour), EndDate: today.Add(10*time.Hour + 15*time.Minute)},
+			{Title: "lunch", OwnerID: 1, BeginDate: today.Add(13 * time.Hour), EndDate: today.Add(14 * time.Hour)},
+			{Title: "tomorrow", OwnerID: 1, BeginDate: today.Add(34 * time.Hour), EndDate: today.Add(35 * time.Hour)},
+			{Title: "foreign", OwnerID: 2, BeginDate: today.Add(11 * time.Hour), EndDate: today.Add(12 * time.Hour)},
+		} {
+			_, err := app.CreateEvent(ctx, event)
+			require.NoError(t, err)
+		}
+
+		_, err = app.SetDigestSubscription(ctx, storage.DigestSubscription{
+			OwnerID: 1, Period: storage.DigestDaily, Time: "08:00", TimeZone: "Europe/Moscow",
+		})
+		require.NoError(t, err)
+		_, err = app.SetDigestSubscription(ctx, storage.DigestSubscription{
+			OwnerID: 2, Period: storage.DigestWeekly, Time: "08:00", TimeZone: "Europe/Moscow", Weekday: 1,
+		})
+		require.NoError(t, err)
+
+		// Nothing is due right after subscribing.
+		digests, err := app.DueDigests(ctx)
+		require.NoError(t, err)
+		require.Empty(t, digests)
+
+		clk.Advance(time.Hour)
+		digests, err = app.DueDigests(ctx)
+		require.NoError(t, err)
+		require.Len(t, digests, 1)
+
+		digest := digests[0]
+		require.Equal(t, int64(1), digest.Subscription.OwnerID)
+		require.True(t, today.Add(8*time.Hour).Equal(digest.DueAt))
+		require.True(t, today.Equal(digest.Begin))
+		require.True(t, today.AddDate(0, 0, 1).Equal(digest.End))
+		require.Len(t, digest.Events, 2)
+		require.Equal(t, "standup", digest.Events[0].Title)
+		require.Equal(t, "lunch", digest.Events[1].Title)
+
+		require.NoError(t, app.MarkDigestSent(ctx, digest))
+		digests, err = app.DueDigests(ctx)
+		require.NoError(t, err)
+		require.Empty(t, digests)
+
+		// Next Monday brings both, the daily one covering that day only.
+		clk.Set(time.Date(2021, time.November, 8, 9, 0, 0, 0, moscow))
+		digests, err = app.DueDigests(ctx)
+		require.NoError(t, err)
+		require.Len(t, digests, 2)
+		require.Equal(t, int64(2), digests[1].Subscription.OwnerID)
+		require.True(t, time.Date(2021, time.November, 15, 0, 0, 0, 0, moscow).Equal(digests[1].End))
+	})
+
+	t.Run("broken subscriber", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		app := New(nil, &failingEvents{memorystorage.New(clk), 2}, clk)
+
+		for _, ownerID := range []int64{1, 2, 3} {
+			_, err := app.SetDigestSubscription(ctx, storage.DigestSubscription{
+				OwnerID: ownerID, Period: storage.DigestDaily, Time: "08:00", TimeZone: "Europe/Moscow",
+			})
+			require.NoError(t, err)
+		}
+
+		// The others get their digests despite the failing one.
+		clk.Advance(time.Hour)
+		digests, err := app.DueDigests(ctx)
+		require.ErrorIs(t, err, ErrBuildDigests)
+		require.Contains(t, err.Error(), "owner 2")
+		require.Len(t, digests, 2)
+		require.Equal(t, int64(1), digests[0].Subscription.OwnerID)
+		require.Equal(t, int64(3), digests[1].Subscription.OwnerID)
+	})
+}
+
+var errEventsUnavailable = errors.New("events unavailable")
+
+// failingEvents fails to read the events of an owner.
+type failingEvents struct {
+	*memorystorage.Storage
+	ownerID int64
+}
+
+func (s *failingEvents) GetOwnerEventsInRange(
+	ctx context.Context,
+	begin, end time.Time,
+	ownerIDs ...int64,
+) ([]storage.Event, error) {
+	for _, ownerID := range ownerIDs {
+		if ownerID == s.ownerID {
+			return nil, errEventsUnavailable
+		}
+	}
+
+	return s.Storage.GetOwnerEventsInRange(ctx, begin, end, ownerIDs...)
+}
@@ -0,0 +1,408 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+const workingHoursClockLayout = "15:04"
+
+// DefaultMaxFreeBusyRange is the longest period free/busy is looked up for unless configured otherwise.
+const DefaultMaxFreeBusyRange = 90 * 24 * time.Hour
+
+var (
+	ErrGetFreeBusy         = errors.New("getting free/busy error")
+	ErrSuggestSlots        = errors.New("suggesting slots error")
+	ErrInvalidPeriod       = errors.New("period end must be after its begin")
+	ErrInvalidRange        = errors.New("period is too long")
+	ErrInvalidWorkingHours = errors.New("invalid working hours")
+	ErrEmptyOwners         = errors.New("at least one owner id is required")
+	ErrInvalidSlotDuration = errors.New("slot duration must be positive")
+	ErrInvalidSlotsLimit   = errors.New("slots limit must be positive")
+)
+
+// Interval is a [Begin, End) time span.
+type Interval struct {
+	Begin time.Time `json:"begin_date"`
+	End   time.Time `json:"end_date"`
+}
+
+// WorkingHours are offsets from midnight bounding the working day.
+// The zero value means the whole day.
+type WorkingHours struct {
+	Begin time.Duration
+	End   time.Duration
+}
+
+// ParseWorkingHours builds working hours from "15:04" formatted strings, "24:00" ending the day.
+// Empty strings stand for the beginning and the end of the day respectively.
+func ParseWorkingHours(begin, end string) (WorkingHours, error) {
+	hours := WorkingHours{End: 24 * time.Hour}
+
+	var err error
+	if begin != "" {
+		if hours.Begin, err = parseClock(begin); err != nil {
+			return hours, err
+		}
+	}
+
+	if end != "" {
+		if hours.End, err = parseClock(end); err != nil {
+			return hours, err
+		}
+	}
+
+	return hours, hours.validate()
+}
+
+// parseClock returns the offset from midnight of a "15:04" formatted clock.
+func parseClock(clock string) (time.Duration, error) {
+	if clock == "24:00" {
+		return 24 * time.Hour, nil
+	}
+
+	t, err := time.Parse(workingHoursClockLayout, clock)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidWorkingHours, err.Error())
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (h WorkingHours) validate() error {
+	if h.Begin == 0 && h.End == 0 {
+		return nil
+	}
+
+	if h.Begin < 0 || h.End > 24*time.Hour || h.Begin >= h.End {
+		return fmt.Errorf("%w: %s - %s", ErrInvalidWorkingHours, h.Begin, h.End)
+	}
+
+	return nil
+}
+
+// windows splits the [begin, end) period into working intervals. The hours are wall clock ones
+// in the location of begin, so days switching to or from the daylight saving time keep them.
+func (h WorkingHours) windows(begin, end time.Time) []Interval {
+	dayBegin, dayEnd := h.Begin, h.End
+	if dayBegin == 0 && dayEnd == 0 {
+		dayEnd = 24 * time.Hour
+	}
+
+	var windows []Interval
+
+	year, month, day := begin.Date()
+	for i := 0; ; i++ {
+		window := Interval{
+			wallClock(year, month, day+i, dayBegin, begin.Location()),
+			wallClock(year, month, day+i, dayEnd, begin.Location()),
+		}
+		if !window.Begin.Before(end) {
+			break
+		}
+
+		if window.Begin.Before(begin) {
+			window.Begin = begin
+		}
+		if window.End.After(end) {
+			window.End = end
+		}
+		if window.Begin.Before(window.End) {
+			windows = append(windows, window)
+		}
+	}
+
+	return windows
+}
+
+// wallClock returns the moment the clock shows the offset from midnight on the day, 24 hours meaning
+// the midnight of the next day.
+func wallClock(year int, month time.Month, day int, offset time.Duration, loc *time.Location) time.Time {
+	return time.Date(year, month, day, int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, loc)
+}
+
+// GetFreeBusy returns merged busy intervals within working hours per each owner, of the events passing the filter.
+// Authenticated requests only see the calendars of the owners shared with them.
+func (a *App) GetFreeBusy(
+	ctx context.Context,
+	ownerIDs []int64,
+	begin, end time.Time,
+	hours WorkingHours,
+	filter EventFilter,
+) (map[int64][]Interval, error) {
+	if err := a.validateFreeBusyQuery(ownerIDs, begin, end, hours); err != nil {
+		return nil, err
+	}
+
+	filter, err := filter.normalized()
+	if err != nil {
+		return nil, err
+	}
+
+	access, err := a.busyAccess(ctx, ownerIDs, filter.CalendarIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	busy, err := a.getBusyIntervals(ctx, ownerIDs, begin, end, hours, access, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrGetFreeBusy, err.Error())
+	}
+
+	return busy, nil
+}
+
+// SuggestSlots returns up to limit earliest slots of the given duration,
+// which are free for all the owners within working hours, only the events passing the filter keeping them busy.
+func (a *App) SuggestSlots(
+	ctx context.Context,
+	ownerIDs []int64,
+	begin, end time.Time,
+	hours WorkingHours,
+	duration time.Duration,
+	limit int,
+	filter EventFilter,
+) ([]Interval, error) {
+	if err := a.validateFreeBusyQuery(ownerIDs, begin, end, hours); err != nil {
+		return nil, err
+	}
+
+	if duration <= 0 {
+		return nil, ErrInvalidSlotDuration
+	}
+
+	if limit <= 0 {
+		return nil, ErrInvalidSlotsLimit
+	}
+
+	filter, err := filter.normalized()
+	if err != nil {
+		return nil, err
+	}
+
+	access, err := a.busyAccess(ctx, ownerIDs, filter.CalendarIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	busy, err := a.getBusyIntervals(ctx, ownerIDs, begin, end, hours, access, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSuggestSlots, err.Error())
+	}
+
+	var common []Interval
+	for _, intervals := range busy {
+		common = append(common, intervals...)
+	}
+	common = mergeIntervals(common)
+
+	slots := make([]Interval, 0, limit)
+	for _, gap := range subtractIntervals(hours.windows(begin, end), common) {
+		for slotBegin := gap.Begin; !slotBegin.Add(duration).After(gap.End); slotBegin = slotBegin.Add(duration) {
+			slots = append(slots, Interval{slotBegin, slotBegin.Add(duration)})
+			if len(slots) == limit {
+				return slots, nil
+			}
+		}
+	}
+
+	return slots, nil
+}
+
+// validateFreeBusyQuery checks the query, bounding the period so that its working windows stay few.
+func (a *App) validateFreeBusyQuery(ownerIDs []int64, begin, end time.Time, hours WorkingHours) error {
+	if len(ownerIDs) == 0 {
+		return ErrEmptyOwners
+	}
+
+	if !begin.Before(end) {
+		return ErrInvalidPeriod
+	}
+
+	if a.MaxFreeBusyRange > 0 && end.Sub(begin) > a.MaxFreeBusyRange {
+		return fmt.Errorf("%w: %s, %s at most", ErrInvalidRange, end.Sub(begin), a.MaxFreeBusyRange)
+	}
+
+	return hours.validate()
+}
+
+// getBusyIntervals returns the busy intervals of the events the request may see and passing the normalized filter.
+// The category and the tags only select among the events the request may read, the others being matched
+// by their calendars alone, as their details are hidden.
+func (a *App) getBusyIntervals(
+	ctx context.Context,
+	ownerIDs []int64,
+	begin, end time.Time,
+	hours WorkingHours,
+	access func(storage.Event) storage.Access,
+	filter EventFilter,
+) (map[int64][]Interval, error) {
+	events, err := a.Storage.GetOwnerEventsInRange(ctx, begin, end, ownerIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	var readable, hidden []storage.Event
+	for _, event := range events {
+		switch {
+		case access(event).Allows(storage.AccessRead):
+			readable = append(readable, event)
+		case access(event).Allows(storage.AccessFreeBusy):
+			hidden = append(hidden, event)
+		}
+	}
+
+	if readable, err = a.filterEvents(ctx, readable, filter); err != nil {
+		return nil, err
+	}
+
+	events = readable
+	for _, event := range hidden {
+		if (EventFilter{CalendarIDs: filter.CalendarIDs}).Match(event) {
+			events = append(events, event)
+		}
+	}
+
+	busy := make(map[int64][]Interval, len(ownerIDs))
+	for _, ownerID := range ownerIDs {
+		busy[ownerID] = []Interval{}
+	}
+
+	for _, event := range events {
+		if _, ok := busy[event.OwnerID]; !ok || !event.EndDate.After(event.BeginDate) {
+			continue
+		}
+		busy[event.OwnerID] = append(busy[event.OwnerID], Interval{event.BeginDate, event.EndDate})
+	}
+
+	windows := hours.windows(begin, end)
+	for ownerID, intervals := range busy {
+		busy[ownerID] = intersectIntervals(mergeIntervals(intervals), windows)
+	}
+
+	return busy, nil
+}
+
+// mergeIntervals sorts intervals and joins overlapping and adjacent ones.
+func mergeIntervals(intervals []Interval) []Interval {
+	if len(intervals) == 0 {
+		return []Interval{}
+	}
+
+	sorted := make([]Interval, len(intervals))
+	copy(sorted, intervals)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Begin.Before(sorted[j].Begin)
+	})
+
+	merged := []Interval{sorted[0]}
+	for _, interval := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if interval.Begin.After(last.End) {
+			merged = append(merged, interval)
+			continue
+		}
+		if interval.End.After(last.End) {
+			last.End = interval.End
+		}
+	}
+
+	return merged
+}
+
+// intersectIntervals clips sorted disjoint intervals a by sorted disjoint intervals b.
+func intersectIntervals(a, b []Interval) []Interval {
+	result := []Interval{}
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		begin, end := a[i].Begin, a[i].End
+		if b[j].Begin.After(begin) {
+			begin = b[j].Begin
+		}
+		if b[j].End.Before(end) {
+			end = b[j].End
+		}
+		if begin.Before(end) {
+			result = append(result, Interval{begin, end})
+		}
+
+		if a[i].End.Before(b[j].End) {
+			i++
+		} else {
+			j++
+		}
+	}
+
+	return result
+}
+
+// subtractIntervals removes sorted disjoint intervals b from sorted disjoint intervals a.
+func subtractIntervals(a, b []Interval) []Interval {
+	result := []Interval{}
+
+	for _, interval := range a {
+		begin := interval.Begin
+		for _, cut := range b {
+			if !cut.End.After(begin) {
+				continue
+			}
+			if !cut.Begin.Before(interval.End) {
+				break
+			}
+			if cut.Begin.After(begin) {
+				result = append(result, Interval{begin, cut.Begin})
+			}
+			begin = cut.End
+		}
+		if begin.Before(interval.End) {
+			result = append(result, Interval{begin, interval.End})
+		}
+	}
+
+	return result
+}
+
+// busyAccess returns the access of the request to the events whose busy time it asks for.
+// Authenticated requests only see their own calendars and the ones shared with them,
+// asking for owners not sharing any calendar with them or for calendars they can't see being denied.
+func (a *App) busyAccess(ctx context.Context, ownerIDs []int64, calendarIDs []int64) (func(storage.Event) storage.Access, error) {
+	for _, id := range calendarIDs {
+		if _, err := a.requireCalendar(ctx, id, storage.AccessFreeBusy); err != nil {
+			return nil, err
+		}
+	}
+
+	authenticated, ok := OwnerFromContext(ctx)
+	if !ok {
+		return func(storage.Event) storage.Access { return storage.AccessOwner }, nil
+	}
+
+	shared, err := a.sharedGrants(ctx, authenticated)
+	if err != nil {
+		return nil, err
+	}
+
+	sharing := make(map[int64]bool, len(shared))
+	for _, grant := range shared {
+		sharing[grant.OwnerID] = true
+	}
+
+	for _, ownerID := range ownerIDs {
+		if ownerID != authenticated && !sharing[ownerID] {
+			return nil, fmt.Errorf("%w: free/busy of owner %d", ErrPermissionDenied, ownerID)
+		}
+	}
+
+	return func(event storage.Event) storage.Access {
+		if event.OwnerID == authenticated {
+			return storage.AccessOwner
+		}
+
+		return shared[event.CalendarID].Access
+	}, nil
+}
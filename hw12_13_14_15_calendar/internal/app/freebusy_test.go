@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeBusy(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	memory := memorystorage.New(clock.Real{})
+	titled := map[string]storage.Event{}
+	for _, event := range []storage.Event{
+		{Title: "standup", OwnerID: 1, BeginDate: at(9, 0), EndDate: at(9, 30)},
+		{Title: "review", OwnerID: 1, BeginDate: at(9, 15), EndDate: at(10, 0)},
+		{Title: "lunch", OwnerID: 1, BeginDate: at(10, 0), EndDate: at(11, 0)},
+		{Title: "late call", OwnerID: 1, BeginDate: at(20, 0), EndDate: at(21, 0)},
+		{Title: "interview", OwnerID: 2, BeginDate: at(11, 30), EndDate: at(12, 30)},
+		{Title: "foreign", OwnerID: 3, BeginDate: at(13, 0), EndDate: at(14, 0)},
+	} {
+		created, err := memory.CreateEvent(ctx, event)
+		require.NoError(t, err)
+		titled[created.Title] = created
+	}
+
+	app := New(nil, memory, clock.Real{})
+	hours, err := ParseWorkingHours("09:00", "18:00")
+	require.NoError(t, err)
+
+	t.Run("busy intervals are merged and clipped by working hours", func(t *testing.T) {
+		busy, err := app.GetFreeBusy(ctx, []int64{1, 2, 4}, day, day.AddDate(0, 0, 1), hours, EventFilter{})
+		require.NoError(t, err)
+
+		require.Equal(t, []Interval{{at(9, 0), at(11, 0)}}, busy[1])
+		require.Equal(t, []Interval{{at(11, 30), at(12, 30)}}, busy[2])
+		require.Equal(t, []Interval{}, busy[4])
+		require.NotContains(t, busy, int64(3))
+	})
+
+	t.Run("common slots skip everybody's busy time", func(t *testing.T) {
+		slots, err := app.SuggestSlots(ctx, []int64{1, 2}, day, day.AddDate(0, 0, 1), hours, 30*time.Minute, 3, EventFilter{})
+		require.NoError(t, err)
+
+		require.Equal(t, []Interval{
+			{at(11, 0), at(11, 30)},
+			{at(12, 30), at(13, 0)},
+			{at(13, 0), at(13, 30)},
+		}, slots)
+	})
+
+	t.Run("slots continue on the next working day", func(t *testing.T) {
+		slots, err := app.SuggestSlots(
+			ctx, []int64{1}, at(17, 0), day.AddDate(0, 0, 2), hours, time.Hour, 2, EventFilter{},
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, []Interval{
+			{at(17, 0), at(18, 0)},
+			{at(9, 0).AddDate(0, 0, 1), at(10, 0).AddDate(0, 0, 1)},
+		}, slots)
+	})
+
+	t.Run("working hours keep the wall clock on daylight saving days", func(t *testing.T) {
+		berlin, err := time.LoadLocation("Europe/Berlin")
+		require.NoError(t, err)
+
+		// Clocks go forward an hour on the night to Sunday.
+		saturday := time.Date(2021, time.March, 27, 0, 0, 0, 0, berlin)
+		windows := hours.windows(saturday, saturday.AddDate(0, 0, 2))
+		require.Equal(t, []Interval{
+			{time.Date(2021, time.March, 27, 9, 0, 0, 0, berlin), time.Date(2021, time.March, 27, 18, 0, 0, 0, berlin)},
+			{time.Date(2021, time.March, 28, 9, 0, 0, 0, berlin), time.Date(2021, time.March, 28, 18, 0, 0, 0, berlin)},
+		}, windows)
+	})
+
+	t.Run("working day may end at midnight", func(t *testing.T) {
+		evening, err := ParseWorkingHours("20:00", "24:00")
+		require.NoError(t, err)
+
+		busy, err := app.GetFreeBusy(ctx, []int64{1}, day, day.AddDate(0, 0, 1), evening, EventFilter{})
+		require.NoError(t, err)
+		require.Equal(t, []Interval{{at(20, 0), at(21, 0)}}, busy[1])
+
+		require.Equal(t, []Interval{{at(20, 0), day.AddDate(0, 0, 1)}}, evening.windows(day, day.AddDate(0, 0, 1)))
+	})
+
+	t.Run("only events passing the filter are busy", func(t *testing.T) {
+		for _, title := range []string{"standup", "review"} {
+			require.NoError(t, memory.SetEventTags(ctx, titled[title].ID, []string{"meeting"}))
+		}
+		meetings := EventFilter{Tags: []string{"Meeting"}}
+
+		busy, err := app.GetFreeBusy(ctx, []int64{1, 2}, day, day.AddDate(0, 0, 1), hours, meetings)
+		require.NoError(t, err)
+		require.Equal(t, []Interval{{at(9, 0), at(10, 0)}}, busy[1])
+		require.Equal(t, []Interval{}, busy[2])
+
+		slots, err := app.SuggestSlots(ctx, []int64{1}, day, day.AddDate(0, 0, 1), hours, time.Hour, 1, meetings)
+		require.NoError(t, err)
+		require.Equal(t, []Interval{{at(10, 0), at(11, 0)}}, slots)
+
+		_, err = app.GetFreeBusy(ctx, []int64{1}, day, day.AddDate(0, 0, 1), hours, EventFilter{Tags: []string{" "}})
+		require.ErrorIs(t, err, ErrInvalidFilter)
+	})
+
+	t.Run("invalid queries", func(t *testing.T) {
+		_, err := app.GetFreeBusy(ctx, nil, day, day.AddDate(0, 0, 1), hours, EventFilter{})
+		require.ErrorIs(t, err, ErrEmptyOwners)
+
+		_, err = app.GetFreeBusy(ctx, []int64{1}, day, day, hours, EventFilter{})
+		require.ErrorIs(t, err, ErrInvalidPeriod)
+
+		_, err = app.SuggestSlots(ctx, []int64{1}, day, day.AddDate(1, 0, 0), hours, time.Hour, 1, EventFilter{})
+		require.ErrorIs(t, err, ErrInvalidRange)
+
+		_, err = ParseWorkingHours("18:00", "09:00")
+		require.ErrorIs(t, err, ErrInvalidWorkingHours)
+
+		_, err = app.SuggestSlots(ctx, []int64{1}, day, day.AddDate(0, 0, 1), hours, 0, 1, EventFilter{})
+		require.ErrorIs(t, err, ErrInvalidSlotDuration)
+	})
+}
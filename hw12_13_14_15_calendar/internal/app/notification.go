@@ -0,0 +1,267 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	// MaxNotificationAttempts is the number of times a reminder is sent before its failures are given up on.
+	MaxNotificationAttempts = 3
+	// NotificationRetryDelay is how long a failed reminder waits to be sent again, doubled on each attempt.
+	NotificationRetryDelay = time.Minute
+)
+
+var (
+	ErrNotificationNotFound      = errors.New("notification not found")
+	ErrNotificationState         = errors.New("notification state does not allow that")
+	ErrInvalidNotificationFilter = errors.New("invalid notification filter")
+	ErrGetNotifications          = errors.New("getting notifications error")
+	ErrUpdateNotification        = errors.New("update notification error")
+)
+
+var notificationStates = map[string]bool{
+	storage.NotificationPending:   true,
+	storage.NotificationPublished: true,
+	storage.NotificationDelivered: true,
+	storage.NotificationFailed:    true,
+}
+
+// ListNotifications returns the notifications selected by the filter ordered by id.
+// Authenticated requests only see their own ones.
+func (a *App) ListNotifications(ctx context.Context, filter storage.NotificationFilter) ([]storage.Notification, error) {
+	if filter.State != "" && !notificationStates[filter.State] {
+		return nil, fmt.Errorf("%w: unknown state %q", ErrInvalidNotificationFilter, filter.State)
+	}
+
+	if ownerID, ok := OwnerFromContext(ctx); ok {
+		if filter.OwnerID != 0 && filter.OwnerID != ownerID {
+			return nil, fmt.Errorf("%w: notifications of owner %d", ErrPermissionDenied, filter.OwnerID)
+		}
+		filter.OwnerID = ownerID
+	}
+
+	if filter.EventID != 0 {
+		if _, err := a.GetEventByID(ctx, filter.EventID); err != nil {
+			return nil, err
+		}
+	}
+
+	notifications, err := a.Storage.ListNotifications(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrGetNotifications, err.Error())
+	}
+
+	return notifications, nil
+}
+
+// GetNotification returns the notification, hiding notifications of other owners from an authenticated request.
+func (a *App) GetNotification(ctx context.Context, id int64) (storage.Notification, error) {
+	notification, err := a.Storage.GetNotification(ctx, id)
+	if err != nil {
+		return notification, wrapNotificationError(ErrGetNotifications, err)
+	}
+
+	if ownerID, ok := OwnerFromContext(ctx); ok && notification.OwnerID != ownerID {
+		return storage.Notification{}, fmt.Errorf("%w: %d", ErrNotificationNotFound, id)
+	}
+
+	return notification, nil
+}
+
+// BeginNotification starts an attempt to send the reminder through the channel, the notification
+// is created on the first one. Sending a delivered reminder once more starts counting attempts anew.
+func (a *App) BeginNotification(
+	ctx context.Context,
+	reminder storage.Reminder,
+	channel string,
+) (storage.Notification, error) {
+	var notification storage.Notification
+
+	err := a.Storage.WithinTransaction(ctx, func(ctx context.Context) error {
+		now := a.Clock.Now()
+
+		found, err := a.Storage.FindNotification(ctx, reminder.EventID, reminder.ID, channel)
+		if errors.Is(err, storage.ErrNotificationNotFound) {
+			notification, err = a.Storage.CreateNotification(ctx, storage.Notification{
+				EventID:    reminder.EventID,
+				ReminderID: reminder.ID,
+				OwnerID:    reminder.OwnerID,
+				Channel:    channel,
+				State:      storage.NotificationPending,
+				Attempts:   1,
+				CreatedAt:  now,
+				UpdatedAt:  now,
+			})
+			if err != nil {
+				return wrapStorageError(ErrUpdateNotification, err)
+			}
+
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrGetNotifications, err.Error())
+		}
+
+		if found.State == storage.NotificationDelivered {
+			found.Attempts = 0
+			found.DeliveredAt = nil
+		}
+		found.State = storage.NotificationPending
+		found.Attempts++
+		found.UpdatedAt = now
+
+		notification, err = a.Storage.UpdateNotification(ctx, found)
+		if err != nil {
+			return wrapNotificationError(ErrUpdateNotification, err)
+		}
+
+		return nil
+	})
+
+	return notification, err
+}
+
+// NotificationPublished records the notification has been handed to the broker and marks its reminder as sent,
+// unless the reminder has been moved from the time it was sent for. A delivery receipt may overtake it,
+// the notification stays delivered and the reminder is marked so then.
+func (a *App) NotificationPublished(ctx context.Context, id int64, sent storage.Reminder) (storage.Notification, error) {
+	return a.changeNotification(ctx, id, func(notification *storage.Notification, reminder *storage.Reminder, now time.Time) error {
+		if notification.State != storage.NotificationPending && notification.State != storage.NotificationDelivered {
+			return fmt.Errorf("%w: %s notification %d cannot be published", ErrNotificationState, notification.State, id)
+		}
+		notification.PublishedAt = &now
+
+		// The reminder may have been snoozed again, dismissed or moved along with its event meanwhile.
+		sending := reminder != nil &&
+			(reminder.State == storage.ReminderPending || reminder.State == storage.ReminderSnoozed) &&
+			reminder.RemindAt.Equal(sent.RemindAt)
+
+		if notification.State == storage.NotificationDelivered {
+			if sending {
+				reminder.State = storage.ReminderDelivered
+			}
+
+			return nil
+		}
+
+		notification.State = storage.NotificationPublished
+		if sending {
+			reminder.State = storage.ReminderSent
+		}
+
+		return nil
+	})
+}
+
+// NotificationDelivered records the delivery receipt of the notification and marks its reminder as delivered.
+func (a *App) NotificationDelivered(ctx context.Context, id int64, at time.Time) (storage.Notification, error) {
+	return a.changeNotification(ctx, id, func(notification *storage.Notification, reminder *storage.Reminder, now time.Time) error {
+		if notification.State != storage.NotificationPending && notification.State != storage.NotificationPublished {
+			return fmt.Errorf("%w: %s notification %d cannot be delivered", ErrNotificationState, notification.State, id)
+		}
+		notification.State = storage.NotificationDelivered
+		notification.DeliveredAt = &at
+
+		// The reminder may have been snoozed or dismissed meanwhile.
+		if reminder != nil && reminder.State == storage.ReminderSent {
+			reminder.State = storage.ReminderDelivered
+		}
+
+		return nil
+	})
+}
+
+// NotificationFailed records the notification could not be published or delivered. Its reminder is sent
+// again after a delay growing with the attempts, until it has been attempted MaxNotificationAttempts times
+// and fails for good.
+func (a *App) NotificationFailed(ctx context.Context, id int64, reason string) (storage.Notification, error) {
+	return a.changeNotification(ctx, id, func(notification *storage.Notification, reminder *storage.Reminder, now time.Time) error {
+		if notification.State != storage.NotificationPending && notification.State != storage.NotificationPublished {
+			return fmt.Errorf("%w: %s notification %d cannot fail", ErrNotificationState, notification.State, id)
+		}
+		notification.State = storage.NotificationFailed
+		notification.LastError = reason
+
+		if reminder == nil || reminder.State == storage.ReminderDismissed || reminder.State == storage.ReminderDelivered {
+			return nil
+		}
+
+		if notification.Attempts >= MaxNotificationAttempts {
+			reminder.State = storage.ReminderFailed
+			return nil
+		}
+
+		// A snoozed reminder stays snoozed, as it is due after its event has begun too.
+		if reminder.State == storage.ReminderSent {
+			reminder.State = storage.ReminderPending
+		}
+		reminder.RemindAt = now.Add(NotificationRetryDelay << (notification.Attempts - 1)).Round(time.Second)
+
+		return nil
+	})
+}
+
+// changeNotification applies the change to the notification and its reminder, if the reminder still exists,
+// in a transaction.
+func (a *App) changeNotification(
+	ctx context.Context,
+	id int64,
+	change func(notification *storage.Notification, reminder *storage.Reminder, now time.Time) error,
+) (storage.Notification, error) {
+	var notification storage.Notification
+
+	err := a.Storage.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		notification, err = a.Storage.GetNotification(ctx, id)
+		if err != nil {
+			return wrapNotificationError(ErrGetNotifications, err)
+		}
+
+		var reminder *storage.Reminder
+		found, err := a.Storage.GetReminder(ctx, notification.ReminderID)
+		switch {
+		case err == nil:
+			reminder = &found
+		case !errors.Is(err, storage.ErrReminderNotFound):
+			return fmt.Errorf("%w: %s", ErrGetReminders, err.Error())
+		}
+
+		read := found
+		now := a.Clock.Now()
+		if err := change(&notification, reminder, now); err != nil {
+			return err
+		}
+		notification.UpdatedAt = now
+
+		if notification, err = a.Storage.UpdateNotification(ctx, notification); err != nil {
+			return wrapNotificationError(ErrUpdateNotification, err)
+		}
+
+		// Only the state and the time change, and only if the reminder has not been moved, snoozed or
+		// dismissed since it was read: the user's change wins over the outcome of an earlier notification.
+		if reminder != nil && (reminder.State != read.State || !reminder.RemindAt.Equal(read.RemindAt)) {
+			_, err := a.Storage.UpdateReminderState(ctx, read, *reminder)
+			if err != nil && !errors.Is(err, storage.ErrReminderChanged) && !errors.Is(err, storage.ErrReminderNotFound) {
+				return wrapReminderError(ErrUpdateReminder, err)
+			}
+		}
+
+		return nil
+	})
+
+	return notification, err
+}
+
+func wrapNotificationError(kind error, err error) error {
+	if errors.Is(err, storage.ErrNotificationNotFound) {
+		return fmt.Errorf("%w: %s", ErrNotificationNotFound, err.Error())
+	}
+
+	return fmt.Errorf("%w: %s", kind, err.Error())
+}
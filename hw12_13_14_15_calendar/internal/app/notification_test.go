@@ -0,0 +1,213 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifications(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, time.November, 2, 9, 0, 0, 0, time.UTC)
+
+	// setup returns an app with an event of the first owner and its due reminder.
+	setup := func(t *testing.T) (*App, *clock.Fake, storage.Reminder) {
+		t.Helper()
+
+		clk := clock.NewFake(start)
+		app := New(nil, memorystorage.New(clk), clk)
+
+		_, err := app.CreateEvent(ctx, storage.Event{
+			Title: "meeting", OwnerID: 1, BeginDate: start.Add(2 * time.Hour), EndDate: start.Add(3 * time.Hour),
+		})
+		require.NoError(t, err)
+
+		due, err := app.Storage.GetDueReminders(ctx)
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+
+		return app, clk, due[0]
+	}
+
+	t.Run("delivered", func(t *testing.T) {
+		app, clk, reminder := setup(t)
+
+		notification, err := app.BeginNotification(ctx, reminder, storage.ChannelConsole)
+		require.NoError(t, err)
+		require.Equal(t, storage.NotificationPending, notification.State)
+		require.Equal(t, 1, notification.Attempts)
+		require.Equal(t, int64(1), notification.OwnerID)
+
+		notification, err = app.NotificationPublished(ctx, notification.ID, reminder)
+		require.NoError(t, err)
+		require.Equal(t, storage.NotificationPublished, notification.State)
+		require.NotNil(t, notification.PublishedAt)
+
+		// A sent reminder is not due anymore.
+		due, err := app.Storage.GetDueReminders(ctx)
+		require.NoError(t, err)
+		require.Empty(t, due)
+
+		clk.Advance(time.Second)
+		notification, err = app.NotificationDelivered(ctx, notification.ID, clk.Now())
+		require.NoError(t, err)
+		require.Equal(t, storage.NotificationDelivered, notification.State)
+		require.True(t, clk.Now().Equal(*notification.DeliveredAt))
+
+		stored, err := app.Storage.GetReminder(ctx, reminder.ID)
+		require.NoError(t, err)
+		require.Equal(t, storage.ReminderDelivered, stored.State)
+
+		_, err = app.NotificationFailed(ctx, notification.ID, "late failure")
+		require.ErrorIs(t, err, ErrNotificationState)
+
+		// Sending a snoozed reminder again reuses the record.
+		_, err = app.SnoozeReminder(ctx, reminder.ID, time.Minute)
+		require.NoError(t, err)
+		again, err := app.BeginNotification(ctx, stored, storage.ChannelConsole)
+		require.NoError(t, err)
+		require.Equal(t, notification.ID, again.ID)
+		require.Equal(t, 1, again.Attempts)
+		require.Nil(t, again.DeliveredAt)
+	})
+
+	t.Run("receipt before publishing", func(t *testing.T) {
+		app, clk, reminder := setup(t)
+
+		notification, err := app.BeginNotification(ctx, reminder, storage.ChannelConsole)
+		require.NoError(t, err)
+
+		_, err = app.NotificationDelivered(ctx, notification.ID, clk.Now())
+		require.NoError(t, err)
+
+		notification, err = app.NotificationPublished(ctx, notification.ID, reminder)
+		require.NoError(t, err)
+		require.Equal(t, storage.NotificationDelivered, notification.State)
+		require.NotNil(t, notification.PublishedAt)
+
+		stored, err := app.Storage.GetReminder(ctx, reminder.ID)
+		require.NoError(t, err)
+		require.Equal(t, storage.ReminderDelivered, stored.State)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		app, clk, reminder := setup(t)
+
+		var notification storage.Notification
+		for attempt := 1; attempt <= MaxNotificationAttempts; attempt++ {
+			due, err := app.Storage.GetDueReminders(ctx)
+			require.NoError(t, err)
+			require.Len(t, due, 1, "attempt %d", attempt)
+
+			notification, err = app.BeginNotification(ctx, due[0], storage.ChannelConsole)
+			require.NoError(t, err)
+			require.Equal(t, attempt, notification.Attempts)
+
+			_, err = app.NotificationPublished(ctx, notification.ID, due[0])
+			require.NoError(t, err)
+
+			notification, err = app.NotificationFailed(ctx, notification.ID, "recipient is unreachable")
+			require.NoError(t, err)
+			require.Equal(t, storage.NotificationFailed, notification.State)
+			require.Equal(t, "recipient is unreachable", notification.LastError)
+
+			if attempt == MaxNotificationAttempts {
+				break
+			}
+
+			// The reminder is sent again once the delay, doubled on each attempt, has passed.
+			delay := NotificationRetryDelay << (attempt - 1)
+			stored, err := app.Storage.GetReminder(ctx, reminder.ID)
+			require.NoError(t, err)
+			require.Equal(t, storage.ReminderPending, stored.State)
+			require.True(t, clk.Now().Add(delay).Equal(stored.RemindAt), "attempt %d", attempt)
+
+			clk.Advance(delay - time.Second)
+			due, err = app.Storage.GetDueReminders(ctx)
+			require.NoError(t, err)
+			require.Empty(t, due, "attempt %d", attempt)
+			clk.Advance(time.Second)
+		}
+
+		// The reminder fails for good after the last attempt.
+		due, err := app.Storage.GetDueReminders(ctx)
+		require.NoError(t, err)
+		require.Empty(t, due)
+
+		stored, err := app.Storage.GetReminder(ctx, reminder.ID)
+		require.NoError(t, err)
+		require.Equal(t, storage.ReminderFailed, stored.State)
+
+		// A failed reminder can be snoozed to try once more.
+		_, err = app.SnoozeReminder(ctx, reminder.ID, time.Minute)
+		require.NoError(t, err)
+
+		_, err = app.NotificationFailed(ctx, 100, "unknown")
+		require.ErrorIs(t, err, ErrNotificationNotFound)
+	})
+
+	t.Run("moved meanwhile", func(t *testing.T) {
+		app, _, reminder := setup(t)
+
+		notification, err := app.BeginNotification(ctx, reminder, storage.ChannelConsole)
+		require.NoError(t, err)
+
+		// The event is moved while the notification is being published.
+		event, err := app.GetEventByID(ctx, reminder.EventID)
+		require.NoError(t, err)
+		event.BeginDate = event.BeginDate.Add(48 * time.Hour)
+		event.EndDate = event.EndDate.Add(48 * time.Hour)
+		_, err = app.UpdateEvent(ctx, event)
+		require.NoError(t, err)
+
+		notification, err = app.NotificationPublished(ctx, notification.ID, reminder)
+		require.NoError(t, err)
+		require.Equal(t, storage.NotificationPublished, notification.State)
+
+		stored, err := app.Storage.GetReminder(ctx, reminder.ID)
+		require.NoError(t, err)
+		require.Equal(t, storage.ReminderPending, stored.State)
+		require.True(t, event.BeginDate.Add(-stored.Offset).Equal(stored.RemindAt))
+	})
+
+	t.Run("owners", func(t *testing.T) {
+		app, _, reminder := setup(t)
+
+		notification, err := app.BeginNotification(ctx, reminder, storage.ChannelConsole)
+		require.NoError(t, err)
+
+		notifications, err := app.ListNotifications(ContextWithOwner(ctx, 1), storage.NotificationFilter{})
+		require.NoError(t, err)
+		require.Len(t, notifications, 1)
+
+		notifications, err = app.ListNotifications(ctx, storage.NotificationFilter{
+			EventID: reminder.EventID, State: storage.NotificationDelivered,
+		})
+		require.NoError(t, err)
+		require.Empty(t, notifications)
+
+		notifications, err = app.ListNotifications(ContextWithOwner(ctx, 2), storage.NotificationFilter{})
+		require.NoError(t, err)
+		require.Empty(t, notifications)
+
+		_, err = app.ListNotifications(ContextWithOwner(ctx, 2), storage.NotificationFilter{OwnerID: 1})
+		require.ErrorIs(t, err, ErrPermissionDenied)
+
+		_, err = app.ListNotifications(ContextWithOwner(ctx, 2), storage.NotificationFilter{EventID: reminder.EventID})
+		require.ErrorIs(t, err, ErrEventNotFound)
+
+		_, err = app.ListNotifications(ctx, storage.NotificationFilter{State: "lost"})
+		require.ErrorIs(t, err, ErrInvalidNotificationFilter)
+
+		_, err = app.GetNotification(ContextWithOwner(ctx, 1), notification.ID)
+		require.NoError(t, err)
+
+		_, err = app.GetNotification(ContextWithOwner(ctx, 2), notification.ID)
+		require.ErrorIs(t, err, ErrNotificationNotFound)
+	})
+}
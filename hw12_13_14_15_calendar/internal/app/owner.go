@@ -0,0 +1,60 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrPermissionDenied = errors.New("permission denied")
+
+type (
+	ownerKey    struct{}
+	internalKey struct{}
+)
+
+// ContextWithOwner returns ctx acting on behalf of the authenticated owner.
+// Requests carrying an owner only see and change the events of the calendars of that owner
+// or the ones shared with it.
+func ContextWithOwner(ctx context.Context, ownerID int64) context.Context {
+	return context.WithValue(ctx, ownerKey{}, ownerID)
+}
+
+// OwnerFromContext returns the authenticated owner of the request, if any.
+func OwnerFromContext(ctx context.Context) (int64, bool) {
+	ownerID, ok := ctx.Value(ownerKey{}).(int64)
+	return ownerID, ok
+}
+
+// ContextInternal returns ctx of a trusted caller acting on behalf of no owner,
+// such as a server running without authentication.
+func ContextInternal(ctx context.Context) context.Context {
+	return context.WithValue(ctx, internalKey{}, true)
+}
+
+// IsInternal tells whether the request is of a trusted caller rather than of an owner.
+func IsInternal(ctx context.Context) bool {
+	internal, _ := ctx.Value(internalKey{}).(bool)
+	_, owned := OwnerFromContext(ctx)
+
+	return internal && !owned
+}
+
+// resolveOwner returns the owner a request acts on, the authenticated one standing for zero.
+// Authenticated requests may not act on behalf of other owners.
+func resolveOwner(ctx context.Context, ownerID int64, what string) (int64, error) {
+	if authenticated, ok := OwnerFromContext(ctx); ok {
+		if ownerID == 0 {
+			ownerID = authenticated
+		}
+		if ownerID != authenticated {
+			return 0, fmt.Errorf("%w: %s of owner %d", ErrPermissionDenied, what, ownerID)
+		}
+	}
+
+	if ownerID <= 0 {
+		return 0, ErrInvalidOwner
+	}
+
+	return ownerID, nil
+}
@@ -0,0 +1,275 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// MaxReminders is the maximum number of reminders of an event.
+const MaxReminders = 10
+
+var (
+	ErrReminderNotFound = errors.New("reminder not found")
+	ErrInvalidReminder  = errors.New("invalid reminder")
+	ErrReminderState    = errors.New("reminder state does not allow that")
+	ErrGetReminders     = errors.New("getting reminders error")
+	ErrUpdateReminder   = errors.New("update reminder error")
+)
+
+// DefaultReminders are the offsets of the reminders an event created without any gets.
+var DefaultReminders = []time.Duration{24 * time.Hour}
+
+// ListReminders returns the reminders of the event ordered by time.
+func (a *App) ListReminders(ctx context.Context, eventID int64) ([]storage.Reminder, error) {
+	if _, err := a.GetEventByID(ctx, eventID); err != nil {
+		return nil, err
+	}
+
+	reminders, err := a.Storage.GetReminders(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrGetReminders, err.Error())
+	}
+
+	return reminders, nil
+}
+
+// SnoozeReminder reminds again the given duration from now, the reminder must have been sent or have failed.
+// The request must be able to write to the event.
+func (a *App) SnoozeReminder(ctx context.Context, id int64, duration time.Duration) (storage.Reminder, error) {
+	if duration <= 0 {
+		return storage.Reminder{}, fmt.Errorf("%w: snooze duration must be positive", ErrInvalidReminder)
+	}
+
+	reminder, err := a.getReminder(ctx, id, storage.AccessWrite)
+	if err != nil {
+		return reminder, err
+	}
+
+	if reminder.State != storage.ReminderSent && reminder.State != storage.ReminderDelivered &&
+		reminder.State != storage.ReminderFailed {
+		return reminder, fmt.Errorf("%w: %s reminder %d cannot be snoozed", ErrReminderState, reminder.State, id)
+	}
+
+	reminder.RemindAt = a.Clock.Now().Add(duration).Round(time.Second)
+	reminder.State = storage.ReminderSnoozed
+
+	return a.updateReminder(ctx, reminder)
+}
+
+// DismissReminder stops the reminder whatever its state is, the request must be able to write to the event.
+func (a *App) DismissReminder(ctx context.Context, id int64) (storage.Reminder, error) {
+	reminder, err := a.getReminder(ctx, id, storage.AccessWrite)
+	if err != nil {
+		return reminder, err
+	}
+
+	reminder.State = storage.ReminderDismissed
+
+	return a.updateReminder(ctx, reminder)
+}
+
+// WatchReminderTimes calls wake with the times of the reminders due within the horizon, all the times
+// known so far, at once, whenever reminders change and every horizon until the context is done.
+// A failed lookup is passed to fail and retried on the next change or horizon.
+func (a *App) WatchReminderTimes(
+	ctx context.Context,
+	horizon time.Duration,
+	wake func(times []time.Time),
+	fail func(err error),
+) {
+	// Subscribing before the first lookup, so that no change slips in between.
+	signals, release := a.Storage.SubscribeReminderChanges()
+	defer release()
+
+	for {
+		reminders, err := a.Storage.GetUpcomingReminders(ctx, a.Clock.Now().Add(horizon))
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			fail(fmt.Errorf("%w: %s", ErrGetReminders, err.Error()))
+		default:
+			times := make([]time.Time, len(reminders))
+			for i, reminder := range reminders {
+				times[i] = reminder.RemindAt
+			}
+			wake(times)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+		case <-a.Clock.After(horizon):
+		}
+	}
+}
+
+// getReminder returns the reminder of an event the request has the required access to,
+// hiding the reminders of the events it can't read.
+func (a *App) getReminder(ctx context.Context, id int64, required storage.Access) (storage.Reminder, error) {
+	reminder, err := a.Storage.GetReminder(ctx, id)
+	if err != nil {
+		return reminder, wrapReminderError(ErrGetReminders, err)
+	}
+
+	if _, err := a.requireEvent(ctx, reminder.EventID, required); err != nil {
+		if errors.Is(err, ErrEventNotFound) {
+			return storage.Reminder{}, fmt.Errorf("%w: %d", ErrReminderNotFound, id)
+		}
+
+		return storage.Reminder{}, err
+	}
+
+	return reminder, nil
+}
+
+func (a *App) updateReminder(ctx context.Context, reminder storage.Reminder) (storage.Reminder, error) {
+	reminder, err := a.Storage.UpdateReminder(ctx, reminder)
+	if err != nil {
+		return reminder, wrapReminderError(ErrUpdateReminder, err)
+	}
+
+	return reminder, nil
+}
+
+// syncReminders makes the event have reminders at the offsets, nil offsets keeping the current ones,
+// and returns the offsets ordered by time. Reminders moved along with the event become pending again.
+func (a *App) syncReminders(ctx context.Context, event storage.Event, offsets []time.Duration) ([]time.Duration, error) {
+	existing, err := a.Storage.GetReminders(ctx, event.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrGetReminders, err.Error())
+	}
+
+	if offsets == nil {
+		offsets = make([]time.Duration, 0, len(existing))
+		for _, reminder := range existing {
+			offsets = append(offsets, reminder.Offset)
+		}
+	}
+
+	wanted := make(map[time.Duration]bool, len(offsets))
+	for _, offset := range offsets {
+		wanted[offset] = true
+	}
+
+	kept := make(map[time.Duration]bool, len(existing))
+	for _, reminder := range existing {
+		if !wanted[reminder.Offset] || kept[reminder.Offset] {
+			if err := a.Storage.RemoveReminder(ctx, reminder.ID); err != nil {
+				return nil, wrapReminderError(ErrUpdateReminder, err)
+			}
+			continue
+		}
+		kept[reminder.Offset] = true
+
+		remindAt := remindTime(event, reminder.Offset)
+		moved := !remindAt.Equal(reminder.RemindAt.Round(time.Second))
+		if !moved && reminder.OwnerID == event.OwnerID {
+			continue
+		}
+
+		if moved {
+			reminder.RemindAt = remindAt
+			reminder.State = storage.ReminderPending
+		}
+		reminder.OwnerID = event.OwnerID
+
+		if _, err := a.Storage.UpdateReminder(ctx, reminder); err != nil {
+			return nil, wrapReminderError(ErrUpdateReminder, err)
+		}
+	}
+
+	for _, offset := range offsets {
+		if kept[offset] {
+			continue
+		}
+		kept[offset] = true
+
+		_, err := a.Storage.CreateReminder(ctx, storage.Reminder{
+			EventID:  event.ID,
+			OwnerID:  event.OwnerID,
+			Offset:   offset,
+			RemindAt: remindTime(event, offset),
+			State:    storage.ReminderPending,
+		})
+		if err != nil {
+			return nil, wrapReminderError(ErrUpdateReminder, err)
+		}
+	}
+
+	sorted := make([]time.Duration, 0, len(kept))
+	for offset := range kept {
+		sorted = append(sorted, offset)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+
+	return sorted, nil
+}
+
+// remindTime returns the time of the reminder at the offset before the event, in whole seconds:
+// the sql storage keeps no fractions and rounds them, so the time is compared once stored.
+func remindTime(event storage.Event, offset time.Duration) time.Time {
+	return event.BeginDate.Add(-offset).Round(time.Second)
+}
+
+// withReminders fills the reminder offsets of the events.
+func (a *App) withReminders(ctx context.Context, events []storage.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+
+	reminders, err := a.Storage.GetReminders(ctx, ids...)
+	if err != nil {
+		return err
+	}
+
+	offsets := make(map[int64][]time.Duration, len(events))
+	for _, reminder := range reminders {
+		offsets[reminder.EventID] = append(offsets[reminder.EventID], reminder.Offset)
+	}
+
+	for i := range events {
+		events[i].Reminders = offsets[events[i].ID]
+	}
+
+	return nil
+}
+
+func validateReminders(offsets []time.Duration) error {
+	if len(offsets) > MaxReminders {
+		return fmt.Errorf("%w: %d reminders, %d at most", ErrInvalidEvent, len(offsets), MaxReminders)
+	}
+
+	seen := make(map[time.Duration]bool, len(offsets))
+	for _, offset := range offsets {
+		if offset < 0 {
+			return fmt.Errorf("%w: reminder offset %s is negative", ErrInvalidEvent, offset)
+		}
+
+		if seen[offset] {
+			return fmt.Errorf("%w: duplicate reminder offset %s", ErrInvalidEvent, offset)
+		}
+		seen[offset] = true
+	}
+
+	return nil
+}
+
+func wrapReminderError(kind error, err error) error {
+	if errors.Is(err, storage.ErrReminderNotFound) {
+		return fmt.Errorf("%w: %s", ErrReminderNotFound, err.Error())
+	}
+
+	return wrapStorageError(kind, err)
+}
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReminders(t *testing.T) {
+	ctx := ContextInternal(context.Background())
+	start := time.Date(2021, time.November, 2, 9, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	app := New(nil, memorystorage.New(clk), clk)
+
+	event, err := app.CreateEvent(ctx, storage.Event{
+		Title: "meeting", OwnerID: 1, BeginDate: start.Add(48 * time.Hour), EndDate: start.Add(49 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, DefaultReminders, event.Reminders)
+
+	t.Run("offsets", func(t *testing.T) {
+		event.Reminders = []time.Duration{15 * time.Minute, 24 * time.Hour}
+		updated, err := app.UpdateEvent(ctx, event)
+		require.NoError(t, err)
+		require.Equal(t, []time.Duration{24 * time.Hour, 15 * time.Minute}, updated.Reminders)
+
+		// Nil reminders keep the current ones.
+		updated.Reminders = nil
+		updated.Title = "renamed"
+		updated, err = app.UpdateEvent(ctx, updated)
+		require.NoError(t, err)
+		require.Len(t, updated.Reminders, 2)
+
+		stored, err := app.GetEventByID(ctx, event.ID)
+		require.NoError(t, err)
+		require.Equal(t, updated.Reminders, stored.Reminders)
+
+		for _, invalid := range [][]time.Duration{{-time.Minute}, {time.Hour, time.Hour}} {
+			event.Reminders = invalid
+			_, err := app.UpdateEvent(ctx, event)
+			require.ErrorIs(t, err, ErrInvalidEvent)
+		}
+
+		none, err := app.CreateEvent(ctx, storage.Event{
+			Title: "silent", OwnerID: 1, BeginDate: start.Add(96 * time.Hour), EndDate: start.Add(97 * time.Hour),
+			Reminders: []time.Duration{},
+		})
+		require.NoError(t, err)
+		require.Empty(t, none.Reminders)
+	})
+
+	t.Run("snooze and dismiss", func(t *testing.T) {
+		reminders, err := app.ListReminders(ctx, event.ID)
+		require.NoError(t, err)
+		require.Len(t, reminders, 2)
+		dayBefore := reminders[0]
+
+		_, err = app.SnoozeReminder(ctx, dayBefore.ID, 10*time.Minute)
+		require.ErrorIs(t, err, ErrReminderState)
+
+		clk.Advance(24 * time.Hour)
+		due, err := app.Storage.GetDueReminders(ctx)
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		require.Equal(t, dayBefore.ID, due[0].ID)
+
+		dayBefore.State = storage.ReminderDelivered
+		_, err = app.Storage.UpdateReminder(ctx, dayBefore)
+		require.NoError(t, err)
+
+		_, err = app.SnoozeReminder(ctx, dayBefore.ID, 0)
+		require.ErrorIs(t, err, ErrInvalidReminder)
+
+		_, err = app.SnoozeReminder(ContextWithOwner(ctx, 2), dayBefore.ID, 10*time.Minute)
+		require.ErrorIs(t, err, ErrReminderNotFound)
+
+		snoozed, err := app.SnoozeReminder(ContextWithOwner(ctx, 1), dayBefore.ID, 10*time.Minute)
+		require.NoError(t, err)
+		require.Equal(t, storage.ReminderSnoozed, snoozed.State)
+		require.True(t, clk.Now().Add(10*time.Minute).Equal(snoozed.RemindAt))
+
+		due, err = app.Storage.GetDueReminders(ctx)
+		require.NoError(t, err)
+		require.Empty(t, due)
+
+		clk.Advance(10 * time.Minute)
+		due, err = app.Storage.GetDueReminders(ctx)
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+
+		dismissed, err := app.DismissReminder(ctx, dayBefore.ID)
+		require.NoError(t, err)
+		require.Equal(t, storage.ReminderDismissed, dismissed.State)
+
+		due, err = app.Storage.GetDueReminders(ctx)
+		require.NoError(t, err)
+		require.Empty(t, due)
+	})
+
+	t.Run("fractions of a second", func(t *testing.T) {
+		begin := start.Add(120*time.Hour + 700*time.Millisecond)
+		fractional, err := app.CreateEvent(ctx, storage.Event{
+			Title: "fractional", OwnerID: 1, BeginDate: begin, EndDate: begin.Add(time.Hour),
+			Reminders: []time.Duration{time.Hour},
+		})
+		require.NoError(t, err)
+
+		// Reminder times are rounded to whole seconds, like the sql storage does.
+		reminders, err := app.ListReminders(ctx, fractional.ID)
+		require.NoError(t, err)
+		require.Len(t, reminders, 1)
+		require.True(t, begin.Add(-time.Hour).Round(time.Second).Equal(reminders[0].RemindAt))
+
+		reminders[0].State = storage.ReminderSent
+		_, err = app.Storage.UpdateReminder(ctx, reminders[0])
+		require.NoError(t, err)
+
+		// An event kept at its time keeps its sent reminder.
+		fractional.Title = "renamed"
+		_, err = app.UpdateEvent(ctx, fractional)
+		require.NoError(t, err)
+
+		reminders, err = app.ListReminders(ctx, fractional.ID)
+		require.NoError(t, err)
+		require.Equal(t, storage.ReminderSent, reminders[0].State)
+	})
+
+	t.Run("moved event", func(t *testing.T) {
+		stored, err := app.GetEventByID(ctx, event.ID)
+		require.NoError(t, err)
+
+		stored.BeginDate = stored.BeginDate.Add(24 * time.Hour)
+		stored.EndDate = stored.EndDate.Add(24 * time.Hour)
+		_, err = app.UpdateEvent(ctx, stored)
+		require.NoError(t, err)
+
+		reminders, err := app.ListReminders(ctx, event.ID)
+		require.NoError(t, err)
+		for _, reminder := range reminders {
+			require.Equal(t, storage.ReminderPending, reminder.State)
+			require.True(t, stored.BeginDate.Add(-reminder.Offset).Equal(reminder.RemindAt))
+		}
+
+		require.NoError(t, app.RemoveEvent(ctx, stored))
+		_, err = app.ListReminders(ctx, event.ID)
+		require.ErrorIs(t, err, ErrEventNotFound)
+	})
+}
+
+func TestWatchReminderTimes(t *testing.T) {
+	start := time.Date(2021, time.November, 2, 9, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	app := New(nil, memorystorage.New(clk), clk)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wakes := make(chan []time.Time, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		app.WatchReminderTimes(ctx, 2*time.Hour, func(times []time.Time) {
+			wakes <- times
+		}, func(err error) {
+			t.Error(err)
+		})
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// next returns the times of the following wake, once the watcher waits for the horizon again.
+	waited := 0
+	next := func() []time.Time {
+		t.Helper()
+
+		var times []time.Time
+		select {
+		case times = <-wakes:
+		case <-time.After(time.Second):
+			t.Fatal("no wake")
+		}
+
+		require.Eventually(t, func() bool {
+			return len(clk.Waits()) > waited
+		}, time.Second, time.Millisecond, "no wait for the horizon")
+		require.Equal(t, 2*time.Hour, clk.Waits()[waited])
+		waited++
+
+		return times
+	}
+
+	require.Empty(t, next())
+
+	_, err := app.CreateEvent(ctx, storage.Event{
+		Title: "meeting", OwnerID: 1, BeginDate: start.Add(2 * time.Hour), EndDate: start.Add(3 * time.Hour),
+		Reminders: []time.Duration{30 * time.Minute},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{start.Add(90 * time.Minute)}, next())
+
+	// Reminders beyond the horizon are looked up later.
+	_, err = app.CreateEvent(ctx, storage.Event{
+		Title: "standup", OwnerID: 1, BeginDate: start.Add(time.Hour), EndDate: start.Add(2 * time.Hour),
+		Reminders: []time.Duration{15 * time.Minute},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{start.Add(45 * time.Minute), start.Add(90 * time.Minute)}, next())
+
+	_, err = app.CreateEvent(ctx, storage.Event{
+		Title: "review", OwnerID: 1, BeginDate: start.Add(4 * time.Hour), EndDate: start.Add(5 * time.Hour),
+		Reminders: []time.Duration{time.Hour},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{start.Add(45 * time.Minute), start.Add(90 * time.Minute)}, next())
+
+	// Every horizon the times are looked up again, the standup and the meeting have begun meanwhile.
+	clk.Advance(2 * time.Hour)
+	require.Equal(t, []time.Time{start.Add(3 * time.Hour)}, next())
+}
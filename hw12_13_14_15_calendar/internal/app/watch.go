@@ -0,0 +1,149 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+var (
+	ErrWatchEvents     = errors.New("watching events error")
+	ErrInvalidOwner    = errors.New("owner id must be positive")
+	ErrRevisionExpired = errors.New("revision is too old, reload events and watch from scratch")
+)
+
+// WatchEvents streams changes of the owner's events passing the filter made after the given revision.
+// Zero revision means changes made from now on. The channel is closed when the context is done,
+// when the watcher falls behind and has to resume or when the access or the tags can't be read.
+// Deleted events pass any filter, updates moving an event out of the filter are not sent.
+// Authenticated requests may only watch their own events and the ones of the calendars shared with them.
+func (a *App) WatchEvents(ctx context.Context, ownerID int64, since int64, filter EventFilter) (<-chan storage.Change, error) {
+	ownerID, err := resolveOwner(ctx, ownerID, "changes")
+	if err != nil {
+		return nil, err
+	}
+
+	if filter, err = filter.normalized(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range filter.CalendarIDs {
+		if _, err := a.requireCalendar(ctx, id, storage.AccessRead); err != nil {
+			return nil, err
+		}
+	}
+
+	// Subscribing before reading the backlog, so nothing slips in between.
+	live, release := a.Storage.SubscribeChanges()
+
+	var backlog []storage.Change
+	if since > 0 {
+		var err error
+		backlog, err = a.Storage.GetChanges(ctx, since)
+		if err != nil {
+			release()
+			if errors.Is(err, storage.ErrRevisionExpired) {
+				return nil, fmt.Errorf("%w: %s", ErrRevisionExpired, err.Error())
+			}
+			return nil, fmt.Errorf("%w: %s", ErrWatchEvents, err.Error())
+		}
+	}
+
+	changes := make(chan storage.Change)
+
+	go func() {
+		defer close(changes)
+		defer release()
+
+		last := since
+		send := func(change storage.Change) bool {
+			if change.Revision <= last {
+				return true
+			}
+			last = change.Revision
+
+			watched, err := a.watchable(ctx, ownerID, change)
+			if err != nil {
+				return false
+			}
+
+			if !watched {
+				return true
+			}
+
+			if change.Type != storage.EventDeleted {
+				events, err := a.filterEvents(ctx, []storage.Event{change.Event}, filter)
+				if err != nil {
+					return false
+				}
+
+				if len(events) == 0 {
+					return true
+				}
+			}
+
+			select {
+			case changes <- change:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for _, change := range backlog {
+			if !send(change) {
+				return
+			}
+		}
+
+		for {
+			select {
+			case change, ok := <-live:
+				if !ok || !send(change) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// watchable reports whether the change is seen by the watcher of the owner's events. Authenticated
+// watchers also see the events of the calendars shared with them for reading, and the deleted events
+// of the owners sharing such calendars, the calendars of deleted events being unknown.
+func (a *App) watchable(ctx context.Context, ownerID int64, change storage.Change) (bool, error) {
+	if change.Event.OwnerID == ownerID {
+		return true, nil
+	}
+
+	if _, ok := OwnerFromContext(ctx); !ok {
+		return false, nil
+	}
+
+	if change.Type != storage.EventDeleted {
+		access, err := a.eventAccess(ctx, change.Event)
+		if err != nil {
+			return false, err
+		}
+
+		return access.Allows(storage.AccessRead), nil
+	}
+
+	shared, err := a.sharedGrants(ctx, ownerID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, grant := range shared {
+		if grant.OwnerID == change.Event.OwnerID && grant.Access.Allows(storage.AccessRead) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
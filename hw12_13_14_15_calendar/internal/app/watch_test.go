@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, changes <-chan storage.Change) storage.Change {
+	t.Helper()
+
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(time.Second):
+		t.Fatal("change has not been received")
+	}
+
+	return storage.Change{}
+}
+
+func TestWatchEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(ContextInternal(context.Background()))
+	defer cancel()
+
+	app := New(nil, memorystorage.New(clock.Real{}), clock.Real{})
+	begin := time.Now().Add(time.Hour)
+	newEvent := func(title string, ownerID int64) storage.Event {
+		return storage.Event{Title: title, OwnerID: ownerID, BeginDate: begin, EndDate: begin}
+	}
+
+	first, err := app.CreateEvent(ctx, newEvent("first", 1))
+	require.NoError(t, err)
+	_, err = app.CreateEvent(ctx, newEvent("foreign", 2))
+	require.NoError(t, err)
+
+	changes, err := app.WatchEvents(ctx, 1, 0, EventFilter{})
+	require.NoError(t, err)
+
+	first.Title = "first updated"
+	_, err = app.UpdateEvent(ctx, first)
+	require.NoError(t, err)
+	_, err = app.CreateEvent(ctx, newEvent("foreign again", 2))
+	require.NoError(t, err)
+	require.NoError(t, app.RemoveEvent(ctx, first))
+
+	updated := receive(t, changes)
+	require.Equal(t, storage.EventUpdated, updated.Type)
+	require.Equal(t, "first updated", updated.Event.Title)
+
+	deleted := receive(t, changes)
+	require.Equal(t, storage.EventDeleted, deleted.Type)
+	require.Equal(t, first.ID, deleted.Event.ID)
+
+	t.Run("resume from revision", func(t *testing.T) {
+		resumed, err := app.WatchEvents(ctx, 1, updated.Revision, EventFilter{})
+		require.NoError(t, err)
+		require.Equal(t, deleted, receive(t, resumed))
+
+		_, err = app.CreateEvent(ctx, newEvent("second", 1))
+		require.NoError(t, err)
+		require.Equal(t, "second", receive(t, resumed).Event.Title)
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		filtered, err := app.WatchEvents(ctx, 1, 0, EventFilter{Tags: []string{" Work"}})
+		require.NoError(t, err)
+
+		untagged, err := app.CreateEvent(ctx, newEvent("untagged", 1))
+		require.NoError(t, err)
+		tagged := newEvent("tagged", 1)
+		tagged.Tags = []string{"work", "team"}
+		tagged, err = app.CreateEvent(ctx, tagged)
+		require.NoError(t, err)
+		require.NoError(t, app.RemoveEvent(ctx, untagged))
+
+		created := receive(t, filtered)
+		require.Equal(t, storage.EventCreated, created.Type)
+		require.Equal(t, tagged.ID, created.Event.ID)
+
+		// Deleted events are sent whatever their tags were.
+		for {
+			change := receive(t, filtered)
+			if change.Type == storage.EventDeleted {
+				require.Equal(t, untagged.ID, change.Event.ID)
+				break
+			}
+			require.Equal(t, tagged.ID, change.Event.ID)
+		}
+
+		_, err = app.WatchEvents(ctx, 1, 0, EventFilter{Tags: []string{""}})
+		require.ErrorIs(t, err, ErrInvalidFilter)
+	})
+
+	t.Run("invalid owner", func(t *testing.T) {
+		_, err := app.WatchEvents(ctx, 0, 0, EventFilter{})
+		require.ErrorIs(t, err, ErrInvalidOwner)
+	})
+
+	t.Run("closed with context", func(t *testing.T) {
+		watchCtx, watchCancel := context.WithCancel(ctx)
+		watched, err := app.WatchEvents(watchCtx, 1, 0, EventFilter{})
+		require.NoError(t, err)
+
+		watchCancel()
+		select {
+		case _, ok := <-watched:
+			require.False(t, ok)
+		case <-time.After(time.Second):
+			t.Fatal("channel has not been closed")
+		}
+	})
+}
@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// TokenPrefix marks opaque API tokens, telling them apart from JWTs.
+const TokenPrefix = "cal_"
+
+var (
+	ErrUnauthenticated = errors.New("authentication required")
+	ErrInvalidToken    = errors.New("invalid token")
+	ErrLoadKeys        = errors.New("unable to load token keys")
+	ErrNoKeys          = errors.New("neither jwt secret, public key nor jwks file is configured")
+	ErrIssueToken      = errors.New("unable to issue api token")
+)
+
+type Config interface {
+	GetAuthJWTSecret() string
+	GetAuthJWTPublicKey() string
+	GetAuthJWKSFile() string
+	GetAuthIssuer() string
+	GetAuthAudience() string
+}
+
+// TokenStore keeps hashed opaque API tokens.
+type TokenStore interface {
+	CreateAPIToken(ctx context.Context, token storage.APIToken) (storage.APIToken, error)
+	GetAPITokenByHash(ctx context.Context, hash string) (storage.APIToken, error)
+	RevokeAPIToken(ctx context.Context, id int64) error
+}
+
+// Authenticator resolves bearer tokens into owner IDs.
+type Authenticator struct {
+	secret   []byte
+	keys     map[string]*rsa.PublicKey
+	issuer   string
+	audience string
+	store    TokenStore
+}
+
+// New returns an authenticator validating JWTs with the configured keys
+// and opaque tokens against the store.
+func New(config Config, store TokenStore) (*Authenticator, error) {
+	a := &Authenticator{
+		secret:   []byte(config.GetAuthJWTSecret()),
+		keys:     make(map[string]*rsa.PublicKey),
+		issuer:   config.GetAuthIssuer(),
+		audience: config.GetAuthAudience(),
+		store:    store,
+	}
+
+	if path := config.GetAuthJWTPublicKey(); path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrLoadKeys, err.Error())
+		}
+
+		key, err := jwt.ParseRSAPublicKeyFromPEM(b)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrLoadKeys, path, err.Error())
+		}
+		a.keys[""] = key
+	}
+
+	if path := config.GetAuthJWKSFile(); path != "" {
+		keys, err := loadJWKS(path)
+		if err != nil {
+			return nil, err
+		}
+		for kid, key := range keys {
+			a.keys[kid] = key
+		}
+	}
+
+	if len(a.secret) == 0 && len(a.keys) == 0 && store == nil {
+		return nil, ErrNoKeys
+	}
+
+	return a, nil
+}
+
+// Authenticate returns the owner ID the token was issued for.
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (int64, error) {
+	if token == "" {
+		return 0, ErrUnauthenticated
+	}
+
+	if strings.HasPrefix(token, TokenPrefix) {
+		return a.authenticateAPIToken(ctx, token)
+	}
+
+	return a.authenticateJWT(token)
+}
+
+// authenticateJWT verifies the signature and the registered claims, the subject is the owner ID.
+// Tokens must expire, the ones without the exp claim being valid forever otherwise.
+func (a *Authenticator) authenticateJWT(token string) (int64, error) {
+	methods := a.methods()
+	if len(methods) == 0 {
+		return 0, fmt.Errorf("%w: jwt is not supported", ErrInvalidToken)
+	}
+
+	claims := &jwt.StandardClaims{}
+	parser := &jwt.Parser{ValidMethods: methods}
+
+	if _, err := parser.ParseWithClaims(token, claims, a.key); err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidToken, err.Error())
+	}
+
+	if claims.ExpiresAt == 0 {
+		return 0, fmt.Errorf("%w: expiration time is required", ErrInvalidToken)
+	}
+
+	if a.issuer != "" && !claims.VerifyIssuer(a.issuer, true) {
+		return 0, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+
+	if a.audience != "" && !claims.VerifyAudience(a.audience, true) {
+		return 0, fmt.Errorf("%w: unexpected audience %q", ErrInvalidToken, claims.Audience)
+	}
+
+	ownerID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil || ownerID <= 0 {
+		return 0, fmt.Errorf("%w: subject %q is not an owner id", ErrInvalidToken, claims.Subject)
+	}
+
+	return ownerID, nil
+}
+
+// methods lists the signing methods there are keys for.
+func (a *Authenticator) methods() []string {
+	var methods []string
+	if len(a.secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if len(a.keys) > 0 {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	return methods
+}
+
+// key picks the verification key by the token algorithm and key id.
+func (a *Authenticator) key(token *jwt.Token) (interface{}, error) {
+	if token.Method == jwt.SigningMethodHS256 {
+		return a.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if key, ok := a.keys[kid]; ok {
+		return key, nil
+	}
+
+	// A single configured public key verifies tokens without a key id.
+	if key, ok := a.keys[""]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (a *Authenticator) authenticateAPIToken(ctx context.Context, token string) (int64, error) {
+	if a.store == nil {
+		return 0, fmt.Errorf("%w: api tokens are not supported", ErrInvalidToken)
+	}
+
+	stored, err := a.store.GetAPITokenByHash(ctx, HashToken(token))
+	if errors.Is(err, storage.ErrTokenNotFound) {
+		return 0, fmt.Errorf("%w: unknown api token", ErrInvalidToken)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if stored.RevokedAt != nil {
+		return 0, fmt.Errorf("%w: api token %d is revoked", ErrInvalidToken, stored.ID)
+	}
+
+	if stored.ExpiresAt != nil && !time.Now().Before(*stored.ExpiresAt) {
+		return 0, fmt.Errorf("%w: api token %d is expired", ErrInvalidToken, stored.ID)
+	}
+
+	return stored.OwnerID, nil
+}
+
+// HashToken returns the digest an opaque token is stored under.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueAPIToken generates a new opaque token for the owner, only its hash is stored.
+// Zero ttl means the token never expires.
+func IssueAPIToken(ctx context.Context, store TokenStore, ownerID int64, name string, ttl time.Duration) (string, storage.APIToken, error) {
+	if ownerID <= 0 {
+		return "", storage.APIToken{}, fmt.Errorf("%w: owner id must be positive", ErrIssueToken)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", storage.APIToken{}, fmt.Errorf("%w: %s", ErrIssueToken, err.Error())
+	}
+	token := TokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	stored := storage.APIToken{
+		Name:    name,
+		OwnerID: ownerID,
+		Hash:    HashToken(token),
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		stored.ExpiresAt = &expiresAt
+	}
+
+	stored, err := store.CreateAPIToken(ctx, stored)
+	if err != nil {
+		return "", stored, fmt.Errorf("%w: %s", ErrIssueToken, err.Error())
+	}
+
+	return token, stored, nil
+}
+
+// BearerToken extracts the token of an Authorization header value.
+func BearerToken(header string) string {
+	const scheme = "bearer "
+	if len(header) > len(scheme) && strings.EqualFold(header[:len(scheme)], scheme) {
+		return strings.TrimSpace(header[len(scheme):])
+	}
+
+	return ""
+}
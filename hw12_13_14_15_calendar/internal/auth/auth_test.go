@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	secret string
+}
+
+func (c testConfig) GetAuthJWTSecret() string    { return c.secret }
+func (c testConfig) GetAuthJWTPublicKey() string { return "" }
+func (c testConfig) GetAuthJWKSFile() string     { return "" }
+func (c testConfig) GetAuthIssuer() string       { return "calendar" }
+func (c testConfig) GetAuthAudience() string     { return "" }
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.StandardClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	require.NoError(t, err)
+
+	return token
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	authenticator, err := New(testConfig{secret: "secret"}, nil)
+	require.NoError(t, err)
+
+	claims := jwt.StandardClaims{
+		Subject:   "42",
+		Issuer:    "calendar",
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		ownerID, err := authenticator.Authenticate(context.Background(), sign(t, jwt.SigningMethodHS256, []byte("secret"), claims))
+		require.NoError(t, err)
+		require.Equal(t, int64(42), ownerID)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		_, err := authenticator.Authenticate(context.Background(), sign(t, jwt.SigningMethodHS256, []byte("other"), claims))
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("unexpected algorithm", func(t *testing.T) {
+		_, err := authenticator.Authenticate(context.Background(), sign(t, jwt.SigningMethodHS512, []byte("secret"), claims))
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		expired := claims
+		expired.ExpiresAt = time.Now().Add(-time.Hour).Unix()
+		_, err := authenticator.Authenticate(context.Background(), sign(t, jwt.SigningMethodHS256, []byte("secret"), expired))
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("no expiration", func(t *testing.T) {
+		eternal := claims
+		eternal.ExpiresAt = 0
+		_, err := authenticator.Authenticate(context.Background(), sign(t, jwt.SigningMethodHS256, []byte("secret"), eternal))
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("wrong issuer", func(t *testing.T) {
+		foreign := claims
+		foreign.Issuer = "somebody"
+		_, err := authenticator.Authenticate(context.Background(), sign(t, jwt.SigningMethodHS256, []byte("secret"), foreign))
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		_, err := authenticator.Authenticate(context.Background(), "")
+		require.ErrorIs(t, err, ErrUnauthenticated)
+	})
+}
+
+func TestAuthenticateAPIToken(t *testing.T) {
+	ctx := context.Background()
+	store := memorystorage.New(clock.Real{})
+
+	authenticator, err := New(testConfig{}, store)
+	require.NoError(t, err)
+
+	token, stored, err := IssueAPIToken(ctx, store, 7, "ci", 0)
+	require.NoError(t, err)
+	require.NotEqual(t, token, stored.Hash)
+
+	ownerID, err := authenticator.Authenticate(ctx, token)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), ownerID)
+
+	_, err = authenticator.Authenticate(ctx, TokenPrefix+"unknown")
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	// Without a secret jwts are rejected rather than checked against an empty key.
+	_, err = authenticator.Authenticate(ctx, sign(t, jwt.SigningMethodHS256, []byte(""), jwt.StandardClaims{Subject: "7"}))
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	require.NoError(t, store.RevokeAPIToken(ctx, stored.ID))
+	_, err = authenticator.Authenticate(ctx, token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestBearerToken(t *testing.T) {
+	require.Equal(t, "abc", BearerToken("Bearer abc"))
+	require.Equal(t, "abc", BearerToken("bearer abc"))
+	require.Equal(t, "", BearerToken("Basic abc"))
+	require.Equal(t, "", BearerToken(""))
+}
@@ -0,0 +1,152 @@
+// Package clock abstracts the current time, so it can be controlled in tests and demos.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+	// After waits for the duration to elapse on the clock and then sends its current time.
+	After(d time.Duration) <-chan time.Time
+}
+
+type Config interface {
+	GetClockTravelTo() time.Time
+}
+
+// New returns the real clock, or the one travelled to the configured moment, if any.
+func New(config Config) Clock {
+	if to := config.GetClockTravelTo(); !to.IsZero() {
+		return Travel(to)
+	}
+
+	return Real{}
+}
+
+// Real is the system clock.
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+func (Real) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Shifted runs at the real pace from a moment other than the real current time.
+type Shifted struct {
+	offset time.Duration
+}
+
+// Travel returns a clock, which shows the given moment now and keeps going from it.
+func Travel(to time.Time) *Shifted {
+	return &Shifted{offset: time.Until(to)}
+}
+
+func (s *Shifted) Now() time.Time {
+	return time.Now().Add(s.offset)
+}
+
+func (s *Shifted) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	time.AfterFunc(d, func() {
+		ch <- s.Now()
+	})
+
+	return ch
+}
+
+// Fake is a clock, which only moves when told to.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+	waits   []time.Duration
+}
+
+type waiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewFake returns a fake clock showing the given moment.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+// After returns a channel receiving the time once the clock is advanced by the duration.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.waits = append(f.waits, d)
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- f.now
+		return ch
+	}
+
+	f.waiters = append(f.waiters, waiter{deadline: f.now.Add(d), ch: ch})
+
+	return ch
+}
+
+// Advance moves the clock forward, firing the waiters due by then.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	now := f.now.Add(d)
+	f.mu.Unlock()
+
+	f.Set(now)
+}
+
+// Set moves the clock to the given moment, firing the waiters due by then in order.
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = now
+
+	sort.SliceStable(f.waiters, func(i, j int) bool {
+		return f.waiters[i].deadline.Before(f.waiters[j].deadline)
+	})
+
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.deadline.After(now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- now
+	}
+	f.waiters = pending
+}
+
+// Waits returns the durations of all the After calls so far in order, letting tests follow the waits
+// of a goroutine without blocking it.
+func (f *Fake) Waits() []time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]time.Duration(nil), f.waits...)
+}
+
+// Waiters returns the number of pending After calls, letting tests wait for a goroutine to block on the clock.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.waiters)
+}
@@ -0,0 +1,52 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2021, time.November, 2, 9, 0, 0, 0, time.UTC)
+	clock := NewFake(start)
+	require.Equal(t, start, clock.Now())
+
+	later := clock.After(2 * time.Hour)
+	sooner := clock.After(time.Hour)
+	require.Equal(t, 2, clock.Waiters())
+
+	clock.Advance(30 * time.Minute)
+	require.Equal(t, start.Add(30*time.Minute), clock.Now())
+	require.Len(t, sooner, 0)
+
+	clock.Advance(30 * time.Minute)
+	require.Equal(t, start.Add(time.Hour), <-sooner)
+	require.Len(t, later, 0)
+	require.Equal(t, 1, clock.Waiters())
+
+	clock.Set(start.AddDate(0, 0, 7))
+	require.Equal(t, start.AddDate(0, 0, 7), <-later)
+	require.Zero(t, clock.Waiters())
+
+	require.Equal(t, clock.Now(), <-clock.After(0))
+	require.Equal(t, []time.Duration{2 * time.Hour, time.Hour, 0}, clock.Waits())
+}
+
+type travelConfig time.Time
+
+func (c travelConfig) GetClockTravelTo() time.Time {
+	return time.Time(c)
+}
+
+func TestTravel(t *testing.T) {
+	require.IsType(t, Real{}, New(travelConfig{}))
+
+	to := time.Now().AddDate(0, 0, 7)
+	clock := New(travelConfig(to))
+	require.WithinDuration(t, to, clock.Now(), time.Second)
+
+	fired := <-clock.After(10 * time.Millisecond)
+	require.True(t, fired.After(to))
+	require.WithinDuration(t, to, fired, time.Second)
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
@@ -19,7 +22,15 @@ type Config struct {
 	Queue     QueueConf
 	Consume   ConsumeConf
 	Publish   PublishConf
+	Receipts  ReceiptsConf
 	Scheduler SchedulerConf
+	Metrics   MetricsConf
+	Tracing   TracingConf
+	Health    HealthConf
+	Auth      AuthConf
+	Limits    LimitsConf
+	Clock     ClockConf
+	FreeBusy  FreeBusyConf
 }
 
 type LoggerConf struct {
@@ -43,6 +54,9 @@ type GRPCConf struct {
 type StorageConf struct {
 	Implementation string
 	DSN            string
+	AutoMigrate    bool
+	CacheSize      int
+	CacheTTL       time.Duration
 }
 
 type RabbitmqConf struct {
@@ -82,73 +96,210 @@ type PublishConf struct {
 	RoutingKey string
 }
 
+type ReceiptsConf struct {
+	Queue string
+}
+
 type SchedulerConf struct {
-	RemindIn int
+	RemindIn  int
+	Interval  time.Duration
+	Retention time.Duration
+	Watch     bool
+	Horizon   time.Duration
+	Jobs      map[string]JobConf
 }
 
+type JobConf struct {
+	Schedule string
+	Jitter   time.Duration
+	Timeout  time.Duration
+}
+
+type FreeBusyConf struct {
+	MaxRange time.Duration
+}
+
+type MetricsConf struct {
+	Host string
+	Port string
+}
+
+type HealthConf struct {
+	Host string
+	Port string
+}
+
+type AuthConf struct {
+	Enabled      bool
+	JWTSecret    string
+	JWTPublicKey string
+	JWKSFile     string
+	Issuer       string
+	Audience     string
+}
+
+type LimitsConf struct {
+	Rate         float64
+	Burst        int
+	MaxBodyBytes int64
+	AddressRate  float64
+	AddressBurst int
+	Routes       map[string]RouteLimitsConf
+}
+
+type RouteLimitsConf struct {
+	Rate         float64
+	Burst        int
+	MaxBodyBytes int64
+}
+
+// ClockConf travels the services in time for demos: with TravelTo, an RFC 3339 moment, they run
+// as if started then, so that reminders and digests come due without waiting. Empty for the real time.
+type ClockConf struct {
+	TravelTo string
+}
+
+type TracingConf struct {
+	Exporter string
+	Endpoint string
+	File     string
+}
+
+// EnvPrefix prefixes the environment variables overriding the config file,
+// e.g. CALENDAR_HTTP_PORT overrides http.port and CALENDAR_EXCHANGE_AUTODELETE overrides exchange.autoDelete.
+const EnvPrefix = "CALENDAR"
+
+// NewConfig reads the config file, the environment variables take precedence over it.
+// The resulting config is validated.
 func NewConfig(path string) (*Config, error) {
-	viper.SetConfigFile(path)
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
 
-	if err := viper.ReadInConfig(); err != nil {
+	if err := v.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("%w: %s", ErrConfigRead, path)
 	}
 
-	return &Config{
+	routeLimits := make(map[string]RouteLimitsConf)
+	if err := v.UnmarshalKey("limits.routes", &routeLimits); err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrConfigRead, path, err.Error())
+	}
+
+	jobs := make(map[string]JobConf)
+	if err := v.UnmarshalKey("scheduler.jobs", &jobs); err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrConfigRead, path, err.Error())
+	}
+
+	config := &Config{
 		LoggerConf{
-			viper.GetString("logger.level"),
-			viper.GetString("logger.file"),
-			viper.GetInt("logger.size"),
-			viper.GetInt("logger.backups"),
-			viper.GetInt("logger.age"),
+			v.GetString("logger.level"),
+			v.GetString("logger.file"),
+			v.GetInt("logger.size"),
+			v.GetInt("logger.backups"),
+			v.GetInt("logger.age"),
 		},
 		HTTPConf{
-			viper.GetString("http.host"),
-			viper.GetString("http.port"),
+			v.GetString("http.host"),
+			v.GetString("http.port"),
 		},
 		StorageConf{
-			viper.GetString("storage.implementation"),
-			viper.GetString("storage.dsn"),
+			v.GetString("storage.implementation"),
+			v.GetString("storage.dsn"),
+			v.GetBool("storage.autoMigrate"),
+			v.GetInt("storage.cacheSize"),
+			v.GetDuration("storage.cacheTTL"),
 		},
 		GRPCConf{
-			viper.GetString("grpc.host"),
-			viper.GetString("grpc.port"),
+			v.GetString("grpc.host"),
+			v.GetString("grpc.port"),
 		},
 		RabbitmqConf{
-			viper.GetString("rabbitmq.dsn"),
+			v.GetString("rabbitmq.dsn"),
 		},
 		ExchangeConf{
-			viper.GetString("exchange.name"),
-			viper.GetString("exchange.kind"),
-			viper.GetBool("exchange.durable"),
-			viper.GetBool("exchange.autoDelete"),
-			viper.GetBool("exchange.internal"),
-			viper.GetBool("exchange.noWait"),
+			v.GetString("exchange.name"),
+			v.GetString("exchange.kind"),
+			v.GetBool("exchange.durable"),
+			v.GetBool("exchange.autoDelete"),
+			v.GetBool("exchange.internal"),
+			v.GetBool("exchange.noWait"),
 		},
 		QueueConf{
-			viper.GetString("queue.name"),
-			viper.GetBool("queue.durable"),
-			viper.GetBool("queue.autoDelete"),
-			viper.GetBool("queue.exclusive"),
-			viper.GetBool("queue.noWait"),
-			viper.GetBool("queue.bindNoWait"),
-			viper.GetString("queue.bindingKey"),
+			v.GetString("queue.name"),
+			v.GetBool("queue.durable"),
+			v.GetBool("queue.autoDelete"),
+			v.GetBool("queue.exclusive"),
+			v.GetBool("queue.noWait"),
+			v.GetBool("queue.bindNoWait"),
+			v.GetString("queue.bindingKey"),
 		},
 		ConsumeConf{
-			viper.GetString("consume.consumer"),
-			viper.GetBool("consume.autoAck"),
-			viper.GetBool("consume.exclusive"),
-			viper.GetBool("consume.noLocal"),
-			viper.GetBool("consume.noWait"),
+			v.GetString("consume.consumer"),
+			v.GetBool("consume.autoAck"),
+			v.GetBool("consume.exclusive"),
+			v.GetBool("consume.noLocal"),
+			v.GetBool("consume.noWait"),
 		},
 		PublishConf{
-			viper.GetBool("publish.mandatory"),
-			viper.GetBool("publish.immediate"),
-			viper.GetString("publish.routingKey"),
+			v.GetBool("publish.mandatory"),
+			v.GetBool("publish.immediate"),
+			v.GetString("publish.routingKey"),
+		},
+		ReceiptsConf{
+			v.GetString("receipts.queue"),
 		},
 		SchedulerConf{
-			viper.GetInt("scheduler.remindIn"),
+			v.GetInt("scheduler.remindIn"),
+			v.GetDuration("scheduler.interval"),
+			v.GetDuration("scheduler.retention"),
+			v.GetBool("scheduler.watch"),
+			v.GetDuration("scheduler.horizon"),
+			jobs,
+		},
+		MetricsConf{
+			v.GetString("metrics.host"),
+			v.GetString("metrics.port"),
+		},
+		TracingConf{
+			v.GetString("tracing.exporter"),
+			v.GetString("tracing.endpoint"),
+			v.GetString("tracing.file"),
+		},
+		HealthConf{
+			v.GetString("health.host"),
+			v.GetString("health.port"),
 		},
-	}, nil
+		AuthConf{
+			v.GetBool("auth.enabled"),
+			v.GetString("auth.jwtSecret"),
+			v.GetString("auth.jwtPublicKey"),
+			v.GetString("auth.jwksFile"),
+			v.GetString("auth.issuer"),
+			v.GetString("auth.audience"),
+		},
+		LimitsConf{
+			v.GetFloat64("limits.rate"),
+			v.GetInt("limits.burst"),
+			v.GetInt64("limits.maxBodyBytes"),
+			v.GetFloat64("limits.addressRate"),
+			v.GetInt("limits.addressBurst"),
+			routeLimits,
+		},
+		ClockConf{
+			v.GetString("clock.travelTo"),
+		},
+		FreeBusyConf{
+			v.GetDuration("freeBusy.maxRange"),
+		},
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
 }
 
 func (c *Config) GetLoggerLevel() string {
@@ -195,6 +346,18 @@ func (c *Config) GetStorageDSN() string {
 	return c.Storage.DSN
 }
 
+func (c *Config) GetStorageAutoMigrate() bool {
+	return c.Storage.AutoMigrate
+}
+
+func (c *Config) GetStorageCacheSize() int {
+	return c.Storage.CacheSize
+}
+
+func (c *Config) GetStorageCacheTTL() time.Duration {
+	return c.Storage.CacheTTL
+}
+
 func (c *Config) GetRabbitDSN() string {
 	return c.Rabbitmq.DSN
 }
@@ -283,6 +446,187 @@ func (c *Config) GetPublishRoutingKey() string {
 	return c.Publish.RoutingKey
 }
 
+// GetReceiptsQueue returns the queue the sender replies to with delivery receipts.
+func (c *Config) GetReceiptsQueue() string {
+	if c.Receipts.Queue == "" {
+		return "calendar.receipts"
+	}
+
+	return c.Receipts.Queue
+}
+
+// GetFreeBusyMaxRange returns the longest period free/busy is looked up for, 90 days by default.
+func (c *Config) GetFreeBusyMaxRange() time.Duration {
+	if c.FreeBusy.MaxRange == 0 {
+		return 90 * 24 * time.Hour
+	}
+
+	return c.FreeBusy.MaxRange
+}
+
 func (c *Config) GetSchedulerRemindIn() int {
 	return c.Scheduler.RemindIn
 }
+
+// GetSchedulerInterval returns how often the scheduler looks for due events, a second by default.
+func (c *Config) GetSchedulerInterval() time.Duration {
+	if c.Scheduler.Interval == 0 {
+		return time.Second
+	}
+
+	return c.Scheduler.Interval
+}
+
+// GetSchedulerRetention returns how long events are kept after they end, a year by default.
+func (c *Config) GetSchedulerRetention() time.Duration {
+	if c.Scheduler.Retention == 0 {
+		return 365 * 24 * time.Hour
+	}
+
+	return c.Scheduler.Retention
+}
+
+// GetSchedulerWatch tells whether the notify job is woken at the reminder times, looked up on storage
+// notifications, rather than only run on its schedule.
+func (c *Config) GetSchedulerWatch() bool {
+	return c.Scheduler.Watch
+}
+
+// GetSchedulerHorizon returns how far ahead the watched reminder times are looked up, and how often
+// regardless of notifications, five minutes by default.
+func (c *Config) GetSchedulerHorizon() time.Duration {
+	if c.Scheduler.Horizon == 0 {
+		return 5 * time.Minute
+	}
+
+	return c.Scheduler.Horizon
+}
+
+// GetSchedulerJobs returns the names of the configured scheduler jobs.
+func (c *Config) GetSchedulerJobs() []string {
+	names := make([]string, 0, len(c.Scheduler.Jobs))
+	for name := range c.Scheduler.Jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// GetSchedulerJobSchedule returns the interval or cron schedule of a job, empty when not configured.
+func (c *Config) GetSchedulerJobSchedule(name string) string {
+	return c.Scheduler.Jobs[name].Schedule
+}
+
+func (c *Config) GetSchedulerJobJitter(name string) time.Duration {
+	return c.Scheduler.Jobs[name].Jitter
+}
+
+func (c *Config) GetSchedulerJobTimeout(name string) time.Duration {
+	return c.Scheduler.Jobs[name].Timeout
+}
+
+func (c *Config) GetMetricsHost() string {
+	return c.Metrics.Host
+}
+
+func (c *Config) GetMetricsPort() string {
+	return c.Metrics.Port
+}
+
+func (c *Config) GetTracingExporter() string {
+	return c.Tracing.Exporter
+}
+
+func (c *Config) GetTracingEndpoint() string {
+	return c.Tracing.Endpoint
+}
+
+func (c *Config) GetTracingFile() string {
+	return c.Tracing.File
+}
+
+func (c *Config) GetHealthHost() string {
+	return c.Health.Host
+}
+
+func (c *Config) GetHealthPort() string {
+	return c.Health.Port
+}
+
+func (c *Config) GetAuthEnabled() bool {
+	return c.Auth.Enabled
+}
+
+func (c *Config) GetAuthJWTSecret() string {
+	return c.Auth.JWTSecret
+}
+
+func (c *Config) GetAuthJWTPublicKey() string {
+	return c.Auth.JWTPublicKey
+}
+
+func (c *Config) GetAuthJWKSFile() string {
+	return c.Auth.JWKSFile
+}
+
+func (c *Config) GetAuthIssuer() string {
+	return c.Auth.Issuer
+}
+
+func (c *Config) GetAuthAudience() string {
+	return c.Auth.Audience
+}
+
+func (c *Config) GetLimitsRate() float64 {
+	return c.Limits.Rate
+}
+
+func (c *Config) GetLimitsBurst() int {
+	return c.Limits.Burst
+}
+
+func (c *Config) GetLimitsMaxBodyBytes() int64 {
+	return c.Limits.MaxBodyBytes
+}
+
+func (c *Config) GetLimitsAddressRate() float64 {
+	return c.Limits.AddressRate
+}
+
+func (c *Config) GetLimitsAddressBurst() int {
+	return c.Limits.AddressBurst
+}
+
+// GetLimitsRoutes returns the routes with limits of their own, lowercased by the config reader.
+func (c *Config) GetLimitsRoutes() []string {
+	routes := make([]string, 0, len(c.Limits.Routes))
+	for route := range c.Limits.Routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	return routes
+}
+
+func (c *Config) GetLimitsRouteRate(route string) float64 {
+	return c.Limits.Routes[route].Rate
+}
+
+func (c *Config) GetLimitsRouteBurst(route string) int {
+	return c.Limits.Routes[route].Burst
+}
+
+func (c *Config) GetLimitsRouteMaxBodyBytes(route string) int64 {
+	return c.Limits.Routes[route].MaxBodyBytes
+}
+
+// GetClockTravelTo returns the moment the services pretend to start at, zero for the real time.
+func (c *Config) GetClockTravelTo() time.Time {
+	to, err := time.Parse(time.RFC3339, c.Clock.TravelTo)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return to
+}
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -12,3 +16,118 @@ func TestStorage(t *testing.T) {
 		require.ErrorIs(t, err, ErrConfigRead, "Error must be: %q, actual: %q", ErrConfigRead, err)
 	})
 }
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "calendar.toml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestShippedConfigs(t *testing.T) {
+	paths, err := filepath.Glob("../../configs/*.toml")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		_, err := NewConfig(path)
+		require.NoError(t, err, path)
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	path := writeConfig(t, `
+[http]
+port = 8080
+
+[exchange]
+autoDelete = false
+`)
+
+	require.NoError(t, os.Setenv("CALENDAR_HTTP_PORT", "9090"))
+	require.NoError(t, os.Setenv("CALENDAR_EXCHANGE_AUTODELETE", "true"))
+	require.NoError(t, os.Setenv("CALENDAR_LOGGER_LEVEL", "warn"))
+	defer func() {
+		os.Unsetenv("CALENDAR_HTTP_PORT")
+		os.Unsetenv("CALENDAR_EXCHANGE_AUTODELETE")
+		os.Unsetenv("CALENDAR_LOGGER_LEVEL")
+	}()
+
+	config, err := NewConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, "9090", config.GetHTTPPort())
+	require.True(t, config.GetExchangeAutoDelete())
+	require.Equal(t, "warn", config.GetLoggerLevel())
+}
+
+func TestValidate(t *testing.T) {
+	path := writeConfig(t, `
+[logger]
+level = "verbose"
+
+[http]
+port = "http"
+
+[grpc]
+port = 70000
+
+[storage]
+implementation = "sql"
+
+[limits]
+rate = -1
+
+[clock]
+travelTo = "next tuesday"
+
+[freeBusy]
+maxRange = "-1h"
+
+[scheduler.jobs.cleanup]
+schedule = "every night"
+jitter = "-1m"
+`)
+
+	_, err := NewConfig(path)
+	require.ErrorIs(t, err, ErrInvalidConfig)
+	for _, key := range []string{
+		"logger.level", "http.port", "grpc.port", "storage.dsn", "limits.rate", "clock.travelTo", "freeBusy.maxRange",
+		"scheduler.jobs.cleanup.schedule", "scheduler.jobs.cleanup.jitter",
+	} {
+		require.Contains(t, err.Error(), key)
+	}
+
+	require.NoError(t, (&Config{}).Validate())
+}
+
+func TestSchedulerJobs(t *testing.T) {
+	path := writeConfig(t, `
+[scheduler]
+retention = "720h"
+watch = true
+
+[scheduler.jobs.notify]
+schedule = "5s"
+timeout = "30s"
+
+[scheduler.jobs.cleanup]
+schedule = "0 3 * * *"
+jitter = "10m"
+`)
+
+	config, err := NewConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, 720*time.Hour, config.GetSchedulerRetention())
+	require.True(t, config.GetSchedulerWatch())
+	require.Equal(t, 5*time.Minute, config.GetSchedulerHorizon())
+	require.Equal(t, []string{"cleanup", "notify"}, config.GetSchedulerJobs())
+	require.Equal(t, "5s", config.GetSchedulerJobSchedule("notify"))
+	require.Equal(t, 30*time.Second, config.GetSchedulerJobTimeout("notify"))
+	require.Equal(t, "0 3 * * *", config.GetSchedulerJobSchedule("cleanup"))
+	require.Equal(t, 10*time.Minute, config.GetSchedulerJobJitter("cleanup"))
+	require.Empty(t, config.GetSchedulerJobSchedule("digest"))
+
+	require.Equal(t, 365*24*time.Hour, (&Config{}).GetSchedulerRetention())
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchReload rereads the config on SIGHUP until the context is done.
+// A config failing to read or validate is reported and the previous one stays in effect.
+func WatchReload(ctx context.Context, path string, apply func(config *Config), fail func(err error)) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			config, err := NewConfig(path)
+			if err != nil {
+				fail(err)
+				continue
+			}
+			apply(config)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/jobs"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+var (
+	loggerLevels           = []string{"debug", "info", "warn", "error"}
+	storageImplementations = []string{"memory", "sql"}
+	tracingExporters       = []string{"none", "otlp", "file"}
+)
+
+// problems collects every invalid value, so that all of them are reported at once.
+type problems []string
+
+func (p *problems) add(key, format string, args ...interface{}) {
+	*p = append(*p, key+": "+fmt.Sprintf(format, args...))
+}
+
+func (p *problems) port(key, value string) {
+	if value == "" {
+		return
+	}
+
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		p.add(key, "must be a port number from 1 to 65535, got %q", value)
+	}
+}
+
+func (p *problems) oneOf(key, value string, allowed []string) {
+	if value == "" {
+		return
+	}
+
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	p.add(key, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
+
+func (p *problems) nonNegative(key string, value float64) {
+	if value < 0 {
+		p.add(key, "must not be negative, got %v", value)
+	}
+}
+
+// Validate checks the values set in the config, left out sections are not required.
+func (c *Config) Validate() error {
+	p := problems{}
+
+	p.oneOf("logger.level", c.Logger.Level, loggerLevels)
+	p.nonNegative("logger.size", float64(c.Logger.Size))
+	p.nonNegative("logger.backups", float64(c.Logger.Backups))
+	p.nonNegative("logger.age", float64(c.Logger.Age))
+
+	p.port("http.port", c.HTTP.Port)
+	p.port("grpc.port", c.GRPC.Port)
+	p.port("metrics.port", c.Metrics.Port)
+	p.port("health.port", c.Health.Port)
+
+	p.oneOf("storage.implementation", c.Storage.Implementation, storageImplementations)
+	if c.Storage.Implementation == "sql" && c.Storage.DSN == "" {
+		p.add("storage.dsn", "is required by the sql storage")
+	}
+	p.nonNegative("storage.cacheSize", float64(c.Storage.CacheSize))
+	p.nonNegative("storage.cacheTTL", float64(c.Storage.CacheTTL))
+
+	p.nonNegative("freeBusy.maxRange", float64(c.FreeBusy.MaxRange))
+
+	p.nonNegative("scheduler.remindIn", float64(c.Scheduler.RemindIn))
+	p.nonNegative("scheduler.interval", float64(c.Scheduler.Interval))
+	p.nonNegative("scheduler.retention", float64(c.Scheduler.Retention))
+	p.nonNegative("scheduler.horizon", float64(c.Scheduler.Horizon))
+	for _, name := range c.GetSchedulerJobs() {
+		job := c.Scheduler.Jobs[name]
+		if job.Schedule != "" {
+			if _, err := jobs.ParseSchedule(job.Schedule); err != nil {
+				p.add("scheduler.jobs."+name+".schedule", "must be an interval, a cron expression or %q, got %q", jobs.Off, job.Schedule)
+			}
+		}
+		p.nonNegative("scheduler.jobs."+name+".jitter", float64(job.Jitter))
+		p.nonNegative("scheduler.jobs."+name+".timeout", float64(job.Timeout))
+	}
+
+	p.oneOf("tracing.exporter", c.Tracing.Exporter, tracingExporters)
+	if c.Tracing.Exporter == "file" && c.Tracing.File == "" {
+		p.add("tracing.file", "is required by the file exporter")
+	}
+
+	p.nonNegative("limits.rate", c.Limits.Rate)
+	p.nonNegative("limits.burst", float64(c.Limits.Burst))
+	p.nonNegative("limits.maxBodyBytes", float64(c.Limits.MaxBodyBytes))
+	p.nonNegative("limits.addressRate", c.Limits.AddressRate)
+	p.nonNegative("limits.addressBurst", float64(c.Limits.AddressBurst))
+	for _, route := range c.GetLimitsRoutes() {
+		rule := c.Limits.Routes[route]
+		p.nonNegative("limits.routes."+route+".rate", rule.Rate)
+		p.nonNegative("limits.routes."+route+".burst", float64(rule.Burst))
+		p.nonNegative("limits.routes."+route+".maxBodyBytes", float64(rule.MaxBodyBytes))
+	}
+
+	if c.Clock.TravelTo != "" {
+		if _, err := time.Parse(time.RFC3339, c.Clock.TravelTo); err != nil {
+			p.add("clock.travelTo", "must be an RFC 3339 time, got %q", c.Clock.TravelTo)
+		}
+	}
+
+	if len(p) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(p, "; "))
+	}
+
+	return nil
+}
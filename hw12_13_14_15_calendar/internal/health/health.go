@@ -0,0 +1,149 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Probe routes.
+const (
+	LivePath  = "/healthz"
+	ReadyPath = "/readyz"
+)
+
+// Dependency statuses.
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+const checkTimeout = 2 * time.Second
+
+// Check reports whether a dependency is reachable.
+type Check func(ctx context.Context) error
+
+// Pinger is a dependency able to verify its connection.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Report is the readiness state of the service and each of its dependencies.
+type Report struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]DependencyReport `json:"dependencies"`
+}
+
+type DependencyReport struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Checker runs readiness checks of the registered dependencies.
+type Checker struct {
+	mu     sync.RWMutex
+	names  []string
+	checks map[string]Check
+}
+
+// New returns a checker without dependencies, which is always ready.
+func New() *Checker {
+	return &Checker{
+		checks: make(map[string]Check),
+	}
+}
+
+// Add registers a dependency check under the given name.
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+// Ready runs all checks concurrently and reports the status of each dependency.
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	reports := make([]DependencyReport, len(c.names))
+
+	var wg sync.WaitGroup
+	for i, name := range c.names {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+
+			reports[i] = DependencyReport{Status: StatusOK}
+			if err := check(ctx); err != nil {
+				reports[i] = DependencyReport{Status: StatusUnavailable, Error: err.Error()}
+			}
+		}(i, c.checks[name])
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:       StatusOK,
+		Dependencies: make(map[string]DependencyReport, len(c.names)),
+	}
+	for i, name := range c.names {
+		report.Dependencies[name] = reports[i]
+		if reports[i].Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+
+	return report
+}
+
+// Watch reports the readiness to the callback every interval until ctx is done.
+func (c *Checker) Watch(ctx context.Context, interval time.Duration, callback func(ready bool)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		callback(c.Ready(ctx).Status == StatusOK)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// LiveHandler responds with 200 while the process is able to serve requests.
+func (c *Checker) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writeJSON(writer, http.StatusOK, Report{Status: StatusOK})
+	})
+}
+
+// ReadyHandler responds with 200 when all dependencies are reachable and 503 otherwise.
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		report := c.Ready(request.Context())
+
+		code := http.StatusOK
+		if report.Status != StatusOK {
+			code = http.StatusServiceUnavailable
+		}
+
+		writeJSON(writer, code, report)
+	})
+}
+
+func writeJSON(writer http.ResponseWriter, code int, report Report) {
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.WriteHeader(code)
+	_ = json.NewEncoder(writer).Encode(report)
+}
@@ -0,0 +1,48 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker(t *testing.T) {
+	checker := New()
+	checker.Add("storage", func(context.Context) error { return nil })
+
+	probe := func(handler http.Handler) (int, Report) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, ReadyPath, nil))
+
+		report := Report{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+
+		return recorder.Code, report
+	}
+
+	t.Run("ready", func(t *testing.T) {
+		code, report := probe(checker.ReadyHandler())
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, StatusOK, report.Status)
+		require.Equal(t, DependencyReport{Status: StatusOK}, report.Dependencies["storage"])
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		checker.Add("rabbitmq", func(context.Context) error { return errors.New("connection closed") })
+
+		code, report := probe(checker.ReadyHandler())
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.Equal(t, StatusUnavailable, report.Status)
+		require.Equal(t, StatusOK, report.Dependencies["storage"].Status)
+		require.Equal(t, DependencyReport{StatusUnavailable, "connection closed"}, report.Dependencies["rabbitmq"])
+
+		code, report = probe(checker.LiveHandler())
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, StatusOK, report.Status)
+	})
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+type Config interface {
+	GetHealthHost() string
+	GetHealthPort() string
+}
+
+// Server is a standalone probe listener for binaries without an HTTP API.
+type Server struct {
+	server *http.Server
+	mux    *http.ServeMux
+}
+
+// NewServer returns a new health server instance.
+func NewServer(config Config, checker *Checker) *Server {
+	mux := http.NewServeMux()
+	mux.Handle(LivePath, checker.LiveHandler())
+	mux.Handle(ReadyPath, checker.ReadyHandler())
+
+	return &Server{
+		server: &http.Server{
+			Addr:    net.JoinHostPort(config.GetHealthHost(), config.GetHealthPort()),
+			Handler: mux,
+		},
+		mux: mux,
+	}
+}
+
+// Handle serves an extra endpoint next to the probes, such as a status page.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start launches the health server.
+func (s *Server) Start() error {
+	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// Stop suspends the health server.
+func (s *Server) Stop(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
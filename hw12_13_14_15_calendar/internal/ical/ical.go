@@ -0,0 +1,356 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	prodID = "-//spendmail//calendar//EN"
+	// ownerProperty keeps the owner of an event across export and import.
+	ownerProperty = "X-CALENDAR-OWNER-ID"
+	// lineLength is the longest content line in octets, longer ones are folded.
+	lineLength = 75
+
+	methodPublish = "PUBLISH"
+	methodCancel  = "CANCEL"
+
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+	dateLayout  = "20060102"
+)
+
+var ErrDecode = errors.New("unable to decode icalendar")
+
+// Encode writes the events as an iCalendar (RFC 5545) stream.
+func Encode(w io.Writer, events []storage.Event) error {
+	return encode(w, "", 0, events)
+}
+
+// EncodeChange writes a change of an event as an iCalendar object of its own, so that a stream of them
+// can be read object by object. Deletions are cancellations (RFC 5546), the revision is the sequence number.
+func EncodeChange(w io.Writer, change storage.Change) error {
+	method := methodPublish
+	if change.Type == storage.EventDeleted {
+		method = methodCancel
+	}
+
+	return encode(w, method, change.Revision, []storage.Event{change.Event})
+}
+
+// encode writes the events, with the method and the sequence number when they are set.
+func encode(w io.Writer, method string, sequence int64, events []storage.Event) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+
+	stamp := time.Now().UTC().Format(utcLayout)
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", prodID)
+	if method != "" {
+		line("METHOD", method)
+	}
+	for _, event := range events {
+		line("BEGIN", "VEVENT")
+		line("UID", fmt.Sprintf("%d@calendar", event.ID))
+		line("DTSTAMP", stamp)
+		if sequence > 0 {
+			line("SEQUENCE", strconv.FormatInt(sequence, 10))
+		}
+		if method == methodCancel {
+			line("STATUS", "CANCELLED")
+		}
+		// Deleted events may come without their dates.
+		if !event.BeginDate.IsZero() {
+			line("DTSTART", event.BeginDate.UTC().Format(utcLayout))
+			line("DTEND", event.EndDate.UTC().Format(utcLayout))
+		}
+		line("SUMMARY", escape(event.Title))
+		if event.Description != "" {
+			line("DESCRIPTION", escape(event.Description))
+		}
+		if event.OwnerID != 0 {
+			line(ownerProperty, strconv.FormatInt(event.OwnerID, 10))
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+
+	return bw.Flush()
+}
+
+// writeFolded writes a content line, folding it without splitting utf-8 sequences.
+func writeFolded(w *bufio.Writer, line string) {
+	limit := lineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// The leading space of a continuation counts towards its length.
+		limit = lineLength - 1
+	}
+	w.WriteString(line + "\r\n")
+}
+
+func escape(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(value)
+}
+
+func unescape(value string) string {
+	return strings.NewReplacer(
+		`\\`, `\`,
+		`\;`, ";",
+		`\,`, ",",
+		`\n`, "\n",
+		`\N`, "\n",
+	).Replace(value)
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Decode reads the events of an iCalendar stream.
+// Events without an end last until their start, all-day ones last a day.
+func Decode(r io.Reader) ([]storage.Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDecode, err.Error())
+	}
+
+	var (
+		events     []storage.Event
+		components []string
+		properties map[string]property
+	)
+
+	for number, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		prop, err := parseProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %s", ErrDecode, number+1, err.Error())
+		}
+
+		switch prop.name {
+		case "BEGIN":
+			components = append(components, strings.ToUpper(prop.value))
+			if strings.EqualFold(prop.value, "VEVENT") {
+				properties = make(map[string]property)
+			}
+			continue
+		case "END":
+			if len(components) == 0 || components[len(components)-1] != strings.ToUpper(prop.value) {
+				return nil, fmt.Errorf("%w: line %d: unexpected end of %s", ErrDecode, number+1, prop.value)
+			}
+			components = components[:len(components)-1]
+			if strings.EqualFold(prop.value, "VEVENT") {
+				event, err := eventOf(properties)
+				if err != nil {
+					return nil, fmt.Errorf("%w: event ending on line %d: %s", ErrDecode, number+1, err.Error())
+				}
+				events = append(events, event)
+			}
+			continue
+		}
+
+		// Properties of nested components, e.g. alarms, are not the event ones.
+		if len(components) > 0 && components[len(components)-1] == "VEVENT" {
+			properties[prop.name] = prop
+		}
+	}
+
+	if len(components) > 0 {
+		return nil, fmt.Errorf("%w: %s is not closed", ErrDecode, components[len(components)-1])
+	}
+
+	return events, nil
+}
+
+// unfold joins the continuation lines, starting with a space or a tab, to the previous ones.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+// parseProperty splits a content line into its name, parameters and value.
+func parseProperty(line string) (property, error) {
+	colon, quoted := -1, false
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, fmt.Errorf("no value in %q", line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := property{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string),
+		value:  line[colon+1:],
+	}
+	for _, param := range parts[1:] {
+		if eq := strings.IndexByte(param, '='); eq > 0 {
+			prop.params[strings.ToUpper(param[:eq])] = strings.Trim(param[eq+1:], `"`)
+		}
+	}
+
+	return prop, nil
+}
+
+func eventOf(properties map[string]property) (storage.Event, error) {
+	event := storage.Event{
+		Title:       unescape(properties["SUMMARY"].value),
+		Description: unescape(properties["DESCRIPTION"].value),
+	}
+
+	start, ok := properties["DTSTART"]
+	if !ok {
+		return event, errors.New("DTSTART is missing")
+	}
+
+	var err error
+	if event.BeginDate, err = parseTime(start); err != nil {
+		return event, err
+	}
+
+	if end, ok := properties["DTEND"]; ok {
+		if event.EndDate, err = parseTime(end); err != nil {
+			return event, err
+		}
+	} else if duration, ok := properties["DURATION"]; ok {
+		d, err := parseDuration(duration.value)
+		if err != nil {
+			return event, err
+		}
+		event.EndDate = event.BeginDate.Add(d)
+	} else if isDate(start) {
+		event.EndDate = event.BeginDate.AddDate(0, 0, 1)
+	} else {
+		event.EndDate = event.BeginDate
+	}
+
+	if owner, ok := properties[ownerProperty]; ok {
+		if event.OwnerID, err = strconv.ParseInt(owner.value, 10, 64); err != nil {
+			return event, fmt.Errorf("%s: %s", ownerProperty, err.Error())
+		}
+	}
+
+	return event, nil
+}
+
+func isDate(prop property) bool {
+	return strings.EqualFold(prop.params["VALUE"], "DATE") || len(prop.value) == len(dateLayout)
+}
+
+// parseTime reads utc, zoned and floating date-times as well as dates, the latter two in local time.
+func parseTime(prop property) (time.Time, error) {
+	if isDate(prop) {
+		return time.ParseInLocation(dateLayout, prop.value, time.Local)
+	}
+
+	if strings.HasSuffix(prop.value, "Z") {
+		return time.Parse(utcLayout, prop.value)
+	}
+
+	location := time.Local
+	if tzid, ok := prop.params["TZID"]; ok {
+		if loaded, err := time.LoadLocation(tzid); err == nil {
+			location = loaded
+		}
+	}
+
+	return time.ParseInLocation(localLayout, prop.value, location)
+}
+
+// parseDuration reads RFC 5545 durations like P1W, P1DT2H or -PT15M.
+func parseDuration(value string) (time.Duration, error) {
+	sign := time.Duration(1)
+	rest := value
+	switch {
+	case strings.HasPrefix(rest, "-"):
+		sign, rest = -1, rest[1:]
+	case strings.HasPrefix(rest, "+"):
+		rest = rest[1:]
+	}
+
+	if !strings.HasPrefix(rest, "P") || len(rest) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	rest = rest[1:]
+
+	units := map[byte]time.Duration{
+		'W': 7 * 24 * time.Hour,
+		'D': 24 * time.Hour,
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+	}
+
+	var total time.Duration
+	number := ""
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c == 'T':
+			continue
+		case c >= '0' && c <= '9':
+			number += string(c)
+		default:
+			unit, ok := units[c]
+			if !ok || number == "" {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			n, _ := strconv.Atoi(number)
+			total += time.Duration(n) * unit
+			number = ""
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	return sign * total, nil
+}
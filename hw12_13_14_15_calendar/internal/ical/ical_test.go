@@ -0,0 +1,120 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	begin := time.Date(2021, 10, 5, 12, 0, 0, 0, time.UTC)
+	events := []storage.Event{
+		{
+			ID:          1,
+			Title:       "Planning; sprint 42, room \\ B",
+			BeginDate:   begin,
+			EndDate:     begin.Add(time.Hour),
+			Description: "Agenda:\n" + strings.Repeat("долгое обсуждение ", 10),
+			OwnerID:     7,
+		},
+		{ID: 2, Title: "Lunch", BeginDate: begin.Add(2 * time.Hour), EndDate: begin.Add(3 * time.Hour)},
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, Encode(buf, events))
+
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		require.LessOrEqual(t, len(line), lineLength, line)
+	}
+
+	decoded, err := Decode(buf)
+	require.NoError(t, err)
+	require.Len(t, decoded, 2)
+	for i, event := range decoded {
+		expected := events[i]
+		expected.ID = 0
+		require.Equal(t, expected.Title, event.Title)
+		require.Equal(t, expected.Description, event.Description)
+		require.Equal(t, expected.OwnerID, event.OwnerID)
+		require.True(t, expected.BeginDate.Equal(event.BeginDate))
+		require.True(t, expected.EndDate.Equal(event.EndDate))
+	}
+}
+
+func TestEncodeChange(t *testing.T) {
+	begin := time.Date(2021, 10, 5, 12, 0, 0, 0, time.UTC)
+
+	buf := &bytes.Buffer{}
+	event := storage.Event{ID: 3, Title: "Review", BeginDate: begin, EndDate: begin.Add(time.Hour), OwnerID: 7}
+	require.NoError(t, EncodeChange(buf, storage.Change{Revision: 12, Type: storage.EventUpdated, Event: event}))
+	require.Contains(t, buf.String(), "METHOD:PUBLISH\r\n")
+	require.Contains(t, buf.String(), "SEQUENCE:12\r\n")
+
+	decoded, err := Decode(buf)
+	require.NoError(t, err)
+	require.Len(t, decoded, 1)
+	require.Equal(t, "Review", decoded[0].Title)
+
+	buf.Reset()
+	deleted := storage.Event{ID: 3, OwnerID: 7}
+	require.NoError(t, EncodeChange(buf, storage.Change{Revision: 13, Type: storage.EventDeleted, Event: deleted}))
+	require.Contains(t, buf.String(), "METHOD:CANCEL\r\n")
+	require.Contains(t, buf.String(), "STATUS:CANCELLED\r\n")
+	require.Contains(t, buf.String(), "UID:3@calendar\r\n")
+	require.NotContains(t, buf.String(), "DTSTART")
+}
+
+func TestDecode(t *testing.T) {
+	t.Run("zones, dates and durations", func(t *testing.T) {
+		input := strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"BEGIN:VEVENT",
+			"DTSTART;TZID=Europe/Moscow:20211005T120000",
+			"DURATION:PT1H30M",
+			"SUMMARY:Zoned",
+			"BEGIN:VALARM",
+			"SUMMARY:Alarm",
+			"END:VALARM",
+			"END:VEVENT",
+			"BEGIN:VEVENT",
+			"DTSTART;VALUE=DATE:20211006",
+			"SUMMARY:All day",
+			"END:VEVENT",
+			"END:VCALENDAR",
+		}, "\n")
+
+		events, err := Decode(strings.NewReader(input))
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+
+		require.Equal(t, "Zoned", events[0].Title)
+		require.Equal(t, time.Date(2021, 10, 5, 9, 0, 0, 0, time.UTC), events[0].BeginDate.UTC())
+		require.Equal(t, 90*time.Minute, events[0].EndDate.Sub(events[0].BeginDate))
+
+		require.Equal(t, 24*time.Hour, events[1].EndDate.Sub(events[1].BeginDate))
+	})
+
+	t.Run("folded lines", func(t *testing.T) {
+		input := "BEGIN:VEVENT\r\nDTSTART:20211005T120000Z\r\nSUMMARY:fol\r\n ded\r\nEND:VEVENT\r\n"
+		events, err := Decode(strings.NewReader(input))
+		require.NoError(t, err)
+		require.Equal(t, "folded", events[0].Title)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, input := range []string{
+			"BEGIN:VEVENT\nSUMMARY:no start\nEND:VEVENT",
+			"BEGIN:VEVENT\nDTSTART:20211005T120000Z",
+			"BEGIN:VEVENT\nDTSTART:yesterday\nEND:VEVENT",
+			"BEGIN:VEVENT\nDTSTART:20211005T120000Z\nDURATION:1H\nEND:VEVENT",
+			"no colon",
+		} {
+			_, err := Decode(strings.NewReader(input))
+			require.ErrorIs(t, err, ErrDecode, input)
+		}
+	})
+}
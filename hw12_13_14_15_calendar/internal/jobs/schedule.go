@@ -0,0 +1,246 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// Off is the schedule spec disabling a job.
+const Off = "off"
+
+// Schedule tells when a job runs next.
+type Schedule interface {
+	// Next returns the first run time after the given one, zero for never.
+	Next(after time.Time) time.Time
+	String() string
+}
+
+// ParseSchedule parses an interval ("30s", "@every 5m"), a cron expression ("0 3 * * *", "@daily")
+// or "off" for a job that never runs.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if spec == Off {
+		return never{}, nil
+	}
+
+	if d, err := time.ParseDuration(strings.TrimPrefix(spec, "@every ")); err == nil {
+		if d <= 0 {
+			return nil, fmt.Errorf("%w: %q, interval must be positive", ErrInvalidSchedule, spec)
+		}
+
+		return Every(d), nil
+	}
+
+	return ParseCron(spec)
+}
+
+// Every runs a job at a fixed interval after the previous run.
+type Every time.Duration
+
+func (e Every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+func (e Every) String() string {
+	return "@every " + time.Duration(e).String()
+}
+
+type never struct{}
+
+func (never) Next(time.Time) time.Time {
+	return time.Time{}
+}
+
+func (never) String() string {
+	return Off
+}
+
+// Cron is a standard five field cron schedule: minute, hour, day of month, month and day of week.
+// It is evaluated in the location of the time passed to Next.
+type Cron struct {
+	spec    string
+	minute  bits
+	hour    bits
+	dom     bits
+	month   bits
+	dow     bits
+	anyDay  bool
+	anyWeek bool
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronFields are the bounds of the cron fields.
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseCron parses a five field cron expression or one of the @yearly, @monthly, @weekly, @daily, @hourly descriptors.
+func ParseCron(spec string) (*Cron, error) {
+	expr := spec
+	if descriptor, ok := cronDescriptors[spec]; ok {
+		expr = descriptor
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("%w: %q, expected %d fields", ErrInvalidSchedule, spec, len(cronFields))
+	}
+
+	parsed := make([]bits, len(fields))
+	for i, field := range fields {
+		b, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q, %s: %s", ErrInvalidSchedule, spec, cronFields[i].name, err.Error())
+		}
+		parsed[i] = b
+	}
+
+	// Sunday is both 0 and 7.
+	if parsed[4].has(7) {
+		parsed[4] |= 1
+	}
+
+	return &Cron{
+		spec:    spec,
+		minute:  parsed[0],
+		hour:    parsed[1],
+		dom:     parsed[2],
+		month:   parsed[3],
+		dow:     parsed[4],
+		anyDay:  strings.HasPrefix(fields[2], "*"),
+		anyWeek: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func (c *Cron) String() string {
+	return c.spec
+}
+
+// Next returns the first matching minute after the given time, zero when there is none within five years.
+func (c *Cron) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		year, month, day := t.Date()
+
+		switch {
+		case !c.month.has(int(month)):
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+		case !c.hour.has(t.Hour()):
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, loc)
+		case !c.minute.has(t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// dayMatches follows cron: when both day fields are restricted, either of them matches.
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom.has(t.Day())
+	dow := c.dow.has(int(t.Weekday()))
+
+	switch {
+	case c.anyDay && c.anyWeek:
+		return true
+	case c.anyDay:
+		return dow
+	case c.anyWeek:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// bits is a set of allowed field values.
+type bits uint64
+
+func (b bits) has(value int) bool {
+	return b&(1<<uint(value)) != 0
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps: "*/15", "1-5", "0,30".
+func parseCronField(field string, min, max int) (bits, error) {
+	var result bits
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part[i+1:])
+			}
+			part = part[:i]
+		}
+
+		low, high := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if low, err = parseCronValue(bounds[0], min, max); err != nil {
+				return 0, err
+			}
+			if high, err = parseCronValue(bounds[1], min, max); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			value, err := parseCronValue(part, min, max)
+			if err != nil {
+				return 0, err
+			}
+			low = value
+			if step == 1 {
+				high = value
+			}
+		}
+
+		for value := low; value <= high; value += step {
+			result |= 1 << uint(value)
+		}
+	}
+
+	return result, nil
+}
+
+func parseCronValue(s string, min, max int) (int, error) {
+	value, err := strconv.Atoi(s)
+	if err != nil || value < min || value > max {
+		return 0, fmt.Errorf("%q is not a number from %d to %d", s, min, max)
+	}
+
+	return value, nil
+}
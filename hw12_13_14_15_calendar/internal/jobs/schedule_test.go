@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	// Tuesday.
+	now := time.Date(2021, time.November, 2, 9, 10, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{spec: "30s", next: now.Add(30 * time.Second)},
+		{spec: "@every 5m", next: now.Add(5 * time.Minute)},
+		{spec: "* * * * *", next: time.Date(2021, time.November, 2, 9, 11, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", next: time.Date(2021, time.November, 2, 9, 15, 0, 0, time.UTC)},
+		{spec: "0 3 * * *", next: time.Date(2021, time.November, 3, 3, 0, 0, 0, time.UTC)},
+		{spec: "0,30 9-17 * * *", next: time.Date(2021, time.November, 2, 9, 30, 0, 0, time.UTC)},
+		{spec: "0 9 * * 1-5", next: time.Date(2021, time.November, 3, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 0 * * 7", next: time.Date(2021, time.November, 7, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 31 * *", next: time.Date(2021, time.December, 31, 0, 0, 0, 0, time.UTC)},
+		// Either of the restricted day fields matches.
+		{spec: "0 0 15 * 5", next: time.Date(2021, time.November, 5, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", next: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{spec: "@hourly", next: time.Date(2021, time.November, 2, 10, 0, 0, 0, time.UTC)},
+		{spec: "@daily", next: time.Date(2021, time.November, 3, 0, 0, 0, 0, time.UTC)},
+		{spec: "@weekly", next: time.Date(2021, time.November, 7, 0, 0, 0, 0, time.UTC)},
+		{spec: "@monthly", next: time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "off"},
+		{spec: "0 0 30 2 *"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			require.NoError(t, err)
+			require.Equal(t, tt.next, schedule.Next(now))
+		})
+	}
+
+	t.Run("local time", func(t *testing.T) {
+		moscow := time.FixedZone("MSK", 3*60*60)
+		schedule, err := ParseSchedule("0 3 * * *")
+		require.NoError(t, err)
+		require.Equal(t, time.Date(2021, time.November, 3, 0, 0, 0, 0, time.UTC), schedule.Next(now.In(moscow)).UTC())
+	})
+
+	for _, spec := range []string{"", "0s", "-1m", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "@often"} {
+		_, err := ParseSchedule(spec)
+		require.ErrorIs(t, err, ErrInvalidSchedule, spec)
+	}
+}
@@ -0,0 +1,333 @@
+// Package jobs runs named jobs on interval or cron schedules.
+package jobs
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/metrics"
+)
+
+var (
+	ErrInvalidJob   = errors.New("invalid job")
+	ErrDuplicateJob = errors.New("job is already added")
+	ErrUnknownJob   = errors.New("unknown job")
+	ErrJobPanicked  = errors.New("job panicked")
+)
+
+// StatusPath is where the status handler is usually served.
+const StatusPath = "/jobs"
+
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// Job is a named piece of work run on a schedule.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Jitter delays each run by a random duration up to it, spreading the load of several instances.
+	Jitter time.Duration
+	// Timeout cancels the context of a run lasting longer, zero for no limit.
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// Status is the last-run state of a job.
+type Status struct {
+	Name        string    `json:"name"`
+	Schedule    string    `json:"schedule"`
+	Running     bool      `json:"running"`
+	NextRun     time.Time `json:"next_run"`
+	LastStart   time.Time `json:"last_start"`
+	LastEnd     time.Time `json:"last_end"`
+	LastSuccess time.Time `json:"last_success"`
+	LastError   string    `json:"last_error,omitempty"`
+	Runs        int       `json:"runs"`
+	Failures    int       `json:"failures"`
+	Skipped     int       `json:"skipped"`
+}
+
+type entry struct {
+	job    Job
+	status Status
+	// reset wakes the job loop up to pick a changed schedule.
+	reset chan struct{}
+	// wakes are the times to run at besides the schedule, rerun tells a wake came while running.
+	wakes wakeHeap
+	rerun bool
+}
+
+// Scheduler runs the added jobs, each one on its own schedule. A run is skipped rather than overlapped
+// while the previous one of the same job is still going on.
+type Scheduler struct {
+	clock  clock.Clock
+	logger Logger
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	random  *rand.Rand
+	wg      sync.WaitGroup
+}
+
+// New returns a scheduler measuring the schedules by the given clock.
+func New(clock clock.Clock, logger Logger) *Scheduler {
+	return &Scheduler{
+		clock:   clock,
+		logger:  logger,
+		entries: make(map[string]*entry),
+		random:  rand.New(rand.NewSource(time.Now().UnixNano())), //nolint:gosec
+	}
+}
+
+// Add registers a job, it should be done before Run.
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" || job.Schedule == nil || job.Run == nil || job.Jitter < 0 || job.Timeout < 0 {
+		return fmt.Errorf("%w: %q", ErrInvalidJob, job.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[job.Name]; ok {
+		return fmt.Errorf("%w: %q", ErrDuplicateJob, job.Name)
+	}
+
+	s.entries[job.Name] = &entry{
+		job:    job,
+		status: Status{Name: job.Name, Schedule: job.Schedule.String()},
+		reset:  make(chan struct{}, 1),
+	}
+
+	return nil
+}
+
+// Reschedule changes the schedule, the jitter and the timeout of a job, e.g. on a config reload.
+// The next run is recalculated from now, a run going on keeps its timeout.
+func (s *Scheduler) Reschedule(name string, schedule Schedule, jitter, timeout time.Duration) error {
+	if schedule == nil || jitter < 0 || timeout < 0 {
+		return fmt.Errorf("%w: %q", ErrInvalidJob, name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownJob, name)
+	}
+
+	e.job.Schedule = schedule
+	e.job.Jitter = jitter
+	e.job.Timeout = timeout
+	e.status.Schedule = schedule.String()
+
+	select {
+	case e.reset <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// WakeAt makes a job run at the given times besides its schedule, replacing the times given before.
+// Times already passed run the job at once. A wake coming while the job runs makes it run again
+// right after, rather than being skipped.
+func (s *Scheduler) WakeAt(name string, times ...time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownJob, name)
+	}
+
+	e.wakes = append(wakeHeap(nil), times...)
+	heap.Init(&e.wakes)
+
+	select {
+	case e.reset <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Run runs the jobs until the context is done, then waits for the runs going on to finish.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		go s.loop(ctx, e)
+	}
+	s.mu.Unlock()
+
+	<-ctx.Done()
+	s.wg.Wait()
+}
+
+// Status returns the state of all the jobs ordered by name.
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.entries))
+	for _, e := range s.entries {
+		statuses = append(statuses, e.status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses
+}
+
+// StatusHandler serves the job statuses as JSON.
+func (s *Scheduler) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.Status())
+	})
+}
+
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	defer s.wg.Done()
+
+	for {
+		now := s.clock.Now()
+		next := s.next(e, now)
+
+		// A job scheduled never waits for a reschedule.
+		var due <-chan time.Time
+		if !next.IsZero() {
+			due = s.clock.After(next.Sub(now))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.reset:
+			continue
+		case <-due:
+		}
+
+		s.trigger(ctx, e)
+	}
+}
+
+// next calculates the next run time of the job, jitter included, and records it in the status.
+// An earlier wake takes the place of the scheduled run.
+func (s *Scheduler) next(e *entry, now time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := e.job.Schedule.Next(now)
+	if !next.IsZero() && e.job.Jitter > 0 {
+		next = next.Add(time.Duration(s.random.Int63n(int64(e.job.Jitter))))
+	}
+	if len(e.wakes) > 0 && (next.IsZero() || e.wakes[0].Before(next)) {
+		next = e.wakes[0]
+	}
+	e.status.NextRun = next
+
+	return next
+}
+
+// trigger starts a run of the job unless the previous one is still going on.
+// The run takes the wakes due by now, which rerun the job instead if it is going on.
+func (s *Scheduler) trigger(ctx context.Context, e *entry) {
+	s.mu.Lock()
+	woken := e.wakes.popUntil(s.clock.Now())
+
+	if e.status.Running && woken {
+		e.rerun = true
+		s.mu.Unlock()
+
+		return
+	}
+
+	if e.status.Running {
+		e.status.Skipped++
+		s.mu.Unlock()
+
+		metrics.SchedulerJobRuns.WithLabelValues(e.job.Name, metrics.ResultSkipped).Inc()
+		s.logger.Warn(fmt.Sprintf("job %s is skipped, the previous run is still going on", e.job.Name))
+
+		return
+	}
+
+	e.status.Running = true
+	e.status.LastStart = s.clock.Now()
+	job := e.job
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		start := time.Now()
+		err := s.run(ctx, job)
+		metrics.SchedulerJobDuration.WithLabelValues(job.Name).Observe(time.Since(start).Seconds())
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		e.status.Running = false
+		e.status.LastEnd = s.clock.Now()
+		e.status.Runs++
+
+		if e.rerun {
+			e.rerun = false
+			heap.Push(&e.wakes, e.status.LastEnd)
+
+			select {
+			case e.reset <- struct{}{}:
+			default:
+			}
+		}
+
+		if err != nil {
+			e.status.Failures++
+			e.status.LastError = err.Error()
+			metrics.SchedulerJobRuns.WithLabelValues(job.Name, metrics.ResultFailure).Inc()
+			s.logger.Error(fmt.Sprintf("job %s failed: %s", job.Name, err.Error()))
+
+			return
+		}
+
+		e.status.LastError = ""
+		e.status.LastSuccess = e.status.LastEnd
+		metrics.SchedulerJobRuns.WithLabelValues(job.Name, metrics.ResultSuccess).Inc()
+		metrics.SchedulerJobLastSuccess.WithLabelValues(job.Name).Set(float64(e.status.LastEnd.Unix()))
+	}()
+}
+
+// run runs the job within its timeout, turning a panic into an error.
+func (s *Scheduler) run(ctx context.Context, job Job) (err error) {
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrJobPanicked, r)
+		}
+	}()
+
+	return job.Run(ctx)
+}
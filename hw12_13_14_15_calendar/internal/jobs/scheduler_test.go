@@ -0,0 +1,240 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+var errJob = errors.New("job error")
+
+func TestScheduler(t *testing.T) {
+	start := time.Date(2021, time.November, 2, 9, 0, 0, 0, time.UTC)
+
+	// run starts the scheduler and waits for the given number of jobs to block on the clock.
+	run := func(t *testing.T, clk *clock.Fake, scheduler *Scheduler, waiters int) {
+		t.Helper()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			scheduler.Run(ctx)
+			close(done)
+		}()
+		t.Cleanup(func() {
+			cancel()
+			<-done
+		})
+
+		require.Eventually(t, func() bool { return clk.Waiters() == waiters }, time.Second, time.Millisecond)
+	}
+
+	status := func(scheduler *Scheduler, name string) Status {
+		for _, status := range scheduler.Status() {
+			if status.Name == name {
+				return status
+			}
+		}
+
+		return Status{}
+	}
+
+	t.Run("runs on schedule", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		scheduler := New(clk, nopLogger{})
+		runs := make(chan time.Time, 10)
+
+		require.NoError(t, scheduler.Add(Job{Name: "ok", Schedule: Every(time.Minute), Run: func(ctx context.Context) error {
+			runs <- clk.Now()
+			return nil
+		}}))
+		require.NoError(t, scheduler.Add(Job{Name: "failing", Schedule: Every(time.Minute), Run: func(ctx context.Context) error {
+			return errJob
+		}}))
+		require.NoError(t, scheduler.Add(Job{Name: "hourly", Schedule: Every(time.Hour), Run: func(ctx context.Context) error {
+			return nil
+		}}))
+		run(t, clk, scheduler, 3)
+
+		require.Equal(t, start.Add(time.Minute), status(scheduler, "ok").NextRun)
+
+		clk.Advance(time.Minute)
+		require.Equal(t, start.Add(time.Minute), <-runs)
+
+		require.Eventually(t, func() bool {
+			return status(scheduler, "ok").Runs == 1 && status(scheduler, "failing").Failures == 1
+		}, time.Second, time.Millisecond)
+
+		ok := status(scheduler, "ok")
+		require.Equal(t, start.Add(time.Minute), ok.LastSuccess)
+		require.Empty(t, ok.LastError)
+
+		failing := status(scheduler, "failing")
+		require.Equal(t, errJob.Error(), failing.LastError)
+		require.True(t, failing.LastSuccess.IsZero())
+
+		require.Zero(t, status(scheduler, "hourly").Runs)
+		require.Equal(t, []string{"failing", "hourly", "ok"}, names(scheduler.Status()))
+	})
+
+	t.Run("skips overlapping runs", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		scheduler := New(clk, nopLogger{})
+		started := make(chan struct{}, 10)
+		release := make(chan struct{})
+
+		require.NoError(t, scheduler.Add(Job{Name: "slow", Schedule: Every(time.Minute), Run: func(ctx context.Context) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		}}))
+		run(t, clk, scheduler, 1)
+
+		clk.Advance(time.Minute)
+		<-started
+		require.Eventually(t, func() bool { return clk.Waiters() == 1 }, time.Second, time.Millisecond)
+
+		clk.Advance(time.Minute)
+		require.Eventually(t, func() bool { return status(scheduler, "slow").Skipped == 1 }, time.Second, time.Millisecond)
+		require.True(t, status(scheduler, "slow").Running)
+		require.Len(t, started, 0)
+
+		close(release)
+		require.Eventually(t, func() bool { return status(scheduler, "slow").Runs == 1 }, time.Second, time.Millisecond)
+		require.False(t, status(scheduler, "slow").Running)
+	})
+
+	t.Run("timeouts and panics", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		scheduler := New(clk, nopLogger{})
+
+		require.NoError(t, scheduler.Add(Job{Name: "stuck", Schedule: Every(time.Minute), Timeout: 10 * time.Millisecond,
+			Run: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}}))
+		require.NoError(t, scheduler.Add(Job{Name: "panicking", Schedule: Every(time.Minute), Run: func(ctx context.Context) error {
+			panic("oops")
+		}}))
+		run(t, clk, scheduler, 2)
+
+		clk.Advance(time.Minute)
+		require.Eventually(t, func() bool {
+			return status(scheduler, "stuck").Failures == 1 && status(scheduler, "panicking").Failures == 1
+		}, time.Second, time.Millisecond)
+		require.Equal(t, context.DeadlineExceeded.Error(), status(scheduler, "stuck").LastError)
+		require.Contains(t, status(scheduler, "panicking").LastError, "oops")
+	})
+
+	t.Run("reschedules", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		scheduler := New(clk, nopLogger{})
+
+		require.NoError(t, scheduler.Add(Job{Name: "job", Schedule: Every(time.Minute), Run: func(ctx context.Context) error {
+			return nil
+		}}))
+		run(t, clk, scheduler, 1)
+
+		cron, err := ParseCron("0 3 * * *")
+		require.NoError(t, err)
+		require.NoError(t, scheduler.Reschedule("job", cron, 0, time.Minute))
+		require.Eventually(t, func() bool {
+			return status(scheduler, "job").NextRun.Equal(time.Date(2021, time.November, 3, 3, 0, 0, 0, time.UTC))
+		}, time.Second, time.Millisecond)
+		require.Equal(t, "0 3 * * *", status(scheduler, "job").Schedule)
+
+		off, err := ParseSchedule(Off)
+		require.NoError(t, err)
+		require.NoError(t, scheduler.Reschedule("job", off, 0, 0))
+		require.Eventually(t, func() bool { return status(scheduler, "job").NextRun.IsZero() }, time.Second, time.Millisecond)
+
+		require.ErrorIs(t, scheduler.Reschedule("unknown", cron, 0, 0), ErrUnknownJob)
+	})
+
+	t.Run("wakes", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		scheduler := New(clk, nopLogger{})
+		runs := make(chan time.Time, 10)
+		release := make(chan struct{})
+
+		require.NoError(t, scheduler.Add(Job{Name: "job", Schedule: Every(time.Hour), Run: func(ctx context.Context) error {
+			runs <- clk.Now()
+			<-release
+			return nil
+		}}))
+		run(t, clk, scheduler, 1)
+
+		require.NoError(t, scheduler.WakeAt("job", start.Add(20*time.Minute), start.Add(10*time.Minute)))
+		require.Eventually(t, func() bool {
+			return status(scheduler, "job").NextRun.Equal(start.Add(10 * time.Minute))
+		}, time.Second, time.Millisecond)
+
+		// The hourly wait left behind by the wakes stays among the waiters.
+		clk.Advance(10 * time.Minute)
+		require.Equal(t, start.Add(10*time.Minute), <-runs)
+		require.Eventually(t, func() bool { return clk.Waiters() == 2 }, time.Second, time.Millisecond)
+
+		// A wake coming while the job runs is not skipped, the job runs again once done.
+		clk.Advance(10 * time.Minute)
+		require.Eventually(t, func() bool { return clk.Waiters() == 2 }, time.Second, time.Millisecond)
+		require.Len(t, runs, 0)
+
+		close(release)
+		require.Equal(t, start.Add(20*time.Minute), <-runs)
+		require.Eventually(t, func() bool {
+			job := status(scheduler, "job")
+			return job.Runs == 2 && job.NextRun.Equal(start.Add(80*time.Minute))
+		}, time.Second, time.Millisecond)
+		require.Zero(t, status(scheduler, "job").Skipped)
+
+		require.ErrorIs(t, scheduler.WakeAt("unknown", start), ErrUnknownJob)
+	})
+
+	t.Run("invalid jobs", func(t *testing.T) {
+		scheduler := New(clock.NewFake(start), nopLogger{})
+		noop := func(ctx context.Context) error { return nil }
+
+		require.ErrorIs(t, scheduler.Add(Job{Schedule: Every(time.Minute), Run: noop}), ErrInvalidJob)
+		require.ErrorIs(t, scheduler.Add(Job{Name: "job", Run: noop}), ErrInvalidJob)
+		require.ErrorIs(t, scheduler.Add(Job{Name: "job", Schedule: Every(time.Minute)}), ErrInvalidJob)
+		require.ErrorIs(t, scheduler.Add(Job{Name: "job", Schedule: Every(time.Minute), Jitter: -1, Run: noop}), ErrInvalidJob)
+
+		require.NoError(t, scheduler.Add(Job{Name: "job", Schedule: Every(time.Minute), Run: noop}))
+		require.ErrorIs(t, scheduler.Add(Job{Name: "job", Schedule: Every(time.Minute), Run: noop}), ErrDuplicateJob)
+	})
+
+	t.Run("status handler", func(t *testing.T) {
+		scheduler := New(clock.NewFake(start), nopLogger{})
+		require.NoError(t, scheduler.Add(Job{Name: "job", Schedule: Every(time.Minute), Run: func(ctx context.Context) error {
+			return nil
+		}}))
+
+		recorder := httptest.NewRecorder()
+		scheduler.StatusHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, StatusPath, nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		require.Contains(t, recorder.Body.String(), `"name":"job","schedule":"@every 1m0s"`)
+	})
+}
+
+func names(statuses []Status) []string {
+	result := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		result = append(result, status.Name)
+	}
+
+	return result
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"container/heap"
+	"time"
+)
+
+// wakeHeap is a min-heap of the times a job is woken up at.
+type wakeHeap []time.Time
+
+func (h wakeHeap) Len() int           { return len(h) }
+func (h wakeHeap) Less(i, j int) bool { return h[i].Before(h[j]) }
+func (h wakeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *wakeHeap) Push(x interface{}) {
+	*h = append(*h, x.(time.Time))
+}
+
+func (h *wakeHeap) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return last
+}
+
+// popUntil drops the times up to the given one, reporting whether there were any.
+func (h *wakeHeap) popUntil(at time.Time) bool {
+	popped := false
+	for len(*h) > 0 && !(*h)[0].After(at) {
+		heap.Pop(h)
+		popped = true
+	}
+
+	return popped
+}
@@ -0,0 +1,252 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+)
+
+// sweepInterval is how often idle client buckets are dropped.
+const sweepInterval = time.Minute
+
+var (
+	ErrRateLimited  = errors.New("rate limit exceeded")
+	ErrBodyTooLarge = errors.New("request body too large")
+)
+
+type Config interface {
+	GetLimitsRate() float64
+	GetLimitsBurst() int
+	GetLimitsMaxBodyBytes() int64
+	GetLimitsAddressRate() float64
+	GetLimitsAddressBurst() int
+	GetLimitsRoutes() []string
+	GetLimitsRouteRate(route string) float64
+	GetLimitsRouteBurst(route string) int
+	GetLimitsRouteMaxBodyBytes(route string) int64
+}
+
+// Rule limits the requests of a single client to a route.
+type Rule struct {
+	// Rate is the number of requests per second refilling the bucket, zero disables rate limiting.
+	Rate float64
+	// Burst is the bucket size, the number of requests allowed at once.
+	Burst int
+	// MaxBodyBytes caps the request body size, zero leaves the body unlimited.
+	MaxBodyBytes int64
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket is refilled, an idle full bucket can be dropped.
+	full time.Time
+}
+
+// Limiter throttles clients with token buckets kept per route and client.
+// Remote addresses have a bucket of their own too, shared by all the routes and checked before authentication.
+type Limiter struct {
+	mu        sync.Mutex
+	defaults  Rule
+	address   Rule
+	routes    map[string]Rule
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	clock     clock.Clock
+}
+
+// New returns a limiter with the default rule of the config and its route overrides, the clock refilling the buckets.
+// Route rule fields left zero fall back to the default ones.
+func New(config Config, clock clock.Clock) *Limiter {
+	l := &Limiter{
+		buckets: make(map[string]*bucket),
+		clock:   clock,
+	}
+	l.Update(config)
+
+	return l
+}
+
+// Update replaces the rules with the ones of the config, the client buckets are kept.
+func (l *Limiter) Update(config Config) {
+	defaults := Rule{
+		Rate:         config.GetLimitsRate(),
+		Burst:        config.GetLimitsBurst(),
+		MaxBodyBytes: config.GetLimitsMaxBodyBytes(),
+	}
+	address := Rule{
+		Rate:  config.GetLimitsAddressRate(),
+		Burst: config.GetLimitsAddressBurst(),
+	}
+
+	routes := make(map[string]Rule)
+	for _, route := range config.GetLimitsRoutes() {
+		rule := Rule{
+			Rate:         config.GetLimitsRouteRate(route),
+			Burst:        config.GetLimitsRouteBurst(route),
+			MaxBodyBytes: config.GetLimitsRouteMaxBodyBytes(route),
+		}
+		if rule.Rate == 0 {
+			rule.Rate = defaults.Rate
+		}
+		if rule.Burst == 0 {
+			rule.Burst = defaults.Burst
+		}
+		if rule.MaxBodyBytes == 0 {
+			rule.MaxBodyBytes = defaults.MaxBodyBytes
+		}
+		routes[normalize(route)] = rule
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.defaults = defaults
+	l.address = address
+	l.routes = routes
+}
+
+// normalize makes route names case insensitive, the config keys are lowercased on read.
+func normalize(route string) string {
+	return strings.ToLower(route)
+}
+
+// Rule returns the rule applied to the route.
+func (l *Limiter) Rule(route string) Rule {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rule(route)
+}
+
+func (l *Limiter) rule(route string) Rule {
+	if rule, ok := l.routes[normalize(route)]; ok {
+		return rule
+	}
+
+	return l.defaults
+}
+
+// MaxBodyBytes returns the largest body cap over all the routes, zero when some route is unlimited.
+func (l *Limiter) MaxBodyBytes() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	max := l.defaults.MaxBodyBytes
+	for _, rule := range l.routes {
+		if max == 0 || rule.MaxBodyBytes == 0 {
+			return 0
+		}
+		if rule.MaxBodyBytes > max {
+			max = rule.MaxBodyBytes
+		}
+	}
+
+	return max
+}
+
+// Allow takes a token of the client bucket for the route.
+// When the bucket is empty, it returns how long to wait for the next token.
+func (l *Limiter) Allow(route, client string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.take(normalize(route)+" "+client, l.rule(route))
+}
+
+// AllowAddress takes a token of the remote address bucket, whoever the request is authenticated as.
+// It keeps unauthenticated clients from guessing tokens and costing authentication lookups at will.
+func (l *Limiter) AllowAddress(remoteAddr string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.take("address "+host(remoteAddr), l.address)
+}
+
+// take takes a token of the bucket with the given key.
+func (l *Limiter) take(key string, rule Rule) (time.Duration, bool) {
+	if rule.Rate <= 0 {
+		return 0, true
+	}
+
+	burst := float64(rule.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	now := l.clock.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rule.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return refill(1-b.tokens, rule.Rate), false
+	}
+	b.tokens--
+	b.full = now.Add(refill(burst-b.tokens, rule.Rate))
+
+	return 0, true
+}
+
+// refill returns how long it takes to refill the tokens at the rate.
+func refill(tokens, rate float64) time.Duration {
+	return time.Duration(tokens / rate * float64(time.Second))
+}
+
+// sweep drops the buckets refilled since their last use, a new bucket starts full anyway.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// ClientKey identifies the client of a request: the authenticated owner, or the remote ip otherwise.
+func ClientKey(ctx context.Context, remoteAddr string) string {
+	if ownerID, ok := app.OwnerFromContext(ctx); ok {
+		return "owner:" + strconv.FormatInt(ownerID, 10)
+	}
+
+	return "ip:" + host(remoteAddr)
+}
+
+// host strips the port of the remote address.
+func host(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+
+	return host
+}
+
+// RetryAfter formats the wait as the whole seconds of a Retry-After header.
+func RetryAfter(wait time.Duration) string {
+	seconds := int64(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return strconv.FormatInt(seconds, 10)
+}
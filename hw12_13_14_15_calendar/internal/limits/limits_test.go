@@ -0,0 +1,86 @@
+package limits
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct{}
+
+func (testConfig) GetLimitsRate() float64                  { return 1 }
+func (testConfig) GetLimitsBurst() int                     { return 2 }
+func (testConfig) GetLimitsMaxBodyBytes() int64            { return 1024 }
+func (testConfig) GetLimitsAddressRate() float64           { return 1 }
+func (testConfig) GetLimitsAddressBurst() int              { return 3 }
+func (testConfig) GetLimitsRoutes() []string               { return []string{"POST /events"} }
+func (testConfig) GetLimitsRouteRate(string) float64       { return 0.5 }
+func (testConfig) GetLimitsRouteBurst(string) int          { return 0 }
+func (testConfig) GetLimitsRouteMaxBodyBytes(string) int64 { return 4096 }
+
+func TestLimiter(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	limiter := New(testConfig{}, clk)
+
+	t.Run("rules", func(t *testing.T) {
+		require.Equal(t, Rule{Rate: 1, Burst: 2, MaxBodyBytes: 1024}, limiter.Rule("GET /events"))
+		require.Equal(t, Rule{Rate: 0.5, Burst: 2, MaxBodyBytes: 4096}, limiter.Rule("post /events"))
+		require.Equal(t, int64(4096), limiter.MaxBodyBytes())
+	})
+
+	t.Run("bucket", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			_, ok := limiter.Allow("GET /events", "ip:10.0.0.1")
+			require.True(t, ok)
+		}
+
+		wait, ok := limiter.Allow("GET /events", "ip:10.0.0.1")
+		require.False(t, ok)
+		require.Equal(t, time.Second, wait)
+
+		// Other clients and routes have buckets of their own.
+		_, ok = limiter.Allow("GET /events", "ip:10.0.0.2")
+		require.True(t, ok)
+		_, ok = limiter.Allow("POST /events", "ip:10.0.0.1")
+		require.True(t, ok)
+
+		clk.Advance(time.Second)
+		_, ok = limiter.Allow("GET /events", "ip:10.0.0.1")
+		require.True(t, ok)
+	})
+
+	t.Run("address", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			_, ok := limiter.AllowAddress("10.0.0.4:4242")
+			require.True(t, ok)
+		}
+
+		// The address bucket is shared by the ports and the routes of the address.
+		_, ok := limiter.AllowAddress("10.0.0.4:4343")
+		require.False(t, ok)
+
+		_, ok = limiter.AllowAddress("10.0.0.5:4242")
+		require.True(t, ok)
+	})
+
+	t.Run("sweep", func(t *testing.T) {
+		clk.Advance(time.Hour)
+		_, ok := limiter.Allow("GET /events", "ip:10.0.0.3")
+		require.True(t, ok)
+		require.Len(t, limiter.buckets, 1)
+	})
+}
+
+func TestClientKey(t *testing.T) {
+	require.Equal(t, "ip:10.0.0.1", ClientKey(context.Background(), "10.0.0.1:4242"))
+	require.Equal(t, "owner:7", ClientKey(app.ContextWithOwner(context.Background(), 7), "10.0.0.1:4242"))
+}
+
+func TestRetryAfter(t *testing.T) {
+	require.Equal(t, "1", RetryAfter(time.Millisecond))
+	require.Equal(t, "3", RetryAfter(2100*time.Millisecond))
+}
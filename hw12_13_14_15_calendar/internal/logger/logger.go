@@ -25,23 +25,27 @@ type Config interface {
 
 type Logger struct {
 	Logger *zap.Logger
+	level  zap.AtomicLevel
 }
 
-func New(config Config) *Logger {
-	var zapCoreLevel zapcore.Level
-
-	switch config.GetLoggerLevel() {
+// parseLevel maps the config level onto the zap one, info by default.
+func parseLevel(level string) zapcore.Level {
+	switch level {
 	case DEBUG:
-		zapCoreLevel = zap.DebugLevel
+		return zap.DebugLevel
 	case INFO:
-		zapCoreLevel = zap.InfoLevel
+		return zap.InfoLevel
 	case WARN:
-		zapCoreLevel = zap.WarnLevel
+		return zap.WarnLevel
 	case ERROR:
-		zapCoreLevel = zap.ErrorLevel
+		return zap.ErrorLevel
 	default:
-		zapCoreLevel = zap.InfoLevel
+		return zap.InfoLevel
 	}
+}
+
+func New(config Config) *Logger {
+	level := zap.NewAtomicLevelAt(parseLevel(config.GetLoggerLevel()))
 
 	encoderConfig := zap.NewProductionEncoderConfig()
 	encoderConfig.EncodeTime = TimeEncoder
@@ -53,15 +57,21 @@ func New(config Config) *Logger {
 			MaxBackups: config.GetLoggerBackups(),
 			MaxAge:     config.GetLoggerAge(),
 		}),
-		zapCoreLevel,
+		level,
 	)
 	logger := zap.New(core)
 
 	return &Logger{
 		Logger: logger,
+		level:  level,
 	}
 }
 
+// SetLevel changes the level of the logger and of its zap loggers on the fly.
+func (l *Logger) SetLevel(level string) {
+	l.level.SetLevel(parseLevel(level))
+}
+
 func TimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 	enc.AppendString(t.Format("2006-01-02 15:04:05"))
 }
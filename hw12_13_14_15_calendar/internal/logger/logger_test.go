@@ -43,4 +43,34 @@ func TestLogger(t *testing.T) {
 		require.Contains(t, content, "warn\twarn_message", "Log doesn't contain string error")
 		require.Contains(t, content, "error\terror_message", "Log doesn't contain string error")
 	})
+
+	t.Run("set level", func(t *testing.T) {
+		config, err := internalconfig.NewConfig("../../configs/calendar.toml")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := os.CreateTemp("/tmp/", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		config.Logger.Level = "error"
+		config.Logger.File = f.Name()
+
+		logger := New(config)
+		logger.Info("muted_message")
+		logger.SetLevel("info")
+		logger.Info("reloaded_message")
+
+		b, err := ioutil.ReadFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content := string(b)
+
+		require.NotContains(t, content, "muted_message")
+		require.Contains(t, content, "info\treloaded_message")
+	})
 }
@@ -0,0 +1,240 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "calendar"
+
+// Delivery outcomes of the sender, also reported back to the scheduler in receipts.
+const (
+	OutcomeDelivered = "delivered"
+	OutcomeFailed    = "failed"
+	OutcomeMalformed = "malformed"
+)
+
+// Rejection reasons of the request limits.
+const (
+	ReasonRateLimited        = "rate_limited"
+	ReasonAddressRateLimited = "address_rate_limited"
+	ReasonBodyTooLarge       = "body_too_large"
+)
+
+// Lookup results of the storage cache.
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// Publish and job run results of the scheduler.
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+	ResultSkipped = "skipped"
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of handled HTTP requests.",
+	}, []string{"method", "route", "code"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of handled HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	RejectedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "limits",
+		Name:      "rejected_requests_total",
+		Help:      "Number of requests rejected by rate and body size limits.",
+	}, []string{"route", "reason"})
+
+	StorageQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of storage queries.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	StorageCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "cache_lookups_total",
+		Help:      "Number of storage cache lookups by operation and result.",
+	}, []string{"operation", "result"})
+
+	StorageCacheInvalidations = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "cache_invalidations_total",
+		Help:      "Number of storage cache entries dropped because of changes.",
+	})
+
+	SchedulerDueEvents = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "due_events_total",
+		Help:      "Number of reminders found due for a notification.",
+	})
+
+	SchedulerPublishes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "publishes_total",
+		Help:      "Number of notifications published to the broker.",
+	}, []string{"result"})
+
+	SchedulerReceipts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "receipts_total",
+		Help:      "Number of delivery receipts consumed from the sender by outcome.",
+	}, []string{"outcome"})
+
+	SchedulerJobRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "job_runs_total",
+		Help:      "Number of scheduled job runs by result, skipped ones overlapped a previous run.",
+	}, []string{"job", "result"})
+
+	SchedulerJobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "job_duration_seconds",
+		Help:      "Duration of scheduled job runs.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"job"})
+
+	SchedulerJobLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "job_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful run of a scheduled job.",
+	}, []string{"job"})
+
+	SenderConsumeLag = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "sender",
+		Name:      "consume_lag_seconds",
+		Help:      "Time between publishing a notification and consuming it.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 4, 10),
+	})
+
+	SenderDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sender",
+		Name:      "deliveries_total",
+		Help:      "Number of consumed notifications by delivery outcome.",
+	}, []string{"outcome"})
+
+	RabbitmqConnected = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "rabbitmq",
+		Name:      "connected",
+		Help:      "Whether the RabbitMQ connection is open (1) or closed (0).",
+	})
+)
+
+// Handler returns the metrics exposition handler.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveHTTPRequest records a handled HTTP request.
+func ObserveHTTPRequest(method, path string, code int, latency time.Duration) {
+	method, route := Method(method), Route(path)
+	HTTPRequests.WithLabelValues(method, route, strconv.Itoa(code)).Inc()
+	HTTPRequestDuration.WithLabelValues(method, route).Observe(latency.Seconds())
+}
+
+// ObserveStorageQuery records the duration of a storage query started at the given time.
+func ObserveStorageQuery(operation string, start time.Time) {
+	StorageQueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// Method returns the method of a request as a label, unknown ones being labeled as OtherRoute.
+func Method(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	default:
+		return OtherRoute
+	}
+}
+
+// OtherRoute labels the requests of the paths outside of the calendar API.
+const OtherRoute = "other"
+
+// routes are the path templates of the calendar API, following the proto http annotations.
+var routes = map[string]bool{
+	"/":                           true,
+	"/openapi.json":               true,
+	Path:                          true,
+	"/healthz":                    true,
+	"/readyz":                     true,
+	"/jobs":                       true,
+	"/events":                     true,
+	"/events/{id}":                true,
+	"/events/batch":               true,
+	"/events/day":                 true,
+	"/events/week":                true,
+	"/events/month":               true,
+	"/events/watch":               true,
+	"/events/{id}/reminders":      true,
+	"/events/{id}/notifications":  true,
+	"/reminders/{id}/snooze":      true,
+	"/reminders/{id}/dismiss":     true,
+	"/notifications":              true,
+	"/notifications/{id}":         true,
+	"/digest":                     true,
+	"/freebusy":                   true,
+	"/freebusy/slots":             true,
+	"/categories":                 true,
+	"/categories/{id}":            true,
+	"/calendars":                  true,
+	"/calendars/{id}":             true,
+	"/calendars/{id}/grants":      true,
+	"/calendars/{id}/grants/{id}": true,
+	"/event/create":               true,
+	"/event/update":               true,
+	"/event/remove":               true,
+	"/event/day":                  true,
+	"/event/week":                 true,
+	"/event/month":                true,
+	"/event/freebusy":             true,
+	"/event/slots":                true,
+}
+
+// Route replaces numeric path segments with a placeholder and unknown paths with OtherRoute,
+// keeping the label cardinality bounded whatever paths clients request.
+func Route(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if _, err := strconv.ParseInt(segment, 10, 64); err == nil {
+			segments[i] = "{id}"
+		}
+	}
+
+	if route := strings.Join(segments, "/"); routes[route] {
+		return route
+	}
+
+	return OtherRoute
+}
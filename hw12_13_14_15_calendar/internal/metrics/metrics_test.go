@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoute(t *testing.T) {
+	require.Equal(t, "/events/{id}", Route("/events/42"))
+	require.Equal(t, "/events/week", Route("/events/week"))
+	require.Equal(t, "/", Route("/"))
+	require.Equal(t, "/calendars/{id}/grants/{id}", Route("/calendars/1/grants/2"))
+	require.Equal(t, OtherRoute, Route("/wp-admin/install.php"))
+	require.Equal(t, OtherRoute, Route("/events/abc"))
+	require.Equal(t, OtherRoute, Method("PROPFIND"))
+}
+
+func TestHandler(t *testing.T) {
+	ObserveHTTPRequest(http.MethodGet, "/events/42", http.StatusOK, time.Millisecond)
+	ObserveStorageQuery("get_event_by_id", time.Now())
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path, nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.String()
+	require.True(t, strings.Contains(body, `calendar_http_requests_total{code="200",method="GET",route="/events/{id}"} 1`))
+	require.True(t, strings.Contains(body, `calendar_storage_query_duration_seconds_count{operation="get_event_by_id"} 1`))
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// Path is the route metrics are exposed on.
+const Path = "/metrics"
+
+type Config interface {
+	GetMetricsHost() string
+	GetMetricsPort() string
+}
+
+// Server is a standalone metrics listener for binaries without an HTTP API.
+type Server struct {
+	server *http.Server
+}
+
+// NewServer returns a new metrics server instance.
+func NewServer(config Config) *Server {
+	mux := http.NewServeMux()
+	mux.Handle(Path, Handler())
+
+	return &Server{
+		server: &http.Server{
+			Addr:    net.JoinHostPort(config.GetMetricsHost(), config.GetMetricsPort()),
+			Handler: mux,
+		},
+	}
+}
+
+// Start launches the metrics server.
+func (s *Server) Start() error {
+	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// Stop suspends the metrics server.
+func (s *Server) Stop(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
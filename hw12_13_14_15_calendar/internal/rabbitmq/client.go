@@ -1,13 +1,20 @@
 package rabbitmq
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/metrics"
 	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Config interface {
@@ -33,6 +40,7 @@ type Config interface {
 	GetPublishMandatory() bool
 	GetPublishImmediate() bool
 	GetPublishRoutingKey() string
+	GetReceiptsQueue() string
 }
 
 type Logger interface {
@@ -49,11 +57,49 @@ type Client struct {
 	Channel    *amqp.Channel
 }
 
+// Types of the published messages, the sender tells them apart by the AMQP type property.
+// Messages without a type are notifications.
+const (
+	TypeNotification = "notification"
+	TypeDigest       = "digest"
+	TypeReceipt      = "receipt"
+)
+
 type Notification struct {
 	ID      int64     `json:"id"`
 	Title   string    `json:"title"`
 	Date    time.Time `json:"date"`
 	OwnerID int64     `json:"owner_id"`
+	// ReminderID is the reminder the notification is sent for.
+	ReminderID int64 `json:"reminder_id,omitempty"`
+	// NotificationID is the record the delivery receipt refers to.
+	NotificationID int64 `json:"notification_id,omitempty"`
+}
+
+// Receipt reports the outcome of delivering a notification back to the scheduler.
+type Receipt struct {
+	NotificationID int64     `json:"notification_id"`
+	Delivered      bool      `json:"delivered"`
+	Error          string    `json:"error,omitempty"`
+	At             time.Time `json:"at"`
+}
+
+// DigestNotification is the agenda of an owner's upcoming day or week.
+type DigestNotification struct {
+	OwnerID int64  `json:"owner_id"`
+	Period  string `json:"period"`
+	// TimeZone is the owner's IANA time zone to render the dates in.
+	TimeZone string        `json:"time_zone"`
+	Begin    time.Time     `json:"begin"`
+	End      time.Time     `json:"end"`
+	Events   []DigestEvent `json:"events"`
+}
+
+type DigestEvent struct {
+	ID    int64     `json:"id"`
+	Title string    `json:"title"`
+	Begin time.Time `json:"begin"`
+	End   time.Time `json:"end"`
 }
 
 var (
@@ -66,6 +112,8 @@ var (
 	ErrRabbitmqPublish         = errors.New("unable to publish a message to rabbitmq")
 	ErrRabbitmqConnectionClose = errors.New("unable to to close rabbitmq connection")
 	ErrRabbitmqChannelClose    = errors.New("unable to to close rabbitmq channel")
+	ErrRabbitmqConnectionLost  = errors.New("rabbitmq connection lost")
+	ErrRabbitmqNotConnected    = errors.New("rabbitmq connection is closed")
 )
 
 // NewClient is a Rabbitmq constructor.
@@ -87,9 +135,30 @@ func NewClient(config Config, logger Logger) (*Client, error) {
 		ch,
 	}
 
+	metrics.RabbitmqConnected.Set(1)
+	go client.watchConnection()
+
 	return client, nil
 }
 
+// watchConnection resets the connection gauge once the connection is closed or lost.
+func (c *Client) watchConnection() {
+	if err := <-c.Connection.NotifyClose(make(chan *amqp.Error, 1)); err != nil {
+		c.Logger.Error(fmt.Errorf("%w: %s", ErrRabbitmqConnectionLost, err.Error()).Error())
+	}
+
+	metrics.RabbitmqConnected.Set(0)
+}
+
+// Ping verifies the broker connection is open.
+func (c *Client) Ping(ctx context.Context) error {
+	if c.Connection.IsClosed() {
+		return ErrRabbitmqNotConnected
+	}
+
+	return nil
+}
+
 // DeclareExchange creates Rabbitmq exchange.
 func (c *Client) DeclareExchange() error {
 	err := c.Channel.ExchangeDeclare(
@@ -158,26 +227,130 @@ func (c *Client) Consume(queue amqp.Queue) (<-chan amqp.Delivery, error) {
 	return messages, nil
 }
 
-// SendEventNotification marshals Event into a Notification json and sends to broker.
-func (c *Client) SendEventNotification(event storage.Event) error {
-	notification := &Notification{
-		ID:      event.ID,
-		Title:   event.Title,
-		Date:    event.BeginDate,
-		OwnerID: event.OwnerID,
+// SendEventNotification marshals Event into a Notification json of the notification record and sends to broker.
+// The sender replies to the receipts queue, the trace context of ctx is passed along in the message headers.
+func (c *Client) SendEventNotification(
+	ctx context.Context,
+	event storage.Event,
+	notification storage.Notification,
+) error {
+	ctx, span := tracing.Start(ctx, "rabbitmq.SendEventNotification", trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int64("calendar.event_id", event.ID),
+		attribute.Int64("calendar.reminder_id", notification.ReminderID),
+		attribute.Int64("calendar.notification_id", notification.ID),
+	)
+
+	jsonBody, _ := json.Marshal(&Notification{
+		ID:             event.ID,
+		Title:          event.Title,
+		Date:           event.BeginDate,
+		OwnerID:        event.OwnerID,
+		ReminderID:     notification.ReminderID,
+		NotificationID: notification.ID,
+	})
+
+	err := c.publish(ctx, c.Config.GetExchangeName(), c.Config.GetPublishRoutingKey(), amqp.Publishing{
+		Type:          TypeNotification,
+		ReplyTo:       c.Config.GetReceiptsQueue(),
+		CorrelationId: strconv.FormatInt(notification.ID, 10),
+		Body:          jsonBody,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
 	}
 
-	jsonBody, _ := json.Marshal(&notification)
+	return nil
+}
+
+// SendDigestNotification marshals the digest into json and sends it to broker.
+// The trace context of ctx is passed along in the message headers.
+func (c *Client) SendDigestNotification(ctx context.Context, digest DigestNotification) error {
+	ctx, span := tracing.Start(ctx, "rabbitmq.SendDigestNotification", trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("calendar.owner_id", digest.OwnerID))
+
+	jsonBody, _ := json.Marshal(&digest)
+
+	err := c.publish(ctx, c.Config.GetExchangeName(), c.Config.GetPublishRoutingKey(), amqp.Publishing{
+		Type: TypeDigest,
+		Body: jsonBody,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// SendReceipt replies to the queue the notification named with its delivery receipt.
+// The trace context of ctx is passed along in the message headers.
+func (c *Client) SendReceipt(ctx context.Context, replyTo string, receipt Receipt) error {
+	ctx, span := tracing.Start(ctx, "rabbitmq.SendReceipt", trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("calendar.notification_id", receipt.NotificationID))
+
+	jsonBody, _ := json.Marshal(&receipt)
+
+	// Replies go through the default exchange, which routes by the queue name.
+	err := c.publish(ctx, "", replyTo, amqp.Publishing{
+		Type:          TypeReceipt,
+		CorrelationId: strconv.FormatInt(receipt.NotificationID, 10),
+		Body:          jsonBody,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeclareReceiptsQueue creates the durable queue of delivery receipts, so that receipts outlive scheduler restarts.
+func (c *Client) DeclareReceiptsQueue() (amqp.Queue, error) {
+	queue, err := c.Channel.QueueDeclare(c.Config.GetReceiptsQueue(), true, false, false, false, nil)
+	if err != nil {
+		return queue, fmt.Errorf("%w: %s", ErrRabbitmqQueueDeclare, err.Error())
+	}
+
+	return queue, nil
+}
+
+// ConsumeReceipts returns channel for receiving delivery receipts, which must be acknowledged.
+func (c *Client) ConsumeReceipts(queue amqp.Queue) (<-chan amqp.Delivery, error) {
+	messages, err := c.Channel.Consume(queue.Name, "", false, false, false, false, nil)
+	if err != nil {
+		return messages, fmt.Errorf("%w: %s", ErrRabbitmqConsume, err.Error())
+	}
+
+	return messages, nil
+}
+
+// publish sends a json message to the exchange with the routing key.
+func (c *Client) publish(ctx context.Context, exchange, routingKey string, message amqp.Publishing) error {
+	headers := amqp.Table{}
+	tracing.Inject(ctx, HeadersCarrier(headers))
+
+	message.Headers = headers
+	message.ContentType = "application/json"
+	message.Timestamp = time.Now()
 
 	err := c.Channel.Publish(
-		c.Config.GetExchangeName(),
-		c.Config.GetPublishRoutingKey(),
+		exchange,
+		routingKey,
 		c.Config.GetPublishMandatory(),
 		c.Config.GetPublishImmediate(),
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        jsonBody,
-		})
+		message,
+	)
 	if err != nil {
 		return fmt.Errorf("%w: %s", ErrRabbitmqPublish, err.Error())
 	}
@@ -0,0 +1,29 @@
+package rabbitmq
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// HeadersCarrier adapts AMQP message headers for trace context propagation.
+type HeadersCarrier amqp.Table
+
+// Get returns the header value for the key.
+func (c HeadersCarrier) Get(key string) string {
+	value, _ := c[key].(string)
+	return value
+}
+
+// Set stores the header value for the key.
+func (c HeadersCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+// Keys lists the header keys.
+func (c HeadersCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
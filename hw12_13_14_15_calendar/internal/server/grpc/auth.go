@@ -0,0 +1,97 @@
+package internalgrpc
+
+import (
+	"context"
+	"strings"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// publicMethodPrefixes are served without authentication.
+var publicMethodPrefixes = []string{
+	"/grpc.health.v1.Health/",
+	"/grpc.reflection.",
+}
+
+// isPublicMethod tells whether the method is served to anyone, without authentication and limits.
+func isPublicMethod(method string) bool {
+	for _, prefix := range publicMethodPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Authenticator resolves bearer tokens into owner IDs.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (int64, error)
+}
+
+// authenticate resolves the bearer token of the call metadata, acting on behalf of its owner.
+// Calls to a server without an authenticator are internal.
+func authenticate(ctx context.Context, authenticator Authenticator, method string) (context.Context, error) {
+	if authenticator == nil {
+		return app.ContextInternal(ctx), nil
+	}
+
+	if isPublicMethod(method) {
+		return ctx, nil
+	}
+
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = auth.BearerToken(values[0])
+		}
+	}
+
+	ownerID, err := authenticator.Authenticate(ctx, token)
+	if err != nil {
+		return ctx, toStatus(err)
+	}
+
+	return app.ContextWithOwner(ctx, ownerID), nil
+}
+
+// authUnaryInterceptor authenticates unary calls.
+func authUnaryInterceptor(authenticator Authenticator) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		request interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, err := authenticate(ctx, authenticator, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, request)
+	}
+}
+
+// authStreamInterceptor authenticates streaming calls.
+func authStreamInterceptor(authenticator Authenticator) grpc.StreamServerInterceptor {
+	return func(
+		server interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, err := authenticate(stream.Context(), authenticator, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+
+		return handler(server, wrapped)
+	}
+}
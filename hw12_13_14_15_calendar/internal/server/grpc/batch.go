@@ -0,0 +1,127 @@
+package internalgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	pb "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc/eventpb"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrEmptyOperation = errors.New("operation is required")
+
+// BatchEvents handles applying a batch of event operations via grpc.
+func (s *Service) BatchEvents(ctx context.Context, request *pb.BatchEventsRequest) (*pb.BatchEventsResponse, error) {
+	operations := make([]app.BatchOperation, len(request.Operations))
+	for i, pbOperation := range request.Operations {
+		operation, err := operationFromPb(pbOperation)
+		if err != nil {
+			return &pb.BatchEventsResponse{}, toStatus(fmt.Errorf("operation %d: %w", i, err))
+		}
+		operations[i] = operation
+	}
+
+	return s.applyBatch(ctx, operations, request.Mode)
+}
+
+// ImportEvents handles creating the streamed events as a single batch via grpc.
+func (s *Service) ImportEvents(stream pb.Calendar_ImportEventsServer) error {
+	var (
+		operations []app.BatchOperation
+		mode       pb.BatchMode
+	)
+
+	for {
+		request, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(operations) == 0 {
+			mode = request.Mode
+		}
+
+		if len(operations) == app.MaxBatchSize {
+			return toStatus(fmt.Errorf("%w: %d events at most", app.ErrBatchTooLarge, app.MaxBatchSize))
+		}
+
+		event, err := EventFromPb(request.Event)
+		if err != nil {
+			return toStatus(fmt.Errorf("event %d: %w", len(operations), err))
+		}
+
+		operations = append(operations, app.BatchOperation{Type: app.OperationCreate, Event: event})
+	}
+
+	response, err := s.applyBatch(stream.Context(), operations, mode)
+	if err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(response)
+}
+
+// applyBatch applies the operations and reports a result per operation,
+// a rolled back batch is a successful response with committed unset.
+func (s *Service) applyBatch(ctx context.Context, operations []app.BatchOperation, mode pb.BatchMode) (*pb.BatchEventsResponse, error) {
+	results, err := s.app.ApplyBatch(ctx, operations, mode != pb.BatchMode_BEST_EFFORT)
+	if err != nil && !errors.Is(err, app.ErrBatchAborted) {
+		return &pb.BatchEventsResponse{}, toStatus(err)
+	}
+
+	response := &pb.BatchEventsResponse{
+		Results:   make([]*pb.BatchResult, len(results)),
+		Committed: err == nil,
+	}
+
+	for i, result := range results {
+		response.Results[i] = batchResultToPb(i, operations[i].Type, result)
+	}
+
+	return response, nil
+}
+
+// operationFromPb converts a grpc batch operation into an application one.
+func operationFromPb(pbOperation *pb.BatchOperation) (app.BatchOperation, error) {
+	switch operation := pbOperation.GetOperation().(type) {
+	case *pb.BatchOperation_Create:
+		event, err := EventFromPb(operation.Create)
+		return app.BatchOperation{Type: app.OperationCreate, Event: event}, err
+	case *pb.BatchOperation_Update:
+		event, err := EventFromPb(operation.Update)
+		return app.BatchOperation{Type: app.OperationUpdate, Event: event}, err
+	case *pb.BatchOperation_Remove:
+		return app.BatchOperation{Type: app.OperationRemove, Event: storage.Event{ID: operation.Remove}}, nil
+	default:
+		return app.BatchOperation{}, ErrEmptyOperation
+	}
+}
+
+// batchResultToPb converts a batch result, removed events are not returned.
+func batchResultToPb(index int, operationType app.OperationType, result app.BatchResult) *pb.BatchResult {
+	pbResult := &pb.BatchResult{Index: int32(index)}
+
+	if result.Err != nil {
+		st := status.Convert(toStatus(result.Err))
+		pbResult.Code = int32(st.Code())
+		pbResult.Message = st.Message()
+
+		return pbResult
+	}
+
+	pbResult.Ok = true
+	pbResult.Code = int32(codes.OK)
+	if operationType != app.OperationRemove {
+		pbResult.Event = EventToPb(result.Event)
+	}
+
+	return pbResult
+}
@@ -0,0 +1,164 @@
+package internalgrpc
+
+import (
+	"context"
+	"errors"
+
+	pb "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc/eventpb"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+var (
+	ErrEmptyCalendar = errors.New("calendar is required")
+	ErrEmptyGrant    = errors.New("grant is required")
+)
+
+var calendarAccesses = map[pb.CalendarAccess]storage.Access{
+	pb.CalendarAccess_CALENDAR_ACCESS_FREE_BUSY: storage.AccessFreeBusy,
+	pb.CalendarAccess_CALENDAR_ACCESS_READ:      storage.AccessRead,
+	pb.CalendarAccess_CALENDAR_ACCESS_WRITE:     storage.AccessWrite,
+	pb.CalendarAccess_CALENDAR_ACCESS_OWNER:     storage.AccessOwner,
+}
+
+var calendarAccessesToPb = map[storage.Access]pb.CalendarAccess{
+	storage.AccessFreeBusy: pb.CalendarAccess_CALENDAR_ACCESS_FREE_BUSY,
+	storage.AccessRead:     pb.CalendarAccess_CALENDAR_ACCESS_READ,
+	storage.AccessWrite:    pb.CalendarAccess_CALENDAR_ACCESS_WRITE,
+	storage.AccessOwner:    pb.CalendarAccess_CALENDAR_ACCESS_OWNER,
+}
+
+// CreateCalendar handles creating a new calendar via grpc.
+func (s *Service) CreateCalendar(ctx context.Context, request *pb.CreateCalendarRequest) (*pb.CreateCalendarResponse, error) {
+	if request.Calendar == nil {
+		return &pb.CreateCalendarResponse{}, toStatus(ErrEmptyCalendar)
+	}
+
+	calendar := calendarFromPb(request.Calendar)
+	calendar.ID = 0
+
+	calendar, err := s.app.CreateCalendar(ctx, calendar)
+	if err != nil {
+		return &pb.CreateCalendarResponse{}, toStatus(err)
+	}
+
+	return &pb.CreateCalendarResponse{Calendar: calendarToPb(calendar)}, nil
+}
+
+// UpdateCalendar handles renaming the calendar via grpc.
+func (s *Service) UpdateCalendar(ctx context.Context, request *pb.UpdateCalendarRequest) (*pb.UpdateCalendarResponse, error) {
+	if request.Calendar == nil {
+		return &pb.UpdateCalendarResponse{}, toStatus(ErrEmptyCalendar)
+	}
+
+	calendar, err := s.app.UpdateCalendar(ctx, calendarFromPb(request.Calendar))
+	if err != nil {
+		return &pb.UpdateCalendarResponse{}, toStatus(err)
+	}
+
+	return &pb.UpdateCalendarResponse{Calendar: calendarToPb(calendar)}, nil
+}
+
+// RemoveCalendar handles removing the calendar via grpc.
+func (s *Service) RemoveCalendar(ctx context.Context, request *pb.RemoveCalendarRequest) (*pb.RemoveCalendarResponse, error) {
+	if err := s.app.RemoveCalendar(ctx, request.Id); err != nil {
+		return &pb.RemoveCalendarResponse{}, toStatus(err)
+	}
+
+	return &pb.RemoveCalendarResponse{}, nil
+}
+
+// GetCalendar handles getting a single calendar via grpc.
+func (s *Service) GetCalendar(ctx context.Context, request *pb.GetCalendarRequest) (*pb.GetCalendarResponse, error) {
+	calendar, err := s.app.GetCalendar(ctx, request.Id)
+	if err != nil {
+		return &pb.GetCalendarResponse{}, toStatus(err)
+	}
+
+	return &pb.GetCalendarResponse{Calendar: calendarToPb(calendar)}, nil
+}
+
+// ListCalendars handles getting the own and the shared calendars of an owner via grpc.
+func (s *Service) ListCalendars(ctx context.Context, request *pb.ListCalendarsRequest) (*pb.ListCalendarsResponse, error) {
+	calendars, err := s.app.ListCalendars(ctx, request.OwnerId)
+	if err != nil {
+		return &pb.ListCalendarsResponse{}, toStatus(err)
+	}
+
+	response := &pb.ListCalendarsResponse{Items: make([]*pb.CalendarInfo, len(calendars))}
+	for i, calendar := range calendars {
+		response.Items[i] = calendarToPb(calendar)
+	}
+
+	return response, nil
+}
+
+// ShareCalendar handles granting the access to a calendar via grpc.
+func (s *Service) ShareCalendar(ctx context.Context, request *pb.ShareCalendarRequest) (*pb.ShareCalendarResponse, error) {
+	if request.Grant == nil {
+		return &pb.ShareCalendarResponse{}, toStatus(ErrEmptyGrant)
+	}
+
+	grant, err := s.app.ShareCalendar(ctx, storage.Grant{
+		CalendarID: request.Grant.CalendarId,
+		GranteeID:  request.Grant.GranteeId,
+		Access:     calendarAccesses[request.Grant.Access],
+	})
+	if err != nil {
+		return &pb.ShareCalendarResponse{}, toStatus(err)
+	}
+
+	return &pb.ShareCalendarResponse{Grant: grantToPb(grant)}, nil
+}
+
+// UnshareCalendar handles revoking the access to a calendar via grpc.
+func (s *Service) UnshareCalendar(ctx context.Context, request *pb.UnshareCalendarRequest) (*pb.UnshareCalendarResponse, error) {
+	if err := s.app.UnshareCalendar(ctx, request.CalendarId, request.GranteeId); err != nil {
+		return &pb.UnshareCalendarResponse{}, toStatus(err)
+	}
+
+	return &pb.UnshareCalendarResponse{}, nil
+}
+
+// ListGrants handles getting the grants of a calendar via grpc.
+func (s *Service) ListGrants(ctx context.Context, request *pb.ListGrantsRequest) (*pb.ListGrantsResponse, error) {
+	grants, err := s.app.ListGrants(ctx, request.CalendarId)
+	if err != nil {
+		return &pb.ListGrantsResponse{}, toStatus(err)
+	}
+
+	response := &pb.ListGrantsResponse{Items: make([]*pb.Grant, len(grants))}
+	for i, grant := range grants {
+		response.Items[i] = grantToPb(grant)
+	}
+
+	return response, nil
+}
+
+// calendarFromPb converts a grpc calendar into a storage one, the access being left out.
+func calendarFromPb(calendar *pb.CalendarInfo) storage.Calendar {
+	return storage.Calendar{
+		ID:      calendar.Id,
+		OwnerID: calendar.OwnerId,
+		Name:    calendar.Name,
+		Default: calendar.Default,
+	}
+}
+
+func calendarToPb(calendar storage.Calendar) *pb.CalendarInfo {
+	return &pb.CalendarInfo{
+		Id:      calendar.ID,
+		OwnerId: calendar.OwnerID,
+		Name:    calendar.Name,
+		Default: calendar.Default,
+		Access:  calendarAccessesToPb[calendar.Access],
+	}
+}
+
+func grantToPb(grant storage.Grant) *pb.Grant {
+	return &pb.Grant{
+		CalendarId: grant.CalendarID,
+		OwnerId:    grant.OwnerID,
+		GranteeId:  grant.GranteeID,
+		Access:     calendarAccessesToPb[grant.Access],
+	}
+}
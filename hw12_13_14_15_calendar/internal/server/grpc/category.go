@@ -0,0 +1,112 @@
+package internalgrpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	pb "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc/eventpb"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+var ErrEmptyCategory = errors.New("category is required")
+
+// CreateCategory handles creating a new category via grpc.
+func (s *Service) CreateCategory(ctx context.Context, request *pb.CreateCategoryRequest) (*pb.CreateCategoryResponse, error) {
+	category, err := categoryFromPb(request.Category)
+	if err != nil {
+		return &pb.CreateCategoryResponse{}, toStatus(err)
+	}
+
+	category.ID = 0
+	category, err = s.app.CreateCategory(ctx, category)
+	if err != nil {
+		return &pb.CreateCategoryResponse{}, toStatus(err)
+	}
+
+	return &pb.CreateCategoryResponse{Category: categoryToPb(category)}, nil
+}
+
+// UpdateCategory handles replacing the category via grpc.
+func (s *Service) UpdateCategory(ctx context.Context, request *pb.UpdateCategoryRequest) (*pb.UpdateCategoryResponse, error) {
+	category, err := categoryFromPb(request.Category)
+	if err != nil {
+		return &pb.UpdateCategoryResponse{}, toStatus(err)
+	}
+
+	category, err = s.app.UpdateCategory(ctx, category)
+	if err != nil {
+		return &pb.UpdateCategoryResponse{}, toStatus(err)
+	}
+
+	return &pb.UpdateCategoryResponse{Category: categoryToPb(category)}, nil
+}
+
+// RemoveCategory handles removing the category via grpc.
+func (s *Service) RemoveCategory(ctx context.Context, request *pb.RemoveCategoryRequest) (*pb.RemoveCategoryResponse, error) {
+	if err := s.app.RemoveCategory(ctx, request.Id); err != nil {
+		return &pb.RemoveCategoryResponse{}, toStatus(err)
+	}
+
+	return &pb.RemoveCategoryResponse{}, nil
+}
+
+// GetCategory handles getting a single category via grpc.
+func (s *Service) GetCategory(ctx context.Context, request *pb.GetCategoryRequest) (*pb.GetCategoryResponse, error) {
+	category, err := s.app.GetCategory(ctx, request.Id)
+	if err != nil {
+		return &pb.GetCategoryResponse{}, toStatus(err)
+	}
+
+	return &pb.GetCategoryResponse{Category: categoryToPb(category)}, nil
+}
+
+// ListCategories handles getting the categories of an owner via grpc.
+func (s *Service) ListCategories(ctx context.Context, request *pb.ListCategoriesRequest) (*pb.ListCategoriesResponse, error) {
+	categories, err := s.app.ListCategories(ctx, request.OwnerId)
+	if err != nil {
+		return &pb.ListCategoriesResponse{}, toStatus(err)
+	}
+
+	response := &pb.ListCategoriesResponse{Items: make([]*pb.Category, len(categories))}
+	for i, category := range categories {
+		response.Items[i] = categoryToPb(category)
+	}
+
+	return response, nil
+}
+
+// categoryFromPb converts a grpc category into a storage one.
+func categoryFromPb(category *pb.Category) (storage.Category, error) {
+	if category == nil {
+		return storage.Category{}, ErrEmptyCategory
+	}
+
+	reminders, err := durationsFromPb("reminders", category.Reminders)
+	if err != nil {
+		return storage.Category{}, err
+	}
+
+	return storage.Category{
+		ID:        category.Id,
+		OwnerID:   category.OwnerId,
+		Name:      category.Name,
+		Color:     category.Color,
+		Reminders: reminders,
+	}, nil
+}
+
+func categoryToPb(category storage.Category) *pb.Category {
+	return &pb.Category{
+		Id:        category.ID,
+		OwnerId:   category.OwnerID,
+		Name:      category.Name,
+		Color:     category.Color,
+		Reminders: durationsToPb(category.Reminders),
+	}
+}
+
+// eventFilter builds the application filter of the list requests.
+func eventFilter(calendarIDs []int64, categoryID int64, tags []string) app.EventFilter {
+	return app.EventFilter{CalendarIDs: calendarIDs, CategoryID: categoryID, Tags: tags}
+}
@@ -0,0 +1,288 @@
+package internalgrpc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/auth"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/limits"
+	pb "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc/eventpb"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	ErrEmptyEvent       = errors.New("event is required")
+	ErrInvalidTimestamp = errors.New("invalid timestamp")
+	ErrInvalidPageToken = errors.New("invalid page token")
+	ErrInvalidFieldMask = errors.New("invalid update mask")
+	ErrInvalidDuration  = errors.New("invalid duration")
+)
+
+// EventFromPb converts a grpc event into a storage one.
+func EventFromPb(pbEvent *pb.Event) (storage.Event, error) {
+	if pbEvent == nil {
+		return storage.Event{}, ErrEmptyEvent
+	}
+
+	event := storage.Event{
+		ID:          pbEvent.Id,
+		Title:       pbEvent.Title,
+		Description: pbEvent.Description,
+		OwnerID:     pbEvent.OwnerId,
+		CalendarID:  pbEvent.CalendarId,
+		CategoryID:  pbEvent.CategoryId,
+		// The storage keeps the notification flags it has, they are carried for the clients reading events.
+		NotificationSent:     pbEvent.NotificationSent,
+		NotificationReceived: pbEvent.NotificationReceived,
+	}
+
+	// No tags stand for none on creation and the current ones on update.
+	if len(pbEvent.Tags) > 0 {
+		event.Tags = pbEvent.Tags
+	}
+
+	var err error
+
+	if event.BeginDate, err = timeFromPb("begin_date", pbEvent.BeginDate); err != nil {
+		return event, err
+	}
+
+	if event.EndDate, err = timeFromPb("end_date", pbEvent.EndDate); err != nil {
+		return event, err
+	}
+
+	// No reminders stand for the defaults on creation and the current ones on update.
+	if len(pbEvent.Reminders) > 0 {
+		if event.Reminders, err = durationsFromPb("reminders", pbEvent.Reminders); err != nil {
+			return event, err
+		}
+	}
+
+	return event, nil
+}
+
+// mergeEventFromPb overwrites the fields of the event listed in the update mask.
+func mergeEventFromPb(event storage.Event, pbEvent *pb.Event, paths []string) (storage.Event, error) {
+	if pbEvent == nil {
+		return event, ErrEmptyEvent
+	}
+
+	var err error
+
+	for _, path := range paths {
+		switch path {
+		case "id":
+			// The identifier is taken from the route and can't be changed.
+		case "title":
+			event.Title = pbEvent.Title
+		case "begin_date":
+			if event.BeginDate, err = timeFromPb(path, pbEvent.BeginDate); err != nil {
+				return event, err
+			}
+		case "end_date":
+			if event.EndDate, err = timeFromPb(path, pbEvent.EndDate); err != nil {
+				return event, err
+			}
+		case "description":
+			event.Description = pbEvent.Description
+		case "owner_id":
+			event.OwnerID = pbEvent.OwnerId
+		case "notification_sent", "notification_received":
+			// The notification flags follow the notification records and can't be changed.
+		case "calendar_id":
+			event.CalendarID = pbEvent.CalendarId
+		case "category_id":
+			event.CategoryID = pbEvent.CategoryId
+		case "tags":
+			// Listed tags replace the current ones, none at all removing them.
+			event.Tags = append([]string{}, pbEvent.Tags...)
+		case "reminders":
+			// Listed reminders replace the current ones, none at all removing them.
+			if event.Reminders, err = durationsFromPb(path, pbEvent.Reminders); err != nil {
+				return event, err
+			}
+		default:
+			return event, fmt.Errorf("%w: unknown field %q", ErrInvalidFieldMask, path)
+		}
+	}
+
+	return event, nil
+}
+
+// EventToPb converts a storage event into a grpc one.
+func EventToPb(event storage.Event) *pb.Event {
+	return &pb.Event{
+		Id:                   event.ID,
+		Title:                event.Title,
+		BeginDate:            timestamppb.New(event.BeginDate),
+		EndDate:              timestamppb.New(event.EndDate),
+		Description:          event.Description,
+		OwnerId:              event.OwnerID,
+		NotificationSent:     event.NotificationSent,
+		NotificationReceived: event.NotificationReceived,
+		Reminders:            durationsToPb(event.Reminders),
+		CategoryId:           event.CategoryID,
+		Tags:                 event.Tags,
+		CalendarId:           event.CalendarID,
+	}
+}
+
+func eventsToPb(events []storage.Event) []*pb.Event {
+	pbEvents := make([]*pb.Event, len(events))
+	for i, event := range events {
+		pbEvents[i] = EventToPb(event)
+	}
+
+	return pbEvents
+}
+
+var changeTypesToPb = map[storage.ChangeType]pb.ChangeType{
+	storage.EventCreated: pb.ChangeType_CREATED,
+	storage.EventUpdated: pb.ChangeType_UPDATED,
+	storage.EventDeleted: pb.ChangeType_DELETED,
+}
+
+var changeTypesFromPb = map[pb.ChangeType]storage.ChangeType{
+	pb.ChangeType_CREATED: storage.EventCreated,
+	pb.ChangeType_UPDATED: storage.EventUpdated,
+	pb.ChangeType_DELETED: storage.EventDeleted,
+}
+
+func changeToPb(change storage.Change) *pb.EventChange {
+	return &pb.EventChange{
+		Revision: change.Revision,
+		Type:     changeTypesToPb[change.Type],
+		Event:    EventToPb(change.Event),
+	}
+}
+
+// ChangeFromPb converts a grpc change into a storage one.
+func ChangeFromPb(pbChange *pb.EventChange) (storage.Change, error) {
+	event, err := EventFromPb(pbChange.Event)
+	if err != nil {
+		return storage.Change{}, err
+	}
+
+	return storage.Change{
+		Revision: pbChange.Revision,
+		Type:     changeTypesFromPb[pbChange.Type],
+		Event:    event,
+	}, nil
+}
+
+func intervalsToPb(intervals []app.Interval) []*pb.Interval {
+	pbIntervals := make([]*pb.Interval, len(intervals))
+	for i, interval := range intervals {
+		pbIntervals[i] = &pb.Interval{
+			BeginDate: timestamppb.New(interval.Begin),
+			EndDate:   timestamppb.New(interval.End),
+		}
+	}
+
+	return pbIntervals
+}
+
+// durationsFromPb validates a list of durations, the result is never nil.
+func durationsFromPb(field string, pbDurations []*durationpb.Duration) ([]time.Duration, error) {
+	durations := make([]time.Duration, 0, len(pbDurations))
+	for _, pbDuration := range pbDurations {
+		if err := pbDuration.CheckValid(); err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrInvalidDuration, field, err.Error())
+		}
+
+		durations = append(durations, pbDuration.AsDuration())
+	}
+
+	return durations, nil
+}
+
+func durationsToPb(durations []time.Duration) []*durationpb.Duration {
+	if len(durations) == 0 {
+		return nil
+	}
+
+	pbDurations := make([]*durationpb.Duration, len(durations))
+	for i, duration := range durations {
+		pbDurations[i] = durationpb.New(duration)
+	}
+
+	return pbDurations
+}
+
+// timeFromPb validates a required timestamp field.
+func timeFromPb(field string, ts *timestamppb.Timestamp) (time.Time, error) {
+	if ts == nil {
+		return time.Time{}, fmt.Errorf("%w: %s is required", ErrInvalidTimestamp, field)
+	}
+
+	if err := ts.CheckValid(); err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s: %s", ErrInvalidTimestamp, field, err.Error())
+	}
+
+	return ts.AsTime(), nil
+}
+
+// toStatus maps application errors onto grpc status codes.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, ErrEmptyEvent),
+		errors.Is(err, ErrInvalidTimestamp),
+		errors.Is(err, ErrInvalidPageToken),
+		errors.Is(err, ErrInvalidFieldMask),
+		errors.Is(err, app.ErrInvalidEvent),
+		errors.Is(err, app.ErrInvalidOwner),
+		errors.Is(err, app.ErrEmptyOwners),
+		errors.Is(err, app.ErrInvalidPeriod),
+		errors.Is(err, app.ErrInvalidRange),
+		errors.Is(err, app.ErrInvalidWorkingHours),
+		errors.Is(err, app.ErrInvalidSlotDuration),
+		errors.Is(err, app.ErrInvalidSlotsLimit),
+		errors.Is(err, ErrEmptyOperation),
+		errors.Is(err, app.ErrEmptyBatch),
+		errors.Is(err, app.ErrBatchTooLarge),
+		errors.Is(err, app.ErrInvalidOperation),
+		errors.Is(err, ErrEmptyDigest),
+		errors.Is(err, app.ErrInvalidDigest),
+		errors.Is(err, ErrInvalidDuration),
+		errors.Is(err, app.ErrInvalidReminder),
+		errors.Is(err, app.ErrInvalidNotificationFilter),
+		errors.Is(err, ErrEmptyCategory),
+		errors.Is(err, app.ErrInvalidCategory),
+		errors.Is(err, app.ErrInvalidFilter),
+		errors.Is(err, ErrEmptyCalendar),
+		errors.Is(err, ErrEmptyGrant),
+		errors.Is(err, app.ErrInvalidCalendar),
+		errors.Is(err, app.ErrInvalidGrant):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, auth.ErrUnauthenticated), errors.Is(err, auth.ErrInvalidToken):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, app.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, app.ErrEventNotFound),
+		errors.Is(err, app.ErrDigestNotFound),
+		errors.Is(err, app.ErrReminderNotFound),
+		errors.Is(err, app.ErrNotificationNotFound),
+		errors.Is(err, app.ErrCategoryNotFound),
+		errors.Is(err, app.ErrCalendarNotFound),
+		errors.Is(err, app.ErrGrantNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, app.ErrReminderState), errors.Is(err, app.ErrCalendarNotEmpty):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, app.ErrCategoryExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, limits.ErrRateLimited), errors.Is(err, limits.ErrBodyTooLarge):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, app.ErrBatchAborted):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, app.ErrRevisionExpired):
+		return status.Error(codes.OutOfRange, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,82 @@
+package internalgrpc
+
+import (
+	"context"
+	"errors"
+
+	pb "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc/eventpb"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var ErrEmptyDigest = errors.New("digest is required")
+
+var digestPeriods = map[pb.DigestPeriod]string{
+	pb.DigestPeriod_DAY:  storage.DigestDaily,
+	pb.DigestPeriod_WEEK: storage.DigestWeekly,
+}
+
+// GetDigest handles getting the digest subscription of an owner via grpc.
+func (s *Service) GetDigest(ctx context.Context, request *pb.GetDigestRequest) (*pb.GetDigestResponse, error) {
+	subscription, err := s.app.GetDigestSubscription(ctx, request.OwnerId)
+	if err != nil {
+		return &pb.GetDigestResponse{}, toStatus(err)
+	}
+
+	return &pb.GetDigestResponse{Digest: digestToPb(subscription)}, nil
+}
+
+// SetDigest handles subscribing an owner to a digest via grpc.
+func (s *Service) SetDigest(ctx context.Context, request *pb.SetDigestRequest) (*pb.SetDigestResponse, error) {
+	if request.Digest == nil {
+		return &pb.SetDigestResponse{}, toStatus(ErrEmptyDigest)
+	}
+
+	subscription, err := s.app.SetDigestSubscription(ctx, digestFromPb(request.Digest))
+	if err != nil {
+		return &pb.SetDigestResponse{}, toStatus(err)
+	}
+
+	return &pb.SetDigestResponse{Digest: digestToPb(subscription)}, nil
+}
+
+// RemoveDigest handles unsubscribing an owner from the digest via grpc.
+func (s *Service) RemoveDigest(ctx context.Context, request *pb.RemoveDigestRequest) (*pb.RemoveDigestResponse, error) {
+	if err := s.app.RemoveDigestSubscription(ctx, request.OwnerId); err != nil {
+		return &pb.RemoveDigestResponse{}, toStatus(err)
+	}
+
+	return &pb.RemoveDigestResponse{}, nil
+}
+
+// digestFromPb converts a grpc digest into a storage subscription, an unknown period is left empty to fail validation.
+func digestFromPb(digest *pb.Digest) storage.DigestSubscription {
+	return storage.DigestSubscription{
+		OwnerID:  digest.OwnerId,
+		Period:   digestPeriods[digest.Period],
+		Time:     digest.Time,
+		TimeZone: digest.TimeZone,
+		Weekday:  int(digest.Weekday),
+	}
+}
+
+func digestToPb(subscription storage.DigestSubscription) *pb.Digest {
+	digest := &pb.Digest{
+		OwnerId:  subscription.OwnerID,
+		Time:     subscription.Time,
+		TimeZone: subscription.TimeZone,
+		Weekday:  int32(subscription.Weekday),
+	}
+
+	for period, name := range digestPeriods {
+		if name == subscription.Period {
+			digest.Period = period
+		}
+	}
+
+	if subscription.LastSentAt != nil {
+		digest.LastSentAt = timestamppb.New(*subscription.LastSentAt)
+	}
+
+	return digest
+}
@@ -0,0 +1,170 @@
+package internalgrpc
+
+import (
+	"context"
+	_ "embed" // The OpenAPI document is embedded into the binary.
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	pb "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc/eventpb"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// OpenAPIPath is the route the generated OpenAPI document is served on.
+const OpenAPIPath = "/openapi.json"
+
+var (
+	createEventResponseName = (&pb.CreateEventResponse{}).ProtoReflect().Descriptor().FullName()
+	removeEventResponseName = (&pb.RemoveEventResponse{}).ProtoReflect().Descriptor().FullName()
+)
+
+var ErrGatewayRegister = errors.New("unable to register http gateway")
+
+//go:embed eventpb/api/EventService.swagger.json
+var generatedOpenAPI []byte
+
+// OpenAPI is the document generated from the EventService.proto http annotations,
+// with 64-bit integer properties typed as the numbers the gateway writes.
+var OpenAPI = numericOpenAPI(generatedOpenAPI)
+
+type gatewayError struct {
+	Error gatewayErrorBody `json:"error"`
+}
+
+type gatewayErrorBody struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewGatewayHandler returns the REST API generated from the proto annotations.
+// Requests are served by the given service in-process, without a grpc connection.
+func NewGatewayHandler(ctx context.Context, service pb.CalendarServer) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &numericJSONPb{runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				UseProtoNames:   true,
+				EmitUnpopulated: true,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: true,
+			},
+		}}),
+		runtime.WithErrorHandler(gatewayErrorHandler),
+		runtime.WithRoutingErrorHandler(gatewayRoutingErrorHandler),
+		runtime.WithForwardResponseOption(gatewayResponseStatus),
+	)
+
+	if err := pb.RegisterCalendarHandlerServer(ctx, mux, service); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrGatewayRegister, err.Error())
+	}
+
+	if err := mux.HandlePath(http.MethodGet, OpenAPIPath, serveOpenAPI); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrGatewayRegister, err.Error())
+	}
+
+	return mux, nil
+}
+
+// serveOpenAPI sends the embedded OpenAPI document.
+func serveOpenAPI(writer http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = writer.Write(OpenAPI)
+}
+
+// numericOpenAPI retypes the int64 properties of the definitions, which the generator describes as strings.
+// Parameters keep the string type, path and query values being strings anyway.
+func numericOpenAPI(document []byte) []byte {
+	var openAPI map[string]interface{}
+	if err := json.Unmarshal(document, &openAPI); err != nil {
+		return document
+	}
+
+	retype := func(schema interface{}) {
+		if schema, ok := schema.(map[string]interface{}); ok {
+			if format := schema["format"]; format == "int64" || format == "uint64" {
+				schema["type"] = "integer"
+			}
+		}
+	}
+
+	definitions, _ := openAPI["definitions"].(map[string]interface{})
+	for _, definition := range definitions {
+		definition, _ := definition.(map[string]interface{})
+		properties, _ := definition["properties"].(map[string]interface{})
+		for _, property := range properties {
+			retype(property)
+			if property, ok := property.(map[string]interface{}); ok {
+				retype(property["items"])
+			}
+		}
+	}
+
+	b, err := json.MarshalIndent(openAPI, "", "  ")
+	if err != nil {
+		return document
+	}
+
+	return b
+}
+
+// gatewayResponseStatus sets resource-oriented status codes for the create and remove calls.
+func gatewayResponseStatus(_ context.Context, writer http.ResponseWriter, message proto.Message) error {
+	switch message.ProtoReflect().Descriptor().FullName() {
+	case createEventResponseName:
+		// Calls with a response_body annotation are wrapped by the generated code.
+		if response, ok := message.(interface{ XXX_ResponseBody() interface{} }); ok {
+			if event, ok := response.XXX_ResponseBody().(*pb.Event); ok {
+				writer.Header().Set("Location", fmt.Sprintf("/events/%d", event.GetId()))
+			}
+		}
+		writer.WriteHeader(http.StatusCreated)
+	case removeEventResponseName:
+		writer.WriteHeader(http.StatusNoContent)
+	}
+
+	return nil
+}
+
+// gatewayErrorHandler wraps grpc statuses into the JSON error envelope.
+func gatewayErrorHandler(
+	_ context.Context,
+	_ *runtime.ServeMux,
+	_ runtime.Marshaler,
+	writer http.ResponseWriter,
+	_ *http.Request,
+	err error,
+) {
+	st := status.Convert(err)
+	writeGatewayError(writer, runtime.HTTPStatusFromCode(st.Code()), st.Message())
+}
+
+// gatewayRoutingErrorHandler wraps unmatched routes into the JSON error envelope.
+func gatewayRoutingErrorHandler(
+	_ context.Context,
+	_ *runtime.ServeMux,
+	_ runtime.Marshaler,
+	writer http.ResponseWriter,
+	request *http.Request,
+	code int,
+) {
+	switch code {
+	case http.StatusNotFound:
+		writeGatewayError(writer, code, fmt.Sprintf("route not found: %s", request.URL.Path))
+	case http.StatusMethodNotAllowed:
+		writeGatewayError(writer, code, fmt.Sprintf("method not allowed: %s", request.Method))
+	default:
+		writeGatewayError(writer, code, http.StatusText(code))
+	}
+}
+
+func writeGatewayError(writer http.ResponseWriter, code int, message string) {
+	writer.Header().Del("Trailer")
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	writer.WriteHeader(code)
+	_ = json.NewEncoder(writer).Encode(gatewayError{gatewayErrorBody{code, message}})
+}
@@ -0,0 +1,142 @@
+package internalgrpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/limits"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// checkLimits throttles the client of the method, routes of the limits are full method names.
+// The rejected calls carry a retry-after header.
+func checkLimits(ctx context.Context, limiter *limits.Limiter, method string) error {
+	if isPublicMethod(method) {
+		return nil
+	}
+
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+
+	wait, ok := limiter.Allow(method, limits.ClientKey(ctx, addr))
+	if ok {
+		return nil
+	}
+
+	metrics.RejectedRequests.WithLabelValues(method, metrics.ReasonRateLimited).Inc()
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", limits.RetryAfter(wait)))
+
+	return toStatus(fmt.Errorf("%w: retry in %ss", limits.ErrRateLimited, limits.RetryAfter(wait)))
+}
+
+// checkAddressLimits throttles the remote address of the call before it is authenticated.
+func checkAddressLimits(ctx context.Context, limiter *limits.Limiter, method string) error {
+	if isPublicMethod(method) {
+		return nil
+	}
+
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+
+	wait, ok := limiter.AllowAddress(addr)
+	if ok {
+		return nil
+	}
+
+	metrics.RejectedRequests.WithLabelValues(method, metrics.ReasonAddressRateLimited).Inc()
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", limits.RetryAfter(wait)))
+
+	return toStatus(fmt.Errorf("%w: retry in %ss", limits.ErrRateLimited, limits.RetryAfter(wait)))
+}
+
+// checkMessageSize caps the size of the method request message.
+func checkMessageSize(limiter *limits.Limiter, method string, request interface{}) error {
+	max := limiter.Rule(method).MaxBodyBytes
+	message, ok := request.(proto.Message)
+	if max <= 0 || !ok {
+		return nil
+	}
+
+	if size := proto.Size(message); int64(size) > max {
+		metrics.RejectedRequests.WithLabelValues(method, metrics.ReasonBodyTooLarge).Inc()
+		return toStatus(fmt.Errorf("%w: limit is %d bytes", limits.ErrBodyTooLarge, max))
+	}
+
+	return nil
+}
+
+// limitsUnaryInterceptor throttles unary calls and caps their request size.
+func limitsUnaryInterceptor(limiter *limits.Limiter) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		request interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if err := checkLimits(ctx, limiter, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		if err := checkMessageSize(limiter, info.FullMethod, request); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, request)
+	}
+}
+
+// limitsStreamInterceptor throttles opening streams.
+func limitsStreamInterceptor(limiter *limits.Limiter) grpc.StreamServerInterceptor {
+	return func(
+		server interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := checkLimits(stream.Context(), limiter, info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(server, stream)
+	}
+}
+
+// addressLimitsUnaryInterceptor throttles the remote addresses of unary calls.
+func addressLimitsUnaryInterceptor(limiter *limits.Limiter) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		request interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if err := checkAddressLimits(ctx, limiter, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, request)
+	}
+}
+
+// addressLimitsStreamInterceptor throttles the remote addresses opening streams.
+func addressLimitsStreamInterceptor(limiter *limits.Limiter) grpc.StreamServerInterceptor {
+	return func(
+		server interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := checkAddressLimits(stream.Context(), limiter, info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(server, stream)
+	}
+}
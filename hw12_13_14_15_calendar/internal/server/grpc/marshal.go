@@ -0,0 +1,181 @@
+package internalgrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// wellKnownPackage holds the types with their own JSON mapping, like timestamps and durations.
+const wellKnownPackage = "google.protobuf"
+
+// numericJSONPb is the gateway marshaler writing 64-bit integers as JSON numbers.
+// Protojson writes them as strings, while the REST API has always had numeric ids.
+// Numbers past 2^53 lose precision in JavaScript clients, the storage ids stay far below that.
+type numericJSONPb struct {
+	runtime.JSONPb
+}
+
+// Marshal marshals messages and the stream chunks wrapping them.
+func (m *numericJSONPb) Marshal(v interface{}) ([]byte, error) {
+	switch value := v.(type) {
+	case proto.Message:
+		b, err := m.MarshalOptions.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return numericMessage(b, value.ProtoReflect().Descriptor())
+	case map[string]interface{}:
+		// Stream chunks are wrapped into a result envelope.
+		chunk := make(map[string]json.RawMessage, len(value))
+		for key, item := range value {
+			b, err := m.Marshal(item)
+			if err != nil {
+				return nil, err
+			}
+			chunk[key] = b
+		}
+
+		return marshalJSON(chunk)
+	default:
+		return m.JSONPb.Marshal(v)
+	}
+}
+
+// NewEncoder returns an encoder writing values delimited with new lines.
+func (m *numericJSONPb) NewEncoder(w io.Writer) runtime.Encoder {
+	return runtime.EncoderFunc(func(v interface{}) error {
+		b, err := m.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		if _, err = w.Write(b); err != nil {
+			return err
+		}
+
+		_, err = w.Write(m.Delimiter())
+		return err
+	})
+}
+
+// numericMessage rewrites the 64-bit integer fields of a marshaled message as numbers.
+func numericMessage(data []byte, descriptor protoreflect.MessageDescriptor) ([]byte, error) {
+	if descriptor.FullName().Parent() == wellKnownPackage || bytes.Equal(data, []byte("null")) {
+		return data, nil
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for key, raw := range fields {
+		field := descriptor.Fields().ByName(protoreflect.Name(key))
+		if field == nil {
+			field = descriptor.Fields().ByJSONName(key)
+		}
+		if field == nil {
+			continue
+		}
+
+		b, err := numericField(raw, field)
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = b
+	}
+
+	return marshalJSON(fields)
+}
+
+// numericField rewrites a single, repeated or map field.
+func numericField(data []byte, field protoreflect.FieldDescriptor) ([]byte, error) {
+	switch {
+	case field.IsMap():
+		values := map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, err
+		}
+
+		for key, raw := range values {
+			b, err := numericValue(raw, field.MapValue())
+			if err != nil {
+				return nil, err
+			}
+			values[key] = b
+		}
+
+		return marshalJSON(values)
+	case field.IsList():
+		values := []json.RawMessage{}
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, err
+		}
+
+		for i, raw := range values {
+			b, err := numericValue(raw, field)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = b
+		}
+
+		return marshalJSON(values)
+	default:
+		return numericValue(data, field)
+	}
+}
+
+// numericValue unquotes a 64-bit integer and descends into a message.
+func numericValue(data []byte, field protoreflect.FieldDescriptor) ([]byte, error) {
+	switch field.Kind() {
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return data, nil
+		}
+
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		return strconv.AppendInt(nil, n, 10), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return data, nil
+		}
+
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		return strconv.AppendUint(nil, n, 10), nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return numericMessage(data, field.Message())
+	default:
+		return data, nil
+	}
+}
+
+// marshalJSON marshals the value without escaping HTML, as protojson does.
+func marshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,84 @@
+package internalgrpc
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc/eventpb"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var notificationStates = map[pb.NotificationState]string{
+	pb.NotificationState_NOTIFICATION_PENDING:   storage.NotificationPending,
+	pb.NotificationState_NOTIFICATION_PUBLISHED: storage.NotificationPublished,
+	pb.NotificationState_NOTIFICATION_DELIVERED: storage.NotificationDelivered,
+	pb.NotificationState_NOTIFICATION_FAILED:    storage.NotificationFailed,
+}
+
+var notificationStatesToPb = map[string]pb.NotificationState{
+	storage.NotificationPending:   pb.NotificationState_NOTIFICATION_PENDING,
+	storage.NotificationPublished: pb.NotificationState_NOTIFICATION_PUBLISHED,
+	storage.NotificationDelivered: pb.NotificationState_NOTIFICATION_DELIVERED,
+	storage.NotificationFailed:    pb.NotificationState_NOTIFICATION_FAILED,
+}
+
+// ListNotifications handles listing notifications via grpc.
+func (s *Service) ListNotifications(
+	ctx context.Context,
+	request *pb.ListNotificationsRequest,
+) (*pb.ListNotificationsResponse, error) {
+	notifications, err := s.app.ListNotifications(ctx, storage.NotificationFilter{
+		EventID: request.EventId,
+		OwnerID: request.OwnerId,
+		State:   notificationStates[request.State],
+	})
+	if err != nil {
+		return &pb.ListNotificationsResponse{}, toStatus(err)
+	}
+
+	items := make([]*pb.Notification, len(notifications))
+	for i, notification := range notifications {
+		items[i] = notificationToPb(notification)
+	}
+
+	return &pb.ListNotificationsResponse{Items: items}, nil
+}
+
+// GetNotification handles getting a single notification via grpc.
+func (s *Service) GetNotification(
+	ctx context.Context,
+	request *pb.GetNotificationRequest,
+) (*pb.GetNotificationResponse, error) {
+	notification, err := s.app.GetNotification(ctx, request.Id)
+	if err != nil {
+		return &pb.GetNotificationResponse{}, toStatus(err)
+	}
+
+	return &pb.GetNotificationResponse{Notification: notificationToPb(notification)}, nil
+}
+
+func notificationToPb(notification storage.Notification) *pb.Notification {
+	return &pb.Notification{
+		Id:          notification.ID,
+		EventId:     notification.EventID,
+		ReminderId:  notification.ReminderID,
+		OwnerId:     notification.OwnerID,
+		Channel:     notification.Channel,
+		State:       notificationStatesToPb[notification.State],
+		Attempts:    int32(notification.Attempts),
+		LastError:   notification.LastError,
+		CreatedAt:   timestamppb.New(notification.CreatedAt),
+		UpdatedAt:   timestamppb.New(notification.UpdatedAt),
+		PublishedAt: optionalTimeToPb(notification.PublishedAt),
+		DeliveredAt: optionalTimeToPb(notification.DeliveredAt),
+	}
+}
+
+func optionalTimeToPb(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+
+	return timestamppb.New(*t)
+}
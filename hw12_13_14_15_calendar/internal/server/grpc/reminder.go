@@ -0,0 +1,70 @@
+package internalgrpc
+
+import (
+	"context"
+	"fmt"
+
+	pb "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc/eventpb"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var reminderStatesToPb = map[string]pb.ReminderState{
+	storage.ReminderPending:   pb.ReminderState_PENDING,
+	storage.ReminderSent:      pb.ReminderState_SENT,
+	storage.ReminderDelivered: pb.ReminderState_DELIVERED,
+	storage.ReminderSnoozed:   pb.ReminderState_SNOOZED,
+	storage.ReminderDismissed: pb.ReminderState_DISMISSED,
+	storage.ReminderFailed:    pb.ReminderState_FAILED,
+}
+
+// ListReminders handles listing the reminders of an event via grpc.
+func (s *Service) ListReminders(ctx context.Context, request *pb.ListRemindersRequest) (*pb.ListRemindersResponse, error) {
+	reminders, err := s.app.ListReminders(ctx, request.EventId)
+	if err != nil {
+		return &pb.ListRemindersResponse{}, toStatus(err)
+	}
+
+	items := make([]*pb.Reminder, len(reminders))
+	for i, reminder := range reminders {
+		items[i] = reminderToPb(reminder)
+	}
+
+	return &pb.ListRemindersResponse{Items: items}, nil
+}
+
+// SnoozeReminder handles snoozing a reminder via grpc.
+func (s *Service) SnoozeReminder(ctx context.Context, request *pb.SnoozeReminderRequest) (*pb.SnoozeReminderResponse, error) {
+	if err := request.Duration.CheckValid(); err != nil {
+		return &pb.SnoozeReminderResponse{}, toStatus(fmt.Errorf("%w: duration: %s", ErrInvalidDuration, err.Error()))
+	}
+
+	reminder, err := s.app.SnoozeReminder(ctx, request.Id, request.Duration.AsDuration())
+	if err != nil {
+		return &pb.SnoozeReminderResponse{}, toStatus(err)
+	}
+
+	return &pb.SnoozeReminderResponse{Reminder: reminderToPb(reminder)}, nil
+}
+
+// DismissReminder handles dismissing a reminder via grpc.
+func (s *Service) DismissReminder(ctx context.Context, request *pb.DismissReminderRequest) (*pb.DismissReminderResponse, error) {
+	reminder, err := s.app.DismissReminder(ctx, request.Id)
+	if err != nil {
+		return &pb.DismissReminderResponse{}, toStatus(err)
+	}
+
+	return &pb.DismissReminderResponse{Reminder: reminderToPb(reminder)}, nil
+}
+
+func reminderToPb(reminder storage.Reminder) *pb.Reminder {
+	return &pb.Reminder{
+		Id:       reminder.ID,
+		EventId:  reminder.EventID,
+		OwnerId:  reminder.OwnerID,
+		Offset:   durationpb.New(reminder.Offset),
+		RemindAt: timestamppb.New(reminder.RemindAt),
+		State:    reminderStatesToPb[reminder.State],
+	}
+}
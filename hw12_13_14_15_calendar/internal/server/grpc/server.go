@@ -1,4 +1,4 @@
-//go:generate protoc --go_out ./eventpb/ --go-grpc_out ./eventpb/ ./api/EventService.proto
+//go:generate protoc -I . -I ./third_party --go_out ./eventpb/ --go-grpc_out ./eventpb/ --grpc-gateway_out ./eventpb/ --openapiv2_out ./eventpb/ ./api/EventService.proto
 package internalgrpc
 
 import (
@@ -6,21 +6,34 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"time"
 
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/limits"
 	pb "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc/eventpb"
 	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-var (
-	EventDateFormat = "2006-01-02T15:04:05Z"
-	ErrServerStart  = errors.New("unable to start grpc server")
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
 )
 
+var ErrServerStart = errors.New("unable to start grpc server")
+
 type Logger interface {
 	Debug(msg string, args ...interface{})
 	Info(msg string, args ...interface{})
@@ -31,6 +44,7 @@ type Logger interface {
 
 type Server struct {
 	server *grpc.Server
+	health *grpchealth.Server
 	config Config
 	logger Logger
 }
@@ -44,9 +58,37 @@ type Application interface {
 	CreateEvent(ctx context.Context, event storage.Event) (storage.Event, error)
 	UpdateEvent(ctx context.Context, event storage.Event) (storage.Event, error)
 	RemoveEvent(ctx context.Context, event storage.Event) error
-	GetDayAheadEvents(ctx context.Context) ([]storage.Event, error)
-	GetWeekAheadEvents(ctx context.Context) ([]storage.Event, error)
-	GetMonthAheadEvents(ctx context.Context) ([]storage.Event, error)
+	GetEventByID(ctx context.Context, id int64) (storage.Event, error)
+	GetEventsInRange(ctx context.Context, begin, end time.Time, filter app.EventFilter) ([]storage.Event, error)
+	GetDayAheadEvents(ctx context.Context, filter app.EventFilter) ([]storage.Event, error)
+	GetWeekAheadEvents(ctx context.Context, filter app.EventFilter) ([]storage.Event, error)
+	GetMonthAheadEvents(ctx context.Context, filter app.EventFilter) ([]storage.Event, error)
+	GetFreeBusy(ctx context.Context, ownerIDs []int64, begin, end time.Time, hours app.WorkingHours, filter app.EventFilter) (map[int64][]app.Interval, error)
+	SuggestSlots(ctx context.Context, ownerIDs []int64, begin, end time.Time, hours app.WorkingHours, duration time.Duration, limit int, filter app.EventFilter) ([]app.Interval, error)
+	WatchEvents(ctx context.Context, ownerID int64, since int64, filter app.EventFilter) (<-chan storage.Change, error)
+	ApplyBatch(ctx context.Context, operations []app.BatchOperation, atomic bool) ([]app.BatchResult, error)
+	GetDigestSubscription(ctx context.Context, ownerID int64) (storage.DigestSubscription, error)
+	SetDigestSubscription(ctx context.Context, subscription storage.DigestSubscription) (storage.DigestSubscription, error)
+	RemoveDigestSubscription(ctx context.Context, ownerID int64) error
+	ListReminders(ctx context.Context, eventID int64) ([]storage.Reminder, error)
+	SnoozeReminder(ctx context.Context, id int64, duration time.Duration) (storage.Reminder, error)
+	DismissReminder(ctx context.Context, id int64) (storage.Reminder, error)
+	ListNotifications(ctx context.Context, filter storage.NotificationFilter) ([]storage.Notification, error)
+	GetNotification(ctx context.Context, id int64) (storage.Notification, error)
+	CreateCategory(ctx context.Context, category storage.Category) (storage.Category, error)
+	UpdateCategory(ctx context.Context, category storage.Category) (storage.Category, error)
+	RemoveCategory(ctx context.Context, id int64) error
+	GetCategory(ctx context.Context, id int64) (storage.Category, error)
+	ListCategories(ctx context.Context, ownerID int64) ([]storage.Category, error)
+	CreateCalendar(ctx context.Context, calendar storage.Calendar) (storage.Calendar, error)
+	UpdateCalendar(ctx context.Context, calendar storage.Calendar) (storage.Calendar, error)
+	RemoveCalendar(ctx context.Context, id int64) error
+	GetCalendar(ctx context.Context, id int64) (storage.Calendar, error)
+	ListCalendars(ctx context.Context, ownerID int64) ([]storage.Calendar, error)
+	ShareCalendar(ctx context.Context, grant storage.Grant) (storage.Grant, error)
+	UnshareCalendar(ctx context.Context, calendarID, granteeID int64) error
+	ListGrants(ctx context.Context, calendarID int64) ([]storage.Grant, error)
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type Service struct {
@@ -56,194 +98,313 @@ type Service struct {
 }
 
 // CreateEvent handles creating a new event via grpc.
-func (s *Service) CreateEvent(ctx context.Context, createEventRequest *pb.CreateEventRequest) (*pb.CreateEventResponse, error) {
-	event := storage.Event{}
-
-	event.Title = createEventRequest.Event.Title
-	event.Description = createEventRequest.Event.Description
-	event.OwnerID = createEventRequest.Event.OwnerId
-	event.NotificationSent = createEventRequest.Event.NotificationSent
-
-	if beginDate, err := time.Parse(EventDateFormat, createEventRequest.Event.BeginDate); err != nil {
-		event.BeginDate = time.Now()
-		s.logger.Error(err.Error())
-	} else {
-		event.BeginDate = beginDate
+func (s *Service) CreateEvent(ctx context.Context, request *pb.CreateEventRequest) (*pb.CreateEventResponse, error) {
+	event, err := EventFromPb(request.Event)
+	if err != nil {
+		return &pb.CreateEventResponse{}, toStatus(err)
 	}
 
-	if endDate, err := time.Parse(EventDateFormat, createEventRequest.Event.EndDate); err != nil {
-		event.EndDate = time.Now()
-		s.logger.Error(err.Error())
-	} else {
-		event.EndDate = endDate
+	event.ID = 0
+	event, err = s.app.CreateEvent(ctx, event)
+	if err != nil {
+		return &pb.CreateEventResponse{}, toStatus(err)
 	}
 
-	event, err := s.app.CreateEvent(ctx, event)
-	if err != nil {
-		s.logger.Error(err.Error())
-	}
-
-	return &pb.CreateEventResponse{
-		Event: &pb.Event{
-			Id:               event.ID,
-			Title:            event.Title,
-			BeginDate:        event.BeginDate.Format(EventDateFormat),
-			EndDate:          event.EndDate.Format(EventDateFormat),
-			Description:      event.Description,
-			OwnerId:          event.OwnerID,
-			NotificationSent: event.NotificationSent,
-		},
-	}, nil
+	return &pb.CreateEventResponse{Event: EventToPb(event)}, nil
 }
 
 // UpdateEvent handles updating given event via grpc.
-func (s *Service) UpdateEvent(ctx context.Context, updateEventRequest *pb.UpdateEventRequest) (*pb.UpdateEventResponse, error) {
-	event := storage.Event{}
-
-	event.ID = updateEventRequest.Event.Id
-	event.Title = updateEventRequest.Event.Title
-	event.Description = updateEventRequest.Event.Description
-	event.OwnerID = updateEventRequest.Event.OwnerId
-	event.NotificationSent = updateEventRequest.Event.NotificationSent
-
-	if beginDate, err := time.Parse(EventDateFormat, updateEventRequest.Event.BeginDate); err != nil {
-		event.BeginDate = time.Now()
-		s.logger.Error(err.Error())
-	} else {
-		event.BeginDate = beginDate
-	}
+// When the update mask is set, only the listed fields are changed.
+func (s *Service) UpdateEvent(ctx context.Context, request *pb.UpdateEventRequest) (*pb.UpdateEventResponse, error) {
+	var (
+		event storage.Event
+		err   error
+	)
+
+	if paths := request.UpdateMask.GetPaths(); len(paths) > 0 {
+		// The fields left out are kept as read, so no other update may come in between.
+		err = s.app.WithinTransaction(ctx, func(ctx context.Context) error {
+			current, err := s.app.GetEventByID(ctx, request.Event.GetId())
+			if err != nil {
+				return err
+			}
 
-	if endDate, err := time.Parse(EventDateFormat, updateEventRequest.Event.EndDate); err != nil {
-		event.EndDate = time.Now()
-		s.logger.Error(err.Error())
+			if event, err = mergeEventFromPb(current, request.Event, paths); err != nil {
+				return err
+			}
+
+			event, err = s.app.UpdateEvent(ctx, event)
+
+			return err
+		})
 	} else {
-		event.EndDate = endDate
+		event, err = EventFromPb(request.Event)
+		if err == nil {
+			event, err = s.app.UpdateEvent(ctx, event)
+		}
 	}
 
-	event, err := s.app.UpdateEvent(ctx, event)
 	if err != nil {
-		s.logger.Error(err.Error())
-	}
-
-	return &pb.UpdateEventResponse{
-		Event: &pb.Event{
-			Id:               event.ID,
-			Title:            event.Title,
-			BeginDate:        event.BeginDate.Format(EventDateFormat),
-			EndDate:          event.EndDate.Format(EventDateFormat),
-			Description:      event.Description,
-			OwnerId:          event.OwnerID,
-			NotificationSent: event.NotificationSent,
-		},
-	}, nil
+		return &pb.UpdateEventResponse{}, toStatus(err)
+	}
+
+	return &pb.UpdateEventResponse{Event: EventToPb(event)}, nil
 }
 
 // RemoveEvent handles removing an event via grpc.
-func (s *Service) RemoveEvent(ctx context.Context, removeEventRequest *pb.RemoveEventRequest) (*pb.RemoveEventResponse, error) {
-	event := storage.Event{}
-	event.ID = removeEventRequest.Id
-
-	err := s.app.RemoveEvent(ctx, event)
+func (s *Service) RemoveEvent(ctx context.Context, request *pb.RemoveEventRequest) (*pb.RemoveEventResponse, error) {
+	err := s.app.RemoveEvent(ctx, storage.Event{ID: request.Id})
 	if err != nil {
-		return &pb.RemoveEventResponse{}, err
+		return &pb.RemoveEventResponse{}, toStatus(err)
 	}
 
 	return &pb.RemoveEventResponse{}, nil
 }
 
-// GetDayAheadEvents handles getting daily events via grpc.
-func (s *Service) GetDayAheadEvents(ctx context.Context, _ *pb.GetDayAheadEventsRequest) (*pb.GetDayAheadEventsResponse, error) {
-	events, err := s.app.GetDayAheadEvents(ctx)
+// GetEvent handles getting a single event via grpc.
+func (s *Service) GetEvent(ctx context.Context, request *pb.GetEventRequest) (*pb.GetEventResponse, error) {
+	event, err := s.app.GetEventByID(ctx, request.Id)
+	if err != nil {
+		return &pb.GetEventResponse{}, toStatus(err)
+	}
+
+	return &pb.GetEventResponse{Event: EventToPb(event)}, nil
+}
+
+// ListEvents handles getting a page of the filtered events within the given period via grpc.
+func (s *Service) ListEvents(ctx context.Context, request *pb.ListEventsRequest) (*pb.ListEventsResponse, error) {
+	begin, err := timeFromPb("begin_date", request.BeginDate)
 	if err != nil {
-		return &pb.GetDayAheadEventsResponse{}, err
+		return &pb.ListEventsResponse{}, toStatus(err)
 	}
 
-	pbEvents := &pb.GetDayAheadEventsResponse{}
-	pbEvents.Items = make([]*pb.Event, len(events))
+	end, err := timeFromPb("end_date", request.EndDate)
+	if err != nil {
+		return &pb.ListEventsResponse{}, toStatus(err)
+	}
 
-	for i, event := range events {
-		pbEvents.Items[i] = &pb.Event{
-			Id:               event.ID,
-			Title:            event.Title,
-			BeginDate:        event.BeginDate.Format(EventDateFormat),
-			EndDate:          event.EndDate.Format(EventDateFormat),
-			Description:      event.Description,
-			OwnerId:          event.OwnerID,
-			NotificationSent: event.NotificationSent,
+	offset := 0
+	if request.PageToken != "" {
+		if offset, err = strconv.Atoi(request.PageToken); err != nil || offset < 0 {
+			return &pb.ListEventsResponse{}, toStatus(fmt.Errorf("%w: %q", ErrInvalidPageToken, request.PageToken))
 		}
 	}
 
-	return pbEvents, nil
+	pageSize := int(request.PageSize)
+	switch {
+	case pageSize <= 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+
+	events, err := s.app.GetEventsInRange(ctx, begin, end, eventFilter(request.CalendarIds, request.CategoryId, request.Tags))
+	if err != nil {
+		return &pb.ListEventsResponse{}, toStatus(err)
+	}
+
+	response := &pb.ListEventsResponse{}
+	if offset >= len(events) {
+		return response, nil
+	}
+
+	last := offset + pageSize
+	if last < len(events) {
+		response.NextPageToken = strconv.Itoa(last)
+	} else {
+		last = len(events)
+	}
+	response.Items = eventsToPb(events[offset:last])
+
+	return response, nil
 }
 
-// GetWeekAheadEvents handles getting weekly events via grpc.
-func (s *Service) GetWeekAheadEvents(ctx context.Context, _ *pb.GetWeekAheadEventsRequest) (*pb.GetWeekAheadEventsResponse, error) {
-	events, err := s.app.GetWeekAheadEvents(ctx)
+// GetDayAheadEvents handles getting the filtered daily events via grpc.
+func (s *Service) GetDayAheadEvents(ctx context.Context, request *pb.GetDayAheadEventsRequest) (*pb.GetDayAheadEventsResponse, error) {
+	events, err := s.app.GetDayAheadEvents(ctx, eventFilter(request.CalendarIds, request.CategoryId, request.Tags))
 	if err != nil {
-		return &pb.GetWeekAheadEventsResponse{}, err
+		return &pb.GetDayAheadEventsResponse{}, toStatus(err)
 	}
 
-	pbEvents := &pb.GetWeekAheadEventsResponse{}
-	pbEvents.Items = make([]*pb.Event, len(events))
+	return &pb.GetDayAheadEventsResponse{Items: eventsToPb(events)}, nil
+}
+
+// GetWeekAheadEvents handles getting the filtered weekly events via grpc.
+func (s *Service) GetWeekAheadEvents(ctx context.Context, request *pb.GetWeekAheadEventsRequest) (*pb.GetWeekAheadEventsResponse, error) {
+	events, err := s.app.GetWeekAheadEvents(ctx, eventFilter(request.CalendarIds, request.CategoryId, request.Tags))
+	if err != nil {
+		return &pb.GetWeekAheadEventsResponse{}, toStatus(err)
+	}
+
+	return &pb.GetWeekAheadEventsResponse{Items: eventsToPb(events)}, nil
+}
+
+// GetMonthAheadEvents handles getting the filtered monthly events via grpc.
+func (s *Service) GetMonthAheadEvents(ctx context.Context, request *pb.GetMonthAheadEventsRequest) (*pb.GetMonthAheadEventsResponse, error) {
+	events, err := s.app.GetMonthAheadEvents(ctx, eventFilter(request.CalendarIds, request.CategoryId, request.Tags))
+	if err != nil {
+		return &pb.GetMonthAheadEventsResponse{}, toStatus(err)
+	}
+
+	return &pb.GetMonthAheadEventsResponse{Items: eventsToPb(events)}, nil
+}
 
-	for i, event := range events {
-		pbEvents.Items[i] = &pb.Event{
-			Id:               event.ID,
-			Title:            event.Title,
-			BeginDate:        event.BeginDate.Format(EventDateFormat),
-			EndDate:          event.EndDate.Format(EventDateFormat),
-			Description:      event.Description,
-			OwnerId:          event.OwnerID,
-			NotificationSent: event.NotificationSent,
+// GetFreeBusy handles getting busy intervals of the given owners via grpc.
+func (s *Service) GetFreeBusy(ctx context.Context, request *pb.GetFreeBusyRequest) (*pb.GetFreeBusyResponse, error) {
+	begin, end, hours, err := parseFreeBusyQuery(request.BeginDate, request.EndDate, request.WorkBegin, request.WorkEnd)
+	if err != nil {
+		return &pb.GetFreeBusyResponse{}, toStatus(err)
+	}
+
+	filter := eventFilter(request.CalendarIds, request.CategoryId, request.Tags)
+	busy, err := s.app.GetFreeBusy(ctx, request.OwnerIds, begin, end, hours, filter)
+	if err != nil {
+		return &pb.GetFreeBusyResponse{}, toStatus(err)
+	}
+
+	response := &pb.GetFreeBusyResponse{}
+	for _, ownerID := range request.OwnerIds {
+		if intervals, ok := busy[ownerID]; ok {
+			response.Items = append(response.Items, &pb.OwnerBusy{
+				OwnerId: ownerID,
+				Busy:    intervalsToPb(intervals),
+			})
+			delete(busy, ownerID)
 		}
 	}
 
-	return pbEvents, nil
+	return response, nil
 }
 
-// GetMonthAheadEvents handles getting monthly events via grpc.
-func (s *Service) GetMonthAheadEvents(ctx context.Context, _ *pb.GetMonthAheadEventsRequest) (*pb.GetMonthAheadEventsResponse, error) {
-	events, err := s.app.GetMonthAheadEvents(ctx)
+// SuggestSlots handles suggesting common free slots via grpc.
+func (s *Service) SuggestSlots(ctx context.Context, request *pb.SuggestSlotsRequest) (*pb.SuggestSlotsResponse, error) {
+	begin, end, hours, err := parseFreeBusyQuery(request.BeginDate, request.EndDate, request.WorkBegin, request.WorkEnd)
 	if err != nil {
-		return &pb.GetMonthAheadEventsResponse{}, err
+		return &pb.SuggestSlotsResponse{}, toStatus(err)
 	}
 
-	pbEvents := &pb.GetMonthAheadEventsResponse{}
-	pbEvents.Items = make([]*pb.Event, len(events))
+	duration := time.Duration(request.DurationMinutes) * time.Minute
+	filter := eventFilter(request.CalendarIds, request.CategoryId, request.Tags)
+	slots, err := s.app.SuggestSlots(ctx, request.OwnerIds, begin, end, hours, duration, int(request.Limit), filter)
+	if err != nil {
+		return &pb.SuggestSlotsResponse{}, toStatus(err)
+	}
+
+	return &pb.SuggestSlotsResponse{Items: intervalsToPb(slots)}, nil
+}
 
-	for i, event := range events {
-		pbEvents.Items[i] = &pb.Event{
-			Id:               event.ID,
-			Title:            event.Title,
-			BeginDate:        event.BeginDate.Format(EventDateFormat),
-			EndDate:          event.EndDate.Format(EventDateFormat),
-			Description:      event.Description,
-			OwnerId:          event.OwnerID,
-			NotificationSent: event.NotificationSent,
+// WatchEvents streams changes of the owner's events passing the filter via grpc.
+func (s *Service) WatchEvents(request *pb.WatchEventsRequest, stream pb.Calendar_WatchEventsServer) error {
+	filter := eventFilter(request.CalendarIds, request.CategoryId, request.Tags)
+	changes, err := s.app.WatchEvents(stream.Context(), request.OwnerId, request.Revision, filter)
+	if err != nil {
+		return toStatus(err)
+	}
+
+	for change := range changes {
+		if err := stream.Send(changeToPb(change)); err != nil {
+			return err
 		}
 	}
 
-	return pbEvents, nil
+	if err := stream.Context().Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+
+	// The watcher has fallen behind, the client resumes from the last received revision.
+	return status.Error(codes.Unavailable, "change stream interrupted, resume from the last revision")
 }
 
-// NewServer returns a new grpc server instance.
-func NewServer(config Config, app Application, logger Logger) *Server {
-	server := grpc.NewServer(
-		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
-			grpc_zap.UnaryServerInterceptor(logger.GetZapLogger()),
-		)),
-	)
+// parseFreeBusyQuery converts free/busy request fields into application arguments.
+func parseFreeBusyQuery(
+	beginDate, endDate *timestamppb.Timestamp,
+	workBegin, workEnd string,
+) (time.Time, time.Time, app.WorkingHours, error) {
+	begin, err := timeFromPb("begin_date", beginDate)
+	if err != nil {
+		return begin, begin, app.WorkingHours{}, err
+	}
+
+	end, err := timeFromPb("end_date", endDate)
+	if err != nil {
+		return begin, end, app.WorkingHours{}, err
+	}
+
+	hours, err := app.ParseWorkingHours(workBegin, workEnd)
 
-	service := &Service{
+	return begin, end, hours, err
+}
+
+// NewService returns a new calendar service, shared by the grpc server and the http gateway.
+func NewService(app Application, logger Logger) *Service {
+	return &Service{
 		pb.UnimplementedCalendarServer{},
 		app,
 		logger,
 	}
+}
+
+// NewServer returns a new grpc server instance.
+// A nil authenticator disables authentication, a nil limiter disables the limits.
+func NewServer(
+	config Config,
+	app Application,
+	logger Logger,
+	authenticator Authenticator,
+	limiter *limits.Limiter,
+) *Server {
+	unary := []grpc.UnaryServerInterceptor{
+		otelgrpc.UnaryServerInterceptor(),
+		grpc_prometheus.UnaryServerInterceptor,
+		grpc_zap.UnaryServerInterceptor(logger.GetZapLogger()),
+	}
+	stream := []grpc.StreamServerInterceptor{
+		otelgrpc.StreamServerInterceptor(),
+		grpc_prometheus.StreamServerInterceptor,
+		grpc_zap.StreamServerInterceptor(logger.GetZapLogger()),
+	}
+
+	// Remote addresses are limited before authentication, clients after it.
+	if limiter != nil {
+		unary = append(unary, addressLimitsUnaryInterceptor(limiter))
+		stream = append(stream, addressLimitsStreamInterceptor(limiter))
+	}
+
+	unary = append(unary, authUnaryInterceptor(authenticator))
+	stream = append(stream, authStreamInterceptor(authenticator))
+
+	options := []grpc.ServerOption{}
+	if limiter != nil {
+		unary = append(unary, limitsUnaryInterceptor(limiter))
+		stream = append(stream, limitsStreamInterceptor(limiter))
+
+		// Messages are dropped by the transport past the largest route cap, the routes are checked by the interceptor.
+		// The transport cap is fixed at startup, config reloads only change the route caps below it.
+		if max := limiter.MaxBodyBytes(); max > 0 {
+			options = append(options, grpc.MaxRecvMsgSize(int(max)))
+		}
+	}
+
+	options = append(options,
+		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unary...)),
+		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(stream...)),
+	)
+	server := grpc.NewServer(options...)
+
+	pb.RegisterCalendarServer(server, NewService(app, logger))
+	reflection.Register(server)
 
-	pb.RegisterCalendarServer(server, service)
+	// The standard health service, serving until readiness reports otherwise.
+	health := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(server, health)
+
+	// Request counters are registered per method, latency histograms are opt-in.
+	grpc_prometheus.EnableHandlingTimeHistogram()
+	grpc_prometheus.Register(server)
 
 	return &Server{
 		server: server,
+		health: health,
 		config: config,
 		logger: logger,
 	}
@@ -263,7 +424,19 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// SetServing reports the readiness of the server and the calendar service to health clients.
+func (s *Server) SetServing(ready bool) {
+	status := healthpb.HealthCheckResponse_SERVING
+	if !ready {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	s.health.SetServingStatus("", status)
+	s.health.SetServingStatus(pb.Calendar_ServiceDesc.ServiceName, status)
+}
+
 // Stop suspends GRPC server.
 func (s *Server) Stop() {
+	s.health.Shutdown()
 	s.server.Stop()
 }
@@ -0,0 +1,537 @@
+package internalgrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	pb "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc/eventpb"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newTestClient(t *testing.T) pb.CalendarClient {
+	t.Helper()
+
+	return newTestAppClient(t, app.New(nil, memorystorage.New(clock.Real{}), clock.Real{}))
+}
+
+// newTestAppClient serves the given app, letting a test change it behind the API.
+func newTestAppClient(t *testing.T, calendar *app.App) pb.CalendarClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	// Calls are internal, as to a server without authentication.
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(authUnaryInterceptor(nil)),
+		grpc.StreamInterceptor(authStreamInterceptor(nil)),
+	)
+	pb.RegisterCalendarServer(server, &Service{app: calendar})
+
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewCalendarClient(conn)
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	begin := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	var ids []int64
+	for i := 0; i < 5; i++ {
+		response, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: &pb.Event{
+			Title:     "event",
+			BeginDate: timestamppb.New(begin.Add(time.Duration(i) * time.Hour)),
+			EndDate:   timestamppb.New(begin.Add(time.Duration(i)*time.Hour + time.Minute)),
+			OwnerId:   1,
+		}})
+		require.NoError(t, err)
+		ids = append(ids, response.Event.Id)
+	}
+
+	t.Run("get event", func(t *testing.T) {
+		response, err := client.GetEvent(ctx, &pb.GetEventRequest{Id: ids[0]})
+		require.NoError(t, err)
+		require.Equal(t, begin, response.Event.BeginDate.AsTime())
+
+		_, err = client.GetEvent(ctx, &pb.GetEventRequest{Id: 100500})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("list events by pages", func(t *testing.T) {
+		request := &pb.ListEventsRequest{
+			BeginDate: timestamppb.New(begin),
+			EndDate:   timestamppb.New(begin.Add(24 * time.Hour)),
+			PageSize:  2,
+		}
+
+		var listed []int64
+		for {
+			response, err := client.ListEvents(ctx, request)
+			require.NoError(t, err)
+			for _, event := range response.Items {
+				listed = append(listed, event.Id)
+			}
+			if response.NextPageToken == "" {
+				break
+			}
+			request.PageToken = response.NextPageToken
+		}
+		require.Equal(t, ids, listed)
+
+		request.PageToken = "abc"
+		_, err := client.ListEvents(ctx, request)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("status codes", func(t *testing.T) {
+		_, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: &pb.Event{Title: "no dates", OwnerId: 1}})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.CreateEvent(ctx, &pb.CreateEventRequest{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.UpdateEvent(ctx, &pb.UpdateEventRequest{Event: &pb.Event{
+			Id:        100500,
+			Title:     "missing",
+			BeginDate: timestamppb.New(begin),
+			EndDate:   timestamppb.New(begin),
+		}})
+		require.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = client.RemoveEvent(ctx, &pb.RemoveEventRequest{Id: 100500})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestBatchEvents(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	begin := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	newEvent := func(title string, hour int) *pb.Event {
+		return &pb.Event{
+			Title:     title,
+			BeginDate: timestamppb.New(begin.Add(time.Duration(hour) * time.Hour)),
+			EndDate:   timestamppb.New(begin.Add(time.Duration(hour)*time.Hour + time.Minute)),
+			OwnerId:   1,
+		}
+	}
+	create := func(event *pb.Event) *pb.BatchOperation {
+		return &pb.BatchOperation{Operation: &pb.BatchOperation_Create{Create: event}}
+	}
+
+	t.Run("all or nothing", func(t *testing.T) {
+		response, err := client.BatchEvents(ctx, &pb.BatchEventsRequest{Operations: []*pb.BatchOperation{
+			create(newEvent("first", 0)),
+			create(newEvent("", 0)),
+		}})
+		require.NoError(t, err)
+		require.False(t, response.Committed)
+		require.Equal(t, int32(codes.Aborted), response.Results[0].Code)
+		require.Equal(t, int32(codes.InvalidArgument), response.Results[1].Code)
+		require.Equal(t, int32(1), response.Results[1].Index)
+
+		listed, err := client.ListEvents(ctx, &pb.ListEventsRequest{
+			BeginDate: timestamppb.New(begin),
+			EndDate:   timestamppb.New(begin.Add(24 * time.Hour)),
+		})
+		require.NoError(t, err)
+		require.Empty(t, listed.Items)
+	})
+
+	t.Run("best effort", func(t *testing.T) {
+		response, err := client.BatchEvents(ctx, &pb.BatchEventsRequest{
+			Mode: pb.BatchMode_BEST_EFFORT,
+			Operations: []*pb.BatchOperation{
+				create(newEvent("first", 0)),
+				{Operation: &pb.BatchOperation_Remove{Remove: 100500}},
+			},
+		})
+		require.NoError(t, err)
+		require.True(t, response.Committed)
+		require.True(t, response.Results[0].Ok)
+		require.Equal(t, "first", response.Results[0].Event.Title)
+		require.Equal(t, int32(codes.NotFound), response.Results[1].Code)
+
+		removed, err := client.BatchEvents(ctx, &pb.BatchEventsRequest{Operations: []*pb.BatchOperation{
+			{Operation: &pb.BatchOperation_Remove{Remove: response.Results[0].Event.Id}},
+		}})
+		require.NoError(t, err)
+		require.True(t, removed.Committed)
+		require.Nil(t, removed.Results[0].Event)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		_, err := client.BatchEvents(ctx, &pb.BatchEventsRequest{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.BatchEvents(ctx, &pb.BatchEventsRequest{Operations: []*pb.BatchOperation{{}}})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("import events", func(t *testing.T) {
+		stream, err := client.ImportEvents(ctx)
+		require.NoError(t, err)
+		for i := 10; i < 13; i++ {
+			require.NoError(t, stream.Send(&pb.ImportEventsRequest{Mode: pb.BatchMode_ALL_OR_NOTHING, Event: newEvent("imported", i)}))
+		}
+
+		response, err := stream.CloseAndRecv()
+		require.NoError(t, err)
+		require.True(t, response.Committed)
+		require.Len(t, response.Results, 3)
+		for _, result := range response.Results {
+			require.True(t, result.Ok)
+			require.NotZero(t, result.Event.Id)
+		}
+	})
+}
+
+func TestDigest(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+
+	_, err := client.GetDigest(ctx, &pb.GetDigestRequest{OwnerId: 1})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	set, err := client.SetDigest(ctx, &pb.SetDigestRequest{Digest: &pb.Digest{
+		OwnerId:  1,
+		Period:   pb.DigestPeriod_WEEK,
+		Time:     "08:30",
+		TimeZone: "Europe/Moscow",
+		Weekday:  1,
+	}})
+	require.NoError(t, err)
+	require.Equal(t, pb.DigestPeriod_WEEK, set.Digest.Period)
+	require.NotNil(t, set.Digest.LastSentAt)
+
+	got, err := client.GetDigest(ctx, &pb.GetDigestRequest{OwnerId: 1})
+	require.NoError(t, err)
+	require.Equal(t, "08:30", got.Digest.Time)
+	require.Equal(t, int32(1), got.Digest.Weekday)
+
+	_, err = client.SetDigest(ctx, &pb.SetDigestRequest{Digest: &pb.Digest{OwnerId: 1, Time: "08:30"}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.SetDigest(ctx, &pb.SetDigestRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.RemoveDigest(ctx, &pb.RemoveDigestRequest{OwnerId: 1})
+	require.NoError(t, err)
+
+	_, err = client.RemoveDigest(ctx, &pb.RemoveDigestRequest{OwnerId: 1})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestReminders(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	begin := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+
+	created, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: &pb.Event{
+		Title:     "event",
+		BeginDate: timestamppb.New(begin),
+		EndDate:   timestamppb.New(begin.Add(time.Hour)),
+		OwnerId:   1,
+		Reminders: []*durationpb.Duration{durationpb.New(15 * time.Minute), durationpb.New(time.Hour)},
+	}})
+	require.NoError(t, err)
+	require.Len(t, created.Event.Reminders, 2)
+	require.Equal(t, time.Hour, created.Event.Reminders[0].AsDuration())
+
+	listed, err := client.ListReminders(ctx, &pb.ListRemindersRequest{EventId: created.Event.Id})
+	require.NoError(t, err)
+	require.Len(t, listed.Items, 2)
+	require.Equal(t, pb.ReminderState_PENDING, listed.Items[1].State)
+	require.Equal(t, begin.Add(-15*time.Minute), listed.Items[1].RemindAt.AsTime())
+
+	id := listed.Items[1].Id
+
+	_, err = client.SnoozeReminder(ctx, &pb.SnoozeReminderRequest{Id: id, Duration: durationpb.New(time.Minute)})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = client.SnoozeReminder(ctx, &pb.SnoozeReminderRequest{Id: id})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	dismissed, err := client.DismissReminder(ctx, &pb.DismissReminderRequest{Id: id})
+	require.NoError(t, err)
+	require.Equal(t, pb.ReminderState_DISMISSED, dismissed.Reminder.State)
+
+	_, err = client.DismissReminder(ctx, &pb.DismissReminderRequest{Id: 100500})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	updated, err := client.UpdateEvent(ctx, &pb.UpdateEventRequest{
+		Event:      &pb.Event{Id: created.Event.Id},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"reminders"}},
+	})
+	require.NoError(t, err)
+	require.Empty(t, updated.Event.Reminders)
+
+	_, err = client.ListReminders(ctx, &pb.ListRemindersRequest{EventId: 100500})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestNotifications(t *testing.T) {
+	ctx := context.Background()
+	calendar := app.New(nil, memorystorage.New(clock.Real{}), clock.Real{})
+	client := newTestAppClient(t, calendar)
+	begin := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+
+	created, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: &pb.Event{
+		Title:     "event",
+		BeginDate: timestamppb.New(begin),
+		EndDate:   timestamppb.New(begin.Add(time.Hour)),
+		OwnerId:   1,
+	}})
+	require.NoError(t, err)
+
+	reminders, err := calendar.ListReminders(ctx, created.Event.Id)
+	require.NoError(t, err)
+	require.Len(t, reminders, 1)
+
+	notification, err := calendar.BeginNotification(ctx, reminders[0], storage.ChannelConsole)
+	require.NoError(t, err)
+	_, err = calendar.NotificationPublished(ctx, notification.ID, reminders[0])
+	require.NoError(t, err)
+
+	listed, err := client.ListNotifications(ctx, &pb.ListNotificationsRequest{EventId: created.Event.Id})
+	require.NoError(t, err)
+	require.Len(t, listed.Items, 1)
+	require.Equal(t, pb.NotificationState_NOTIFICATION_PUBLISHED, listed.Items[0].State)
+	require.Equal(t, reminders[0].ID, listed.Items[0].ReminderId)
+	require.Equal(t, storage.ChannelConsole, listed.Items[0].Channel)
+	require.NotNil(t, listed.Items[0].PublishedAt)
+	require.Nil(t, listed.Items[0].DeliveredAt)
+
+	// The event is flagged as notified, whatever an update says.
+	updated, err := client.UpdateEvent(ctx, &pb.UpdateEventRequest{
+		Event:      &pb.Event{Id: created.Event.Id, Title: "renamed"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title", "notification_sent"}},
+	})
+	require.NoError(t, err)
+	require.True(t, updated.Event.NotificationSent)
+	require.False(t, updated.Event.NotificationReceived)
+
+	listed, err = client.ListNotifications(ctx, &pb.ListNotificationsRequest{State: pb.NotificationState_NOTIFICATION_DELIVERED})
+	require.NoError(t, err)
+	require.Empty(t, listed.Items)
+
+	got, err := client.GetNotification(ctx, &pb.GetNotificationRequest{Id: notification.ID})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), got.Notification.Attempts)
+
+	_, err = client.GetNotification(ctx, &pb.GetNotificationRequest{Id: 100500})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.ListNotifications(ctx, &pb.ListNotificationsRequest{EventId: 100500})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestCategories(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	begin := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+
+	created, err := client.CreateCategory(ctx, &pb.CreateCategoryRequest{Category: &pb.Category{
+		OwnerId:   1,
+		Name:      "work",
+		Color:     "#00FF00",
+		Reminders: []*durationpb.Duration{durationpb.New(time.Hour)},
+	}})
+	require.NoError(t, err)
+	category := created.Category
+	require.NotZero(t, category.Id)
+	require.Equal(t, "#00ff00", category.Color)
+
+	event, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: &pb.Event{
+		Title:      "meeting",
+		BeginDate:  timestamppb.New(begin),
+		EndDate:    timestamppb.New(begin.Add(time.Hour)),
+		OwnerId:    1,
+		CategoryId: category.Id,
+		Tags:       []string{"Client"},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"client"}, event.Event.Tags)
+	require.Equal(t, time.Hour, event.Event.Reminders[0].AsDuration())
+
+	_, err = client.CreateEvent(ctx, &pb.CreateEventRequest{Event: &pb.Event{
+		Title:     "lunch",
+		BeginDate: timestamppb.New(begin.Add(2 * time.Hour)),
+		EndDate:   timestamppb.New(begin.Add(3 * time.Hour)),
+		OwnerId:   1,
+	}})
+	require.NoError(t, err)
+
+	day, err := client.GetDayAheadEvents(ctx, &pb.GetDayAheadEventsRequest{CategoryId: category.Id})
+	require.NoError(t, err)
+	require.Len(t, day.Items, 1)
+	require.Equal(t, event.Event.Id, day.Items[0].Id)
+
+	listed, err := client.ListEvents(ctx, &pb.ListEventsRequest{
+		BeginDate: timestamppb.New(begin.Add(-time.Hour)),
+		EndDate:   timestamppb.New(begin.Add(4 * time.Hour)),
+		Tags:      []string{"client"},
+	})
+	require.NoError(t, err)
+	require.Len(t, listed.Items, 1)
+
+	// Clearing the tags through the mask.
+	updated, err := client.UpdateEvent(ctx, &pb.UpdateEventRequest{
+		Event:      &pb.Event{Id: event.Event.Id},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"tags"}},
+	})
+	require.NoError(t, err)
+	require.Empty(t, updated.Event.Tags)
+	require.Equal(t, category.Id, updated.Event.CategoryId)
+
+	category.Name = "office"
+	renamed, err := client.UpdateCategory(ctx, &pb.UpdateCategoryRequest{Category: category})
+	require.NoError(t, err)
+	require.Equal(t, "office", renamed.Category.Name)
+
+	categories, err := client.ListCategories(ctx, &pb.ListCategoriesRequest{OwnerId: 1})
+	require.NoError(t, err)
+	require.Len(t, categories.Items, 1)
+
+	_, err = client.CreateCategory(ctx, &pb.CreateCategoryRequest{Category: &pb.Category{OwnerId: 1, Name: "office"}})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = client.CreateCategory(ctx, &pb.CreateCategoryRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.ListEvents(ctx, &pb.ListEventsRequest{
+		BeginDate: timestamppb.New(begin),
+		EndDate:   timestamppb.New(begin.Add(time.Hour)),
+		Tags:      []string{""},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.RemoveCategory(ctx, &pb.RemoveCategoryRequest{Id: category.Id})
+	require.NoError(t, err)
+
+	_, err = client.GetCategory(ctx, &pb.GetCategoryRequest{Id: category.Id})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestCalendars(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	begin := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+
+	created, err := client.CreateCalendar(ctx, &pb.CreateCalendarRequest{Calendar: &pb.CalendarInfo{
+		OwnerId: 1,
+		Name:    "work",
+	}})
+	require.NoError(t, err)
+	calendar := created.Calendar
+	require.NotZero(t, calendar.Id)
+	require.Equal(t, pb.CalendarAccess_CALENDAR_ACCESS_OWNER, calendar.Access)
+
+	event, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: &pb.Event{
+		Title:      "meeting",
+		BeginDate:  timestamppb.New(begin),
+		EndDate:    timestamppb.New(begin.Add(time.Hour)),
+		CalendarId: calendar.Id,
+	}})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), event.Event.OwnerId)
+	require.Equal(t, calendar.Id, event.Event.CalendarId)
+
+	day, err := client.GetDayAheadEvents(ctx, &pb.GetDayAheadEventsRequest{CalendarIds: []int64{calendar.Id}})
+	require.NoError(t, err)
+	require.Len(t, day.Items, 1)
+
+	shared, err := client.ShareCalendar(ctx, &pb.ShareCalendarRequest{Grant: &pb.Grant{
+		CalendarId: calendar.Id,
+		GranteeId:  2,
+		Access:     pb.CalendarAccess_CALENDAR_ACCESS_READ,
+	}})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), shared.Grant.OwnerId)
+
+	calendars, err := client.ListCalendars(ctx, &pb.ListCalendarsRequest{OwnerId: 2})
+	require.NoError(t, err)
+	require.Len(t, calendars.Items, 1)
+	require.Equal(t, pb.CalendarAccess_CALENDAR_ACCESS_READ, calendars.Items[0].Access)
+
+	grants, err := client.ListGrants(ctx, &pb.ListGrantsRequest{CalendarId: calendar.Id})
+	require.NoError(t, err)
+	require.Len(t, grants.Items, 1)
+
+	_, err = client.ShareCalendar(ctx, &pb.ShareCalendarRequest{Grant: &pb.Grant{CalendarId: calendar.Id, GranteeId: 2}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.RemoveCalendar(ctx, &pb.RemoveCalendarRequest{Id: calendar.Id})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = client.UnshareCalendar(ctx, &pb.UnshareCalendarRequest{CalendarId: calendar.Id, GranteeId: 2})
+	require.NoError(t, err)
+
+	_, err = client.RemoveEvent(ctx, &pb.RemoveEventRequest{Id: event.Event.Id})
+	require.NoError(t, err)
+
+	_, err = client.RemoveCalendar(ctx, &pb.RemoveCalendarRequest{Id: calendar.Id})
+	require.NoError(t, err)
+
+	_, err = client.GetCalendar(ctx, &pb.GetCalendarRequest{Id: calendar.Id})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGatewayMarshaler(t *testing.T) {
+	marshaler := &numericJSONPb{}
+	begin := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("message", func(t *testing.T) {
+		b, err := marshaler.Marshal(&pb.GetFreeBusyResponse{Items: []*pb.OwnerBusy{{
+			OwnerId: 42,
+			Busy:    []*pb.Interval{{BeginDate: timestamppb.New(begin), EndDate: timestamppb.New(begin.Add(time.Hour))}},
+		}}})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"items":[{"ownerId":42,"busy":[{"beginDate":"2021-12-01T10:00:00Z","endDate":"2021-12-01T11:00:00Z"}]}]}`, string(b))
+	})
+
+	t.Run("stream chunk", func(t *testing.T) {
+		b, err := marshaler.Marshal(map[string]interface{}{"result": &pb.EventChange{Revision: 7, Event: &pb.Event{Id: 3}}})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"result":{"revision":7,"event":{"id":3}}}`, string(b))
+	})
+
+	t.Run("openapi", func(t *testing.T) {
+		document := struct {
+			Definitions map[string]struct {
+				Properties map[string]struct {
+					Type string `json:"type"`
+				} `json:"properties"`
+			} `json:"definitions"`
+		}{}
+		require.NoError(t, json.Unmarshal(OpenAPI, &document))
+		require.Equal(t, "integer", document.Definitions["eventEvent"].Properties["id"].Type)
+		require.Equal(t, "string", document.Definitions["eventEvent"].Properties["title"].Type)
+	})
+}
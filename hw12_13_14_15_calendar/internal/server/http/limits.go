@@ -0,0 +1,93 @@
+package internalhttp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/limits"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/metrics"
+)
+
+// limitedBody fails reading past the body cap instead of silently truncating it.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, limits.ErrBodyTooLarge
+	}
+
+	// Reading a byte more than allowed tells a body at the cap from a larger one.
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), limits.ErrBodyTooLarge
+	}
+
+	return n, err
+}
+
+// limitRoute returns the limits route of the request: the method and the path with ids replaced.
+// Paths outside of the API share a single route, so that clients can't make up buckets at will.
+func limitRoute(request *http.Request) string {
+	return metrics.Method(request.Method) + " " + metrics.Route(request.URL.Path)
+}
+
+// Throttles the remote address of the request, whoever it is authenticated as.
+// Runs before authentication, so that failing requests are throttled as well.
+func (h *RequestHandler) addressLimitsMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if h.Limiter == nil {
+			next(writer, request)
+			return
+		}
+
+		if wait, ok := h.Limiter.AllowAddress(request.RemoteAddr); !ok {
+			metrics.RejectedRequests.WithLabelValues(metrics.Route(request.URL.Path), metrics.ReasonAddressRateLimited).Inc()
+			writer.Header().Set("Retry-After", limits.RetryAfter(wait))
+			h.writeError(writer, fmt.Errorf("%w: retry in %s", limits.ErrRateLimited, limits.RetryAfter(wait)+"s"))
+			return
+		}
+
+		next(writer, request)
+	}
+}
+
+// Throttles clients of the route and caps the request body size.
+// Runs after authentication, so that authenticated clients are told apart by owner.
+func (h *RequestHandler) limitsMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if h.Limiter == nil {
+			next(writer, request)
+			return
+		}
+
+		route := limitRoute(request)
+		rule := h.Limiter.Rule(route)
+
+		if wait, ok := h.Limiter.Allow(route, limits.ClientKey(request.Context(), request.RemoteAddr)); !ok {
+			metrics.RejectedRequests.WithLabelValues(metrics.Route(request.URL.Path), metrics.ReasonRateLimited).Inc()
+			writer.Header().Set("Retry-After", limits.RetryAfter(wait))
+			h.writeError(writer, fmt.Errorf("%w: retry in %s", limits.ErrRateLimited, limits.RetryAfter(wait)+"s"))
+			return
+		}
+
+		if rule.MaxBodyBytes > 0 {
+			if request.ContentLength > rule.MaxBodyBytes {
+				metrics.RejectedRequests.WithLabelValues(metrics.Route(request.URL.Path), metrics.ReasonBodyTooLarge).Inc()
+				h.writeError(writer, fmt.Errorf("%w: limit is %d bytes", limits.ErrBodyTooLarge, rule.MaxBodyBytes))
+				return
+			}
+			request.Body = &limitedBody{request.Body, rule.MaxBodyBytes}
+		}
+
+		next(writer, request)
+	}
+}
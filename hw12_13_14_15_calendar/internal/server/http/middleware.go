@@ -1,8 +1,14 @@
 package internalhttp
 
 import (
+	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/auth"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/metrics"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type statusCodeWrapper struct {
@@ -15,7 +21,14 @@ func (w *statusCodeWrapper) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
-// Wraps request handler, adding request access log.
+// Flush lets streaming handlers push data through the wrapper.
+func (w *statusCodeWrapper) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Wraps request handler, adding request access log and metrics.
 func loggingMiddleware(next http.HandlerFunc, logger Logger) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
 		start := time.Now()
@@ -24,6 +37,9 @@ func loggingMiddleware(next http.HandlerFunc, logger Logger) http.HandlerFunc {
 
 		next(wrapper, request)
 
+		latency := time.Since(start)
+		metrics.ObserveHTTPRequest(request.Method, request.URL.Path, wrapper.statusCode, latency)
+
 		logger.Info(
 			"access",
 			"addr", request.RemoteAddr,
@@ -31,8 +47,52 @@ func loggingMiddleware(next http.HandlerFunc, logger Logger) http.HandlerFunc {
 			"path", request.URL.Path,
 			"proto", request.Proto,
 			"code", wrapper.statusCode,
-			"latency", time.Since(start),
+			"latency", latency,
 			"agent", request.UserAgent(),
 		)
 	}
 }
+
+// Marks responses of a deprecated route, pointing to its successor.
+func deprecatedMiddleware(next http.HandlerFunc, successor string) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Deprecation", "true")
+		writer.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+
+		next(writer, request)
+	}
+}
+
+// Wraps the routes, continuing the trace context of incoming requests.
+// Spans are named by the bounded route labels of the metrics, unknown paths sharing a single name.
+func tracingMiddleware(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "http", otelhttp.WithSpanNameFormatter(func(_ string, request *http.Request) string {
+		return metrics.Method(request.Method) + " " + metrics.Route(request.URL.Path)
+	}))
+}
+
+// Authenticates the request bearer token, acting on behalf of its owner.
+// The root page and the API description stay public, requests to a server without authentication are internal.
+func (h *RequestHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if h.Auth == nil {
+			next(writer, request.WithContext(app.ContextInternal(request.Context())))
+			return
+		}
+
+		if request.URL.Path == "/" || request.URL.Path == openAPIPath {
+			next(writer, request)
+			return
+		}
+
+		token := auth.BearerToken(request.Header.Get("Authorization"))
+		ownerID, err := h.Auth.Authenticate(request.Context(), token)
+		if err != nil {
+			writer.Header().Set("WWW-Authenticate", `Bearer realm="calendar"`)
+			h.writeError(writer, err)
+			return
+		}
+
+		next(writer, request.WithContext(app.ContextWithOwner(request.Context(), ownerID)))
+	}
+}
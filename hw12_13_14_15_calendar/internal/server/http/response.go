@@ -0,0 +1,130 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/auth"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/limits"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrReadRequest      = errors.New("unable to read the request")
+	ErrUnmarshalRequest = errors.New("unable to unmarshal the request")
+	ErrInvalidParameter = errors.New("invalid request parameter")
+	ErrMethodNotAllowed = errors.New("method not allowed")
+	ErrRouteNotFound    = errors.New("route not found")
+)
+
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorStatus maps application errors onto HTTP status codes.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrReadRequest),
+		errors.Is(err, ErrUnmarshalRequest),
+		errors.Is(err, ErrInvalidParameter),
+		errors.Is(err, app.ErrInvalidEvent),
+		errors.Is(err, app.ErrInvalidOwner),
+		errors.Is(err, app.ErrEmptyOwners),
+		errors.Is(err, app.ErrInvalidPeriod),
+		errors.Is(err, app.ErrInvalidRange),
+		errors.Is(err, app.ErrInvalidWorkingHours),
+		errors.Is(err, app.ErrInvalidSlotDuration),
+		errors.Is(err, app.ErrInvalidSlotsLimit),
+		errors.Is(err, app.ErrInvalidFilter),
+		errors.Is(err, app.ErrInvalidCalendar):
+		return http.StatusBadRequest
+	case errors.Is(err, auth.ErrUnauthenticated), errors.Is(err, auth.ErrInvalidToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, app.ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, app.ErrEventNotFound), errors.Is(err, app.ErrCalendarNotFound), errors.Is(err, ErrRouteNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrMethodNotAllowed):
+		return http.StatusMethodNotAllowed
+	case errors.Is(err, app.ErrRevisionExpired):
+		return http.StatusGone
+	case errors.Is(err, limits.ErrBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, limits.ErrRateLimited):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// decodeJSON reads the request body into the given value.
+func decodeJSON(request *http.Request, v interface{}) error {
+	b, err := io.ReadAll(request.Body)
+	if errors.Is(err, limits.ErrBodyTooLarge) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrReadRequest, err.Error())
+	}
+
+	if err = json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%w: %s", ErrUnmarshalRequest, err.Error())
+	}
+
+	return nil
+}
+
+// writeJSON sends the value as a JSON response with the given status code.
+func (h *RequestHandler) writeJSON(writer http.ResponseWriter, code int, v interface{}) {
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	writer.WriteHeader(code)
+
+	if v == nil {
+		return
+	}
+
+	if err := json.NewEncoder(writer).Encode(v); err != nil {
+		h.Logger.Error(err.Error())
+	}
+}
+
+// writeError sends the error wrapped into a JSON envelope.
+func (h *RequestHandler) writeError(writer http.ResponseWriter, err error) {
+	code, message := errorStatus(err), err.Error()
+	// Errors of the service carry grpc codes, mapped as the gateway does.
+	if st, ok := status.FromError(err); ok {
+		code, message = runtime.HTTPStatusFromCode(st.Code()), st.Message()
+	}
+
+	if code == http.StatusInternalServerError {
+		h.Logger.Error(message)
+	}
+
+	h.writeJSON(writer, code, errorResponse{errorBody{code, message}})
+}
+
+// allowMethods responds with 405 unless the request method is one of the given.
+func (h *RequestHandler) allowMethods(writer http.ResponseWriter, request *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if request.Method == method {
+			return true
+		}
+	}
+
+	for _, method := range methods {
+		writer.Header().Add("Allow", method)
+	}
+	h.writeError(writer, fmt.Errorf("%w: %s", ErrMethodNotAllowed, request.Method))
+
+	return false
+}
@@ -2,13 +2,26 @@ package internalhttp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net"
 	"net/http"
-
+	"strconv"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/health"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/limits"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/metrics"
+	internalgrpc "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc"
+	pb "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc/eventpb"
 	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	eventsPath = "/events"
+	// openAPIPath is served by the gateway.
+	openAPIPath = "/openapi.json"
 )
 
 type Logger interface {
@@ -29,21 +42,48 @@ type Config interface {
 }
 
 type Application interface {
-	CreateEvent(ctx context.Context, event storage.Event) (storage.Event, error)
-	UpdateEvent(ctx context.Context, event storage.Event) (storage.Event, error)
-	RemoveEvent(ctx context.Context, event storage.Event) error
-	GetDayAheadEvents(ctx context.Context) ([]storage.Event, error)
-	GetWeekAheadEvents(ctx context.Context) ([]storage.Event, error)
-	GetMonthAheadEvents(ctx context.Context) ([]storage.Event, error)
+	WatchEvents(ctx context.Context, ownerID int64, since int64, filter app.EventFilter) (<-chan storage.Change, error)
+}
+
+// Service is the calendar service the deprecated routes are translated to.
+type Service interface {
+	CreateEvent(ctx context.Context, request *pb.CreateEventRequest) (*pb.CreateEventResponse, error)
+	UpdateEvent(ctx context.Context, request *pb.UpdateEventRequest) (*pb.UpdateEventResponse, error)
+	RemoveEvent(ctx context.Context, request *pb.RemoveEventRequest) (*pb.RemoveEventResponse, error)
+	GetDayAheadEvents(ctx context.Context, request *pb.GetDayAheadEventsRequest) (*pb.GetDayAheadEventsResponse, error)
+	GetWeekAheadEvents(ctx context.Context, request *pb.GetWeekAheadEventsRequest) (*pb.GetWeekAheadEventsResponse, error)
+	GetMonthAheadEvents(ctx context.Context, request *pb.GetMonthAheadEventsRequest) (*pb.GetMonthAheadEventsResponse, error)
+	GetFreeBusy(ctx context.Context, request *pb.GetFreeBusyRequest) (*pb.GetFreeBusyResponse, error)
+	SuggestSlots(ctx context.Context, request *pb.SuggestSlotsRequest) (*pb.SuggestSlotsResponse, error)
+}
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (int64, error)
 }
 
 type RequestHandler struct {
-	App    Application
-	Logger Logger
+	App     Application
+	Service Service
+	Logger  Logger
+	Gateway http.Handler
+	// Auth authenticates requests on behalf of an owner, nil disables authentication.
+	Auth Authenticator
+	// Limiter throttles clients and caps request bodies, nil disables the limits.
+	Limiter *limits.Limiter
 }
 
-// Hello processes a root url.
+// Hello processes a root url, the rest of the paths are passed to the REST API gateway.
 func (h *RequestHandler) Hello(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != "/" {
+		if h.Gateway == nil {
+			h.writeError(writer, fmt.Errorf("%w: %s", ErrRouteNotFound, request.URL.Path))
+			return
+		}
+
+		h.Gateway.ServeHTTP(writer, request)
+		return
+	}
+
 	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
 	writer.WriteHeader(http.StatusOK)
 	_, err := writer.Write([]byte("Hello, World!"))
@@ -53,152 +93,343 @@ func (h *RequestHandler) Hello(writer http.ResponseWriter, request *http.Request
 }
 
 // Create handles creating a new event.
+//
+// Deprecated: use POST /events.
 func (h *RequestHandler) Create(writer http.ResponseWriter, request *http.Request) {
-	event := storage.Event{}
-
-	b, err := ioutil.ReadAll(request.Body)
-	if err != nil {
-		writer.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(writer).Encode(fmt.Sprintf("Unable to read the request: %q", err.Error()))
+	if !h.allowMethods(writer, request, http.MethodPost) {
 		return
 	}
 
-	if err = json.Unmarshal(b, &event); err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(writer).Encode(fmt.Sprintf("Unable to unmarshal the request: %q", err.Error()))
+	event := storage.Event{}
+	if err := decodeJSON(request, &event); err != nil {
+		h.writeError(writer, err)
 		return
 	}
 
-	event, err = h.App.CreateEvent(context.Background(), event)
+	response, err := h.Service.CreateEvent(request.Context(), &pb.CreateEventRequest{Event: internalgrpc.EventToPb(event)})
 	if err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(writer).Encode(fmt.Sprintf("Unable to save the event: %q", err.Error()))
+		h.writeError(writer, err)
 		return
 	}
 
-	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
-	writer.WriteHeader(http.StatusOK)
-	json.NewEncoder(writer).Encode(fmt.Sprintf("Event %q (%d) has been created successfully.", event.Title, event.ID))
+	message := fmt.Sprintf("Event %q (%d) has been created successfully.", response.Event.GetTitle(), response.Event.GetId())
+	h.writeJSON(writer, http.StatusOK, message)
 }
 
 // Update handles updating the event.
+//
+// Deprecated: use PUT /events/{id}.
 func (h *RequestHandler) Update(writer http.ResponseWriter, request *http.Request) {
-	b, err := ioutil.ReadAll(request.Body)
-	if err != nil {
-		writer.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(writer).Encode(fmt.Sprintf("Unable to read the request: %q", err.Error()))
+	if !h.allowMethods(writer, request, http.MethodPost) {
 		return
 	}
 
 	event := storage.Event{}
-	if err = json.Unmarshal(b, &event); err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(writer).Encode(fmt.Sprintf("Unable to unmarshal the request: %q", err.Error()))
+	if err := decodeJSON(request, &event); err != nil {
+		h.writeError(writer, err)
 		return
 	}
 
-	event, err = h.App.UpdateEvent(context.Background(), event)
+	response, err := h.Service.UpdateEvent(request.Context(), &pb.UpdateEventRequest{Event: internalgrpc.EventToPb(event)})
 	if err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(writer).Encode(fmt.Sprintf("Unable to update the event: %q", err.Error()))
+		h.writeError(writer, err)
 		return
 	}
 
-	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
-	writer.WriteHeader(http.StatusOK)
-	json.NewEncoder(writer).Encode(fmt.Sprintf("Event %q (%d) has been updated successfully.", event.Title, event.ID))
+	message := fmt.Sprintf("Event %q (%d) has been updated successfully.", response.Event.GetTitle(), response.Event.GetId())
+	h.writeJSON(writer, http.StatusOK, message)
 }
 
 // Remove handles removing the event.
+//
+// Deprecated: use DELETE /events/{id}.
 func (h *RequestHandler) Remove(writer http.ResponseWriter, request *http.Request) {
+	if !h.allowMethods(writer, request, http.MethodPost) {
+		return
+	}
+
 	event := storage.Event{}
+	if err := decodeJSON(request, &event); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	if _, err := h.Service.RemoveEvent(request.Context(), &pb.RemoveEventRequest{Id: event.ID}); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSON(writer, http.StatusOK, fmt.Sprintf("Event %d has been removed successfully.", event.ID))
+}
 
-	b, err := ioutil.ReadAll(request.Body)
+// GetDayAheadEvents returns daily events filtered by the calendar_ids, category_id and tags query parameters.
+//
+// Deprecated: use GET /events?period=day.
+func (h *RequestHandler) GetDayAheadEvents(writer http.ResponseWriter, request *http.Request) {
+	filter, err := readEventFilter(request)
 	if err != nil {
-		writer.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(writer).Encode(fmt.Sprintf("Unable to read the request: %q", err.Error()))
+		h.writeError(writer, err)
 		return
 	}
 
-	if err = json.Unmarshal(b, &event); err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(writer).Encode(fmt.Sprintf("Unable to unmarshal the request: %q", err.Error()))
+	response, err := h.Service.GetDayAheadEvents(request.Context(), &pb.GetDayAheadEventsRequest{
+		CalendarIds: filter.CalendarIDs,
+		CategoryId:  filter.CategoryID,
+		Tags:        filter.Tags,
+	})
+	if err != nil {
+		h.writeError(writer, err)
 		return
 	}
 
-	err = h.App.RemoveEvent(context.Background(), event)
+	h.writeEvents(writer, response.Items)
+}
+
+// GetWeekAheadEvents returns weekly events filtered by the calendar_ids, category_id and tags query parameters.
+//
+// Deprecated: use GET /events?period=week.
+func (h *RequestHandler) GetWeekAheadEvents(writer http.ResponseWriter, request *http.Request) {
+	filter, err := readEventFilter(request)
 	if err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(writer).Encode(fmt.Sprintf("Unable to remove the event: %q", err.Error()))
+		h.writeError(writer, err)
 		return
 	}
 
-	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
-	writer.WriteHeader(http.StatusOK)
-	json.NewEncoder(writer).Encode(fmt.Sprintf("Event %d has been removed successfully.", event.ID))
+	response, err := h.Service.GetWeekAheadEvents(request.Context(), &pb.GetWeekAheadEventsRequest{
+		CalendarIds: filter.CalendarIDs,
+		CategoryId:  filter.CategoryID,
+		Tags:        filter.Tags,
+	})
+	if err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeEvents(writer, response.Items)
 }
 
-// GetDayAheadEvents returns daily events.
-func (h *RequestHandler) GetDayAheadEvents(writer http.ResponseWriter, request *http.Request) {
-	events, err := h.App.GetDayAheadEvents(context.Background())
+// GetMonthAheadEvents returns monthly events filtered by the calendar_ids, category_id and tags query parameters.
+//
+// Deprecated: use GET /events?period=month.
+func (h *RequestHandler) GetMonthAheadEvents(writer http.ResponseWriter, request *http.Request) {
+	filter, err := readEventFilter(request)
 	if err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(writer).Encode(fmt.Sprintf("Unable to get daily events: %q", err.Error()))
+		h.writeError(writer, err)
 		return
 	}
 
-	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
-	writer.WriteHeader(http.StatusOK)
-	json.NewEncoder(writer).Encode(events)
+	response, err := h.Service.GetMonthAheadEvents(request.Context(), &pb.GetMonthAheadEventsRequest{
+		CalendarIds: filter.CalendarIDs,
+		CategoryId:  filter.CategoryID,
+		Tags:        filter.Tags,
+	})
+	if err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeEvents(writer, response.Items)
 }
 
-// GetWeekAheadEvents returns weekly events.
-func (h *RequestHandler) GetWeekAheadEvents(writer http.ResponseWriter, request *http.Request) {
-	events, err := h.App.GetWeekAheadEvents(context.Background())
+// writeEvents sends the events of the service in the storage format of the deprecated routes.
+func (h *RequestHandler) writeEvents(writer http.ResponseWriter, pbEvents []*pb.Event) {
+	events := make([]storage.Event, 0, len(pbEvents))
+	for _, pbEvent := range pbEvents {
+		event, err := internalgrpc.EventFromPb(pbEvent)
+		if err != nil {
+			h.writeError(writer, err)
+			return
+		}
+		events = append(events, event)
+	}
+
+	h.writeJSON(writer, http.StatusOK, events)
+}
+
+// readEventFilter parses the optional category_id and the repeated calendar_ids and tags query parameters,
+// named as the gateway routes name them.
+func readEventFilter(request *http.Request) (app.EventFilter, error) {
+	query := request.URL.Query()
+	filter := app.EventFilter{Tags: query["tags"]}
+
+	for _, value := range query["calendar_ids"] {
+		calendarID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("%w: calendar_ids: %s", ErrInvalidParameter, err.Error())
+		}
+		filter.CalendarIDs = append(filter.CalendarIDs, calendarID)
+	}
+
+	if categoryID := query.Get("category_id"); categoryID != "" {
+		var err error
+		if filter.CategoryID, err = strconv.ParseInt(categoryID, 10, 64); err != nil {
+			return filter, fmt.Errorf("%w: category_id: %s", ErrInvalidParameter, err.Error())
+		}
+	}
+
+	return filter, nil
+}
+
+type freeBusyRequest struct {
+	OwnerIDs        []int64   `json:"owner_ids"`
+	BeginDate       time.Time `json:"begin_date"`
+	EndDate         time.Time `json:"end_date"`
+	WorkBegin       string    `json:"work_begin"`
+	WorkEnd         string    `json:"work_end"`
+	DurationMinutes int32     `json:"duration_minutes"`
+	Limit           int32     `json:"limit"`
+	CategoryID      int64     `json:"category_id"`
+	Tags            []string  `json:"tags"`
+	CalendarIDs     []int64   `json:"calendar_ids"`
+}
+
+type ownerBusy struct {
+	OwnerID int64          `json:"owner_id"`
+	Busy    []app.Interval `json:"busy"`
+}
+
+// intervalsFromPb converts the intervals of the service into the format of the deprecated routes.
+func intervalsFromPb(pbIntervals []*pb.Interval) []app.Interval {
+	intervals := make([]app.Interval, len(pbIntervals))
+	for i, pbInterval := range pbIntervals {
+		intervals[i] = app.Interval{
+			Begin: pbInterval.BeginDate.AsTime(),
+			End:   pbInterval.EndDate.AsTime(),
+		}
+	}
+
+	return intervals
+}
+
+// FreeBusy returns busy intervals of the given owners.
+//
+// Deprecated: use POST /freebusy.
+func (h *RequestHandler) FreeBusy(writer http.ResponseWriter, request *http.Request) {
+	if !h.allowMethods(writer, request, http.MethodPost) {
+		return
+	}
+
+	query := freeBusyRequest{}
+	if err := decodeJSON(request, &query); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	busy, err := h.Service.GetFreeBusy(request.Context(), &pb.GetFreeBusyRequest{
+		OwnerIds:    query.OwnerIDs,
+		BeginDate:   timestamppb.New(query.BeginDate),
+		EndDate:     timestamppb.New(query.EndDate),
+		WorkBegin:   query.WorkBegin,
+		WorkEnd:     query.WorkEnd,
+		CategoryId:  query.CategoryID,
+		Tags:        query.Tags,
+		CalendarIds: query.CalendarIDs,
+	})
 	if err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(writer).Encode(fmt.Sprintf("Unable to get weekly events: %q", err.Error()))
+		h.writeError(writer, err)
 		return
 	}
 
-	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
-	writer.WriteHeader(http.StatusOK)
-	json.NewEncoder(writer).Encode(events)
+	response := make([]ownerBusy, 0, len(busy.Items))
+	for _, item := range busy.Items {
+		response = append(response, ownerBusy{item.OwnerId, intervalsFromPb(item.Busy)})
+	}
+
+	h.writeJSON(writer, http.StatusOK, response)
 }
 
-// GetMonthAheadEvents returns weekly events.
-func (h *RequestHandler) GetMonthAheadEvents(writer http.ResponseWriter, request *http.Request) {
-	events, err := h.App.GetMonthAheadEvents(context.Background())
+// SuggestSlots returns common free slots of the given owners.
+//
+// Deprecated: use POST /freebusy/slots.
+func (h *RequestHandler) SuggestSlots(writer http.ResponseWriter, request *http.Request) {
+	if !h.allowMethods(writer, request, http.MethodPost) {
+		return
+	}
+
+	query := freeBusyRequest{}
+	if err := decodeJSON(request, &query); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	slots, err := h.Service.SuggestSlots(request.Context(), &pb.SuggestSlotsRequest{
+		OwnerIds:        query.OwnerIDs,
+		BeginDate:       timestamppb.New(query.BeginDate),
+		EndDate:         timestamppb.New(query.EndDate),
+		WorkBegin:       query.WorkBegin,
+		WorkEnd:         query.WorkEnd,
+		DurationMinutes: query.DurationMinutes,
+		Limit:           query.Limit,
+		CategoryId:      query.CategoryID,
+		Tags:            query.Tags,
+		CalendarIds:     query.CalendarIDs,
+	})
 	if err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(writer).Encode(fmt.Sprintf("Unable to get monthly events: %q", err.Error()))
+		h.writeError(writer, err)
 		return
 	}
 
-	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
-	writer.WriteHeader(http.StatusOK)
-	json.NewEncoder(writer).Encode(events)
+	h.writeJSON(writer, http.StatusOK, intervalsFromPb(slots.Items))
 }
 
-// NewServer returns a new server instance.
-func NewServer(config Config, app Application, logger Logger) *Server {
+// NewHandler returns the calendar API routes.
+func NewHandler(
+	app Application,
+	service Service,
+	logger Logger,
+	gateway http.Handler,
+	checker *health.Checker,
+	authenticator Authenticator,
+	limiter *limits.Limiter,
+) http.Handler {
 	handler := &RequestHandler{
-		App:    app,
-		Logger: logger,
+		App:     app,
+		Service: service,
+		Logger:  logger,
+		Gateway: gateway,
+		Auth:    authenticator,
+		Limiter: limiter,
+	}
+
+	// API routes are logged, limited by address, authenticated and limited by client, in that order.
+	api := func(next http.HandlerFunc) http.HandlerFunc {
+		return loggingMiddleware(handler.addressLimitsMiddleware(handler.authMiddleware(handler.limitsMiddleware(next))), logger)
 	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", loggingMiddleware(handler.Hello, logger))
-	mux.HandleFunc("/event/create", loggingMiddleware(handler.Create, logger))
-	mux.HandleFunc("/event/update", loggingMiddleware(handler.Update, logger))
-	mux.HandleFunc("/event/remove", loggingMiddleware(handler.Remove, logger))
-	mux.HandleFunc("/event/day", loggingMiddleware(handler.GetDayAheadEvents, logger))
-	mux.HandleFunc("/event/week", loggingMiddleware(handler.GetWeekAheadEvents, logger))
-	mux.HandleFunc("/event/month", loggingMiddleware(handler.GetMonthAheadEvents, logger))
+	mux.HandleFunc("/", api(handler.Hello))
+	mux.HandleFunc(eventsPath+"/watch", api(handler.WatchEvents))
+	mux.Handle(metrics.Path, metrics.Handler())
+	mux.Handle(health.LivePath, checker.LiveHandler())
+	mux.Handle(health.ReadyPath, checker.ReadyHandler())
+
+	// Deprecated RPC-style routes, kept until clients move to the resource API.
+	mux.HandleFunc("/event/create", api(deprecatedMiddleware(handler.Create, eventsPath)))
+	mux.HandleFunc("/event/update", api(deprecatedMiddleware(handler.Update, eventsPath)))
+	mux.HandleFunc("/event/remove", api(deprecatedMiddleware(handler.Remove, eventsPath)))
+	mux.HandleFunc("/event/day", api(deprecatedMiddleware(handler.GetDayAheadEvents, eventsPath)))
+	mux.HandleFunc("/event/week", api(deprecatedMiddleware(handler.GetWeekAheadEvents, eventsPath)))
+	mux.HandleFunc("/event/month", api(deprecatedMiddleware(handler.GetMonthAheadEvents, eventsPath)))
+	mux.HandleFunc("/event/freebusy", api(deprecatedMiddleware(handler.FreeBusy, "/freebusy")))
+	mux.HandleFunc("/event/slots", api(deprecatedMiddleware(handler.SuggestSlots, "/freebusy/slots")))
+
+	return tracingMiddleware(mux)
+}
 
+// NewServer returns a new server instance.
+func NewServer(
+	config Config,
+	app Application,
+	service Service,
+	logger Logger,
+	gateway http.Handler,
+	checker *health.Checker,
+	authenticator Authenticator,
+	limiter *limits.Limiter,
+) *Server {
 	server := &http.Server{
 		Addr:    net.JoinHostPort(config.GetHTTPHost(), config.GetHTTPPort()),
-		Handler: mux,
+		Handler: NewHandler(app, service, logger, gateway, checker, authenticator, limiter),
 	}
 
 	return &Server{
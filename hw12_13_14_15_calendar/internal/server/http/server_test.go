@@ -0,0 +1,335 @@
+package internalhttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/auth"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/health"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/limits"
+	internalgrpc "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/server/grpc"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+func do(t *testing.T, handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func TestEventsAPI(t *testing.T) {
+	calendar := app.New(nopLogger{}, memorystorage.New(clock.Real{}), clock.Real{})
+	service := internalgrpc.NewService(calendar, nil)
+	gateway, err := internalgrpc.NewGatewayHandler(context.Background(), service)
+	require.NoError(t, err)
+
+	handler := NewHandler(calendar, service, nopLogger{}, gateway, health.New(), nil, nil)
+	begin := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	eventJSON := func(title string, offset time.Duration) string {
+		return fmt.Sprintf(
+			`{"title":%q,"begin_date":%q,"end_date":%q,"owner_id":1}`,
+			title,
+			begin.Add(offset).Format(time.RFC3339),
+			begin.Add(offset+time.Hour).Format(time.RFC3339),
+		)
+	}
+
+	response := do(t, handler, http.MethodPost, "/events", eventJSON("meeting", 0))
+	require.Equal(t, http.StatusCreated, response.Code)
+	created := apiEvent{}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &created))
+	require.Equal(t, "meeting", created.Title)
+	require.Equal(t, fmt.Sprintf("/events/%d", created.ID), response.Header().Get("Location"))
+
+	location := response.Header().Get("Location")
+
+	t.Run("get", func(t *testing.T) {
+		response := do(t, handler, http.MethodGet, location, "")
+		require.Equal(t, http.StatusOK, response.Code)
+		event := apiEvent{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &event))
+		require.Equal(t, created, event)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		target := fmt.Sprintf(
+			"/events?begin_date=%s&end_date=%s",
+			begin.Format(time.RFC3339),
+			begin.Add(24*time.Hour).Format(time.RFC3339),
+		)
+		for _, target := range []string{target, "/events/week"} {
+			response := do(t, handler, http.MethodGet, target, "")
+			require.Equal(t, http.StatusOK, response.Code, target)
+			list := struct {
+				Items []apiEvent `json:"items"`
+			}{}
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &list))
+			require.Equal(t, []apiEvent{created}, list.Items, target)
+		}
+	})
+
+	t.Run("filtered by tags", func(t *testing.T) {
+		// The deprecated routes name the parameters as the gateway ones do.
+		for _, target := range []string{"/events/week?tags=absent", "/event/week?tags=absent"} {
+			response := do(t, handler, http.MethodGet, target, "")
+			require.Equal(t, http.StatusOK, response.Code, target)
+			require.NotContains(t, response.Body.String(), "meeting", target)
+		}
+
+		response := do(t, handler, http.MethodGet, "/event/week?tags=", "")
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("deprecated", func(t *testing.T) {
+		response := do(t, handler, http.MethodPost, "/event/create", eventJSON("standup", 2*time.Hour))
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Contains(t, response.Body.String(), "standup")
+
+		response = do(t, handler, http.MethodGet, "/event/day", "")
+		require.Equal(t, http.StatusOK, response.Code)
+		events := []storage.Event{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &events))
+		require.Len(t, events, 2)
+
+		removed := fmt.Sprintf(`{"id":%d}`, events[0].ID+events[1].ID-created.ID)
+		response = do(t, handler, http.MethodPost, "/event/remove", removed)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		response = do(t, handler, http.MethodPost, "/event/remove", removed)
+		require.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("openapi", func(t *testing.T) {
+		response := do(t, handler, http.MethodGet, internalgrpc.OpenAPIPath, "")
+		require.Equal(t, http.StatusOK, response.Code)
+		require.JSONEq(t, string(internalgrpc.OpenAPI), response.Body.String())
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		response := do(t, handler, http.MethodPatch, location, `{"description":"agenda"}`)
+		require.Equal(t, http.StatusOK, response.Code)
+		event := apiEvent{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &event))
+		require.Equal(t, "meeting", event.Title)
+		require.Equal(t, "agenda", event.Description)
+	})
+
+	t.Run("put", func(t *testing.T) {
+		response := do(t, handler, http.MethodPut, location, eventJSON("renamed", 0))
+		require.Equal(t, http.StatusOK, response.Code)
+		event := apiEvent{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &event))
+		require.Equal(t, created.ID, event.ID)
+		require.Equal(t, "renamed", event.Title)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cases := []struct {
+			method, target, body string
+			code                 int
+		}{
+			{http.MethodPost, "/events", `{"title":`, http.StatusBadRequest},
+			{http.MethodPost, "/events", `{"title":""}`, http.StatusBadRequest},
+			{http.MethodGet, "/events/100500", "", http.StatusNotFound},
+			{http.MethodGet, "/events/abc", "", http.StatusBadRequest},
+			{http.MethodPut, "/events/100500", eventJSON("missing", 24*time.Hour), http.StatusNotFound},
+			{http.MethodPost, location, "", http.StatusMethodNotAllowed},
+			{http.MethodDelete, "/events", "", http.StatusMethodNotAllowed},
+			{http.MethodPatch, location, `{"color":"red"}`, http.StatusBadRequest},
+			{http.MethodGet, "/events", "", http.StatusBadRequest},
+			{http.MethodGet, "/unknown", "", http.StatusNotFound},
+			{http.MethodPost, "/event/create", `not json`, http.StatusBadRequest},
+			{http.MethodGet, "/event/create", "", http.StatusMethodNotAllowed},
+			{http.MethodPut, "/event/update", eventJSON("missing", 24*time.Hour), http.StatusMethodNotAllowed},
+			{http.MethodDelete, "/event/remove", "", http.StatusMethodNotAllowed},
+		}
+
+		for _, c := range cases {
+			response := do(t, handler, c.method, c.target, c.body)
+			require.Equal(t, c.code, response.Code, "%s %s", c.method, c.target)
+
+			envelope := errorResponse{}
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &envelope))
+			require.Equal(t, c.code, envelope.Error.Code)
+			require.NotEmpty(t, envelope.Error.Message)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		response := do(t, handler, http.MethodDelete, location, "")
+		require.Equal(t, http.StatusNoContent, response.Code)
+
+		response = do(t, handler, http.MethodDelete, location, "")
+		require.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+// apiEvent is an event as rendered by the gateway.
+type apiEvent struct {
+	ID          int64  `json:"id"`
+	Title       string `json:"title"`
+	BeginDate   string `json:"begin_date"`
+	EndDate     string `json:"end_date"`
+	Description string `json:"description"`
+	OwnerID     int64  `json:"owner_id"`
+}
+
+type tokenAuthenticator map[string]int64
+
+func (a tokenAuthenticator) Authenticate(ctx context.Context, token string) (int64, error) {
+	if token == "" {
+		return 0, auth.ErrUnauthenticated
+	}
+	if ownerID, ok := a[token]; ok {
+		return ownerID, nil
+	}
+
+	return 0, auth.ErrInvalidToken
+}
+
+func TestAuthentication(t *testing.T) {
+	calendar := app.New(nopLogger{}, memorystorage.New(clock.Real{}), clock.Real{})
+	service := internalgrpc.NewService(calendar, nil)
+	gateway, err := internalgrpc.NewGatewayHandler(context.Background(), service)
+	require.NoError(t, err)
+
+	handler := NewHandler(calendar, service, nopLogger{}, gateway, health.New(), tokenAuthenticator{"alice": 1, "bob": 2}, nil)
+	request := func(method, target, token, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	begin := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	body := fmt.Sprintf(
+		`{"title":"meeting","begin_date":%q,"end_date":%q,"owner_id":2}`,
+		begin.Format(time.RFC3339),
+		begin.Add(time.Hour).Format(time.RFC3339),
+	)
+
+	t.Run("missing token", func(t *testing.T) {
+		response := request(http.MethodPost, "/events", "", body)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		require.NotEmpty(t, response.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		response := request(http.MethodPost, "/events", "mallory", body)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("public routes", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, request(http.MethodGet, "/", "", "").Code)
+		require.Equal(t, http.StatusOK, request(http.MethodGet, "/openapi.json", "", "").Code)
+		require.Equal(t, http.StatusOK, request(http.MethodGet, health.LivePath, "", "").Code)
+	})
+
+	t.Run("owner scoping", func(t *testing.T) {
+		response := request(http.MethodPost, "/events", "alice", body)
+		require.Equal(t, http.StatusCreated, response.Code)
+		created := apiEvent{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &created))
+		require.Equal(t, int64(1), created.OwnerID)
+
+		location := response.Header().Get("Location")
+		require.Equal(t, http.StatusOK, request(http.MethodGet, location, "alice", "").Code)
+		require.Equal(t, http.StatusNotFound, request(http.MethodGet, location, "bob", "").Code)
+		require.Equal(t, http.StatusNotFound, request(http.MethodDelete, location, "bob", "").Code)
+	})
+}
+
+type limitsConfig struct {
+	addressBurst int
+}
+
+func (limitsConfig) GetLimitsRate() float64                  { return 1 }
+func (limitsConfig) GetLimitsBurst() int                     { return 2 }
+func (limitsConfig) GetLimitsMaxBodyBytes() int64            { return 1024 }
+func (limitsConfig) GetLimitsAddressRate() float64           { return 1 }
+func (c limitsConfig) GetLimitsAddressBurst() int            { return c.addressBurst }
+func (limitsConfig) GetLimitsRoutes() []string               { return []string{"POST /events"} }
+func (limitsConfig) GetLimitsRouteRate(string) float64       { return 0 }
+func (limitsConfig) GetLimitsRouteBurst(string) int          { return 100 }
+func (limitsConfig) GetLimitsRouteMaxBodyBytes(string) int64 { return 16 }
+
+func TestLimits(t *testing.T) {
+	calendar := app.New(nopLogger{}, memorystorage.New(clock.Real{}), clock.Real{})
+	service := internalgrpc.NewService(calendar, nil)
+	gateway, err := internalgrpc.NewGatewayHandler(context.Background(), service)
+	require.NoError(t, err)
+
+	handler := NewHandler(
+		calendar, service, nopLogger{}, gateway, health.New(), nil, limits.New(limitsConfig{addressBurst: 100}, clock.Real{}),
+	)
+
+	t.Run("rate", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			require.Equal(t, http.StatusOK, do(t, handler, http.MethodGet, "/", "").Code)
+		}
+
+		response := do(t, handler, http.MethodGet, "/", "")
+		require.Equal(t, http.StatusTooManyRequests, response.Code)
+		require.Equal(t, "1", response.Header().Get("Retry-After"))
+
+		// The limits are not shared between routes.
+		require.Equal(t, http.StatusOK, do(t, handler, http.MethodGet, "/event/day", "").Code)
+	})
+
+	t.Run("body size", func(t *testing.T) {
+		response := do(t, handler, http.MethodPost, "/events", `{"title":"far too long for the route"}`)
+		require.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+
+		// Bodies of unknown length are cut at the cap while reading.
+		request := httptest.NewRequest(http.MethodPost, "/event/create", strings.NewReader(strings.Repeat(" ", 2048)+"{}"))
+		request.ContentLength = -1
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	})
+	t.Run("address", func(t *testing.T) {
+		handler := NewHandler(
+			calendar, service, nopLogger{}, gateway, health.New(),
+			tokenAuthenticator{"alice": 1}, limits.New(limitsConfig{addressBurst: 2}, clock.Real{}),
+		)
+		request := httptest.NewRequest(http.MethodGet, "/events/day", nil)
+		request.Header.Set("Authorization", "Bearer mallory")
+
+		// Guessed tokens are throttled before they are checked.
+		for i := 0; i < 2; i++ {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusUnauthorized, recorder.Code)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		require.Equal(t, "1", recorder.Header().Get("Retry-After"))
+	})
+}
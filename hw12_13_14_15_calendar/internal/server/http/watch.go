@@ -0,0 +1,96 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// watchKeepAlive is an interval of comments keeping idle streams open through proxies.
+const watchKeepAlive = 15 * time.Second
+
+var ErrStreamingUnsupported = errors.New("streaming is not supported")
+
+// WatchEvents streams changes of the owner's events as Server-Sent Events, filtered by the calendar_ids,
+// category_id and tags query parameters.
+// Reconnecting clients resume from the Last-Event-ID header or the "revision" parameter.
+func (h *RequestHandler) WatchEvents(writer http.ResponseWriter, request *http.Request) {
+	if !h.allowMethods(writer, request, http.MethodGet) {
+		return
+	}
+
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		h.writeError(writer, ErrStreamingUnsupported)
+		return
+	}
+
+	query := request.URL.Query()
+	ownerID, err := strconv.ParseInt(query.Get("owner_id"), 10, 64)
+	if err != nil {
+		h.writeError(writer, fmt.Errorf("%w: owner_id: %s", ErrInvalidParameter, err.Error()))
+		return
+	}
+
+	revision := request.Header.Get("Last-Event-ID")
+	if revision == "" {
+		revision = query.Get("revision")
+	}
+
+	var since int64
+	if revision != "" {
+		if since, err = strconv.ParseInt(revision, 10, 64); err != nil {
+			h.writeError(writer, fmt.Errorf("%w: revision: %s", ErrInvalidParameter, err.Error()))
+			return
+		}
+	}
+
+	filter, err := readEventFilter(request)
+	if err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	changes, err := h.App.WatchEvents(request.Context(), ownerID, since, filter)
+	if err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("Connection", "keep-alive")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(watchKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(change.Event)
+			if err != nil {
+				h.Logger.Error(err.Error())
+				return
+			}
+
+			if _, err := fmt.Fprintf(writer, "id: %d\nevent: %s\ndata: %s\n\n", change.Revision, change.Type, data); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,632 @@
+// Package cachestorage is a read-through cache of events in front of another storage.
+package cachestorage
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/metrics"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	// windowAlignment widens cached ranges to whole hours, so that the views starting
+	// at the current time keep hitting the same entry for a while.
+	windowAlignment = time.Hour
+
+	// bucketSize is the span of time cached ranges are indexed by, so that a changed event only
+	// looks at the ranges around it. Ranges spanning more than maxBuckets are looked at on every change.
+	bucketSize = 24 * time.Hour
+	maxBuckets = 62
+
+	// resubscribeDelay is the first pause before subscribing to changes again after a subscription
+	// dropped right away, doubling up to maxResubscribeDelay while they keep dropping.
+	resubscribeDelay    = time.Second
+	maxResubscribeDelay = time.Minute
+
+	operationRange      = "get_events_in_range"
+	operationOwnerRange = "get_owner_events_in_range"
+	operationEvent      = "get_event_by_id"
+)
+
+// Stats are the counters of the cache since it was created.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+	// Evictions are entries dropped for the capacity or expired.
+	Evictions uint64
+	// Invalidations are entries dropped because of changes.
+	Invalidations uint64
+	Entries       int
+}
+
+// key identifies a cached event by id or a cached range of events by its bounds
+// and the owners it is limited to.
+type key struct {
+	id     int64
+	begin  int64
+	end    int64
+	owners string
+}
+
+type entry struct {
+	key   key
+	begin time.Time
+	end   time.Time
+	// owners the range is limited to, nil for all of them.
+	owners  map[int64]bool
+	events  []storage.Event
+	expires time.Time
+	element *list.Element
+}
+
+// entrySet is a set of entries of an index.
+type entrySet map[*entry]struct{}
+
+// stale collects the entries a change makes outdated, called under the lock.
+type stale func(s *Storage, outdated entrySet)
+
+type txKey struct{}
+
+// transaction collects the changes made within it, which invalidate the cache once it ends.
+type transaction struct {
+	storage *Storage
+	changes []stale
+}
+
+// Storage caches events by id and ranges of events of the wrapped storage, everything else
+// is passed through. Entries are dropped by the changes made through the cache or seen
+// in the change feed, see Watch, and expire after the ttl at the latest. Nothing is cached
+// until Watch follows the change feed.
+type Storage struct {
+	app.Storage
+	clock clock.Clock
+	size  int
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	entries map[key]*entry
+	lru     *list.List
+	// Entries are indexed by the events and the categories they hold, ranges also by the buckets
+	// they span, the too wide ones being kept aside.
+	byEvent    map[int64]entrySet
+	byCategory map[int64]entrySet
+	byBucket   map[int64]entrySet
+	wide       entrySet
+	// generation grows with every invalidation, results read before it are not cached.
+	generation uint64
+	// detached is set while the changes of other instances are not followed, nothing is cached then.
+	detached bool
+	stats    Stats
+}
+
+// New returns a cache of at most size entries in front of the storage, zero ttl keeps
+// entries until they are changed or evicted.
+func New(inner app.Storage, clock clock.Clock, size int, ttl time.Duration) *Storage {
+	return &Storage{
+		Storage: inner,
+		clock:   clock,
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[key]*entry),
+		lru:     list.New(),
+
+		byEvent:    make(map[int64]entrySet),
+		byCategory: make(map[int64]entrySet),
+		byBucket:   make(map[int64]entrySet),
+		wide:       make(entrySet),
+		detached:   true,
+	}
+}
+
+// Stats returns the counters of the cache.
+func (s *Storage) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.Entries = s.lru.Len()
+
+	return stats
+}
+
+// Watch drops the entries changed by other instances sharing the storage until ctx is done.
+// Everything is dropped on subscribing, changes made before it might have been missed,
+// so also when the change feed disconnects a lagging subscriber. Nothing is cached until
+// subscribed again, subscriptions dropping right away being retried after growing pauses,
+// nor once ctx is done.
+func (s *Storage) Watch(ctx context.Context) {
+	var delay time.Duration
+	for {
+		changes, release := s.Storage.SubscribeChanges()
+		s.detach(false)
+		subscribed := s.clock.Now()
+		s.follow(ctx, changes)
+		release()
+		s.detach(true)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		switch {
+		case s.clock.Now().Sub(subscribed) >= maxResubscribeDelay:
+			delay = 0
+			continue
+		case delay == 0:
+			delay = resubscribeDelay
+		case delay < maxResubscribeDelay:
+			delay *= 2
+			if delay > maxResubscribeDelay {
+				delay = maxResubscribeDelay
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(delay):
+		}
+	}
+}
+
+func (s *Storage) follow(ctx context.Context, changes <-chan storage.Change) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+
+			switch change.Type {
+			case storage.EventCreated, storage.EventUpdated:
+				s.invalidate(eventChanged(change.Event))
+			case storage.EventDeleted:
+				s.invalidate(eventRemoved(change.Event.ID))
+			default:
+				s.invalidate(all)
+			}
+		}
+	}
+}
+
+// CreateEvent saves the event, dropping the cached ranges it falls into.
+func (s *Storage) CreateEvent(ctx context.Context, event storage.Event) (storage.Event, error) {
+	created, err := s.Storage.CreateEvent(ctx, event)
+	if err == nil {
+		s.changed(ctx, eventChanged(created))
+	}
+
+	return created, err
+}
+
+// UpdateEvent updates the event, dropping the cached entries holding it or overlapping it.
+func (s *Storage) UpdateEvent(ctx context.Context, event storage.Event) (storage.Event, error) {
+	updated, err := s.Storage.UpdateEvent(ctx, event)
+	if err == nil {
+		s.changed(ctx, eventChanged(updated))
+	}
+
+	return updated, err
+}
+
+// RemoveEvent removes the event, dropping the cached entries holding it.
+func (s *Storage) RemoveEvent(ctx context.Context, event storage.Event) error {
+	err := s.Storage.RemoveEvent(ctx, event)
+	if err == nil {
+		s.changed(ctx, eventRemoved(event.ID))
+	}
+
+	return err
+}
+
+// RemoveExpiredEvents removes the expired events, dropping the whole cache.
+func (s *Storage) RemoveExpiredEvents(ctx context.Context, retention time.Duration) error {
+	err := s.Storage.RemoveExpiredEvents(ctx, retention)
+	if err == nil {
+		s.changed(ctx, all)
+	}
+
+	return err
+}
+
+// RemoveCategory removes the category, dropping the cached entries holding its events.
+func (s *Storage) RemoveCategory(ctx context.Context, id int64) error {
+	err := s.Storage.RemoveCategory(ctx, id)
+	if err == nil {
+		s.changed(ctx, categoryRemoved(id))
+	}
+
+	return err
+}
+
+// GetEventsInRange returns events overlapping the [begin, end) period, reading the whole hours
+// around it from the storage on a miss.
+func (s *Storage) GetEventsInRange(ctx context.Context, begin, end time.Time) ([]storage.Event, error) {
+	if s.inTransaction(ctx) {
+		return s.Storage.GetEventsInRange(ctx, begin, end)
+	}
+
+	windowBegin, windowEnd := align(begin, end)
+	k := key{begin: windowBegin.UnixNano(), end: windowEnd.UnixNano()}
+
+	events, ok, generation := s.get(k, operationRange)
+	if !ok {
+		var err error
+		if events, err = s.Storage.GetEventsInRange(ctx, windowBegin, windowEnd); err != nil {
+			return nil, err
+		}
+
+		s.put(&entry{key: k, begin: windowBegin, end: windowEnd, events: events}, generation)
+	}
+
+	return inRange(events, begin, end), nil
+}
+
+// GetOwnerEventsInRange returns events of the owners overlapping the [begin, end) period,
+// reading the whole hours around it from the storage on a miss.
+func (s *Storage) GetOwnerEventsInRange(
+	ctx context.Context,
+	begin, end time.Time,
+	ownerIDs ...int64,
+) ([]storage.Event, error) {
+	if s.inTransaction(ctx) {
+		return s.Storage.GetOwnerEventsInRange(ctx, begin, end, ownerIDs...)
+	}
+
+	owners := make(map[int64]bool, len(ownerIDs))
+	for _, ownerID := range ownerIDs {
+		owners[ownerID] = true
+	}
+
+	sorted := make([]int64, 0, len(owners))
+	for ownerID := range owners {
+		sorted = append(sorted, ownerID)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	windowBegin, windowEnd := align(begin, end)
+	k := key{begin: windowBegin.UnixNano(), end: windowEnd.UnixNano(), owners: fmt.Sprint(sorted)}
+
+	events, ok, generation := s.get(k, operationOwnerRange)
+	if !ok {
+		var err error
+		if events, err = s.Storage.GetOwnerEventsInRange(ctx, windowBegin, windowEnd, sorted...); err != nil {
+			return nil, err
+		}
+
+		s.put(&entry{key: k, begin: windowBegin, end: windowEnd, owners: owners, events: events}, generation)
+	}
+
+	return inRange(events, begin, end), nil
+}
+
+// GetDayAheadEvents returns a day events slice.
+func (s *Storage) GetDayAheadEvents(ctx context.Context) ([]storage.Event, error) {
+	now := s.clock.Now()
+	return s.GetEventsInRange(ctx, now, now.AddDate(0, 0, 1))
+}
+
+// GetWeekAheadEvents returns a week events slice.
+func (s *Storage) GetWeekAheadEvents(ctx context.Context) ([]storage.Event, error) {
+	now := s.clock.Now()
+	return s.GetEventsInRange(ctx, now, now.AddDate(0, 0, 7))
+}
+
+// GetMonthAheadEvents returns a month events slice.
+func (s *Storage) GetMonthAheadEvents(ctx context.Context) ([]storage.Event, error) {
+	now := s.clock.Now()
+	return s.GetEventsInRange(ctx, now, now.AddDate(0, 1, 0))
+}
+
+// GetEventByID returns event by id, if exists.
+func (s *Storage) GetEventByID(ctx context.Context, id int64) (storage.Event, error) {
+	if s.inTransaction(ctx) {
+		return s.Storage.GetEventByID(ctx, id)
+	}
+
+	k := key{id: id}
+
+	events, ok, generation := s.get(k, operationEvent)
+	if ok {
+		return events[0], nil
+	}
+
+	event, err := s.Storage.GetEventByID(ctx, id)
+	if err != nil {
+		return event, err
+	}
+
+	s.put(&entry{key: k, events: []storage.Event{event}}, generation)
+
+	return event, nil
+}
+
+// WithinTransaction runs fn in a transaction of the storage. Reads within it bypass the cache,
+// the changes made by fn invalidate it once the transaction ends.
+func (s *Storage) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.inTransaction(ctx) {
+		return s.Storage.WithinTransaction(ctx, fn)
+	}
+
+	tx := &transaction{storage: s}
+	err := s.Storage.WithinTransaction(context.WithValue(ctx, txKey{}, tx), fn)
+
+	// Changes of a rolled back transaction drop a few entries for nothing, which is harmless.
+	for _, change := range tx.changes {
+		s.invalidate(change)
+	}
+
+	return err
+}
+
+func (s *Storage) inTransaction(ctx context.Context) bool {
+	tx, ok := ctx.Value(txKey{}).(*transaction)
+	return ok && tx.storage == s
+}
+
+// changed invalidates the cache right away or, within a transaction, once it ends.
+func (s *Storage) changed(ctx context.Context, change stale) {
+	if tx, ok := ctx.Value(txKey{}).(*transaction); ok && tx.storage == s {
+		tx.changes = append(tx.changes, change)
+		return
+	}
+
+	s.invalidate(change)
+}
+
+// get returns the cached events along with the generation a miss is to be filled at.
+func (s *Storage) get(k key, operation string) ([]storage.Event, bool, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cached, ok := s.entries[k]; ok {
+		if s.ttl == 0 || s.clock.Now().Before(cached.expires) {
+			s.lru.MoveToFront(cached.element)
+			s.stats.Hits++
+			metrics.StorageCacheLookups.WithLabelValues(operation, metrics.CacheHit).Inc()
+
+			return cached.events, true, s.generation
+		}
+
+		s.remove(cached)
+		s.stats.Evictions++
+	}
+
+	s.stats.Misses++
+	metrics.StorageCacheLookups.WithLabelValues(operation, metrics.CacheMiss).Inc()
+
+	return nil, false, s.generation
+}
+
+// put caches the entry unless the cache was invalidated since the entry was read.
+func (s *Storage) put(cached *entry, generation uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if generation != s.generation || s.detached {
+		return
+	}
+
+	cached.expires = s.clock.Now().Add(s.ttl)
+
+	if current, ok := s.entries[cached.key]; ok {
+		s.remove(current)
+	}
+
+	cached.element = s.lru.PushFront(cached)
+	s.entries[cached.key] = cached
+	s.index(cached)
+
+	for s.lru.Len() > s.size {
+		s.remove(s.lru.Back().Value.(*entry))
+		s.stats.Evictions++
+	}
+}
+
+// detach stops or resumes caching, dropping everything as changes might have been missed.
+func (s *Storage) detach(detached bool) {
+	s.mu.Lock()
+	s.detached = detached
+	s.mu.Unlock()
+
+	s.invalidate(all)
+}
+
+// invalidate drops the entries outdated by the change.
+func (s *Storage) invalidate(change stale) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Results being read right now might miss the change, so they are not cached either.
+	s.generation++
+
+	outdated := make(entrySet)
+	change(s, outdated)
+
+	for cached := range outdated {
+		s.remove(cached)
+	}
+
+	dropped := uint64(len(outdated))
+	s.stats.Invalidations += dropped
+	metrics.StorageCacheInvalidations.Add(float64(dropped))
+}
+
+// index adds the entry to the indexes, must be called under the lock.
+func (s *Storage) index(cached *entry) {
+	for _, event := range cached.events {
+		addTo(s.byEvent, event.ID, cached)
+		if event.CategoryID != 0 {
+			addTo(s.byCategory, event.CategoryID, cached)
+		}
+	}
+
+	if cached.key.id != 0 {
+		return
+	}
+
+	if buckets, ok := bucketsOf(cached.begin, cached.end, false); ok {
+		for _, bucket := range buckets {
+			addTo(s.byBucket, bucket, cached)
+		}
+	} else {
+		s.wide[cached] = struct{}{}
+	}
+}
+
+// remove drops the entry along with its index records, must be called under the lock.
+func (s *Storage) remove(cached *entry) {
+	for _, event := range cached.events {
+		removeFrom(s.byEvent, event.ID, cached)
+		removeFrom(s.byCategory, event.CategoryID, cached)
+	}
+
+	if cached.key.id == 0 {
+		if buckets, ok := bucketsOf(cached.begin, cached.end, false); ok {
+			for _, bucket := range buckets {
+				removeFrom(s.byBucket, bucket, cached)
+			}
+		}
+		delete(s.wide, cached)
+	}
+
+	delete(s.entries, cached.key)
+	s.lru.Remove(cached.element)
+}
+
+func addTo(index map[int64]entrySet, id int64, cached *entry) {
+	set, ok := index[id]
+	if !ok {
+		set = make(entrySet)
+		index[id] = set
+	}
+	set[cached] = struct{}{}
+}
+
+func removeFrom(index map[int64]entrySet, id int64, cached *entry) {
+	if set, ok := index[id]; ok {
+		delete(set, cached)
+		if len(set) == 0 {
+			delete(index, id)
+		}
+	}
+}
+
+// bucketsOf returns the buckets the period spans, the one of its end included when it is closed.
+// Periods spanning more than maxBuckets are not split.
+func bucketsOf(begin, end time.Time, closed bool) ([]int64, bool) {
+	var buckets []int64
+	for bucket := begin.Truncate(bucketSize); bucket.Before(end) || closed && bucket.Equal(end); {
+		if len(buckets) == maxBuckets {
+			return nil, false
+		}
+		buckets = append(buckets, bucket.Unix())
+
+		if bucket = bucket.Add(bucketSize); closed && bucket.After(end) {
+			break
+		}
+	}
+
+	return buckets, true
+}
+
+func all(s *Storage, outdated entrySet) {
+	for _, cached := range s.entries {
+		outdated[cached] = struct{}{}
+	}
+}
+
+// eventChanged outdates the entries holding the event, which covers its former state,
+// and the ranges of its owner overlapping its current state.
+func eventChanged(event storage.Event) stale {
+	held := eventRemoved(event.ID)
+
+	return func(s *Storage, outdated entrySet) {
+		held(s, outdated)
+
+		overlapping := func(candidates entrySet) {
+			for cached := range candidates {
+				if (cached.owners == nil || cached.owners[event.OwnerID]) && overlaps(event, cached.begin, cached.end) {
+					outdated[cached] = struct{}{}
+				}
+			}
+		}
+
+		overlapping(s.wide)
+
+		buckets, ok := bucketsOf(event.BeginDate, endOf(event), true)
+		if !ok {
+			for _, candidates := range s.byBucket {
+				overlapping(candidates)
+			}
+			return
+		}
+
+		for _, bucket := range buckets {
+			overlapping(s.byBucket[bucket])
+		}
+	}
+}
+
+// eventRemoved outdates the entries holding the event.
+func eventRemoved(id int64) stale {
+	return func(s *Storage, outdated entrySet) {
+		for cached := range s.byEvent[id] {
+			outdated[cached] = struct{}{}
+		}
+	}
+}
+
+// categoryRemoved outdates the entries holding the events of the category.
+func categoryRemoved(id int64) stale {
+	return func(s *Storage, outdated entrySet) {
+		for cached := range s.byCategory[id] {
+			outdated[cached] = struct{}{}
+		}
+	}
+}
+
+// inRange returns the events overlapping the [begin, end) period.
+func inRange(events []storage.Event, begin, end time.Time) []storage.Event {
+	var result []storage.Event
+	for _, event := range events {
+		if overlaps(event, begin, end) {
+			result = append(result, event)
+		}
+	}
+
+	return result
+}
+
+// align widens the period to whole hours.
+func align(begin, end time.Time) (time.Time, time.Time) {
+	alignedEnd := end.Truncate(windowAlignment)
+	if alignedEnd.Before(end) {
+		alignedEnd = alignedEnd.Add(windowAlignment)
+	}
+
+	return begin.Truncate(windowAlignment), alignedEnd
+}
+
+// overlaps reports whether the event intersects the [begin, end) period the way the storages do.
+func overlaps(event storage.Event, begin, end time.Time) bool {
+	return event.BeginDate.Before(end) && endOf(event).After(begin)
+}
+
+// endOf returns the end of the event, events without an end date being treated as instant ones.
+func endOf(event storage.Event) time.Time {
+	if event.EndDate.Before(event.BeginDate) {
+		return event.BeginDate
+	}
+
+	return event.EndDate
+}
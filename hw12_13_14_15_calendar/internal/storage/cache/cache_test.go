@@ -0,0 +1,320 @@
+package cachestorage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// droppingFeed is a storage whose change subscriptions are dropped right away, telling of every one.
+type droppingFeed struct {
+	app.Storage
+	subscribed chan struct{}
+}
+
+func (f droppingFeed) SubscribeChanges() (<-chan storage.Change, func()) {
+	f.subscribed <- struct{}{}
+
+	changes := make(chan storage.Change)
+	close(changes)
+
+	return changes, func() {}
+}
+
+// quietFeed is a storage changed by nobody else, its change subscriptions never delivering.
+type quietFeed struct {
+	app.Storage
+}
+
+func (quietFeed) SubscribeChanges() (<-chan storage.Change, func()) {
+	return make(chan storage.Change), func() {}
+}
+
+// newWatched returns a cache following the change feed of the storage until the test ends, once subscribed.
+func newWatched(t *testing.T, inner app.Storage, clk clock.Clock, size int, ttl time.Duration) *Storage {
+	t.Helper()
+
+	cache := New(inner, clk, size, ttl)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go cache.Watch(ctx)
+
+	require.Eventually(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+
+		return !cache.detached
+	}, time.Second, time.Millisecond)
+
+	return cache
+}
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, clk clock.Clock) app.Storage {
+		return newWatched(t, memorystorage.New(clk), clk, 100, time.Minute)
+	})
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, time.November, 2, 9, 10, 0, 0, time.UTC)
+
+	newEvent := func(title string, begin time.Time) storage.Event {
+		return storage.Event{Title: title, BeginDate: begin, EndDate: begin.Add(time.Hour), OwnerID: 1}
+	}
+
+	titles := func(events []storage.Event) []string {
+		result := make([]string, 0, len(events))
+		for _, event := range events {
+			result = append(result, event.Title)
+		}
+
+		return result
+	}
+
+	t.Run("hits", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		inner := memorystorage.New(clk)
+		cache := newWatched(t, quietFeed{inner}, clk, 10, time.Hour)
+
+		_, err := inner.CreateEvent(ctx, newEvent("standup", start.Add(10*time.Minute)))
+		require.NoError(t, err)
+		_, err = inner.CreateEvent(ctx, newEvent("lunch", start.Add(3*time.Hour)))
+		require.NoError(t, err)
+
+		events, err := cache.GetDayAheadEvents(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"standup", "lunch"}, titles(events))
+
+		// The view moved on within the same hour is served from the same entry.
+		clk.Advance(30 * time.Minute)
+		events, err = cache.GetDayAheadEvents(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"standup", "lunch"}, titles(events))
+
+		events, err = cache.GetEventsInRange(ctx, start.Add(2*time.Hour), start.Add(4*time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, []string{"lunch"}, titles(events))
+
+		require.Equal(t, Stats{Hits: 1, Misses: 2, Entries: 2}, cache.Stats())
+	})
+
+	t.Run("invalidation", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		cache := newWatched(t, quietFeed{memorystorage.New(clk)}, clk, 10, time.Minute)
+
+		standup, err := cache.CreateEvent(ctx, newEvent("standup", start.Add(time.Hour)))
+		require.NoError(t, err)
+
+		_, err = cache.GetEventByID(ctx, standup.ID)
+		require.NoError(t, err)
+		_, err = cache.GetDayAheadEvents(ctx)
+		require.NoError(t, err)
+		_, err = cache.GetEventsInRange(ctx, start.AddDate(0, 0, 2), start.AddDate(0, 0, 3))
+		require.NoError(t, err)
+
+		// Only the entries holding the event or overlapping it are dropped.
+		standup.Title = "retro"
+		_, err = cache.UpdateEvent(ctx, standup)
+		require.NoError(t, err)
+		require.Equal(t, 1, cache.Stats().Entries)
+
+		stored, err := cache.GetEventByID(ctx, standup.ID)
+		require.NoError(t, err)
+		require.Equal(t, "retro", stored.Title)
+
+		// Moving the event away drops the range it was in before.
+		events, err := cache.GetDayAheadEvents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"retro"}, titles(events))
+
+		standup.BeginDate = start.AddDate(0, 0, 5)
+		standup.EndDate = standup.BeginDate.Add(time.Hour)
+		_, err = cache.UpdateEvent(ctx, standup)
+		require.NoError(t, err)
+
+		events, err = cache.GetDayAheadEvents(ctx)
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		require.NoError(t, cache.RemoveEvent(ctx, standup))
+		_, err = cache.GetEventByID(ctx, standup.ID)
+		require.ErrorIs(t, err, storage.ErrEventNotFound)
+		require.Equal(t, uint64(4), cache.Stats().Invalidations)
+	})
+
+	t.Run("owners", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		cache := newWatched(t, quietFeed{memorystorage.New(clk)}, clk, 10, time.Minute)
+		end := start.AddDate(0, 0, 1)
+
+		_, err := cache.GetOwnerEventsInRange(ctx, start, end, 1, 3)
+		require.NoError(t, err)
+		_, err = cache.GetEventsInRange(ctx, start, end)
+		require.NoError(t, err)
+
+		// Owners given in another order share the entry.
+		_, err = cache.GetOwnerEventsInRange(ctx, start, end, 3, 1, 3)
+		require.NoError(t, err)
+		require.Equal(t, Stats{Hits: 1, Misses: 2, Entries: 2}, cache.Stats())
+
+		// Events of other owners keep the ranges of these ones.
+		other := newEvent("other", start.Add(time.Hour))
+		other.OwnerID = 2
+		_, err = cache.CreateEvent(ctx, other)
+		require.NoError(t, err)
+		require.Equal(t, 1, cache.Stats().Entries)
+
+		_, err = cache.CreateEvent(ctx, newEvent("own", start.Add(time.Hour)))
+		require.NoError(t, err)
+
+		events, err := cache.GetOwnerEventsInRange(ctx, start, end, 1, 3)
+		require.NoError(t, err)
+		require.Equal(t, []string{"own"}, titles(events))
+	})
+
+	t.Run("transactions", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		cache := newWatched(t, quietFeed{memorystorage.New(clk)}, clk, 10, time.Minute)
+
+		_, err := cache.GetDayAheadEvents(ctx)
+		require.NoError(t, err)
+
+		rollback := errors.New("rollback")
+		err = cache.WithinTransaction(ctx, func(ctx context.Context) error {
+			if _, err := cache.CreateEvent(ctx, newEvent("draft", start.Add(time.Hour))); err != nil {
+				return err
+			}
+
+			// Reads within a transaction see its changes and are never cached.
+			events, err := cache.GetDayAheadEvents(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []string{"draft"}, titles(events))
+
+			return rollback
+		})
+		require.ErrorIs(t, err, rollback)
+
+		events, err := cache.GetDayAheadEvents(ctx)
+		require.NoError(t, err)
+		require.Empty(t, events)
+		require.Equal(t, Stats{Misses: 2, Invalidations: 1, Entries: 1}, cache.Stats())
+	})
+
+	t.Run("change feed", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		inner := memorystorage.New(clk)
+		cache := New(inner, clk, 10, time.Minute)
+
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go cache.Watch(watchCtx)
+
+		_, err := cache.GetDayAheadEvents(ctx)
+		require.NoError(t, err)
+
+		// Another instance writing to the shared storage.
+		_, err = inner.CreateEvent(ctx, newEvent("standup", start.Add(time.Hour)))
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			events, err := cache.GetDayAheadEvents(ctx)
+			return err == nil && len(events) == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("unwatched", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		cache := New(memorystorage.New(clk), clk, 10, time.Minute)
+
+		// Nothing is cached before the changes are followed.
+		for i := 0; i < 2; i++ {
+			_, err := cache.GetDayAheadEvents(ctx)
+			require.NoError(t, err)
+		}
+		require.Equal(t, Stats{Misses: 2}, cache.Stats())
+	})
+
+	t.Run("dropped change feed", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		feed := droppingFeed{Storage: memorystorage.New(clk), subscribed: make(chan struct{})}
+		cache := New(feed, clk, 10, time.Minute)
+
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go cache.Watch(watchCtx)
+		<-feed.subscribed
+
+		// Subscribing again waits for the clock rather than spinning.
+		select {
+		case <-feed.subscribed:
+			t.Fatal("subscribed again right away")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		// Nothing is cached while the changes are not followed.
+		for i := 0; i < 2; i++ {
+			_, err := cache.GetDayAheadEvents(ctx)
+			require.NoError(t, err)
+		}
+		require.Zero(t, cache.Stats().Hits)
+
+		require.Eventually(t, func() bool {
+			clk.Advance(resubscribeDelay)
+			select {
+			case <-feed.subscribed:
+				return true
+			default:
+				return false
+			}
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("wide ranges and long events", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		cache := newWatched(t, quietFeed{memorystorage.New(clk)}, clk, 10, time.Minute)
+
+		_, err := cache.GetEventsInRange(ctx, start, start.AddDate(0, 0, 1))
+		require.NoError(t, err)
+		_, err = cache.GetEventsInRange(ctx, start.AddDate(0, 0, 10), start.AddDate(0, 0, 11))
+		require.NoError(t, err)
+		_, err = cache.GetEventsInRange(ctx, start.AddDate(0, -6, 0), start.AddDate(0, 6, 0))
+		require.NoError(t, err)
+
+		// An event far from the daily ranges only drops the wide one.
+		_, err = cache.CreateEvent(ctx, newEvent("later", start.AddDate(0, 3, 0)))
+		require.NoError(t, err)
+		require.Equal(t, 2, cache.Stats().Entries)
+
+		// A long event drops every range it overlaps.
+		vacation := newEvent("vacation", start.AddDate(0, 0, -30))
+		vacation.EndDate = start.AddDate(0, 0, 60)
+		_, err = cache.CreateEvent(ctx, vacation)
+		require.NoError(t, err)
+		require.Zero(t, cache.Stats().Entries)
+	})
+
+	t.Run("expiry and eviction", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		cache := newWatched(t, quietFeed{memorystorage.New(clk)}, clk, 2, time.Minute)
+
+		for day := 0; day < 3; day++ {
+			_, err := cache.GetEventsInRange(ctx, start.AddDate(0, 0, day), start.AddDate(0, 0, day+1))
+			require.NoError(t, err)
+		}
+		require.Equal(t, Stats{Misses: 3, Evictions: 1, Entries: 2}, cache.Stats())
+
+		clk.Advance(time.Minute)
+		_, err := cache.GetEventsInRange(ctx, start.AddDate(0, 0, 2), start.AddDate(0, 0, 3))
+		require.NoError(t, err)
+		require.Equal(t, Stats{Misses: 4, Evictions: 2, Entries: 2}, cache.Stats())
+	})
+}
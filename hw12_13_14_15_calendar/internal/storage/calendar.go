@@ -0,0 +1,69 @@
+package storage
+
+import "errors"
+
+var (
+	ErrCalendarNotFound = errors.New("calendar not found")
+	ErrCalendarExists   = errors.New("owner already has a default calendar")
+	ErrCalendarNotEmpty = errors.New("calendar still has events")
+	ErrGrantNotFound    = errors.New("grant not found")
+)
+
+// Access is a level of access to a calendar, each level allowing everything the lower ones do.
+type Access string
+
+const (
+	AccessNone Access = ""
+	// AccessFreeBusy only lets the busy intervals of the calendar be seen.
+	AccessFreeBusy Access = "free_busy"
+	// AccessRead lets the events of the calendar be seen.
+	AccessRead Access = "read"
+	// AccessWrite lets the events of the calendar be created, changed and removed.
+	AccessWrite Access = "write"
+	// AccessOwner also lets the calendar itself be changed and shared, it is never granted.
+	AccessOwner Access = "owner"
+)
+
+var accessLevels = map[Access]int{
+	AccessFreeBusy: 1,
+	AccessRead:     2,
+	AccessWrite:    3,
+	AccessOwner:    4,
+}
+
+// Allows reports whether the access is enough for the required one.
+func (a Access) Allows(required Access) bool {
+	return accessLevels[a] >= accessLevels[required]
+}
+
+// Valid reports whether the access is a known level.
+func (a Access) Valid() bool {
+	_, ok := accessLevels[a]
+	return ok
+}
+
+// Calendar holds events of an owner, e.g. work, personal or team ones.
+type Calendar struct {
+	ID      int64  `db:"id" json:"id"`
+	OwnerID int64  `db:"owner_id" json:"owner_id"`
+	Name    string `db:"name" json:"name"`
+	// Default calendar of the owner gets the events created without a calendar.
+	Default bool `db:"is_default" json:"default,omitempty"`
+	// Access is what the request listing the calendar may do with it, it is not stored.
+	Access Access `db:"-" json:"access,omitempty"`
+}
+
+// Grant shares a calendar with another user.
+type Grant struct {
+	CalendarID int64 `db:"calendar_id" json:"calendar_id"`
+	// OwnerID is the owner of the calendar.
+	OwnerID   int64  `db:"owner_id" json:"owner_id"`
+	GranteeID int64  `db:"grantee_id" json:"grantee_id"`
+	Access    Access `db:"access" json:"access"`
+}
+
+// GrantFilter selects grants, zero fields matching any.
+type GrantFilter struct {
+	CalendarID int64
+	GranteeID  int64
+}
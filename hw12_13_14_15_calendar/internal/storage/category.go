@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrCategoryExists   = errors.New("category with that name already exists")
+)
+
+// Category groups events of an owner, e.g. on-call duties or customer meetings.
+type Category struct {
+	ID      int64  `db:"id" json:"id"`
+	OwnerID int64  `db:"owner_id" json:"owner_id"`
+	Name    string `db:"name" json:"name"`
+	// Color is a "#rrggbb" hex color, empty for none.
+	Color string `db:"color" json:"color,omitempty"`
+	// Reminders are the default reminder offsets of the events created in the category,
+	// none falling back to the calendar defaults.
+	Reminders Offsets `db:"reminders" json:"reminders,omitempty"`
+}
+
+// Offsets are durations stored as a JSON array of nanoseconds.
+type Offsets []time.Duration
+
+// Value implements driver.Valuer.
+func (o Offsets) Value() (driver.Value, error) {
+	if o == nil {
+		return "[]", nil
+	}
+
+	data, err := json.Marshal([]time.Duration(o))
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner.
+func (o *Offsets) Scan(src interface{}) error {
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		*o = nil
+		return nil
+	case string:
+		data = []byte(src)
+	case []byte:
+		data = src
+	default:
+		return fmt.Errorf("unsupported offsets type %T", src)
+	}
+
+	var offsets []time.Duration
+	if err := json.Unmarshal(data, &offsets); err != nil {
+		return err
+	}
+
+	*o = nil
+	if len(offsets) > 0 {
+		*o = offsets
+	}
+
+	return nil
+}
+
+// EqualTags reports whether the sorted tags are the same.
+func EqualTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+)
+
+const (
+	EventCreated ChangeType = "created"
+	EventUpdated ChangeType = "updated"
+	EventDeleted ChangeType = "deleted"
+)
+
+// changeBufferSize is a number of changes a slow subscriber may lag behind
+// before it gets disconnected.
+const changeBufferSize = 256
+
+var ErrRevisionExpired = errors.New("revision is too old to resume from")
+
+type ChangeType string
+
+// Change is a single modification of an event. Revisions grow monotonically
+// and identify a position in the change stream, changes becoming visible in their order.
+//
+// The event of a change may be newer than the change itself: the sql storage fills in
+// the current row of the event when the change is read, and only the id and the owner
+// of a deleted one. The memory storage keeps the state of the event as changed.
+type Change struct {
+	Revision int64      `json:"revision"`
+	Type     ChangeType `json:"type"`
+	Event    Event      `json:"event"`
+}
+
+// ChangeFeed fans changes out to live subscribers.
+type ChangeFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan Change]struct{}
+}
+
+// NewChangeFeed returns a feed without subscribers.
+func NewChangeFeed() *ChangeFeed {
+	return &ChangeFeed{
+		subscribers: make(map[chan Change]struct{}),
+	}
+}
+
+// Subscribe returns a channel of changes published from now on and a func releasing it.
+// The channel is closed when the subscriber falls too far behind.
+func (f *ChangeFeed) Subscribe() (<-chan Change, func()) {
+	ch := make(chan Change, changeBufferSize)
+
+	f.mu.Lock()
+	f.subscribers[ch] = struct{}{}
+	f.mu.Unlock()
+
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if _, ok := f.subscribers[ch]; ok {
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish delivers the change to every subscriber without blocking.
+func (f *ChangeFeed) Publish(change Change) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subscribers {
+		select {
+		case ch <- change:
+		default:
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+}
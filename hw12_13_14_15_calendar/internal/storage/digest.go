@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrDigestNotFound = errors.New("digest subscription not found")
+
+// Periods covered by digests.
+const (
+	DigestDaily  = "day"
+	DigestWeekly = "week"
+)
+
+// DigestSubscription asks for an agenda of the owner's upcoming day or week at a local time.
+type DigestSubscription struct {
+	OwnerID int64  `db:"owner_id" json:"owner_id"`
+	Period  string `db:"period" json:"period"`
+	// Time is the local time of the day to send the digest at, "15:04" formatted.
+	Time string `db:"send_time" json:"time"`
+	// TimeZone is an IANA time zone name, UTC when empty.
+	TimeZone string `db:"time_zone" json:"time_zone"`
+	// Weekday is the day to send weekly digests on, Sunday being zero.
+	Weekday    int        `db:"weekday" json:"weekday"`
+	LastSentAt *time.Time `db:"last_sent_at" json:"last_sent_at,omitempty"`
+}
@@ -1,14 +1,29 @@
 package storage
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+var ErrEventNotFound = errors.New("event not found")
 
 type Event struct {
-	ID                   int64     `db:"id" json:"id"`
-	Title                string    `db:"title" json:"title"`
-	BeginDate            time.Time `db:"begin_date" json:"begin_date"`
-	EndDate              time.Time `db:"end_date" json:"end_date"`
-	Description          string    `db:"description" json:"description"`
-	OwnerID              int64     `db:"owner_id" json:"owner_id"`
-	NotificationSent     bool      `db:"notification_sent" json:"notification_sent"`
-	NotificationReceived bool      `db:"notification_received" json:"notification_received"`
+	ID          int64     `db:"id" json:"id"`
+	Title       string    `db:"title" json:"title"`
+	BeginDate   time.Time `db:"begin_date" json:"begin_date"`
+	EndDate     time.Time `db:"end_date" json:"end_date"`
+	Description string    `db:"description" json:"description"`
+	OwnerID     int64     `db:"owner_id" json:"owner_id"`
+	// NotificationSent and NotificationReceived tell whether a reminder of the event has been published
+	// and delivered. The storage sets them as the notifications go, they are not written with the event.
+	NotificationSent     bool `db:"notification_sent" json:"notification_sent"`
+	NotificationReceived bool `db:"notification_received" json:"notification_received"`
+	// CalendarID is the calendar holding the event, its owner being the owner of the event.
+	CalendarID int64 `db:"calendar_id" json:"calendar_id,omitempty"`
+	// CategoryID is the category of the event, zero for none.
+	CategoryID int64 `db:"category_id" json:"category_id,omitempty"`
+	// Tags are free-form labels of the event, stored on their own.
+	Tags []string `db:"-" json:"tags,omitempty"`
+	// Reminders are the offsets before the beginning to remind at, stored as reminders of their own.
+	Reminders []time.Duration `db:"-" json:"reminders,omitempty"`
 }
@@ -2,8 +2,10 @@ package factorystorage
 
 import (
 	"context"
+	"io/ioutil"
 
 	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
 	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
 	sqlstorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/sql"
 )
@@ -11,19 +13,28 @@ import (
 type Config interface {
 	GetStorageImplementation() string
 	GetStorageDSN() string
+	GetStorageAutoMigrate() bool
 }
 
-// GetStorage is a storage factory method.
-func GetStorage(ctx context.Context, config Config) (app.Storage, error) {
+// GetStorage is a storage factory method, the storage tells the current time by the clock.
+func GetStorage(ctx context.Context, config Config, clock clock.Clock) (app.Storage, error) {
 	switch config.GetStorageImplementation() {
 	case sqlstorage.Alias:
-		storage := sqlstorage.New(config)
-		err := storage.Connect(ctx)
-		return storage, err
+		storage := sqlstorage.New(config, clock)
+		if err := storage.Connect(ctx); err != nil {
+			return storage, err
+		}
+
+		// Pending migrations are applied before anything touches the schema.
+		if config.GetStorageAutoMigrate() {
+			return storage, storage.Migrate(ctx, sqlstorage.MigrateUp, ioutil.Discard)
+		}
+
+		return storage, nil
 
 	case memorystorage.Alias:
-		return memorystorage.New(), nil
+		return memorystorage.New(clock), nil
 	default:
-		return memorystorage.New(), nil
+		return memorystorage.New(clock), nil
 	}
 }
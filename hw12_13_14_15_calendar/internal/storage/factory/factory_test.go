@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
 	internalconfig "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/config"
 	memorystorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/memory"
 	"github.com/stretchr/testify/require"
@@ -18,7 +19,7 @@ func TestLogger(t *testing.T) {
 
 		config.Storage.Implementation = "memory"
 
-		storage, err := GetStorage(context.Background(), config)
+		storage, err := GetStorage(context.Background(), config, clock.Real{})
 		if err != nil {
 			t.Fatal(err)
 		}
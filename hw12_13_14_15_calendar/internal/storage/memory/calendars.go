@@ -0,0 +1,201 @@
+package memorystorage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// grantKey identifies a grant of a calendar to a grantee.
+type grantKey struct {
+	calendarID int64
+	granteeID  int64
+}
+
+// CreateCalendar saves the calendar into a memory storage, an owner may have a single default one.
+func (s *Storage) CreateCalendar(ctx context.Context, calendar storage.Calendar) (storage.Calendar, error) {
+	defer s.lock(ctx)()
+
+	if err := s.checkDefaultCalendar(calendar); err != nil {
+		return calendar, err
+	}
+
+	calendar.Access = storage.AccessNone
+	s.calendarIncrement++
+	calendar.ID = s.calendarIncrement
+	s.keepCalendar(calendar.ID)
+	s.calendars[calendar.ID] = calendar
+
+	return calendar, nil
+}
+
+// UpdateCalendar updates the calendar in memory storage if exists.
+func (s *Storage) UpdateCalendar(ctx context.Context, calendar storage.Calendar) (storage.Calendar, error) {
+	defer s.lock(ctx)()
+
+	if _, ok := s.calendars[calendar.ID]; !ok {
+		return calendar, fmt.Errorf("%w: %d", storage.ErrCalendarNotFound, calendar.ID)
+	}
+
+	if err := s.checkDefaultCalendar(calendar); err != nil {
+		return calendar, err
+	}
+
+	calendar.Access = storage.AccessNone
+	s.keepCalendar(calendar.ID)
+	s.calendars[calendar.ID] = calendar
+
+	return calendar, nil
+}
+
+// RemoveCalendar removes the calendar along with its grants from memory storage,
+// calendars still having events are kept.
+func (s *Storage) RemoveCalendar(ctx context.Context, id int64) error {
+	defer s.lock(ctx)()
+
+	if _, ok := s.calendars[id]; !ok {
+		return fmt.Errorf("%w: %d", storage.ErrCalendarNotFound, id)
+	}
+
+	for _, event := range s.events {
+		if event.CalendarID == id {
+			return fmt.Errorf("%w: %d", storage.ErrCalendarNotEmpty, id)
+		}
+	}
+
+	s.keepCalendar(id)
+	delete(s.calendars, id)
+	for key := range s.grants {
+		if key.calendarID == id {
+			s.keepGrant(key)
+			delete(s.grants, key)
+		}
+	}
+
+	return nil
+}
+
+// GetCalendar returns the calendar by id, if exists.
+func (s *Storage) GetCalendar(ctx context.Context, id int64) (storage.Calendar, error) {
+	defer s.rlock(ctx)()
+
+	calendar, ok := s.calendars[id]
+	if !ok {
+		return storage.Calendar{}, fmt.Errorf("%w: %d", storage.ErrCalendarNotFound, id)
+	}
+
+	return calendar, nil
+}
+
+// GetDefaultCalendar returns the default calendar of the owner, if exists.
+func (s *Storage) GetDefaultCalendar(ctx context.Context, ownerID int64) (storage.Calendar, error) {
+	defer s.rlock(ctx)()
+
+	for _, calendar := range s.calendars {
+		if calendar.OwnerID == ownerID && calendar.Default {
+			return calendar, nil
+		}
+	}
+
+	return storage.Calendar{}, fmt.Errorf("%w: default of owner %d", storage.ErrCalendarNotFound, ownerID)
+}
+
+// ListCalendars returns the calendars of the owner ordered by name.
+func (s *Storage) ListCalendars(ctx context.Context, ownerID int64) ([]storage.Calendar, error) {
+	defer s.rlock(ctx)()
+
+	var calendars []storage.Calendar
+	for _, calendar := range s.calendars {
+		if calendar.OwnerID == ownerID {
+			calendars = append(calendars, calendar)
+		}
+	}
+	sort.Slice(calendars, func(i, j int) bool {
+		if calendars[i].Name == calendars[j].Name {
+			return calendars[i].ID < calendars[j].ID
+		}
+		return calendars[i].Name < calendars[j].Name
+	})
+
+	return calendars, nil
+}
+
+// SetGrant grants the access to an existing calendar, replacing the former access of the grantee.
+func (s *Storage) SetGrant(ctx context.Context, grant storage.Grant) (storage.Grant, error) {
+	defer s.lock(ctx)()
+
+	if _, ok := s.calendars[grant.CalendarID]; !ok {
+		return grant, fmt.Errorf("%w: %d", storage.ErrCalendarNotFound, grant.CalendarID)
+	}
+
+	key := grantKey{grant.CalendarID, grant.GranteeID}
+	s.keepGrant(key)
+	s.grants[key] = grant
+
+	return grant, nil
+}
+
+// RemoveGrant revokes the access of the grantee to the calendar.
+func (s *Storage) RemoveGrant(ctx context.Context, calendarID, granteeID int64) error {
+	defer s.lock(ctx)()
+
+	key := grantKey{calendarID, granteeID}
+	if _, ok := s.grants[key]; !ok {
+		return fmt.Errorf("%w: calendar %d, grantee %d", storage.ErrGrantNotFound, calendarID, granteeID)
+	}
+	s.keepGrant(key)
+	delete(s.grants, key)
+
+	return nil
+}
+
+// GetGrant returns the grant of the calendar to the grantee, if exists.
+func (s *Storage) GetGrant(ctx context.Context, calendarID, granteeID int64) (storage.Grant, error) {
+	defer s.rlock(ctx)()
+
+	grant, ok := s.grants[grantKey{calendarID, granteeID}]
+	if !ok {
+		return storage.Grant{}, fmt.Errorf("%w: calendar %d, grantee %d", storage.ErrGrantNotFound, calendarID, granteeID)
+	}
+
+	return grant, nil
+}
+
+// ListGrants returns the grants passing the filter ordered by calendar and grantee.
+func (s *Storage) ListGrants(ctx context.Context, filter storage.GrantFilter) ([]storage.Grant, error) {
+	defer s.rlock(ctx)()
+
+	var grants []storage.Grant
+	for _, grant := range s.grants {
+		if filter.CalendarID != 0 && grant.CalendarID != filter.CalendarID ||
+			filter.GranteeID != 0 && grant.GranteeID != filter.GranteeID {
+			continue
+		}
+		grants = append(grants, grant)
+	}
+	sort.Slice(grants, func(i, j int) bool {
+		if grants[i].CalendarID == grants[j].CalendarID {
+			return grants[i].GranteeID < grants[j].GranteeID
+		}
+		return grants[i].CalendarID < grants[j].CalendarID
+	})
+
+	return grants, nil
+}
+
+// checkDefaultCalendar makes sure the owner has no other default calendar, must be called under the lock.
+func (s *Storage) checkDefaultCalendar(calendar storage.Calendar) error {
+	if !calendar.Default {
+		return nil
+	}
+
+	for _, other := range s.calendars {
+		if other.OwnerID == calendar.OwnerID && other.Default && other.ID != calendar.ID {
+			return fmt.Errorf("%w: owner %d", storage.ErrCalendarExists, calendar.OwnerID)
+		}
+	}
+
+	return nil
+}
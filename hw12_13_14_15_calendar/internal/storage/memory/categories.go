@@ -0,0 +1,148 @@
+package memorystorage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// CreateCategory saves the category into a memory storage, names are unique per owner.
+func (s *Storage) CreateCategory(ctx context.Context, category storage.Category) (storage.Category, error) {
+	defer s.lock(ctx)()
+
+	if err := s.checkCategoryName(category); err != nil {
+		return category, err
+	}
+
+	s.categoryIncrement++
+	category.ID = s.categoryIncrement
+	s.keepCategory(category.ID)
+	s.categories[category.ID] = category
+
+	return category, nil
+}
+
+// UpdateCategory updates the category in memory storage if exists.
+func (s *Storage) UpdateCategory(ctx context.Context, category storage.Category) (storage.Category, error) {
+	defer s.lock(ctx)()
+
+	if _, ok := s.categories[category.ID]; !ok {
+		return category, fmt.Errorf("%w: %d", storage.ErrCategoryNotFound, category.ID)
+	}
+
+	if err := s.checkCategoryName(category); err != nil {
+		return category, err
+	}
+
+	s.keepCategory(category.ID)
+	s.categories[category.ID] = category
+
+	return category, nil
+}
+
+// RemoveCategory removes the category from memory storage if exists, its events are left without a category.
+func (s *Storage) RemoveCategory(ctx context.Context, id int64) error {
+	defer s.lock(ctx)()
+
+	if _, ok := s.categories[id]; !ok {
+		return fmt.Errorf("%w: %d", storage.ErrCategoryNotFound, id)
+	}
+	s.keepCategory(id)
+	delete(s.categories, id)
+
+	for _, event := range s.events {
+		if event.CategoryID == id {
+			event.CategoryID = 0
+			s.putEvent(event)
+			s.record(storage.EventUpdated, event)
+		}
+	}
+
+	return nil
+}
+
+// GetCategory returns the category by id, if exists.
+func (s *Storage) GetCategory(ctx context.Context, id int64) (storage.Category, error) {
+	defer s.rlock(ctx)()
+
+	category, ok := s.categories[id]
+	if !ok {
+		return storage.Category{}, fmt.Errorf("%w: %d", storage.ErrCategoryNotFound, id)
+	}
+
+	return category, nil
+}
+
+// ListCategories returns the categories of the owner ordered by name.
+func (s *Storage) ListCategories(ctx context.Context, ownerID int64) ([]storage.Category, error) {
+	defer s.rlock(ctx)()
+
+	var categories []storage.Category
+	for _, category := range s.categories {
+		if category.OwnerID == ownerID {
+			categories = append(categories, category)
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].Name == categories[j].Name {
+			return categories[i].ID < categories[j].ID
+		}
+		return categories[i].Name < categories[j].Name
+	})
+
+	return categories, nil
+}
+
+// SetEventTags replaces the tags of an existing event, recording an update of the event if they differ.
+func (s *Storage) SetEventTags(ctx context.Context, eventID int64, tags []string) error {
+	defer s.lock(ctx)()
+
+	event, ok := s.events[eventID]
+	if !ok {
+		return fmt.Errorf("%w: %d", storage.ErrEventNotFound, eventID)
+	}
+
+	sorted := make([]string, len(tags))
+	copy(sorted, tags)
+	sort.Strings(sorted)
+	if storage.EqualTags(s.tags[eventID], sorted) {
+		return nil
+	}
+
+	s.keepTags(eventID)
+	if len(sorted) == 0 {
+		delete(s.tags, eventID)
+	} else {
+		s.tags[eventID] = sorted
+	}
+	s.record(storage.EventUpdated, event)
+
+	return nil
+}
+
+// GetEventTags returns the sorted tags of the given events, events without tags are left out.
+func (s *Storage) GetEventTags(ctx context.Context, eventIDs ...int64) (map[int64][]string, error) {
+	defer s.rlock(ctx)()
+
+	tags := make(map[int64][]string, len(eventIDs))
+	for _, eventID := range eventIDs {
+		if eventTags, ok := s.tags[eventID]; ok {
+			tags[eventID] = append([]string(nil), eventTags...)
+		}
+	}
+
+	return tags, nil
+}
+
+// checkCategoryName makes sure no other category of the owner has the same name, must be called under the lock.
+func (s *Storage) checkCategoryName(category storage.Category) error {
+	for _, other := range s.categories {
+		if other.OwnerID == category.OwnerID && other.Name == category.Name && other.ID != category.ID {
+			return fmt.Errorf("%w: %q", storage.ErrCategoryExists, category.Name)
+		}
+	}
+
+	return nil
+}
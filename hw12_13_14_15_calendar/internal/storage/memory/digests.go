@@ -0,0 +1,82 @@
+package memorystorage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// SetDigestSubscription creates or replaces the digest subscription of the owner.
+// The time the last digest was sent is kept from the replaced subscription.
+func (s *Storage) SetDigestSubscription(
+	ctx context.Context,
+	subscription storage.DigestSubscription,
+) (storage.DigestSubscription, error) {
+	defer s.lock(ctx)()
+
+	if existing, ok := s.digests[subscription.OwnerID]; ok {
+		subscription.LastSentAt = existing.LastSentAt
+	}
+	s.keepDigest(subscription.OwnerID)
+	s.digests[subscription.OwnerID] = subscription
+
+	return subscription, nil
+}
+
+// GetDigestSubscription returns the digest subscription of the owner, if exists.
+func (s *Storage) GetDigestSubscription(ctx context.Context, ownerID int64) (storage.DigestSubscription, error) {
+	defer s.rlock(ctx)()
+
+	subscription, ok := s.digests[ownerID]
+	if !ok {
+		return storage.DigestSubscription{}, fmt.Errorf("%w: owner %d", storage.ErrDigestNotFound, ownerID)
+	}
+
+	return subscription, nil
+}
+
+// RemoveDigestSubscription removes the digest subscription of the owner, if exists.
+func (s *Storage) RemoveDigestSubscription(ctx context.Context, ownerID int64) error {
+	defer s.lock(ctx)()
+
+	if _, ok := s.digests[ownerID]; !ok {
+		return fmt.Errorf("%w: owner %d", storage.ErrDigestNotFound, ownerID)
+	}
+	s.keepDigest(ownerID)
+	delete(s.digests, ownerID)
+
+	return nil
+}
+
+// ListDigestSubscriptions returns all digest subscriptions ordered by owner.
+func (s *Storage) ListDigestSubscriptions(ctx context.Context) ([]storage.DigestSubscription, error) {
+	defer s.rlock(ctx)()
+
+	subscriptions := make([]storage.DigestSubscription, 0, len(s.digests))
+	for _, subscription := range s.digests {
+		subscriptions = append(subscriptions, subscription)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].OwnerID < subscriptions[j].OwnerID
+	})
+
+	return subscriptions, nil
+}
+
+// MarkDigestSent records the time the owner's digest was last sent at.
+func (s *Storage) MarkDigestSent(ctx context.Context, ownerID int64, at time.Time) error {
+	defer s.lock(ctx)()
+
+	subscription, ok := s.digests[ownerID]
+	if !ok {
+		return fmt.Errorf("%w: owner %d", storage.ErrDigestNotFound, ownerID)
+	}
+	subscription.LastSentAt = &at
+	s.keepDigest(ownerID)
+	s.digests[ownerID] = subscription
+
+	return nil
+}
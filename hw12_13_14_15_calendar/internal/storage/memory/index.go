@@ -0,0 +1,179 @@
+package memorystorage
+
+import (
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// indexNode is a node of a treap of events ordered by their begin dates and ids. Every node
+// knows the latest end in its subtree, which makes the treap an interval tree answering
+// overlap queries in O(log n + k).
+//
+// Nodes are never changed once built: inserting and deleting copy the path to the changed node,
+// so a root is a snapshot of the index, which readers may walk without holding the lock
+// and transactions may roll back to.
+type indexNode struct {
+	event    *storage.Event
+	end      time.Time
+	maxEnd   time.Time
+	priority uint64
+	left     *indexNode
+	right    *indexNode
+}
+
+func newIndexNode(event storage.Event) *indexNode {
+	node := &indexNode{
+		event:    &event,
+		end:      endOf(event),
+		priority: priorityOf(event.ID),
+	}
+	node.maxEnd = node.end
+
+	return node
+}
+
+// withChildren returns a copy of the node with the given children.
+func (n *indexNode) withChildren(left, right *indexNode) *indexNode {
+	node := *n
+	node.left, node.right = left, right
+
+	node.maxEnd = node.end
+	if left != nil && left.maxEnd.After(node.maxEnd) {
+		node.maxEnd = left.maxEnd
+	}
+	if right != nil && right.maxEnd.After(node.maxEnd) {
+		node.maxEnd = right.maxEnd
+	}
+
+	return &node
+}
+
+// before reports whether the node goes before the event in the index.
+func (n *indexNode) before(event *storage.Event) bool {
+	if n.event.BeginDate.Equal(event.BeginDate) {
+		return n.event.ID < event.ID
+	}
+
+	return n.event.BeginDate.Before(event.BeginDate)
+}
+
+// insert returns the index with the event added, the event must not be in it yet.
+func (n *indexNode) insert(event storage.Event) *indexNode {
+	return n.insertNode(newIndexNode(event))
+}
+
+func (n *indexNode) insertNode(node *indexNode) *indexNode {
+	if n == nil {
+		return node
+	}
+
+	if node.priority > n.priority {
+		left, right := n.split(node.event)
+		return node.withChildren(left, right)
+	}
+
+	if n.before(node.event) {
+		return n.withChildren(n.left, n.right.insertNode(node))
+	}
+
+	return n.withChildren(n.left.insertNode(node), n.right)
+}
+
+// split returns the nodes going before the event and the rest.
+func (n *indexNode) split(event *storage.Event) (*indexNode, *indexNode) {
+	if n == nil {
+		return nil, nil
+	}
+
+	if n.before(event) {
+		left, right := n.right.split(event)
+		return n.withChildren(n.left, left), right
+	}
+
+	left, right := n.left.split(event)
+
+	return left, n.withChildren(right, n.right)
+}
+
+// remove returns the index without the event, which is matched by its begin date and id.
+func (n *indexNode) remove(event storage.Event) *indexNode {
+	if n == nil {
+		return nil
+	}
+
+	if n.event.ID == event.ID && n.event.BeginDate.Equal(event.BeginDate) {
+		return merge(n.left, n.right)
+	}
+
+	if n.before(&event) {
+		return n.withChildren(n.left, n.right.remove(event))
+	}
+
+	return n.withChildren(n.left.remove(event), n.right)
+}
+
+// merge joins two indexes, all the nodes of the left one going before the right one.
+func merge(left, right *indexNode) *indexNode {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+
+	if left.priority > right.priority {
+		return left.withChildren(left.left, merge(left.right, right))
+	}
+
+	return right.withChildren(merge(left, right.left), right.right)
+}
+
+// overlapping calls visit for the events intersecting the [begin, end) period in the begin date order.
+func (n *indexNode) overlapping(begin, end time.Time, visit func(event storage.Event)) {
+	if n == nil || !n.maxEnd.After(begin) {
+		return
+	}
+
+	n.left.overlapping(begin, end, visit)
+
+	if !n.event.BeginDate.Before(end) {
+		return
+	}
+
+	if n.end.After(begin) {
+		visit(*n.event)
+	}
+
+	n.right.overlapping(begin, end, visit)
+}
+
+// endedBefore calls visit for the events ended before the given time.
+func (n *indexNode) endedBefore(at time.Time, visit func(event storage.Event)) {
+	if n == nil {
+		return
+	}
+
+	n.left.endedBefore(at, visit)
+
+	// Events beginning later end later too.
+	if !n.event.BeginDate.Before(at) {
+		return
+	}
+
+	if n.end.Before(at) {
+		visit(*n.event)
+	}
+
+	n.right.endedBefore(at, visit)
+}
+
+// priorityOf spreads event ids over the priorities, keeping the treap balanced
+// and the same for the same events.
+func priorityOf(id int64) uint64 {
+	x := uint64(id) + 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+
+	return x ^ x>>31
+}
@@ -0,0 +1,291 @@
+package memorystorage
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+var indexReference = time.Date(2021, time.November, 2, 9, 0, 0, 0, time.UTC)
+
+// randomEvent returns an event of one of a few owners within a week, some of them instant or without an end.
+func randomEvent(r *rand.Rand, days int) storage.Event {
+	begin := indexReference.Add(time.Duration(r.Int63n(int64(days) * int64(24*time.Hour))))
+	end := begin.Add(time.Duration(r.Intn(8*60)) * time.Minute)
+	if r.Intn(10) == 0 {
+		end = time.Time{}
+	}
+
+	return storage.Event{Title: "random", BeginDate: begin, EndDate: end, OwnerID: int64(r.Intn(5) + 1)}
+}
+
+// scan returns the events of the owners overlapping the period the way the index should, all for no owners.
+func scan(events map[int64]storage.Event, begin, end time.Time, ownerIDs ...int64) []int64 {
+	owners := make(map[int64]bool, len(ownerIDs))
+	for _, ownerID := range ownerIDs {
+		owners[ownerID] = true
+	}
+
+	var found []storage.Event
+	for _, event := range events {
+		if (len(owners) == 0 || owners[event.OwnerID]) && event.BeginDate.Before(end) && endOf(event).After(begin) {
+			found = append(found, event)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].BeginDate.Equal(found[j].BeginDate) {
+			return found[i].ID < found[j].ID
+		}
+		return found[i].BeginDate.Before(found[j].BeginDate)
+	})
+
+	result := make([]int64, 0, len(found))
+	for _, event := range found {
+		result = append(result, event.ID)
+	}
+
+	return result
+}
+
+func eventIDs(events []storage.Event) []int64 {
+	result := make([]int64, 0, len(events))
+	for _, event := range events {
+		result = append(result, event.ID)
+	}
+
+	return result
+}
+
+func TestIndex(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(1))
+	clk := clock.NewFake(indexReference)
+	s := New(clk)
+	events := make(map[int64]storage.Event)
+
+	for i := 0; i < 3000; i++ {
+		switch op := r.Intn(10); {
+		case op < 6 || len(events) == 0:
+			event, err := s.CreateEvent(ctx, randomEvent(r, 7))
+			require.NoError(t, err)
+			events[event.ID] = event
+
+		case op < 9:
+			for id := range events {
+				event := randomEvent(r, 7)
+				event.ID = id
+				_, err := s.UpdateEvent(ctx, event)
+				require.NoError(t, err)
+				events[id] = event
+
+				break
+			}
+
+		default:
+			for id, event := range events {
+				require.NoError(t, s.RemoveEvent(ctx, event))
+				delete(events, id)
+
+				break
+			}
+		}
+
+		if i%100 != 0 {
+			continue
+		}
+
+		begin := indexReference.Add(time.Duration(r.Int63n(int64(7 * 24 * time.Hour))))
+		end := begin.Add(time.Duration(r.Intn(48)) * time.Hour)
+
+		found, err := s.GetEventsInRange(ctx, begin, end)
+		require.NoError(t, err)
+		require.Equal(t, scan(events, begin, end), eventIDs(found))
+
+		found, err = s.GetOwnerEventsInRange(ctx, begin, end, 2, 4)
+		require.NoError(t, err)
+		require.Equal(t, scan(events, begin, end, 2, 4), eventIDs(found))
+	}
+
+	// Expiry removes exactly the events ended before the retention.
+	clk.Advance(4 * 24 * time.Hour)
+	require.NoError(t, s.RemoveExpiredEvents(ctx, 24*time.Hour))
+	for id, event := range events {
+		if endOf(event).Before(indexReference.Add(3 * 24 * time.Hour)) {
+			delete(events, id)
+		}
+	}
+	require.Len(t, s.events, len(events))
+
+	found, err := s.GetEventsInRange(ctx, indexReference, indexReference.AddDate(0, 0, 8))
+	require.NoError(t, err)
+	require.Equal(t, scan(events, indexReference, indexReference.AddDate(0, 0, 8)), eventIDs(found))
+}
+
+const (
+	benchmarkEvents = 1000000
+	benchmarkOwners = 1000
+	benchmarkDays   = 5 * 365
+)
+
+var (
+	benchmarkOnce    sync.Once
+	benchmarkStorage *Storage
+)
+
+// million returns a storage of a million events of a thousand owners spread over five years.
+func million() *Storage {
+	benchmarkOnce.Do(func() {
+		r := rand.New(rand.NewSource(1))
+		benchmarkStorage = New(clock.NewFake(indexReference))
+
+		for i := 0; i < benchmarkEvents; i++ {
+			event := randomEvent(r, benchmarkDays)
+			event.OwnerID = int64(r.Intn(benchmarkOwners) + 1)
+
+			benchmarkStorage.increment++
+			event.ID = benchmarkStorage.increment
+			benchmarkStorage.putEvent(event)
+		}
+	})
+
+	return benchmarkStorage
+}
+
+func BenchmarkGetEventsInRange(b *testing.B) {
+	s := million()
+	ctx := context.Background()
+	middle := indexReference.AddDate(0, 0, benchmarkDays/2)
+
+	for _, period := range []struct {
+		name string
+		end  time.Time
+	}{
+		{"day", middle.AddDate(0, 0, 1)},
+		{"week", middle.AddDate(0, 0, 7)},
+		{"month", middle.AddDate(0, 1, 0)},
+	} {
+		end := period.end
+
+		b.Run(period.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := s.GetEventsInRange(ctx, middle, end); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(period.name+" of an owner", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := s.GetOwnerEventsInRange(ctx, middle, end, int64(i%benchmarkOwners+1)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+
+	// The full scan the index replaced, for comparison.
+	b.Run("day by scan", func(b *testing.B) {
+		end := middle.AddDate(0, 0, 1)
+
+		for i := 0; i < b.N; i++ {
+			var events []storage.Event
+
+			s.mu.RLock()
+			for _, event := range s.events {
+				if event.BeginDate.Before(end) && endOf(event).After(middle) {
+					events = append(events, event)
+				}
+			}
+			s.mu.RUnlock()
+		}
+	})
+}
+
+// BenchmarkGetEventsInRangeWhileWriting measures readers racing a writer, which only holds the lock
+// for changing the index, not while they walk it. The transactional writer also pays for its undo log,
+// which grows with the changes made rather than with the stored events.
+func BenchmarkGetEventsInRangeWhileWriting(b *testing.B) {
+	s := million()
+	ctx := context.Background()
+	middle := indexReference.AddDate(0, 0, benchmarkDays/2)
+
+	for _, writer := range []struct {
+		name  string
+		write func(event storage.Event) error
+	}{
+		{"locked writes", func(event storage.Event) error {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			s.increment++
+			event.ID = s.increment
+			s.putEvent(event)
+			s.deleteEvent(event)
+
+			return nil
+		}},
+		{"transaction writes", func(event storage.Event) error {
+			return s.WithinTransaction(ctx, func(ctx context.Context) error {
+				created, err := s.CreateEvent(ctx, event)
+				if err != nil {
+					return err
+				}
+
+				return s.RemoveEvent(ctx, created)
+			})
+		}},
+	} {
+		write := writer.write
+
+		b.Run(writer.name, func(b *testing.B) {
+			var (
+				done    = make(chan struct{})
+				stopped = make(chan struct{})
+				writes  int
+			)
+
+			go func() {
+				defer close(stopped)
+
+				r := rand.New(rand.NewSource(2))
+				for {
+					select {
+					case <-done:
+						return
+					default:
+					}
+
+					if err := write(randomEvent(r, benchmarkDays)); err != nil {
+						b.Error(err)
+						return
+					}
+					writes++
+				}
+			}()
+
+			b.ResetTimer()
+			start := time.Now()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := s.GetEventsInRange(ctx, middle, middle.AddDate(0, 0, 7)); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.StopTimer()
+			elapsed := time.Since(start)
+
+			close(done)
+			<-stopped
+			b.ReportMetric(float64(writes)/elapsed.Seconds(), "writes/s")
+		})
+	}
+}
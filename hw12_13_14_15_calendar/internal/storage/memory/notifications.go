@@ -0,0 +1,146 @@
+package memorystorage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// CreateNotification saves the notification of an existing event into a memory storage.
+func (s *Storage) CreateNotification(
+	ctx context.Context,
+	notification storage.Notification,
+) (storage.Notification, error) {
+	defer s.lock(ctx)()
+
+	if _, ok := s.events[notification.EventID]; !ok {
+		return notification, fmt.Errorf("%w: %d", storage.ErrEventNotFound, notification.EventID)
+	}
+
+	s.notificationIncrement++
+	notification.ID = s.notificationIncrement
+	s.keepNotification(notification.ID)
+	s.notifications[notification.ID] = notification
+	s.markEventNotified(notification)
+
+	return notification, nil
+}
+
+// UpdateNotification updates the state of the notification, if exists.
+func (s *Storage) UpdateNotification(
+	ctx context.Context,
+	notification storage.Notification,
+) (storage.Notification, error) {
+	defer s.lock(ctx)()
+
+	stored, ok := s.notifications[notification.ID]
+	if !ok {
+		return notification, fmt.Errorf("%w: %d", storage.ErrNotificationNotFound, notification.ID)
+	}
+
+	stored.State = notification.State
+	stored.Attempts = notification.Attempts
+	stored.LastError = notification.LastError
+	stored.UpdatedAt = notification.UpdatedAt
+	stored.PublishedAt = notification.PublishedAt
+	stored.DeliveredAt = notification.DeliveredAt
+	s.keepNotification(notification.ID)
+	s.notifications[notification.ID] = stored
+	s.markEventNotified(stored)
+
+	return stored, nil
+}
+
+// GetNotification returns the notification by id, if exists.
+func (s *Storage) GetNotification(ctx context.Context, id int64) (storage.Notification, error) {
+	defer s.rlock(ctx)()
+
+	notification, ok := s.notifications[id]
+	if !ok {
+		return notification, fmt.Errorf("%w: %d", storage.ErrNotificationNotFound, id)
+	}
+
+	return notification, nil
+}
+
+// FindNotification returns the notification of the reminder through the channel, if exists.
+func (s *Storage) FindNotification(
+	ctx context.Context,
+	eventID, reminderID int64,
+	channel string,
+) (storage.Notification, error) {
+	defer s.rlock(ctx)()
+
+	for _, notification := range s.notifications {
+		if notification.EventID == eventID && notification.ReminderID == reminderID && notification.Channel == channel {
+			return notification, nil
+		}
+	}
+
+	return storage.Notification{}, fmt.Errorf(
+		"%w: event %d, reminder %d, channel %s", storage.ErrNotificationNotFound, eventID, reminderID, channel,
+	)
+}
+
+// ListNotifications returns the notifications selected by the filter ordered by id.
+func (s *Storage) ListNotifications(
+	ctx context.Context,
+	filter storage.NotificationFilter,
+) ([]storage.Notification, error) {
+	defer s.rlock(ctx)()
+
+	var notifications []storage.Notification
+	for _, notification := range s.notifications {
+		if filter.Match(notification) {
+			notifications = append(notifications, notification)
+		}
+	}
+
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[i].ID < notifications[j].ID
+	})
+
+	return notifications, nil
+}
+
+// markEventNotified sets the notification flags of the event once the notification is published or delivered,
+// the caller holds the lock. The flags are never reset, like the columns maintained by the sql storage.
+func (s *Storage) markEventNotified(notification storage.Notification) {
+	event, ok := s.events[notification.EventID]
+	if !ok {
+		return
+	}
+
+	sent := notification.State == storage.NotificationPublished || notification.State == storage.NotificationDelivered
+	received := notification.State == storage.NotificationDelivered
+	if (!sent || event.NotificationSent) && (!received || event.NotificationReceived) {
+		return
+	}
+
+	event.NotificationSent = true
+	event.NotificationReceived = event.NotificationReceived || received
+	s.putEvent(event)
+	s.record(storage.EventUpdated, event)
+}
+
+// removeNotifications drops the notifications of a removed event, the caller holds the lock.
+func (s *Storage) removeNotifications(eventID int64) {
+	for id, notification := range s.notifications {
+		if notification.EventID == eventID {
+			s.keepNotification(id)
+			delete(s.notifications, id)
+		}
+	}
+}
+
+// removeReminderNotifications drops the notifications of a removed reminder, the caller holds the lock.
+func (s *Storage) removeReminderNotifications(reminderID int64) {
+	for id, notification := range s.notifications {
+		if notification.ReminderID == reminderID {
+			s.keepNotification(id)
+			delete(s.notifications, id)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package memorystorage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// CreateReminder saves the reminder into a memory storage.
+func (s *Storage) CreateReminder(ctx context.Context, reminder storage.Reminder) (storage.Reminder, error) {
+	defer s.lock(ctx)()
+
+	if _, ok := s.events[reminder.EventID]; !ok {
+		return reminder, fmt.Errorf("%w: %d", storage.ErrEventNotFound, reminder.EventID)
+	}
+
+	s.reminderIncrement++
+	reminder.ID = s.reminderIncrement
+	s.keepReminder(reminder.ID)
+	s.reminders[reminder.ID] = reminder
+	s.signalReminder(reminder)
+
+	return reminder, nil
+}
+
+// UpdateReminder updates the owner, the time and the state of the reminder, if exists.
+func (s *Storage) UpdateReminder(ctx context.Context, reminder storage.Reminder) (storage.Reminder, error) {
+	defer s.lock(ctx)()
+
+	stored, ok := s.reminders[reminder.ID]
+	if !ok {
+		return reminder, fmt.Errorf("%w: %d", storage.ErrReminderNotFound, reminder.ID)
+	}
+
+	stored.OwnerID = reminder.OwnerID
+	stored.Offset = reminder.Offset
+	stored.RemindAt = reminder.RemindAt
+	stored.State = reminder.State
+	s.keepReminder(reminder.ID)
+	s.reminders[reminder.ID] = stored
+	s.signalReminder(stored)
+
+	return stored, nil
+}
+
+// UpdateReminderState sets the state and the time of the reminder, unless they differ from the ones read.
+func (s *Storage) UpdateReminderState(
+	ctx context.Context,
+	read, reminder storage.Reminder,
+) (storage.Reminder, error) {
+	defer s.lock(ctx)()
+
+	stored, ok := s.reminders[read.ID]
+	if !ok {
+		return reminder, fmt.Errorf("%w: %d", storage.ErrReminderNotFound, read.ID)
+	}
+
+	if stored.State != read.State || !stored.RemindAt.Equal(read.RemindAt) {
+		return stored, fmt.Errorf("%w: %d", storage.ErrReminderChanged, read.ID)
+	}
+
+	stored.State = reminder.State
+	stored.RemindAt = reminder.RemindAt
+	s.keepReminder(read.ID)
+	s.reminders[read.ID] = stored
+	s.signalReminder(stored)
+
+	return stored, nil
+}
+
+// RemoveReminder removes the reminder with its notifications, if exists.
+func (s *Storage) RemoveReminder(ctx context.Context, id int64) error {
+	defer s.lock(ctx)()
+
+	if _, ok := s.reminders[id]; !ok {
+		return fmt.Errorf("%w: %d", storage.ErrReminderNotFound, id)
+	}
+	s.keepReminder(id)
+	delete(s.reminders, id)
+	s.removeReminderNotifications(id)
+
+	return nil
+}
+
+// GetReminder returns the reminder by id, if exists.
+func (s *Storage) GetReminder(ctx context.Context, id int64) (storage.Reminder, error) {
+	defer s.rlock(ctx)()
+
+	reminder, ok := s.reminders[id]
+	if !ok {
+		return reminder, fmt.Errorf("%w: %d", storage.ErrReminderNotFound, id)
+	}
+
+	return reminder, nil
+}
+
+// GetReminders returns the reminders of the events ordered by event and time.
+func (s *Storage) GetReminders(ctx context.Context, eventIDs ...int64) ([]storage.Reminder, error) {
+	defer s.rlock(ctx)()
+
+	wanted := make(map[int64]bool, len(eventIDs))
+	for _, id := range eventIDs {
+		wanted[id] = true
+	}
+
+	var reminders []storage.Reminder
+	for _, reminder := range s.reminders {
+		if wanted[reminder.EventID] {
+			reminders = append(reminders, reminder)
+		}
+	}
+	sort.Slice(reminders, func(i, j int) bool {
+		if reminders[i].EventID != reminders[j].EventID {
+			return reminders[i].EventID < reminders[j].EventID
+		}
+
+		return remindsEarlier(reminders[i], reminders[j])
+	})
+
+	return reminders, nil
+}
+
+// GetDueReminders returns the reminders due by now ordered by time.
+func (s *Storage) GetDueReminders(ctx context.Context) ([]storage.Reminder, error) {
+	return s.GetUpcomingReminders(ctx, s.clock.Now())
+}
+
+// GetUpcomingReminders returns the reminders due by the given time ordered by time,
+// as far as their events are known now.
+func (s *Storage) GetUpcomingReminders(ctx context.Context, until time.Time) ([]storage.Reminder, error) {
+	defer s.rlock(ctx)()
+
+	now := s.clock.Now()
+
+	var reminders []storage.Reminder
+	for _, reminder := range s.reminders {
+		if reminder.RemindAt.After(until) {
+			continue
+		}
+
+		switch reminder.State {
+		case storage.ReminderPending:
+			if s.events[reminder.EventID].BeginDate.After(now) {
+				reminders = append(reminders, reminder)
+			}
+		case storage.ReminderSnoozed:
+			reminders = append(reminders, reminder)
+		}
+	}
+	sort.Slice(reminders, func(i, j int) bool {
+		return remindsEarlier(reminders[i], reminders[j])
+	})
+
+	return reminders, nil
+}
+
+// SubscribeReminderChanges returns a channel signalled when reminders may come due earlier than known,
+// and a func releasing it.
+func (s *Storage) SubscribeReminderChanges() (<-chan struct{}, func()) {
+	return s.reminderFeed.Subscribe()
+}
+
+// signalReminder tells the subscribers about the reminder, if it is going to come due.
+func (s *Storage) signalReminder(reminder storage.Reminder) {
+	if reminder.State == storage.ReminderPending || reminder.State == storage.ReminderSnoozed {
+		s.reminderFeed.Publish()
+	}
+}
+
+// removeReminders drops the reminders of a removed event, the caller holds the lock.
+func (s *Storage) removeReminders(eventID int64) {
+	for id, reminder := range s.reminders {
+		if reminder.EventID == eventID {
+			s.keepReminder(id)
+			delete(s.reminders, id)
+		}
+	}
+}
+
+// remindsEarlier orders reminders by time, then by id.
+func remindsEarlier(a, b storage.Reminder) bool {
+	if !a.RemindAt.Equal(b.RemindAt) {
+		return a.RemindAt.Before(b.RemindAt)
+	}
+
+	return a.ID < b.ID
+}
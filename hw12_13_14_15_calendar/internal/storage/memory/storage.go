@@ -2,169 +2,324 @@ package memorystorage
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
 	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
 )
 
-const Alias = "memory"
+const (
+	Alias = "memory"
+
+	// changeLogSize is a number of latest changes kept for resuming watchers.
+	changeLogSize = 10000
+)
 
 type Storage struct {
 	mu        sync.RWMutex
+	clock     clock.Clock
 	increment int64
 	events    map[int64]storage.Event
+	// index orders all the events by time, owners order the events of each owner.
+	index    *indexNode
+	owners   map[int64]*indexNode
+	revision int64
+	changes  []storage.Change
+	feed     *storage.ChangeFeed
+	// pending holds changes of the running transaction until it commits.
+	pending []storage.Change
+	// undo restores the entries changed by the running transaction when it fails.
+	undo []func()
+	inTx bool
+
+	tokenIncrement int64
+	tokens         map[int64]storage.APIToken
+
+	digests map[int64]storage.DigestSubscription
+
+	reminderIncrement int64
+	reminders         map[int64]storage.Reminder
+	reminderFeed      *storage.SignalFeed
+
+	notificationIncrement int64
+	notifications         map[int64]storage.Notification
+
+	categoryIncrement int64
+	categories        map[int64]storage.Category
+	tags              map[int64][]string
+
+	calendarIncrement int64
+	calendars         map[int64]storage.Calendar
+	grants            map[grantKey]storage.Grant
 }
 
-// New returns a new memory storage instance.
-func New() *Storage {
+// New returns a new memory storage instance, the clock tells the current time to the time-relative queries.
+func New(clock clock.Clock) *Storage {
 	return &Storage{
-		events: make(map[int64]storage.Event),
+		clock:         clock,
+		events:        make(map[int64]storage.Event),
+		owners:        make(map[int64]*indexNode),
+		feed:          storage.NewChangeFeed(),
+		tokens:        make(map[int64]storage.APIToken),
+		digests:       make(map[int64]storage.DigestSubscription),
+		reminders:     make(map[int64]storage.Reminder),
+		reminderFeed:  storage.NewSignalFeed(),
+		notifications: make(map[int64]storage.Notification),
+		categories:    make(map[int64]storage.Category),
+		tags:          make(map[int64][]string),
+		calendars:     make(map[int64]storage.Calendar),
+		grants:        make(map[grantKey]storage.Grant),
 	}
 }
 
-// CreateEvent saves event into a memory storage.
+// Ping reports a memory storage is always reachable.
+func (s *Storage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// CreateEvent saves event into a memory storage, its tags and reminders are stored on their own.
 func (s *Storage) CreateEvent(ctx context.Context, event storage.Event) (storage.Event, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.lock(ctx)()
 
+	event.Tags = nil
+	event.Reminders = nil
+	event.NotificationSent = false
+	event.NotificationReceived = false
 	s.increment++
 	event.ID = s.increment
-	s.events[event.ID] = event
+	s.putEvent(event)
+	s.record(storage.EventCreated, event)
 
 	return event, nil
 }
 
-// UpdateEvent updates event in memory storage if exists.
+// UpdateEvent updates event in memory storage if exists, its tags, reminders and notification flags
+// are stored on their own.
 func (s *Storage) UpdateEvent(ctx context.Context, event storage.Event) (storage.Event, error) {
-	var err error = nil
-
-	s.mu.RLock()
-	_, isSet := s.events[event.ID] //nolint:ifshort
-	s.mu.RUnlock()
+	defer s.lock(ctx)()
 
-	if isSet {
-		s.mu.Lock()
-		s.events[event.ID] = event
-		s.mu.Unlock()
-	} else {
-		event, err = s.CreateEvent(ctx, event)
+	event.Tags = nil
+	event.Reminders = nil
+	stored, ok := s.events[event.ID]
+	if !ok {
+		return event, fmt.Errorf("%w: %d", storage.ErrEventNotFound, event.ID)
 	}
+	event.NotificationSent = stored.NotificationSent
+	event.NotificationReceived = stored.NotificationReceived
 
-	return event, err
+	s.putEvent(event)
+	s.record(storage.EventUpdated, event)
+
+	return event, nil
 }
 
-// RemoveEvent removes event from memory storage if exists.
+// RemoveEvent removes event with its tags, reminders and notifications from memory storage if exists.
 func (s *Storage) RemoveEvent(ctx context.Context, event storage.Event) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.lock(ctx)()
 
-	delete(s.events, event.ID)
+	removed, ok := s.events[event.ID]
+	if !ok {
+		return fmt.Errorf("%w: %d", storage.ErrEventNotFound, event.ID)
+	}
+
+	s.deleteEvent(removed)
+	s.keepTags(event.ID)
+	delete(s.tags, event.ID)
+	s.removeReminders(event.ID)
+	s.removeNotifications(event.ID)
+	s.record(storage.EventDeleted, removed)
 
 	return nil
 }
 
-// GetDayAheadEvents returns a day events slice.
-func (s *Storage) GetDayAheadEvents(ctx context.Context) ([]storage.Event, error) {
-	start := time.Now()
-	end := time.Now().Add(24 * time.Hour)
+// GetEventsInRange returns events overlapping the [begin, end) period ordered by their begin dates.
+func (s *Storage) GetEventsInRange(ctx context.Context, begin, end time.Time) ([]storage.Event, error) {
+	unlock := s.rlock(ctx)
+	index := s.index
+	unlock()
+
 	var events []storage.Event
+	index.overlapping(begin, end, func(event storage.Event) {
+		events = append(events, event)
+	})
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	return events, nil
+}
 
-	for _, event := range s.events {
-		if event.BeginDate.After(start) && event.BeginDate.Before(end) {
-			events = append(events, event)
+// GetOwnerEventsInRange returns events of the owners overlapping the [begin, end) period
+// ordered by their begin dates.
+func (s *Storage) GetOwnerEventsInRange(
+	ctx context.Context,
+	begin, end time.Time,
+	ownerIDs ...int64,
+) ([]storage.Event, error) {
+	unlock := s.rlock(ctx)
+	indexes := make(map[int64]*indexNode, len(ownerIDs))
+	for _, ownerID := range ownerIDs {
+		if index, ok := s.owners[ownerID]; ok {
+			indexes[ownerID] = index
 		}
 	}
+	unlock()
+
+	var events []storage.Event
+	for _, index := range indexes {
+		index.overlapping(begin, end, func(event storage.Event) {
+			events = append(events, event)
+		})
+	}
+
+	if len(indexes) > 1 {
+		sort.Slice(events, func(i, j int) bool {
+			if events[i].BeginDate.Equal(events[j].BeginDate) {
+				return events[i].ID < events[j].ID
+			}
+			return events[i].BeginDate.Before(events[j].BeginDate)
+		})
+	}
 
 	return events, nil
 }
 
+// GetDayAheadEvents returns a day events slice.
+func (s *Storage) GetDayAheadEvents(ctx context.Context) ([]storage.Event, error) {
+	now := s.clock.Now()
+	return s.GetEventsInRange(ctx, now, now.AddDate(0, 0, 1))
+}
+
 // GetWeekAheadEvents returns a week events slice.
 func (s *Storage) GetWeekAheadEvents(ctx context.Context) ([]storage.Event, error) {
-	start := time.Now()
-	end := time.Now().Add(24 * 7 * time.Hour)
-	var events []storage.Event
+	now := s.clock.Now()
+	return s.GetEventsInRange(ctx, now, now.AddDate(0, 0, 7))
+}
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// GetMonthAheadEvents returns a month events slice.
+func (s *Storage) GetMonthAheadEvents(ctx context.Context) ([]storage.Event, error) {
+	now := s.clock.Now()
+	return s.GetEventsInRange(ctx, now, now.AddDate(0, 1, 0))
+}
 
-	for _, event := range s.events {
-		if event.BeginDate.After(start) && event.BeginDate.Before(end) {
-			events = append(events, event)
-		}
+// RemoveExpiredEvents removes events that ended longer than the retention period ago.
+// Events without an end date are treated as instant ones.
+func (s *Storage) RemoveExpiredEvents(ctx context.Context, retention time.Duration) error {
+	defer s.lock(ctx)()
+
+	var expired []storage.Event
+	s.index.endedBefore(s.clock.Now().Add(-retention), func(event storage.Event) {
+		expired = append(expired, event)
+	})
+
+	for _, event := range expired {
+		s.deleteEvent(event)
+		s.keepTags(event.ID)
+		delete(s.tags, event.ID)
+		s.removeReminders(event.ID)
+		s.removeNotifications(event.ID)
+		s.record(storage.EventDeleted, event)
 	}
 
-	return events, nil
+	return nil
 }
 
-// GetMonthAheadEvents returns a month events slice.
-func (s *Storage) GetMonthAheadEvents(ctx context.Context) ([]storage.Event, error) {
-	start := time.Now()
-	end := time.Now().Add(24 * 7 * 30 * time.Hour)
-	var events []storage.Event
-
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// GetEventByID returns events by id, if exists.
+func (s *Storage) GetEventByID(ctx context.Context, id int64) (storage.Event, error) {
+	defer s.rlock(ctx)()
 
-	for _, event := range s.events {
-		if event.BeginDate.After(start) && event.BeginDate.Before(end) {
-			events = append(events, event)
-		}
+	event, ok := s.events[id]
+	if !ok {
+		return storage.Event{ID: id}, fmt.Errorf("%w: %d", storage.ErrEventNotFound, id)
 	}
 
-	return events, nil
+	return event, nil
 }
 
-// GetComingEvents returns events slice that need to be notified.
-func (s *Storage) GetComingEvents(ctx context.Context) ([]storage.Event, error) {
-	start := time.Now()
-	end := time.Now().Add(24 * 7 * 30 * time.Hour)
-	var events []storage.Event
+// GetChanges returns retained changes made after the given revision.
+func (s *Storage) GetChanges(ctx context.Context, since int64) ([]storage.Change, error) {
+	defer s.rlock(ctx)()
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	if since >= s.revision {
+		return nil, nil
+	}
 
-	for _, event := range s.events {
-		if event.BeginDate.After(start) && event.BeginDate.Before(end) && !event.NotificationSent {
-			events = append(events, event)
-		}
+	if len(s.changes) == 0 || since < s.changes[0].Revision-1 {
+		return nil, fmt.Errorf("%w: %d", storage.ErrRevisionExpired, since)
 	}
 
-	return events, nil
+	first := len(s.changes) - int(s.revision-since)
+	changes := make([]storage.Change, len(s.changes)-first)
+	copy(changes, s.changes[first:])
+
+	return changes, nil
 }
 
-// RemoveExpiredEvents removes events that happened more than one year ago.
-func (s *Storage) RemoveExpiredEvents(ctx context.Context) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// SubscribeChanges returns a channel of changes made from now on and a func releasing it.
+func (s *Storage) SubscribeChanges() (<-chan storage.Change, func()) {
+	return s.feed.Subscribe()
+}
 
-	oneYearAgo := time.Now().AddDate(-1, 0, 0)
-	for _, event := range s.events {
-		if event.EndDate.Before(oneYearAgo) {
-			delete(s.events, event.ID)
-		}
+// putEvent saves the event and indexes it in place of its former state, must be called under the write lock.
+func (s *Storage) putEvent(event storage.Event) {
+	if former, ok := s.events[event.ID]; ok {
+		s.unindexEvent(former)
 	}
 
-	return nil
+	s.keepEvent(event.ID)
+	s.events[event.ID] = event
+	s.index = s.index.insert(event)
+	s.keepOwnerIndex(event.OwnerID)
+	s.owners[event.OwnerID] = s.owners[event.OwnerID].insert(event)
 }
 
-// GetEventByID returns events by id, if exists.
-func (s *Storage) GetEventByID(ctx context.Context, id int64) (storage.Event, error) {
-	event := storage.Event{
-		ID: id,
+// deleteEvent removes the stored event along with its indexes, must be called under the write lock.
+func (s *Storage) deleteEvent(event storage.Event) {
+	s.keepEvent(event.ID)
+	delete(s.events, event.ID)
+	s.unindexEvent(event)
+}
+
+func (s *Storage) unindexEvent(event storage.Event) {
+	s.index = s.index.remove(event)
+
+	s.keepOwnerIndex(event.OwnerID)
+	if index := s.owners[event.OwnerID].remove(event); index != nil {
+		s.owners[event.OwnerID] = index
+	} else {
+		delete(s.owners, event.OwnerID)
+	}
+}
+
+// record appends a change to the log and publishes it, must be called under the write lock.
+// Changes made in a transaction are published on commit.
+func (s *Storage) record(changeType storage.ChangeType, event storage.Event) {
+	s.revision++
+	change := storage.Change{
+		Revision: s.revision,
+		Type:     changeType,
+		Event:    event,
 	}
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	// The log is resliced rather than shifted in place, a transaction snapshot may share its array.
+	if len(s.changes) == changeLogSize {
+		s.changes = s.changes[1:]
+	}
+	s.changes = append(s.changes, change)
 
-	for _, event := range s.events {
-		if event.ID == id {
-			break
-		}
+	if s.inTx {
+		s.pending = append(s.pending, change)
+		return
 	}
 
-	return event, nil
+	s.feed.Publish(change)
+}
+
+// endOf returns the end of the event, which is its begin for events without an end date.
+func endOf(event storage.Event) time.Time {
+	if event.EndDate.Before(event.BeginDate) {
+		return event.BeginDate
+	}
+
+	return event.EndDate
 }
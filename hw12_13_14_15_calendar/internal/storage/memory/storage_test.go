@@ -2,16 +2,20 @@ package memorystorage
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
 	internalstorage "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/storagetest"
 	"github.com/stretchr/testify/require"
 )
 
 func TestStorage(t *testing.T) {
 	t.Run("storage memory", func(t *testing.T) {
-		storage := New()
+		storage := New(clock.Real{})
 
 		ctx := context.Background()
 
@@ -82,3 +86,125 @@ func TestStorage(t *testing.T) {
 		require.Len(t, events, 2, "Len is %s, but expected %s", len(events), 2)
 	})
 }
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, clock clock.Clock) app.Storage {
+		return New(clock)
+	})
+}
+
+// TestRollback checks a failed transaction restores every entry it changed from the undo log.
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+	begin := time.Now().Add(time.Hour)
+	s := New(clock.Real{})
+
+	event, err := s.CreateEvent(ctx, internalstorage.Event{Title: "kept", BeginDate: begin, EndDate: begin, OwnerID: 1})
+	require.NoError(t, err)
+	require.NoError(t, s.SetEventTags(ctx, event.ID, []string{"work"}))
+	reminder, err := s.CreateReminder(ctx, internalstorage.Reminder{EventID: event.ID, OwnerID: 1, RemindAt: begin})
+	require.NoError(t, err)
+	_, err = s.CreateNotification(ctx, internalstorage.Notification{EventID: event.ID, ReminderID: reminder.ID, OwnerID: 1})
+	require.NoError(t, err)
+	category, err := s.CreateCategory(ctx, internalstorage.Category{OwnerID: 1, Name: "work"})
+	require.NoError(t, err)
+	calendar, err := s.CreateCalendar(ctx, internalstorage.Calendar{OwnerID: 1, Name: "team"})
+	require.NoError(t, err)
+	_, err = s.SetGrant(ctx, internalstorage.Grant{CalendarID: calendar.ID, OwnerID: 1, GranteeID: 2})
+	require.NoError(t, err)
+	token, err := s.CreateAPIToken(ctx, internalstorage.APIToken{Name: "ci", OwnerID: 1})
+	require.NoError(t, err)
+	_, err = s.SetDigestSubscription(ctx, internalstorage.DigestSubscription{OwnerID: 1, Period: "daily"})
+	require.NoError(t, err)
+
+	before := dump(s)
+
+	errRollback := errors.New("rollback")
+	err = s.WithinTransaction(ctx, func(ctx context.Context) error {
+		_, err := s.CreateEvent(ctx, internalstorage.Event{Title: "new", BeginDate: begin, EndDate: begin, OwnerID: 3})
+		require.NoError(t, err)
+		require.NoError(t, s.RemoveEvent(ctx, event))
+		require.NoError(t, s.RemoveCategory(ctx, category.ID))
+		require.NoError(t, s.RemoveGrant(ctx, calendar.ID, 2))
+		require.NoError(t, s.RemoveCalendar(ctx, calendar.ID))
+		require.NoError(t, s.RevokeAPIToken(ctx, token.ID))
+		require.NoError(t, s.RemoveDigestSubscription(ctx, 1))
+		_, err = s.CreateCategory(ctx, internalstorage.Category{OwnerID: 1, Name: "new"})
+		require.NoError(t, err)
+
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+	require.Equal(t, before, dump(s))
+}
+
+// storageState is a copy of the memory storage contents.
+type storageState struct {
+	increments    []int64
+	events        map[int64]internalstorage.Event
+	index         *indexNode
+	owners        map[int64]*indexNode
+	changes       []internalstorage.Change
+	tokens        map[int64]internalstorage.APIToken
+	digests       map[int64]internalstorage.DigestSubscription
+	reminders     map[int64]internalstorage.Reminder
+	notifications map[int64]internalstorage.Notification
+	categories    map[int64]internalstorage.Category
+	tags          map[int64][]string
+	calendars     map[int64]internalstorage.Calendar
+	grants        map[grantKey]internalstorage.Grant
+}
+
+func dump(s *Storage) storageState {
+	state := storageState{
+		increments: []int64{
+			s.increment, s.revision, s.tokenIncrement, s.reminderIncrement,
+			s.notificationIncrement, s.categoryIncrement, s.calendarIncrement,
+		},
+		events:        map[int64]internalstorage.Event{},
+		index:         s.index,
+		owners:        map[int64]*indexNode{},
+		changes:       append([]internalstorage.Change(nil), s.changes...),
+		tokens:        map[int64]internalstorage.APIToken{},
+		digests:       map[int64]internalstorage.DigestSubscription{},
+		reminders:     map[int64]internalstorage.Reminder{},
+		notifications: map[int64]internalstorage.Notification{},
+		categories:    map[int64]internalstorage.Category{},
+		tags:          map[int64][]string{},
+		calendars:     map[int64]internalstorage.Calendar{},
+		grants:        map[grantKey]internalstorage.Grant{},
+	}
+
+	for id, event := range s.events {
+		state.events[id] = event
+	}
+	for ownerID, index := range s.owners {
+		state.owners[ownerID] = index
+	}
+	for id, token := range s.tokens {
+		state.tokens[id] = token
+	}
+	for ownerID, subscription := range s.digests {
+		state.digests[ownerID] = subscription
+	}
+	for id, reminder := range s.reminders {
+		state.reminders[id] = reminder
+	}
+	for id, notification := range s.notifications {
+		state.notifications[id] = notification
+	}
+	for id, category := range s.categories {
+		state.categories[id] = category
+	}
+	for eventID, tags := range s.tags {
+		state.tags[eventID] = tags
+	}
+	for id, calendar := range s.calendars {
+		state.calendars[id] = calendar
+	}
+	for key, grant := range s.grants {
+		state.grants[key] = grant
+	}
+
+	return state
+}
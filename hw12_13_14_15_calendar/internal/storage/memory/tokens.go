@@ -0,0 +1,72 @@
+package memorystorage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// CreateAPIToken saves the hashed api token into a memory storage.
+func (s *Storage) CreateAPIToken(ctx context.Context, token storage.APIToken) (storage.APIToken, error) {
+	defer s.lock(ctx)()
+
+	s.tokenIncrement++
+	token.ID = s.tokenIncrement
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	s.keepToken(token.ID)
+	s.tokens[token.ID] = token
+
+	return token, nil
+}
+
+// GetAPITokenByHash returns the api token with the given hash, if exists.
+func (s *Storage) GetAPITokenByHash(ctx context.Context, hash string) (storage.APIToken, error) {
+	defer s.rlock(ctx)()
+
+	for _, token := range s.tokens {
+		if token.Hash == hash {
+			return token, nil
+		}
+	}
+
+	return storage.APIToken{}, storage.ErrTokenNotFound
+}
+
+// ListAPITokens returns all api tokens ordered by id.
+func (s *Storage) ListAPITokens(ctx context.Context) ([]storage.APIToken, error) {
+	defer s.rlock(ctx)()
+
+	tokens := make([]storage.APIToken, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].ID < tokens[j].ID
+	})
+
+	return tokens, nil
+}
+
+// RevokeAPIToken marks the api token as revoked, if exists.
+func (s *Storage) RevokeAPIToken(ctx context.Context, id int64) error {
+	defer s.lock(ctx)()
+
+	token, ok := s.tokens[id]
+	if !ok {
+		return fmt.Errorf("%w: %d", storage.ErrTokenNotFound, id)
+	}
+
+	if token.RevokedAt == nil {
+		now := time.Now()
+		token.RevokedAt = &now
+		s.keepToken(id)
+		s.tokens[id] = token
+	}
+
+	return nil
+}
@@ -0,0 +1,272 @@
+package memorystorage
+
+import (
+	"context"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+type txKey struct{}
+
+// snapshot holds the counters and index roots a failed transaction is rolled back to,
+// the map entries it changed are restored from the undo log.
+type snapshot struct {
+	increment             int64
+	index                 *indexNode
+	revision              int64
+	changes               []storage.Change
+	tokenIncrement        int64
+	reminderIncrement     int64
+	notificationIncrement int64
+	categoryIncrement     int64
+	calendarIncrement     int64
+}
+
+// WithinTransaction runs fn holding the storage write lock, changes made by fn are discarded
+// when it fails. Calls nested in fn join the outer transaction.
+// Change notifications of the transaction are delivered on commit.
+func (s *Storage) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.inTransaction(ctx) {
+		return fn(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := s.snapshot()
+	s.inTx = true
+
+	err := fn(context.WithValue(ctx, txKey{}, s))
+
+	s.inTx = false
+	pending := s.pending
+	s.pending = nil
+	undo := s.undo
+	s.undo = nil
+
+	if err != nil {
+		s.restore(saved, undo)
+		return err
+	}
+
+	for _, change := range pending {
+		s.feed.Publish(change)
+	}
+
+	return nil
+}
+
+// inTransaction reports whether ctx carries a transaction of this storage, which already holds the lock.
+func (s *Storage) inTransaction(ctx context.Context) bool {
+	tx, ok := ctx.Value(txKey{}).(*Storage)
+	return ok && tx == s
+}
+
+// lock takes the write lock unless ctx is in a transaction, the returned func releases it.
+func (s *Storage) lock(ctx context.Context) func() {
+	if s.inTransaction(ctx) {
+		return func() {}
+	}
+
+	s.mu.Lock()
+
+	return s.mu.Unlock
+}
+
+// rlock takes the read lock unless ctx is in a transaction, the returned func releases it.
+func (s *Storage) rlock(ctx context.Context) func() {
+	if s.inTransaction(ctx) {
+		return func() {}
+	}
+
+	s.mu.RLock()
+
+	return s.mu.RUnlock
+}
+
+// snapshot saves the counters and index roots, which are cheap to copy: the indexes are never
+// changed in place and the change log is only appended to or resliced.
+func (s *Storage) snapshot() snapshot {
+	return snapshot{
+		increment:             s.increment,
+		index:                 s.index,
+		revision:              s.revision,
+		changes:               s.changes,
+		tokenIncrement:        s.tokenIncrement,
+		reminderIncrement:     s.reminderIncrement,
+		notificationIncrement: s.notificationIncrement,
+		categoryIncrement:     s.categoryIncrement,
+		calendarIncrement:     s.calendarIncrement,
+	}
+}
+
+// restore undoes the map changes latest first and resets the counters.
+func (s *Storage) restore(saved snapshot, undo []func()) {
+	for i := len(undo) - 1; i >= 0; i-- {
+		undo[i]()
+	}
+
+	s.increment = saved.increment
+	s.index = saved.index
+	s.revision = saved.revision
+	s.changes = saved.changes
+	s.tokenIncrement = saved.tokenIncrement
+	s.reminderIncrement = saved.reminderIncrement
+	s.notificationIncrement = saved.notificationIncrement
+	s.categoryIncrement = saved.categoryIncrement
+	s.calendarIncrement = saved.calendarIncrement
+}
+
+// The keep funcs add the current state of an entry to the undo log of the running transaction,
+// they are called under the write lock before the entry is changed.
+
+func (s *Storage) keepEvent(id int64) {
+	if !s.inTx {
+		return
+	}
+
+	event, ok := s.events[id]
+	s.undo = append(s.undo, func() {
+		if ok {
+			s.events[id] = event
+		} else {
+			delete(s.events, id)
+		}
+	})
+}
+
+func (s *Storage) keepOwnerIndex(ownerID int64) {
+	if !s.inTx {
+		return
+	}
+
+	index, ok := s.owners[ownerID]
+	s.undo = append(s.undo, func() {
+		if ok {
+			s.owners[ownerID] = index
+		} else {
+			delete(s.owners, ownerID)
+		}
+	})
+}
+
+func (s *Storage) keepToken(id int64) {
+	if !s.inTx {
+		return
+	}
+
+	token, ok := s.tokens[id]
+	s.undo = append(s.undo, func() {
+		if ok {
+			s.tokens[id] = token
+		} else {
+			delete(s.tokens, id)
+		}
+	})
+}
+
+func (s *Storage) keepDigest(ownerID int64) {
+	if !s.inTx {
+		return
+	}
+
+	subscription, ok := s.digests[ownerID]
+	s.undo = append(s.undo, func() {
+		if ok {
+			s.digests[ownerID] = subscription
+		} else {
+			delete(s.digests, ownerID)
+		}
+	})
+}
+
+func (s *Storage) keepReminder(id int64) {
+	if !s.inTx {
+		return
+	}
+
+	reminder, ok := s.reminders[id]
+	s.undo = append(s.undo, func() {
+		if ok {
+			s.reminders[id] = reminder
+		} else {
+			delete(s.reminders, id)
+		}
+	})
+}
+
+func (s *Storage) keepNotification(id int64) {
+	if !s.inTx {
+		return
+	}
+
+	notification, ok := s.notifications[id]
+	s.undo = append(s.undo, func() {
+		if ok {
+			s.notifications[id] = notification
+		} else {
+			delete(s.notifications, id)
+		}
+	})
+}
+
+func (s *Storage) keepCategory(id int64) {
+	if !s.inTx {
+		return
+	}
+
+	category, ok := s.categories[id]
+	s.undo = append(s.undo, func() {
+		if ok {
+			s.categories[id] = category
+		} else {
+			delete(s.categories, id)
+		}
+	})
+}
+
+// keepTags relies on the tags slices being replaced rather than changed in place.
+func (s *Storage) keepTags(eventID int64) {
+	if !s.inTx {
+		return
+	}
+
+	tags, ok := s.tags[eventID]
+	s.undo = append(s.undo, func() {
+		if ok {
+			s.tags[eventID] = tags
+		} else {
+			delete(s.tags, eventID)
+		}
+	})
+}
+
+func (s *Storage) keepCalendar(id int64) {
+	if !s.inTx {
+		return
+	}
+
+	calendar, ok := s.calendars[id]
+	s.undo = append(s.undo, func() {
+		if ok {
+			s.calendars[id] = calendar
+		} else {
+			delete(s.calendars, id)
+		}
+	})
+}
+
+func (s *Storage) keepGrant(key grantKey) {
+	if !s.inTx {
+		return
+	}
+
+	grant, ok := s.grants[key]
+	s.undo = append(s.undo, func() {
+		if ok {
+			s.grants[key] = grant
+		} else {
+			delete(s.grants, key)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// Notification states. A notification is published to the broker, then delivered or failed
+// as reported by the sender, and becomes pending again when it is sent once more.
+const (
+	NotificationPending   = "pending"
+	NotificationPublished = "published"
+	NotificationDelivered = "delivered"
+	NotificationFailed    = "failed"
+)
+
+// ChannelConsole is the channel of the sender printing the notifications out.
+const ChannelConsole = "console"
+
+// Notification tracks sending a reminder of an event through a channel.
+type Notification struct {
+	ID          int64      `db:"id" json:"id"`
+	EventID     int64      `db:"event_id" json:"event_id"`
+	ReminderID  int64      `db:"reminder_id" json:"reminder_id"`
+	OwnerID     int64      `db:"owner_id" json:"owner_id"`
+	Channel     string     `db:"channel" json:"channel"`
+	State       string     `db:"state" json:"state"`
+	Attempts    int        `db:"attempts" json:"attempts"`
+	LastError   string     `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+	PublishedAt *time.Time `db:"published_at" json:"published_at,omitempty"`
+	DeliveredAt *time.Time `db:"delivered_at" json:"delivered_at,omitempty"`
+}
+
+// NotificationFilter selects notifications, zero fields matching any value.
+type NotificationFilter struct {
+	EventID int64
+	OwnerID int64
+	State   string
+}
+
+// Match reports whether the notification is selected by the filter.
+func (f NotificationFilter) Match(notification Notification) bool {
+	return (f.EventID == 0 || notification.EventID == f.EventID) &&
+		(f.OwnerID == 0 || notification.OwnerID == f.OwnerID) &&
+		(f.State == "" || notification.State == f.State)
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrReminderNotFound = errors.New("reminder not found")
+	ErrReminderChanged  = errors.New("reminder changed meanwhile")
+)
+
+// Reminder states. A pending reminder is due at its time while the event has not begun yet,
+// a snoozed one is due at its time regardless. A failed one has not been sent in all the attempts.
+const (
+	ReminderPending   = "pending"
+	ReminderSent      = "sent"
+	ReminderDelivered = "delivered"
+	ReminderSnoozed   = "snoozed"
+	ReminderDismissed = "dismissed"
+	ReminderFailed    = "failed"
+)
+
+// Reminder notifies the owner of an event the offset before the event begins.
+type Reminder struct {
+	ID      int64 `db:"id" json:"id"`
+	EventID int64 `db:"event_id" json:"event_id"`
+	OwnerID int64 `db:"owner_id" json:"owner_id"`
+	// Offset is stored in nanoseconds.
+	Offset   time.Duration `db:"offset_ns" json:"offset"`
+	RemindAt time.Time     `db:"remind_at" json:"remind_at"`
+	State    string        `db:"state" json:"state"`
+}
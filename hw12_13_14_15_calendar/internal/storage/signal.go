@@ -0,0 +1,49 @@
+package storage
+
+import "sync"
+
+// SignalFeed wakes live subscribers up without telling them anything else. Signals published
+// while a subscriber has not received the previous one yet are merged into it.
+type SignalFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+}
+
+// NewSignalFeed returns a feed without subscribers.
+func NewSignalFeed() *SignalFeed {
+	return &SignalFeed{
+		subscribers: make(map[chan struct{}]struct{}),
+	}
+}
+
+// Subscribe returns a channel of signals published from now on and a func releasing it.
+func (f *SignalFeed) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	f.mu.Lock()
+	f.subscribers[ch] = struct{}{}
+	f.mu.Unlock()
+
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if _, ok := f.subscribers[ch]; ok {
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish signals every subscriber without blocking.
+func (f *SignalFeed) Publish() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx"
+	"github.com/jmoiron/sqlx"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// foreignKeyViolation is the postgres error code of a reference to a missing row.
+const foreignKeyViolation = "23503"
+
+var (
+	ErrCreateCalendar = errors.New("create calendar error")
+	ErrUpdateCalendar = errors.New("update calendar error")
+	ErrRemoveCalendar = errors.New("removing calendar error")
+	ErrGetCalendar    = errors.New("getting calendar error")
+	ErrSetGrant       = errors.New("setting grant error")
+	ErrRemoveGrant    = errors.New("removing grant error")
+	ErrGetGrant       = errors.New("getting grant error")
+)
+
+// CreateCalendar saves the calendar into a sql storage, an owner may have a single default one.
+// A second default calendar is skipped rather than failing the insert, so that the transaction
+// creating it may go on reading the existing one.
+func (s *Storage) CreateCalendar(ctx context.Context, calendar storage.Calendar) (storage.Calendar, error) {
+	ctx, done := instrument(ctx, "create_calendar")
+	defer done()
+
+	calendar.Access = storage.AccessNone
+
+	query := `
+		INSERT INTO app_calendar (owner_id, name, is_default)
+		VALUES (:owner_id, :name, :is_default)
+		ON CONFLICT (owner_id) WHERE is_default DO NOTHING
+		RETURNING id
+	`
+
+	rows, err := sqlx.NamedQueryContext(ctx, s.ext(ctx), query, calendar)
+	if err != nil {
+		return calendar, calendarError(ErrCreateCalendar, calendar, err)
+	}
+
+	defer rows.Close()
+
+	created := false
+	for rows.Next() {
+		if err := rows.Scan(&calendar.ID); err != nil {
+			return calendar, fmt.Errorf("%w: %v", ErrCreateCalendar, err)
+		}
+		created = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return calendar, calendarError(ErrCreateCalendar, calendar, err)
+	}
+
+	if !created {
+		return calendar, fmt.Errorf("%w: owner %d", storage.ErrCalendarExists, calendar.OwnerID)
+	}
+
+	return calendar, nil
+}
+
+// UpdateCalendar updates the calendar in sql storage if exists.
+func (s *Storage) UpdateCalendar(ctx context.Context, calendar storage.Calendar) (storage.Calendar, error) {
+	ctx, done := instrument(ctx, "update_calendar")
+	defer done()
+
+	calendar.Access = storage.AccessNone
+
+	query := `
+		UPDATE app_calendar
+		SET owner_id = :owner_id, name = :name, is_default = :is_default
+		WHERE id = :id
+	`
+
+	result, err := sqlx.NamedExecContext(ctx, s.ext(ctx), query, calendar)
+	if err != nil {
+		return calendar, calendarError(ErrUpdateCalendar, calendar, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return calendar, fmt.Errorf("%w: %v", ErrUpdateCalendar, err)
+	}
+
+	if affected == 0 {
+		return calendar, fmt.Errorf("%w: %d", storage.ErrCalendarNotFound, calendar.ID)
+	}
+
+	return calendar, nil
+}
+
+// RemoveCalendar removes the calendar along with its grants from sql storage,
+// calendars still having events are kept.
+func (s *Storage) RemoveCalendar(ctx context.Context, id int64) error {
+	return s.WithinTransaction(ctx, func(ctx context.Context) error {
+		ctx, done := instrument(ctx, "remove_calendar")
+		defer done()
+
+		ext := s.ext(ctx)
+
+		// Locking the calendar keeps events from being moved into it meanwhile.
+		var locked int64
+		err := sqlx.GetContext(ctx, ext, &locked, "SELECT id FROM app_calendar WHERE id = $1 FOR UPDATE", id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %d", storage.ErrCalendarNotFound, id)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrRemoveCalendar, err)
+		}
+
+		var used bool
+		query := "SELECT EXISTS (SELECT 1 FROM app_event WHERE calendar_id = $1)"
+		if err := sqlx.GetContext(ctx, ext, &used, query, id); err != nil {
+			return fmt.Errorf("%w: %v", ErrRemoveCalendar, err)
+		}
+
+		if used {
+			return fmt.Errorf("%w: %d", storage.ErrCalendarNotEmpty, id)
+		}
+
+		// Grants are removed by the cascade.
+		if _, err := ext.ExecContext(ctx, "DELETE FROM app_calendar WHERE id = $1", id); err != nil {
+			return fmt.Errorf("%w: %v", ErrRemoveCalendar, err)
+		}
+
+		return nil
+	})
+}
+
+// GetCalendar returns the calendar by id, if exists.
+func (s *Storage) GetCalendar(ctx context.Context, id int64) (storage.Calendar, error) {
+	ctx, done := instrument(ctx, "get_calendar")
+	defer done()
+
+	calendar := storage.Calendar{}
+
+	err := sqlx.GetContext(ctx, s.ext(ctx), &calendar, "SELECT * FROM app_calendar WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return calendar, fmt.Errorf("%w: %d", storage.ErrCalendarNotFound, id)
+	}
+	if err != nil {
+		return calendar, fmt.Errorf("%w: %v", ErrGetCalendar, err)
+	}
+
+	return calendar, nil
+}
+
+// GetDefaultCalendar returns the default calendar of the owner, if exists.
+func (s *Storage) GetDefaultCalendar(ctx context.Context, ownerID int64) (storage.Calendar, error) {
+	ctx, done := instrument(ctx, "get_default_calendar")
+	defer done()
+
+	calendar := storage.Calendar{}
+
+	query := "SELECT * FROM app_calendar WHERE owner_id = $1 AND is_default"
+	err := sqlx.GetContext(ctx, s.ext(ctx), &calendar, query, ownerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return calendar, fmt.Errorf("%w: default of owner %d", storage.ErrCalendarNotFound, ownerID)
+	}
+	if err != nil {
+		return calendar, fmt.Errorf("%w: %v", ErrGetCalendar, err)
+	}
+
+	return calendar, nil
+}
+
+// ListCalendars returns the calendars of the owner ordered by name.
+func (s *Storage) ListCalendars(ctx context.Context, ownerID int64) ([]storage.Calendar, error) {
+	ctx, done := instrument(ctx, "list_calendars")
+	defer done()
+
+	var calendars []storage.Calendar
+
+	query := "SELECT * FROM app_calendar WHERE owner_id = $1 ORDER BY name, id"
+	if err := sqlx.SelectContext(ctx, s.ext(ctx), &calendars, query, ownerID); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetCalendar, err)
+	}
+
+	return calendars, nil
+}
+
+// SetGrant grants the access to an existing calendar, replacing the former access of the grantee.
+func (s *Storage) SetGrant(ctx context.Context, grant storage.Grant) (storage.Grant, error) {
+	ctx, done := instrument(ctx, "set_grant")
+	defer done()
+
+	query := `
+		INSERT INTO app_calendar_grant (calendar_id, owner_id, grantee_id, access)
+		VALUES (:calendar_id, :owner_id, :grantee_id, :access)
+		ON CONFLICT (calendar_id, grantee_id) DO UPDATE SET owner_id = EXCLUDED.owner_id, access = EXCLUDED.access
+	`
+
+	if _, err := sqlx.NamedExecContext(ctx, s.ext(ctx), query, grant); err != nil {
+		var pgErr pgx.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return grant, fmt.Errorf("%w: %d", storage.ErrCalendarNotFound, grant.CalendarID)
+		}
+
+		return grant, fmt.Errorf("%w: %v", ErrSetGrant, err)
+	}
+
+	return grant, nil
+}
+
+// RemoveGrant revokes the access of the grantee to the calendar.
+func (s *Storage) RemoveGrant(ctx context.Context, calendarID, granteeID int64) error {
+	ctx, done := instrument(ctx, "remove_grant")
+	defer done()
+
+	query := "DELETE FROM app_calendar_grant WHERE calendar_id = $1 AND grantee_id = $2"
+	result, err := s.ext(ctx).ExecContext(ctx, query, calendarID, granteeID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRemoveGrant, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRemoveGrant, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%w: calendar %d, grantee %d", storage.ErrGrantNotFound, calendarID, granteeID)
+	}
+
+	return nil
+}
+
+// GetGrant returns the grant of the calendar to the grantee, if exists.
+func (s *Storage) GetGrant(ctx context.Context, calendarID, granteeID int64) (storage.Grant, error) {
+	ctx, done := instrument(ctx, "get_grant")
+	defer done()
+
+	grant := storage.Grant{}
+
+	query := "SELECT * FROM app_calendar_grant WHERE calendar_id = $1 AND grantee_id = $2"
+	err := sqlx.GetContext(ctx, s.ext(ctx), &grant, query, calendarID, granteeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return grant, fmt.Errorf("%w: calendar %d, grantee %d", storage.ErrGrantNotFound, calendarID, granteeID)
+	}
+	if err != nil {
+		return grant, fmt.Errorf("%w: %v", ErrGetGrant, err)
+	}
+
+	return grant, nil
+}
+
+// ListGrants returns the grants passing the filter ordered by calendar and grantee.
+func (s *Storage) ListGrants(ctx context.Context, filter storage.GrantFilter) ([]storage.Grant, error) {
+	ctx, done := instrument(ctx, "list_grants")
+	defer done()
+
+	var (
+		conditions = []string{"TRUE"}
+		args       []interface{}
+	)
+	if filter.CalendarID != 0 {
+		args = append(args, filter.CalendarID)
+		conditions = append(conditions, fmt.Sprintf("calendar_id = $%d", len(args)))
+	}
+	if filter.GranteeID != 0 {
+		args = append(args, filter.GranteeID)
+		conditions = append(conditions, fmt.Sprintf("grantee_id = $%d", len(args)))
+	}
+
+	var grants []storage.Grant
+
+	query := "SELECT * FROM app_calendar_grant WHERE " + strings.Join(conditions, " AND ") + " ORDER BY calendar_id, grantee_id"
+	if err := sqlx.SelectContext(ctx, s.ext(ctx), &grants, query, args...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetGrant, err)
+	}
+
+	return grants, nil
+}
+
+// calendarError reports a second default calendar apart from other failures.
+func calendarError(kind error, calendar storage.Calendar, err error) error {
+	var pgErr pgx.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return fmt.Errorf("%w: owner %d", storage.ErrCalendarExists, calendar.OwnerID)
+	}
+
+	return fmt.Errorf("%w: %v", kind, err)
+}
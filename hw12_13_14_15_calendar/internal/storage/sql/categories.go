@@ -0,0 +1,245 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx"
+	"github.com/jmoiron/sqlx"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// uniqueViolation is the postgres error code of a broken unique constraint.
+const uniqueViolation = "23505"
+
+var (
+	ErrCreateCategory = errors.New("create category error")
+	ErrUpdateCategory = errors.New("update category error")
+	ErrRemoveCategory = errors.New("removing category error")
+	ErrGetCategory    = errors.New("getting category error")
+	ErrSetTags        = errors.New("setting event tags error")
+	ErrGetTags        = errors.New("getting event tags error")
+)
+
+// CreateCategory saves the category into a sql storage, names are unique per owner.
+func (s *Storage) CreateCategory(ctx context.Context, category storage.Category) (storage.Category, error) {
+	ctx, done := instrument(ctx, "create_category")
+	defer done()
+
+	query := `
+		INSERT INTO app_category (owner_id, name, color, reminders)
+		VALUES (:owner_id, :name, :color, :reminders)
+		RETURNING id
+	`
+
+	rows, err := sqlx.NamedQueryContext(ctx, s.ext(ctx), query, category)
+	if err != nil {
+		return category, categoryError(ErrCreateCategory, category, err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := rows.Scan(&category.ID); err != nil {
+			return category, fmt.Errorf("%w: %v", ErrCreateCategory, err)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return category, categoryError(ErrCreateCategory, category, err)
+	}
+
+	return category, nil
+}
+
+// UpdateCategory updates the category in sql storage if exists.
+func (s *Storage) UpdateCategory(ctx context.Context, category storage.Category) (storage.Category, error) {
+	ctx, done := instrument(ctx, "update_category")
+	defer done()
+
+	query := `
+		UPDATE app_category
+		SET owner_id = :owner_id, name = :name, color = :color, reminders = :reminders
+		WHERE id = :id
+	`
+
+	result, err := sqlx.NamedExecContext(ctx, s.ext(ctx), query, category)
+	if err != nil {
+		return category, categoryError(ErrUpdateCategory, category, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return category, fmt.Errorf("%w: %v", ErrUpdateCategory, err)
+	}
+
+	if affected == 0 {
+		return category, fmt.Errorf("%w: %d", storage.ErrCategoryNotFound, category.ID)
+	}
+
+	return category, nil
+}
+
+// RemoveCategory removes the category from sql storage if exists, its events are left without a category.
+func (s *Storage) RemoveCategory(ctx context.Context, id int64) error {
+	return s.WithinTransaction(ctx, func(ctx context.Context) error {
+		ctx, done := instrument(ctx, "remove_category")
+		defer done()
+
+		// Updating the events records their changes for the watchers, before the reference is gone.
+		_, err := s.ext(ctx).ExecContext(ctx, "UPDATE app_event SET category_id = NULL WHERE category_id = $1", id)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrRemoveCategory, err)
+		}
+
+		result, err := s.ext(ctx).ExecContext(ctx, "DELETE FROM app_category WHERE id = $1", id)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrRemoveCategory, err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrRemoveCategory, err)
+		}
+
+		if affected == 0 {
+			return fmt.Errorf("%w: %d", storage.ErrCategoryNotFound, id)
+		}
+
+		return nil
+	})
+}
+
+// GetCategory returns the category by id, if exists.
+func (s *Storage) GetCategory(ctx context.Context, id int64) (storage.Category, error) {
+	ctx, done := instrument(ctx, "get_category")
+	defer done()
+
+	category := storage.Category{}
+
+	err := sqlx.GetContext(ctx, s.ext(ctx), &category, "SELECT * FROM app_category WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return category, fmt.Errorf("%w: %d", storage.ErrCategoryNotFound, id)
+	}
+	if err != nil {
+		return category, fmt.Errorf("%w: %v", ErrGetCategory, err)
+	}
+
+	return category, nil
+}
+
+// ListCategories returns the categories of the owner ordered by name.
+func (s *Storage) ListCategories(ctx context.Context, ownerID int64) ([]storage.Category, error) {
+	ctx, done := instrument(ctx, "list_categories")
+	defer done()
+
+	var categories []storage.Category
+
+	query := "SELECT * FROM app_category WHERE owner_id = $1 ORDER BY name, id"
+	if err := sqlx.SelectContext(ctx, s.ext(ctx), &categories, query, ownerID); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetCategory, err)
+	}
+
+	return categories, nil
+}
+
+// SetEventTags replaces the tags of an existing event, recording an update of the event if they differ.
+func (s *Storage) SetEventTags(ctx context.Context, eventID int64, tags []string) error {
+	return s.WithinTransaction(ctx, func(ctx context.Context) error {
+		ctx, done := instrument(ctx, "set_event_tags")
+		defer done()
+
+		ext := s.ext(ctx)
+
+		var current []string
+		query := "SELECT tag FROM app_event_tag WHERE event_id = $1 ORDER BY tag"
+		if err := sqlx.SelectContext(ctx, ext, &current, query, eventID); err != nil {
+			return fmt.Errorf("%w: %v", ErrSetTags, err)
+		}
+
+		sorted := make([]string, len(tags))
+		copy(sorted, tags)
+		sort.Strings(sorted)
+
+		if storage.EqualTags(current, sorted) {
+			var exists bool
+			query := "SELECT EXISTS (SELECT 1 FROM app_event WHERE id = $1)"
+			if err := sqlx.GetContext(ctx, ext, &exists, query, eventID); err != nil {
+				return fmt.Errorf("%w: %v", ErrSetTags, err)
+			}
+			if !exists {
+				return fmt.Errorf("%w: %d", storage.ErrEventNotFound, eventID)
+			}
+
+			return nil
+		}
+
+		// Touching the event records its update and keeps it from being removed meanwhile.
+		var id int64
+		err := sqlx.GetContext(ctx, ext, &id, "UPDATE app_event SET id = id WHERE id = $1 RETURNING id", eventID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %d", storage.ErrEventNotFound, eventID)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrSetTags, err)
+		}
+
+		if _, err := ext.ExecContext(ctx, "DELETE FROM app_event_tag WHERE event_id = $1", eventID); err != nil {
+			return fmt.Errorf("%w: %v", ErrSetTags, err)
+		}
+
+		for _, tag := range sorted {
+			query := "INSERT INTO app_event_tag (event_id, tag) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+			if _, err := ext.ExecContext(ctx, query, eventID, tag); err != nil {
+				return fmt.Errorf("%w: %v", ErrSetTags, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetEventTags returns the sorted tags of the given events, events without tags are left out.
+func (s *Storage) GetEventTags(ctx context.Context, eventIDs ...int64) (map[int64][]string, error) {
+	tags := make(map[int64][]string, len(eventIDs))
+	if len(eventIDs) == 0 {
+		return tags, nil
+	}
+
+	ctx, done := instrument(ctx, "get_event_tags")
+	defer done()
+
+	query, args, err := sqlx.In("SELECT event_id, tag FROM app_event_tag WHERE event_id IN (?) ORDER BY event_id, tag", eventIDs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetTags, err)
+	}
+
+	var rows []struct {
+		EventID int64  `db:"event_id"`
+		Tag     string `db:"tag"`
+	}
+
+	ext := s.ext(ctx)
+	if err := sqlx.SelectContext(ctx, ext, &rows, ext.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetTags, err)
+	}
+
+	for _, row := range rows {
+		tags[row.EventID] = append(tags[row.EventID], row.Tag)
+	}
+
+	return tags, nil
+}
+
+// categoryError reports a duplicate name apart from other failures.
+func categoryError(kind error, category storage.Category, err error) error {
+	var pgErr pgx.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return fmt.Errorf("%w: %q", storage.ErrCategoryExists, category.Name)
+	}
+
+	return fmt.Errorf("%w: %v", kind, err)
+}
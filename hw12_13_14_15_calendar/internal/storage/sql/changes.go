@@ -0,0 +1,270 @@
+package sqlstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/jmoiron/sqlx"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	changeChannel   = "app_event_change"
+	reminderChannel = "app_reminder_change"
+
+	// listenRetryInterval is a pause before reconnecting a broken listener.
+	listenRetryInterval = time.Second
+
+	// unknownRevision stands for the revision to publish the changes after, until it is read.
+	unknownRevision = -1
+)
+
+var (
+	ErrGetChanges = errors.New("getting event changes error")
+	ErrListen     = errors.New("listening for database notifications error")
+)
+
+type changeRow struct {
+	Revision int64  `db:"revision"`
+	Type     string `db:"type"`
+	EventID  int64  `db:"event_id"`
+	OwnerID  int64  `db:"owner_id"`
+}
+
+// GetChanges returns retained changes made after the given revision.
+// Created and updated changes carry the current state of their events rather than the one
+// at the time of the change, so several changes of an event may carry the same state.
+// Changes get their revisions once committed, see numberChanges, so a revision never commits
+// after a greater one.
+func (s *Storage) GetChanges(ctx context.Context, since int64) ([]storage.Change, error) {
+	ctx, done := instrument(ctx, "get_changes")
+	defer done()
+
+	if err := s.numberChanges(ctx); err != nil {
+		return nil, err
+	}
+
+	var oldest int64
+	err := sqlx.GetContext(ctx, s.ext(ctx), &oldest, "SELECT COALESCE(MIN(revision), 0) FROM app_event_change")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetChanges, err)
+	}
+
+	if oldest > 0 && since < oldest-1 {
+		return nil, fmt.Errorf("%w: %d", storage.ErrRevisionExpired, since)
+	}
+
+	var rows []changeRow
+	query := "SELECT revision, type, event_id, owner_id FROM app_event_change WHERE revision > $1 ORDER BY revision"
+	if err := sqlx.SelectContext(ctx, s.ext(ctx), &rows, query, since); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetChanges, err)
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	events, err := s.getEventsByIDs(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]storage.Change, len(rows))
+	for i, row := range rows {
+		event, ok := events[row.EventID]
+		if !ok {
+			event = storage.Event{ID: row.EventID, OwnerID: row.OwnerID}
+		}
+
+		changes[i] = storage.Change{
+			Revision: row.Revision,
+			Type:     storage.ChangeType(row.Type),
+			Event:    event,
+		}
+	}
+
+	return changes, nil
+}
+
+// numberChanges gives the committed changes without a revision the following ones, in the order recorded.
+// Readers take turns on a lock held until the numbers commit, so the numbers become visible in their order.
+// It runs in a transaction of its own, the changes of the one of the caller being numbered once committed.
+func (s *Storage) numberChanges(ctx context.Context) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrGetChanges, err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('app_event_change'))"); err != nil {
+		return fmt.Errorf("%w: %v", ErrGetChanges, err)
+	}
+
+	query := `
+		UPDATE app_event_change c
+		SET revision = n.revision
+		FROM (SELECT id, nextval('app_event_change_revision_seq') AS revision
+		      FROM (SELECT id FROM app_event_change WHERE revision IS NULL ORDER BY id) unnumbered) n
+		WHERE c.id = n.id
+	`
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("%w: %v", ErrGetChanges, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %v", ErrGetChanges, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) getEventsByIDs(ctx context.Context, rows []changeRow) (map[int64]storage.Event, error) {
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		ids[i] = row.EventID
+	}
+
+	query, args, err := sqlx.In("SELECT "+eventColumns+" FROM app_event WHERE id IN (?)", ids)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetChanges, err)
+	}
+
+	var found []eventRow
+	if err := sqlx.SelectContext(ctx, s.ext(ctx), &found, s.ext(ctx).Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetChanges, err)
+	}
+
+	events := make(map[int64]storage.Event, len(found))
+	for _, row := range found {
+		events[row.ID] = row.event()
+	}
+
+	return events, nil
+}
+
+// SubscribeChanges returns a channel of changes made from now on and a func releasing it.
+// The first subscription starts listening for database notifications. Should the current revision
+// fail to be read, the listener reads it once connected and publishes the changes made after it.
+func (s *Storage) SubscribeChanges() (<-chan storage.Change, func()) {
+	s.listenOnce.Do(func() {
+		revision, err := s.lastRevision(s.listening)
+		if err != nil {
+			s.notifyListenError(fmt.Errorf("%w: %v", ErrListen, err))
+			revision = unknownRevision
+		}
+
+		go s.listen(s.listening, changeChannel, func(ctx context.Context) error {
+			return s.publishChanges(ctx, &revision)
+		})
+	})
+
+	return s.feed.Subscribe()
+}
+
+// lastRevision returns the revision of the last retained change, zero without any.
+func (s *Storage) lastRevision(ctx context.Context) (int64, error) {
+	var revision int64
+	err := s.db.GetContext(ctx, &revision, "SELECT COALESCE(MAX(revision), 0) FROM app_event_change")
+
+	return revision, err
+}
+
+// SubscribeReminderChanges returns a channel signalled when reminders may come due earlier than known,
+// and a func releasing it. The first subscription starts listening for database notifications
+// on a connection of its own, so that event changes are not read unless someone watches them.
+func (s *Storage) SubscribeReminderChanges() (<-chan struct{}, func()) {
+	signals, release := s.reminderFeed.Subscribe()
+
+	s.reminderListenOnce.Do(func() {
+		go s.listen(s.listening, reminderChannel, func(ctx context.Context) error {
+			s.reminderFeed.Publish()
+			return nil
+		})
+	})
+
+	return signals, release
+}
+
+// listen calls handle on every notification of the channel until the context is done,
+// and on connecting, which catches up with notifications missed while reconnecting.
+func (s *Storage) listen(ctx context.Context, channel string, handle func(ctx context.Context) error) {
+	for ctx.Err() == nil {
+		err := s.listenConnection(ctx, channel, handle)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+
+		s.notifyListenError(fmt.Errorf("%w: %s: %v", ErrListen, channel, err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryInterval):
+		}
+	}
+}
+
+func (s *Storage) listenConnection(ctx context.Context, channel string, handle func(ctx context.Context) error) error {
+	config, err := pgx.ParseConnectionString(s.Config.GetStorageDSN())
+	if err != nil {
+		return err
+	}
+
+	conn, err := pgx.Connect(config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.Listen(channel); err != nil {
+		return err
+	}
+
+	for {
+		if err := handle(ctx); err != nil {
+			return err
+		}
+
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// publishChanges publishes the changes made after the revision, advancing it. An unknown revision
+// is read first, nothing being published until it is known.
+func (s *Storage) publishChanges(ctx context.Context, revision *int64) error {
+	if *revision == unknownRevision {
+		last, err := s.lastRevision(ctx)
+		if err != nil {
+			return err
+		}
+		*revision = last
+
+		return nil
+	}
+
+	changes, err := s.GetChanges(ctx, *revision)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		s.feed.Publish(change)
+		*revision = change.Revision
+	}
+
+	return nil
+}
+
+func (s *Storage) notifyListenError(err error) {
+	if s.OnListenError != nil {
+		s.OnListenError(err)
+	}
+}
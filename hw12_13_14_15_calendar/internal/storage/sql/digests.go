@@ -0,0 +1,136 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+var (
+	ErrSetDigest    = errors.New("setting digest subscription error")
+	ErrGetDigest    = errors.New("getting digest subscription error")
+	ErrRemoveDigest = errors.New("removing digest subscription error")
+)
+
+// SetDigestSubscription creates or replaces the digest subscription of the owner.
+// The time the last digest was sent is kept from the replaced subscription.
+func (s *Storage) SetDigestSubscription(
+	ctx context.Context,
+	subscription storage.DigestSubscription,
+) (storage.DigestSubscription, error) {
+	ctx, done := instrument(ctx, "set_digest_subscription")
+	defer done()
+
+	if subscription.LastSentAt != nil {
+		at := subscription.LastSentAt.UTC()
+		subscription.LastSentAt = &at
+	}
+
+	query := `
+		INSERT INTO app_digest_subscription (owner_id, period, send_time, time_zone, weekday, last_sent_at)
+		VALUES (:owner_id, :period, :send_time, :time_zone, :weekday, :last_sent_at)
+		ON CONFLICT (owner_id) DO UPDATE
+		SET period = EXCLUDED.period, send_time = EXCLUDED.send_time,
+			time_zone = EXCLUDED.time_zone, weekday = EXCLUDED.weekday
+		RETURNING last_sent_at
+	`
+
+	rows, err := sqlx.NamedQueryContext(ctx, s.ext(ctx), query, subscription)
+	if err != nil {
+		return subscription, fmt.Errorf("%w: %v", ErrSetDigest, err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := rows.Scan(&subscription.LastSentAt); err != nil {
+			return subscription, fmt.Errorf("%w: %v", ErrSetDigest, err)
+		}
+	}
+
+	return subscription, nil
+}
+
+// GetDigestSubscription returns the digest subscription of the owner, if exists.
+func (s *Storage) GetDigestSubscription(ctx context.Context, ownerID int64) (storage.DigestSubscription, error) {
+	ctx, done := instrument(ctx, "get_digest_subscription")
+	defer done()
+
+	subscription := storage.DigestSubscription{}
+
+	query := "SELECT * FROM app_digest_subscription WHERE owner_id = $1"
+	err := sqlx.GetContext(ctx, s.ext(ctx), &subscription, query, ownerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return subscription, fmt.Errorf("%w: owner %d", storage.ErrDigestNotFound, ownerID)
+	}
+	if err != nil {
+		return subscription, fmt.Errorf("%w: %v", ErrGetDigest, err)
+	}
+
+	return subscription, nil
+}
+
+// RemoveDigestSubscription removes the digest subscription of the owner, if exists.
+func (s *Storage) RemoveDigestSubscription(ctx context.Context, ownerID int64) error {
+	ctx, done := instrument(ctx, "remove_digest_subscription")
+	defer done()
+
+	result, err := s.ext(ctx).ExecContext(ctx, "DELETE FROM app_digest_subscription WHERE owner_id = $1", ownerID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRemoveDigest, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRemoveDigest, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%w: owner %d", storage.ErrDigestNotFound, ownerID)
+	}
+
+	return nil
+}
+
+// ListDigestSubscriptions returns all digest subscriptions ordered by owner.
+func (s *Storage) ListDigestSubscriptions(ctx context.Context) ([]storage.DigestSubscription, error) {
+	ctx, done := instrument(ctx, "list_digest_subscriptions")
+	defer done()
+
+	var subscriptions []storage.DigestSubscription
+
+	query := "SELECT * FROM app_digest_subscription ORDER BY owner_id"
+	if err := sqlx.SelectContext(ctx, s.ext(ctx), &subscriptions, query); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetDigest, err)
+	}
+
+	return subscriptions, nil
+}
+
+// MarkDigestSent records the time the owner's digest was last sent at.
+func (s *Storage) MarkDigestSent(ctx context.Context, ownerID int64, at time.Time) error {
+	ctx, done := instrument(ctx, "mark_digest_sent")
+	defer done()
+
+	query := "UPDATE app_digest_subscription SET last_sent_at = $1 WHERE owner_id = $2"
+	result, err := s.ext(ctx).ExecContext(ctx, query, at.UTC(), ownerID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSetDigest, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSetDigest, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%w: owner %d", storage.ErrDigestNotFound, ownerID)
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package sqlstorage
+
+import (
+	"context"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/metrics"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrument starts a query span, the returned func ends it and records the query duration.
+func instrument(ctx context.Context, operation string) (context.Context, func()) {
+	start := time.Now()
+	ctx, span := tracing.Start(
+		ctx,
+		"sqlstorage."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperationKey.String(operation)),
+	)
+
+	return ctx, func() {
+		span.End()
+		metrics.ObserveStorageQuery(operation, start)
+	}
+}
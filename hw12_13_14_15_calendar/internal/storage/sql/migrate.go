@@ -0,0 +1,77 @@
+package sqlstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/pressly/goose/v3"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/migrations"
+)
+
+// Migration commands.
+const (
+	MigrateUp     = "up"
+	MigrateDown   = "down"
+	MigrateStatus = "status"
+	MigrateRedo   = "redo"
+)
+
+// migrationsLockID keys the advisory lock serializing migrations of concurrent instances.
+const migrationsLockID = 20210722120225
+
+// gooseMu guards the package level state of goose: the dialect, the logger and the migrations file system.
+var gooseMu sync.Mutex
+
+var (
+	ErrMigrate               = errors.New("migration error")
+	ErrUnknownMigrateCommand = errors.New("unknown migration command")
+)
+
+// Migrate runs the migration command over the embedded migrations, reporting the progress to out.
+// Instances running migrations at the same time wait for each other on an advisory lock.
+func (s *Storage) Migrate(ctx context.Context, command string, out io.Writer) error {
+	run, ok := map[string]func() error{
+		MigrateUp:     func() error { return goose.Up(s.db.DB, ".") },
+		MigrateDown:   func() error { return goose.Down(s.db.DB, ".") },
+		MigrateStatus: func() error { return goose.Status(s.db.DB, ".") },
+		MigrateRedo:   func() error { return goose.Redo(s.db.DB, ".") },
+	}[command]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownMigrateCommand, command)
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMigrate, err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationsLockID); err != nil {
+		return fmt.Errorf("%w: %v", ErrMigrate, err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationsLockID) //nolint:errcheck
+
+	gooseMu.Lock()
+	defer gooseMu.Unlock()
+
+	// The goose state is restored for other users of the package.
+	goose.SetBaseFS(migrations.FS)
+	defer goose.SetBaseFS(nil)
+	goose.SetLogger(log.New(out, "", 0))
+	defer goose.SetLogger(log.New(os.Stderr, "", log.LstdFlags))
+
+	if err := goose.SetDialect("postgres"); err != nil {
+		return fmt.Errorf("%w: %v", ErrMigrate, err)
+	}
+
+	if err := run(); err != nil {
+		return fmt.Errorf("%w: %v", ErrMigrate, err)
+	}
+
+	return nil
+}
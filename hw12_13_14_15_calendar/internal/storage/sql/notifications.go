@@ -0,0 +1,192 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+var (
+	ErrCreateNotification = errors.New("create notification error")
+	ErrUpdateNotification = errors.New("update notification error")
+	ErrGetNotification    = errors.New("getting notification error")
+)
+
+// CreateNotification saves the notification of an existing event into a sql storage.
+func (s *Storage) CreateNotification(
+	ctx context.Context,
+	notification storage.Notification,
+) (storage.Notification, error) {
+	ctx, done := instrument(ctx, "create_notification")
+	defer done()
+
+	query := `
+		INSERT INTO app_notification (event_id, reminder_id, owner_id, channel, state, attempts, last_error,
+			created_at, updated_at, published_at, delivered_at)
+		SELECT id, :reminder_id, :owner_id, :channel, :state, :attempts, :last_error,
+			:created_at, :updated_at, :published_at, :delivered_at
+		FROM app_event WHERE id = :event_id
+		RETURNING id
+	`
+
+	rows, err := sqlx.NamedQueryContext(ctx, s.ext(ctx), query, notificationInUTC(notification))
+	if err != nil {
+		return notification, fmt.Errorf("%w: %v", ErrCreateNotification, err)
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return notification, fmt.Errorf("%w: %v", ErrCreateNotification, err)
+		}
+
+		return notification, fmt.Errorf("%w: %d", storage.ErrEventNotFound, notification.EventID)
+	}
+
+	if err := rows.Scan(&notification.ID); err != nil {
+		return notification, fmt.Errorf("%w: %v", ErrCreateNotification, err)
+	}
+
+	return notification, nil
+}
+
+// UpdateNotification updates the state of the notification, if exists, and returns it as stored.
+func (s *Storage) UpdateNotification(
+	ctx context.Context,
+	notification storage.Notification,
+) (storage.Notification, error) {
+	ctx, done := instrument(ctx, "update_notification")
+	defer done()
+
+	query := `
+		UPDATE app_notification
+		SET state = :state, attempts = :attempts, last_error = :last_error,
+			updated_at = :updated_at, published_at = :published_at, delivered_at = :delivered_at
+		WHERE id = :id
+		RETURNING *
+	`
+
+	rows, err := sqlx.NamedQueryContext(ctx, s.ext(ctx), query, notificationInUTC(notification))
+	if err != nil {
+		return notification, fmt.Errorf("%w: %v", ErrUpdateNotification, err)
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return notification, fmt.Errorf("%w: %v", ErrUpdateNotification, err)
+		}
+
+		return notification, fmt.Errorf("%w: %d", storage.ErrNotificationNotFound, notification.ID)
+	}
+
+	stored := storage.Notification{}
+	if err := rows.StructScan(&stored); err != nil {
+		return notification, fmt.Errorf("%w: %v", ErrUpdateNotification, err)
+	}
+
+	return stored, nil
+}
+
+// GetNotification returns the notification by id, if exists.
+func (s *Storage) GetNotification(ctx context.Context, id int64) (storage.Notification, error) {
+	ctx, done := instrument(ctx, "get_notification")
+	defer done()
+
+	notification := storage.Notification{}
+
+	err := sqlx.GetContext(ctx, s.ext(ctx), &notification, "SELECT * FROM app_notification WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notification, fmt.Errorf("%w: %d", storage.ErrNotificationNotFound, id)
+	}
+	if err != nil {
+		return notification, fmt.Errorf("%w: %v", ErrGetNotification, err)
+	}
+
+	return notification, nil
+}
+
+// FindNotification returns the notification of the reminder through the channel, if exists.
+func (s *Storage) FindNotification(
+	ctx context.Context,
+	eventID, reminderID int64,
+	channel string,
+) (storage.Notification, error) {
+	ctx, done := instrument(ctx, "find_notification")
+	defer done()
+
+	notification := storage.Notification{}
+
+	query := "SELECT * FROM app_notification WHERE event_id = $1 AND reminder_id = $2 AND channel = $3"
+	err := sqlx.GetContext(ctx, s.ext(ctx), &notification, query, eventID, reminderID, channel)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notification, fmt.Errorf(
+			"%w: event %d, reminder %d, channel %s", storage.ErrNotificationNotFound, eventID, reminderID, channel,
+		)
+	}
+	if err != nil {
+		return notification, fmt.Errorf("%w: %v", ErrGetNotification, err)
+	}
+
+	return notification, nil
+}
+
+// ListNotifications returns the notifications selected by the filter ordered by id.
+func (s *Storage) ListNotifications(
+	ctx context.Context,
+	filter storage.NotificationFilter,
+) ([]storage.Notification, error) {
+	ctx, done := instrument(ctx, "list_notifications")
+	defer done()
+
+	var (
+		conditions = []string{"TRUE"}
+		args       []interface{}
+	)
+	if filter.EventID != 0 {
+		args = append(args, filter.EventID)
+		conditions = append(conditions, fmt.Sprintf("event_id = $%d", len(args)))
+	}
+	if filter.OwnerID != 0 {
+		args = append(args, filter.OwnerID)
+		conditions = append(conditions, fmt.Sprintf("owner_id = $%d", len(args)))
+	}
+	if filter.State != "" {
+		args = append(args, filter.State)
+		conditions = append(conditions, fmt.Sprintf("state = $%d", len(args)))
+	}
+
+	var notifications []storage.Notification
+
+	query := "SELECT * FROM app_notification WHERE " + strings.Join(conditions, " AND ") + " ORDER BY id"
+	if err := sqlx.SelectContext(ctx, s.ext(ctx), &notifications, query, args...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetNotification, err)
+	}
+
+	return notifications, nil
+}
+
+// notificationInUTC returns the notification with times in UTC, as timestamps are stored without a zone.
+func notificationInUTC(notification storage.Notification) storage.Notification {
+	notification.CreatedAt = notification.CreatedAt.UTC()
+	notification.UpdatedAt = notification.UpdatedAt.UTC()
+
+	if notification.PublishedAt != nil {
+		at := notification.PublishedAt.UTC()
+		notification.PublishedAt = &at
+	}
+
+	if notification.DeliveredAt != nil {
+		at := notification.DeliveredAt.UTC()
+		notification.DeliveredAt = &at
+	}
+
+	return notification
+}
@@ -0,0 +1,224 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+var (
+	ErrCreateReminder = errors.New("create reminder error")
+	ErrUpdateReminder = errors.New("update reminder error")
+	ErrRemoveReminder = errors.New("removing reminder error")
+	ErrGetReminder    = errors.New("getting reminder error")
+)
+
+// CreateReminder saves the reminder of an existing event into a sql storage.
+func (s *Storage) CreateReminder(ctx context.Context, reminder storage.Reminder) (storage.Reminder, error) {
+	ctx, done := instrument(ctx, "create_reminder")
+	defer done()
+
+	reminder.RemindAt = reminder.RemindAt.UTC()
+
+	query := `
+		INSERT INTO app_reminder (event_id, owner_id, offset_ns, remind_at, state)
+		SELECT id, :owner_id, :offset_ns, :remind_at, :state FROM app_event WHERE id = :event_id
+		RETURNING id
+	`
+
+	rows, err := sqlx.NamedQueryContext(ctx, s.ext(ctx), query, reminder)
+	if err != nil {
+		return reminder, fmt.Errorf("%w: %v", ErrCreateReminder, err)
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return reminder, fmt.Errorf("%w: %v", ErrCreateReminder, err)
+		}
+
+		return reminder, fmt.Errorf("%w: %d", storage.ErrEventNotFound, reminder.EventID)
+	}
+
+	if err := rows.Scan(&reminder.ID); err != nil {
+		return reminder, fmt.Errorf("%w: %v", ErrCreateReminder, err)
+	}
+
+	return reminder, nil
+}
+
+// UpdateReminder updates the owner, the time and the state of the reminder, if exists.
+func (s *Storage) UpdateReminder(ctx context.Context, reminder storage.Reminder) (storage.Reminder, error) {
+	ctx, done := instrument(ctx, "update_reminder")
+	defer done()
+
+	reminder.RemindAt = reminder.RemindAt.UTC()
+
+	query := `
+		UPDATE app_reminder
+		SET owner_id = :owner_id, offset_ns = :offset_ns, remind_at = :remind_at, state = :state
+		WHERE id = :id
+		RETURNING event_id
+	`
+
+	rows, err := sqlx.NamedQueryContext(ctx, s.ext(ctx), query, reminder)
+	if err != nil {
+		return reminder, fmt.Errorf("%w: %v", ErrUpdateReminder, err)
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return reminder, fmt.Errorf("%w: %v", ErrUpdateReminder, err)
+		}
+
+		return reminder, fmt.Errorf("%w: %d", storage.ErrReminderNotFound, reminder.ID)
+	}
+
+	if err := rows.Scan(&reminder.EventID); err != nil {
+		return reminder, fmt.Errorf("%w: %v", ErrUpdateReminder, err)
+	}
+
+	return reminder, nil
+}
+
+// UpdateReminderState sets the state and the time of the reminder, unless they differ from the ones read.
+func (s *Storage) UpdateReminderState(
+	ctx context.Context,
+	read, reminder storage.Reminder,
+) (storage.Reminder, error) {
+	ctx, done := instrument(ctx, "update_reminder_state")
+	defer done()
+
+	query := `
+		UPDATE app_reminder SET state = $1, remind_at = $2
+		WHERE id = $3 AND state = $4 AND remind_at = $5
+		RETURNING *
+	`
+
+	stored := storage.Reminder{}
+	err := sqlx.GetContext(ctx, s.ext(ctx), &stored, query,
+		reminder.State, reminder.RemindAt.UTC(), read.ID, read.State, read.RemindAt.UTC())
+	if err == nil {
+		return stored, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return reminder, fmt.Errorf("%w: %v", ErrUpdateReminder, err)
+	}
+
+	if stored, err = s.GetReminder(ctx, read.ID); err != nil {
+		return reminder, err
+	}
+
+	return stored, fmt.Errorf("%w: %d", storage.ErrReminderChanged, read.ID)
+}
+
+// RemoveReminder removes the reminder, if exists, its notifications go along with it.
+func (s *Storage) RemoveReminder(ctx context.Context, id int64) error {
+	ctx, done := instrument(ctx, "remove_reminder")
+	defer done()
+
+	result, err := s.ext(ctx).ExecContext(ctx, "DELETE FROM app_reminder WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRemoveReminder, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRemoveReminder, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%w: %d", storage.ErrReminderNotFound, id)
+	}
+
+	return nil
+}
+
+// GetReminder returns the reminder by id, if exists.
+func (s *Storage) GetReminder(ctx context.Context, id int64) (storage.Reminder, error) {
+	ctx, done := instrument(ctx, "get_reminder")
+	defer done()
+
+	reminder := storage.Reminder{}
+
+	err := sqlx.GetContext(ctx, s.ext(ctx), &reminder, "SELECT * FROM app_reminder WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return reminder, fmt.Errorf("%w: %d", storage.ErrReminderNotFound, id)
+	}
+	if err != nil {
+		return reminder, fmt.Errorf("%w: %v", ErrGetReminder, err)
+	}
+
+	return reminder, nil
+}
+
+// GetReminders returns the reminders of the events ordered by event and time.
+func (s *Storage) GetReminders(ctx context.Context, eventIDs ...int64) ([]storage.Reminder, error) {
+	if len(eventIDs) == 0 {
+		return nil, nil
+	}
+
+	ctx, done := instrument(ctx, "get_reminders")
+	defer done()
+
+	query, args, err := sqlx.In(
+		"SELECT * FROM app_reminder WHERE event_id IN (?) ORDER BY event_id, remind_at, id",
+		eventIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetReminder, err)
+	}
+
+	var reminders []storage.Reminder
+
+	ext := s.ext(ctx)
+	if err := sqlx.SelectContext(ctx, ext, &reminders, ext.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetReminder, err)
+	}
+
+	return reminders, nil
+}
+
+// GetDueReminders returns the reminders due by now ordered by time.
+// Pending reminders are due while their event has not begun, snoozed ones regardless.
+func (s *Storage) GetDueReminders(ctx context.Context) ([]storage.Reminder, error) {
+	ctx, done := instrument(ctx, "get_due_reminders")
+	defer done()
+
+	return s.getRemindersDueBy(ctx, s.Clock.Now())
+}
+
+// GetUpcomingReminders returns the reminders due by the given time ordered by time,
+// as far as their events are known now.
+func (s *Storage) GetUpcomingReminders(ctx context.Context, until time.Time) ([]storage.Reminder, error) {
+	ctx, done := instrument(ctx, "get_upcoming_reminders")
+	defer done()
+
+	return s.getRemindersDueBy(ctx, until)
+}
+
+func (s *Storage) getRemindersDueBy(ctx context.Context, until time.Time) ([]storage.Reminder, error) {
+	var reminders []storage.Reminder
+
+	query := `
+		SELECT r.* FROM app_reminder r
+		JOIN app_event e ON e.id = r.event_id
+		WHERE r.remind_at <= $1 AND (r.state = $2 AND e.begin_date > $3 OR r.state = $4)
+		ORDER BY r.remind_at, r.id
+	`
+	err := sqlx.SelectContext(ctx, s.ext(ctx), &reminders, query,
+		until.UTC(), storage.ReminderPending, s.Clock.Now().UTC(), storage.ReminderSnoozed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetReminder, err)
+	}
+
+	return reminders, nil
+}
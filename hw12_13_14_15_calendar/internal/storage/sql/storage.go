@@ -2,25 +2,34 @@ package sqlstorage
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
 	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
 )
 
 const Alias = "sql"
 
+// eventColumns are the columns of the events read into eventRow.
+const eventColumns = `id, title, begin_date, end_date, description, owner_id, notification_sent, notification_received,
+	calendar_id, category_id`
+
 var (
 	ErrDatabaseConnect     = errors.New("unable to connect to database")
 	ErrDatabaseClose       = errors.New("unable to close database")
+	ErrDatabasePing        = errors.New("unable to reach database")
 	ErrCreateEvent         = errors.New("create event error")
 	ErrUpdateEvent         = errors.New("update event error")
 	ErrRemoveEvent         = errors.New("removing event error")
 	ErrGetDayAheadEvents   = errors.New("getting day events error")
 	ErrGetWeekAheadEvents  = errors.New("getting week events error")
 	ErrGetMonthAheadEvents = errors.New("getting month events error")
-	ErrGetComingEvents     = errors.New("getting coming events error")
+	ErrGetEventsInRange    = errors.New("getting events in range error")
 	ErrGetEvent            = errors.New("getting event error")
 )
 
@@ -30,13 +39,30 @@ type Config interface {
 
 type Storage struct {
 	Config Config
-	db     *sqlx.DB
+	Clock  clock.Clock
+	// OnListenError receives errors of the background change listener, if set.
+	OnListenError func(err error)
+	db            *sqlx.DB
+	feed          *storage.ChangeFeed
+	reminderFeed  *storage.SignalFeed
+	// listening is the context of the background listeners, canceled on close.
+	listening          context.Context
+	stopListening      context.CancelFunc
+	listenOnce         sync.Once
+	reminderListenOnce sync.Once
 }
 
-// New returns a new sql storage instance.
-func New(config Config) *Storage {
+// New returns a new sql storage instance, the clock tells the current time to the time-relative queries.
+func New(config Config, clock clock.Clock) *Storage {
+	listening, stopListening := context.WithCancel(context.Background())
+
 	return &Storage{
-		Config: config,
+		Config:        config,
+		Clock:         clock,
+		feed:          storage.NewChangeFeed(),
+		reminderFeed:  storage.NewSignalFeed(),
+		listening:     listening,
+		stopListening: stopListening,
 	}
 }
 
@@ -52,8 +78,23 @@ func (s *Storage) Connect(ctx context.Context) error {
 	return nil
 }
 
-// Close breaks the database connection.
+// Ping verifies the database connection is alive.
+func (s *Storage) Ping(ctx context.Context) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: not connected", ErrDatabasePing)
+	}
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabasePing, err)
+	}
+
+	return nil
+}
+
+// Close stops listening for changes and breaks the database connection.
 func (s *Storage) Close() error {
+	s.stopListening()
+
 	err := s.db.Close()
 	if err != nil {
 		err = fmt.Errorf("%w: %v", ErrDatabaseClose, err)
@@ -64,13 +105,17 @@ func (s *Storage) Close() error {
 
 // CreateEvent saves event into a sql storage.
 func (s *Storage) CreateEvent(ctx context.Context, event storage.Event) (storage.Event, error) {
+	ctx, done := instrument(ctx, "create_event")
+	defer done()
+
 	query := `
-		INSERT INTO app_event (id, title, begin_date, end_date, description, owner_id) 
-		VALUES (nextval('app_event_id_seq'), :title, :begin_date, :end_date, :description, :owner_id)
+		INSERT INTO app_event (id, title, begin_date, end_date, description, owner_id, calendar_id, category_id) 
+		VALUES (nextval('app_event_id_seq'), :title, :begin_date, :end_date, :description, :owner_id,
+			NULLIF(:calendar_id, 0), NULLIF(:category_id, 0))
 		RETURNING id
 	`
 
-	rows, err := s.db.NamedQueryContext(ctx, query, event)
+	rows, err := sqlx.NamedQueryContext(ctx, s.ext(ctx), query, inUTC(event))
 	if err != nil {
 		return storage.Event{}, fmt.Errorf("%w: %v", ErrCreateEvent, err)
 	}
@@ -87,11 +132,18 @@ func (s *Storage) CreateEvent(ctx context.Context, event storage.Event) (storage
 		event.ID = results["id"].(int64)
 	}
 
+	// The notification flags start unset, whatever the event says.
+	event.NotificationSent = false
+	event.NotificationReceived = false
+
 	return event, nil
 }
 
-// UpdateEvent updates event in sql storage if exists.
+// UpdateEvent updates event in sql storage if exists, the notification flags are kept as stored.
 func (s *Storage) UpdateEvent(ctx context.Context, event storage.Event) (storage.Event, error) {
+	ctx, done := instrument(ctx, "update_event")
+	defer done()
+
 	query := `
 		UPDATE app_event 
 		    SET title = :title,
@@ -99,51 +151,106 @@ func (s *Storage) UpdateEvent(ctx context.Context, event storage.Event) (storage
 		        end_date = :end_date,
 		        description = :description,
 		        owner_id = :owner_id,
-		        notification_sent = :notification_sent,
-		        notification_received = :notification_received
+		        calendar_id = NULLIF(:calendar_id, 0),
+		        category_id = NULLIF(:category_id, 0)
 		WHERE id = :id
+		RETURNING notification_sent, notification_received
 	`
 
-	_, err := s.db.NamedExecContext(ctx, query, event)
+	rows, err := sqlx.NamedQueryContext(ctx, s.ext(ctx), query, inUTC(event))
 	if err != nil {
 		return storage.Event{}, fmt.Errorf("%w: %v", ErrUpdateEvent, err)
 	}
 
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return storage.Event{}, fmt.Errorf("%w: %v", ErrUpdateEvent, err)
+		}
+
+		return storage.Event{}, fmt.Errorf("%w: %d", storage.ErrEventNotFound, event.ID)
+	}
+
+	if err := rows.Scan(&event.NotificationSent, &event.NotificationReceived); err != nil {
+		return storage.Event{}, fmt.Errorf("%w: %v", ErrUpdateEvent, err)
+	}
+
 	return event, nil
 }
 
-// RemoveEvent removes event from sql storage if exists.
+// RemoveEvent removes event from sql storage if exists, its tags, reminders and notifications go along with it.
 func (s *Storage) RemoveEvent(ctx context.Context, event storage.Event) error {
+	ctx, done := instrument(ctx, "remove_event")
+	defer done()
+
 	query := "DELETE FROM app_event WHERE id = :id"
-	_, err := s.db.NamedExecContext(ctx, query, event)
+	result, err := sqlx.NamedExecContext(ctx, s.ext(ctx), query, event)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrRemoveEvent, err)
 	}
 
-	return nil
+	return checkAffected(result, event.ID)
 }
 
-// GetDayAheadEvents returns a day events slice.
-func (s *Storage) GetDayAheadEvents(ctx context.Context) ([]storage.Event, error) {
-	var events []storage.Event
+// GetEventsInRange returns events overlapping the [begin, end) period ordered by their begin dates.
+// Events without an end date are treated as instant ones.
+func (s *Storage) GetEventsInRange(ctx context.Context, begin, end time.Time) ([]storage.Event, error) {
+	ctx, done := instrument(ctx, "get_events_in_range")
+	defer done()
+
+	var rows []eventRow
 
-	query := "SELECT * FROM app_event WHERE begin_date > NOW() AND begin_date < NOW() + interval '1 day'"
-	rows, err := s.db.NamedQueryContext(ctx, query, storage.Event{})
+	query := `
+		SELECT ` + eventColumns + ` FROM app_event
+		WHERE begin_date < $1 AND GREATEST(end_date, begin_date) > $2
+		ORDER BY begin_date, id
+	`
+	if err := sqlx.SelectContext(ctx, s.ext(ctx), &rows, query, end.UTC(), begin.UTC()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetEventsInRange, err)
+	}
+
+	return eventsOf(rows), nil
+}
+
+// GetOwnerEventsInRange returns events of the owners overlapping the [begin, end) period
+// ordered by their begin dates.
+func (s *Storage) GetOwnerEventsInRange(
+	ctx context.Context,
+	begin, end time.Time,
+	ownerIDs ...int64,
+) ([]storage.Event, error) {
+	if len(ownerIDs) == 0 {
+		return nil, nil
+	}
+
+	ctx, done := instrument(ctx, "get_owner_events_in_range")
+	defer done()
+
+	query, args, err := sqlx.In(`
+		SELECT `+eventColumns+` FROM app_event
+		WHERE owner_id IN (?) AND begin_date < ? AND GREATEST(end_date, begin_date) > ?
+		ORDER BY begin_date, id
+	`, ownerIDs, end.UTC(), begin.UTC())
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrGetDayAheadEvents, err)
+		return nil, fmt.Errorf("%w: %v", ErrGetEventsInRange, err)
 	}
 
-	defer rows.Close()
+	var rows []eventRow
+	if err := sqlx.SelectContext(ctx, s.ext(ctx), &rows, s.ext(ctx).Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetEventsInRange, err)
+	}
 
-	for rows.Next() {
-		var event storage.Event
+	return eventsOf(rows), nil
+}
 
-		err := rows.StructScan(&event)
-		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrGetDayAheadEvents, err)
-		}
+// GetDayAheadEvents returns a day events slice.
+func (s *Storage) GetDayAheadEvents(ctx context.Context) ([]storage.Event, error) {
+	now := s.Clock.Now()
 
-		events = append(events, event)
+	events, err := s.GetEventsInRange(ctx, now, now.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetDayAheadEvents, err)
 	}
 
 	return events, nil
@@ -151,86 +258,44 @@ func (s *Storage) GetDayAheadEvents(ctx context.Context) ([]storage.Event, error
 
 // GetWeekAheadEvents returns a week events slice.
 func (s *Storage) GetWeekAheadEvents(ctx context.Context) ([]storage.Event, error) {
-	var events []storage.Event
+	now := s.Clock.Now()
 
-	query := "SELECT * FROM app_event WHERE begin_date > NOW() AND begin_date < NOW() + interval '1 week'"
-	rows, err := s.db.NamedQueryContext(ctx, query, storage.Event{})
+	events, err := s.GetEventsInRange(ctx, now, now.AddDate(0, 0, 7))
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrGetWeekAheadEvents, err)
 	}
 
-	defer rows.Close()
-
-	for rows.Next() {
-		var event storage.Event
-
-		err := rows.StructScan(&event)
-		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrGetWeekAheadEvents, err)
-		}
-
-		events = append(events, event)
-	}
-
 	return events, nil
 }
 
 // GetMonthAheadEvents returns a month events slice.
 func (s *Storage) GetMonthAheadEvents(ctx context.Context) ([]storage.Event, error) {
-	var events []storage.Event
+	now := s.Clock.Now()
 
-	query := "SELECT * FROM app_event WHERE begin_date > NOW() AND begin_date < NOW() + interval '1 month'"
-	rows, err := s.db.NamedQueryContext(ctx, query, storage.Event{})
+	events, err := s.GetEventsInRange(ctx, now, now.AddDate(0, 1, 0))
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrGetMonthAheadEvents, err)
 	}
 
-	defer rows.Close()
-
-	for rows.Next() {
-		var event storage.Event
-
-		err := rows.StructScan(&event)
-		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrGetMonthAheadEvents, err)
-		}
-
-		events = append(events, event)
-	}
-
 	return events, nil
 }
 
-// GetComingEvents returns events slice, that need to be notified.
-func (s *Storage) GetComingEvents(ctx context.Context) ([]storage.Event, error) {
-	var events []storage.Event
+// RemoveExpiredEvents removes events ended longer than the retention period ago.
+// Events without an end date are treated as instant ones.
+func (s *Storage) RemoveExpiredEvents(ctx context.Context, retention time.Duration) error {
+	ctx, done := instrument(ctx, "remove_expired_events")
+	defer done()
 
-	query := "SELECT * FROM app_event WHERE begin_date > NOW() AND begin_date < NOW() + interval '1 day' AND notification_sent is FALSE"
-	rows, err := s.db.NamedQueryContext(ctx, query, storage.Event{})
+	query := "DELETE FROM app_event WHERE GREATEST(end_date, begin_date) < $1"
+	_, err := s.ext(ctx).ExecContext(ctx, query, s.Clock.Now().UTC().Add(-retention))
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrGetComingEvents, err)
-	}
-
-	defer rows.Close()
-
-	for rows.Next() {
-		var event storage.Event
-
-		err := rows.StructScan(&event)
-		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrGetComingEvents, err)
-		}
-
-		events = append(events, event)
+		return fmt.Errorf("%w: %v", ErrRemoveEvent, err)
 	}
 
-	return events, nil
-}
-
-// RemoveExpiredEvents removes events happened more than one year ago.
-func (s *Storage) RemoveExpiredEvents(ctx context.Context) error {
-	query := "DELETE FROM app_event WHERE end_date < NOW() - interval '1 year'"
-	_, err := s.db.ExecContext(ctx, query)
+	// Changes are kept for a real week, which is long enough for watchers to resume.
+	// Their creation time is set by the database, so the clock is not involved.
+	query = "DELETE FROM app_event_change WHERE created_at < NOW() - interval '1 week'"
+	_, err = s.ext(ctx).ExecContext(ctx, query)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrRemoveEvent, err)
 	}
@@ -240,23 +305,84 @@ func (s *Storage) RemoveExpiredEvents(ctx context.Context) error {
 
 // GetEventByID returns events by id, if exists.
 func (s *Storage) GetEventByID(ctx context.Context, id int64) (storage.Event, error) {
+	ctx, done := instrument(ctx, "get_event_by_id")
+	defer done()
+
 	event := storage.Event{
 		ID: id,
 	}
 
-	query := "SELECT * FROM app_event WHERE id = :id LIMIT 1"
-	rows, err := s.db.NamedQueryContext(ctx, query, event)
+	query := "SELECT " + eventColumns + " FROM app_event WHERE id = :id LIMIT 1"
+	rows, err := sqlx.NamedQueryContext(ctx, s.ext(ctx), query, event)
 	if err != nil {
 		return event, fmt.Errorf("%w: %v", ErrGetEvent, err)
 	}
 
 	defer rows.Close()
 
-	rows.Next()
-	err = rows.StructScan(&event)
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return event, fmt.Errorf("%w: %v", ErrGetEvent, err)
+		}
+
+		return event, fmt.Errorf("%w: %d", storage.ErrEventNotFound, id)
+	}
+
+	row := eventRow{}
+	err = rows.StructScan(&row)
 	if err != nil {
 		return event, fmt.Errorf("%w: %v", ErrGetEvent, err)
 	}
 
-	return event, nil
+	return row.event(), nil
+}
+
+// eventRow is an event as read, events without a calendar or a category referring to none with NULL.
+type eventRow struct {
+	storage.Event
+	CalendarID sql.NullInt64 `db:"calendar_id"`
+	CategoryID sql.NullInt64 `db:"category_id"`
+}
+
+func (r eventRow) event() storage.Event {
+	event := r.Event
+	event.CalendarID = r.CalendarID.Int64
+	event.CategoryID = r.CategoryID.Int64
+
+	return event
+}
+
+func eventsOf(rows []eventRow) []storage.Event {
+	if rows == nil {
+		return nil
+	}
+
+	events := make([]storage.Event, len(rows))
+	for i, row := range rows {
+		events[i] = row.event()
+	}
+
+	return events
+}
+
+// inUTC returns the event with dates in UTC, as timestamps are stored by their wall clock without a zone.
+func inUTC(event storage.Event) storage.Event {
+	event.BeginDate = event.BeginDate.UTC()
+	event.EndDate = event.EndDate.UTC()
+
+	return event
+}
+
+// checkAffected reports a missing event when a statement changed no rows.
+func checkAffected(result sql.Result, id int64) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrGetEvent, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%w: %d", storage.ErrEventNotFound, id)
+	}
+
+	return nil
 }
@@ -0,0 +1,140 @@
+package sqlstorage
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// dsnEnv names the variable with a disposable database for the tests, they wipe it.
+const dsnEnv = "TESTS_POSTGRES_DSN"
+
+type testConfig string
+
+func (c testConfig) GetStorageDSN() string {
+	return string(c)
+}
+
+// connect returns a storage of the test database, migrated and wiped, skipping the test without one.
+func connect(t *testing.T, clock clock.Clock) *Storage {
+	t.Helper()
+
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", dsnEnv)
+	}
+
+	ctx := context.Background()
+
+	s := New(testConfig(dsn), clock)
+	require.NoError(t, s.Connect(ctx))
+	t.Cleanup(func() { s.Close() })
+
+	require.NoError(t, s.Migrate(ctx, MigrateUp, io.Discard))
+	_, err := s.db.ExecContext(ctx, "TRUNCATE app_event, app_event_change, app_digest_subscription, app_reminder, app_notification, app_event_tag, app_category, app_calendar_grant, app_calendar RESTART IDENTITY")
+	require.NoError(t, err)
+
+	return s
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, clock clock.Clock) app.Storage {
+		return connect(t, clock)
+	})
+}
+
+// TestChangeOrder checks a revision never commits after a greater one, which would make watchers
+// reading past the greater one skip it.
+func TestChangeOrder(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, time.November, 2, 9, 0, 0, 0, time.UTC)
+	s := connect(t, clock.NewFake(start))
+
+	created := make(chan struct{})
+	commit := make(chan struct{})
+	slow := make(chan error, 1)
+	go func() {
+		slow <- s.WithinTransaction(ctx, func(ctx context.Context) error {
+			_, err := s.CreateEvent(ctx, storage.Event{Title: "slow", OwnerID: 1, BeginDate: start, EndDate: start.Add(time.Hour)})
+			close(created)
+			<-commit
+			return err
+		})
+	}()
+	<-created
+
+	// The fast writer does not wait for the slow one, its change is read first.
+	_, err := s.CreateEvent(ctx, storage.Event{Title: "fast", OwnerID: 1, BeginDate: start, EndDate: start.Add(time.Hour)})
+	require.NoError(t, err)
+
+	changes, err := s.GetChanges(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, "fast", changes[0].Event.Title)
+
+	close(commit)
+	require.NoError(t, <-slow)
+
+	// The slow change commits after the fast one was read, so it comes after it.
+	later, err := s.GetChanges(ctx, changes[0].Revision)
+	require.NoError(t, err)
+	require.Len(t, later, 1)
+	require.Equal(t, "slow", later[0].Event.Title)
+	require.Greater(t, later[0].Revision, changes[0].Revision)
+}
+
+// TestDefaultCalendarRace checks that an event created while another transaction creates the default calendar
+// of its owner goes to that calendar, rather than failing the transaction on the second default one.
+func TestDefaultCalendarRace(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, time.November, 2, 9, 0, 0, 0, time.UTC)
+	s := connect(t, clock.NewFake(start))
+	calendar := app.New(nil, s, clock.NewFake(start))
+
+	created := make(chan storage.Calendar)
+	commit := make(chan struct{})
+	slow := make(chan error, 1)
+	go func() {
+		slow <- s.WithinTransaction(ctx, func(ctx context.Context) error {
+			defaultCalendar, err := s.CreateCalendar(ctx, storage.Calendar{OwnerID: 1, Name: "Default", Default: true})
+			created <- defaultCalendar
+			<-commit
+			return err
+		})
+	}()
+	defaultCalendar := <-created
+
+	// The event waits for the default calendar to commit and goes there.
+	placed := make(chan storage.Event, 1)
+	fast := make(chan error, 1)
+	go func() {
+		event, err := calendar.CreateEvent(ctx, storage.Event{
+			Title: "planning", OwnerID: 1, BeginDate: start.Add(time.Hour), EndDate: start.Add(2 * time.Hour),
+		})
+		placed <- event
+		fast <- err
+	}()
+
+	select {
+	case err := <-fast:
+		t.Fatalf("event created before the default calendar committed: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(commit)
+	require.NoError(t, <-slow)
+	require.NoError(t, <-fast)
+	require.Equal(t, defaultCalendar.ID, (<-placed).CalendarID)
+
+	calendars, err := s.ListCalendars(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, calendars, 1)
+}
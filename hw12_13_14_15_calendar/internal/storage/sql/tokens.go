@@ -0,0 +1,99 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+var (
+	ErrCreateAPIToken = errors.New("create api token error")
+	ErrGetAPIToken    = errors.New("getting api token error")
+	ErrRevokeAPIToken = errors.New("revoking api token error")
+)
+
+// CreateAPIToken saves the hashed api token into a sql storage.
+func (s *Storage) CreateAPIToken(ctx context.Context, token storage.APIToken) (storage.APIToken, error) {
+	ctx, done := instrument(ctx, "create_api_token")
+	defer done()
+
+	query := `
+		INSERT INTO app_api_token (name, owner_id, token_hash, expires_at)
+		VALUES (:name, :owner_id, :token_hash, :expires_at)
+		RETURNING id, created_at
+	`
+
+	rows, err := sqlx.NamedQueryContext(ctx, s.ext(ctx), query, token)
+	if err != nil {
+		return token, fmt.Errorf("%w: %v", ErrCreateAPIToken, err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := rows.Scan(&token.ID, &token.CreatedAt); err != nil {
+			return token, fmt.Errorf("%w: %v", ErrCreateAPIToken, err)
+		}
+	}
+
+	return token, nil
+}
+
+// GetAPITokenByHash returns the api token with the given hash, if exists.
+func (s *Storage) GetAPITokenByHash(ctx context.Context, hash string) (storage.APIToken, error) {
+	ctx, done := instrument(ctx, "get_api_token_by_hash")
+	defer done()
+
+	token := storage.APIToken{}
+
+	err := sqlx.GetContext(ctx, s.ext(ctx), &token, "SELECT * FROM app_api_token WHERE token_hash = $1", hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return token, storage.ErrTokenNotFound
+	}
+	if err != nil {
+		return token, fmt.Errorf("%w: %v", ErrGetAPIToken, err)
+	}
+
+	return token, nil
+}
+
+// ListAPITokens returns all api tokens ordered by id.
+func (s *Storage) ListAPITokens(ctx context.Context) ([]storage.APIToken, error) {
+	ctx, done := instrument(ctx, "list_api_tokens")
+	defer done()
+
+	var tokens []storage.APIToken
+
+	if err := sqlx.SelectContext(ctx, s.ext(ctx), &tokens, "SELECT * FROM app_api_token ORDER BY id"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetAPIToken, err)
+	}
+
+	return tokens, nil
+}
+
+// RevokeAPIToken marks the api token as revoked, if exists.
+func (s *Storage) RevokeAPIToken(ctx context.Context, id int64) error {
+	ctx, done := instrument(ctx, "revoke_api_token")
+	defer done()
+
+	query := "UPDATE app_api_token SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1"
+	result, err := s.ext(ctx).ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRevokeAPIToken, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRevokeAPIToken, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%w: %d", storage.ErrTokenNotFound, id)
+	}
+
+	return nil
+}
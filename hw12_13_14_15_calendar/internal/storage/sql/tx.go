@@ -0,0 +1,53 @@
+package sqlstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrTransaction = errors.New("transaction error")
+
+type txKey struct{}
+
+// ext returns the transaction carried by ctx, if any, or the database otherwise.
+func (s *Storage) ext(ctx context.Context) sqlx.ExtContext {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+
+	return s.db
+}
+
+// WithinTransaction runs fn in a database transaction, which is committed when fn succeeds
+// and rolled back otherwise. Calls nested in fn join the outer transaction.
+// Change notifications of the transaction are delivered on commit.
+func (s *Storage) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	ctx, done := instrument(ctx, "transaction")
+	defer done()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTransaction, err)
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w: %v (rollback: %v)", ErrTransaction, err, rollbackErr)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %v", ErrTransaction, err)
+	}
+
+	return nil
+}
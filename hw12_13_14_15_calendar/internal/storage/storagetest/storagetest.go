@@ -0,0 +1,946 @@
+// Package storagetest is a conformance suite making sure storage implementations behave the same.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/clock"
+	"github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+var errRollback = errors.New("rollback")
+
+// Constructor returns an empty storage telling the current time by the clock, it is called once per test case.
+type Constructor func(t *testing.T, clock clock.Clock) app.Storage
+
+// Reference is the current time of the suite, a Tuesday morning.
+var Reference = time.Date(2021, time.November, 2, 9, 0, 0, 0, time.UTC)
+
+// Run runs the conformance suite against the storage returned by newStorage.
+func Run(t *testing.T, newStorage Constructor) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		test func(t *testing.T, s app.Storage, clk *clock.Fake)
+	}{
+		{"crud", testCRUD},
+		{"range boundaries", testRangeBoundaries},
+		{"owner ranges", testOwnerRanges},
+		{"ahead events", testAheadEvents},
+		{"reminders", testReminders},
+		{"expiry", testExpiry},
+		{"changes", testChanges},
+		{"transactions", testTransactions},
+		{"concurrency", testConcurrency},
+		{"digests", testDigests},
+		{"notifications", testNotifications},
+		{"categories", testCategories},
+		{"calendars", testCalendars},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			clk := clock.NewFake(Reference)
+			tc.test(t, newStorage(t, clk), clk)
+		})
+	}
+}
+
+func newEvent(title string, begin time.Time, duration time.Duration) storage.Event {
+	return storage.Event{
+		Title:       title,
+		BeginDate:   begin,
+		EndDate:     begin.Add(duration),
+		Description: title + " description",
+		OwnerID:     1,
+	}
+}
+
+func create(t *testing.T, s app.Storage, event storage.Event) storage.Event {
+	t.Helper()
+
+	created, err := s.CreateEvent(context.Background(), event)
+	require.NoError(t, err)
+	require.NotZero(t, created.ID)
+
+	return created
+}
+
+// requireEvent compares events regardless of the location of their dates.
+func requireEvent(t *testing.T, expected, actual storage.Event) {
+	t.Helper()
+
+	require.True(t, expected.BeginDate.Equal(actual.BeginDate), "begin date %s, expected %s", actual.BeginDate, expected.BeginDate)
+	require.True(t, expected.EndDate.Equal(actual.EndDate), "end date %s, expected %s", actual.EndDate, expected.EndDate)
+
+	expected.BeginDate, actual.BeginDate = time.Time{}, time.Time{}
+	expected.EndDate, actual.EndDate = time.Time{}, time.Time{}
+	require.Equal(t, expected, actual)
+}
+
+func ids(events []storage.Event) []int64 {
+	result := make([]int64, 0, len(events))
+	for _, event := range events {
+		result = append(result, event.ID)
+	}
+
+	return result
+}
+
+func testCRUD(t *testing.T, s app.Storage, clk *clock.Fake) {
+	ctx := context.Background()
+	begin := clk.Now().Add(time.Hour)
+
+	first := create(t, s, newEvent("first", begin, time.Hour))
+	second := create(t, s, newEvent("second", begin.Add(2*time.Hour), time.Hour))
+	require.NotEqual(t, first.ID, second.ID)
+
+	stored, err := s.GetEventByID(ctx, first.ID)
+	require.NoError(t, err)
+	requireEvent(t, first, stored)
+
+	// Dates are instants, whatever location they are given in.
+	moscow := time.FixedZone("MSK", 3*60*60)
+	first.Title = "first updated"
+	first.BeginDate = first.BeginDate.In(moscow)
+	first.EndDate = first.EndDate.Add(time.Hour).In(moscow)
+	_, err = s.UpdateEvent(ctx, first)
+	require.NoError(t, err)
+
+	stored, err = s.GetEventByID(ctx, first.ID)
+	require.NoError(t, err)
+	requireEvent(t, first, stored)
+
+	events, err := s.GetEventsInRange(ctx, first.EndDate.Add(-time.Minute), first.EndDate)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []int64{first.ID}, ids(events))
+
+	require.NoError(t, s.RemoveEvent(ctx, first))
+	_, err = s.GetEventByID(ctx, first.ID)
+	require.ErrorIs(t, err, storage.ErrEventNotFound)
+
+	missing := newEvent("missing", begin, time.Hour)
+	missing.ID = first.ID
+	_, err = s.UpdateEvent(ctx, missing)
+	require.ErrorIs(t, err, storage.ErrEventNotFound)
+	require.ErrorIs(t, s.RemoveEvent(ctx, missing), storage.ErrEventNotFound)
+
+	stored, err = s.GetEventByID(ctx, second.ID)
+	require.NoError(t, err)
+	requireEvent(t, second, stored)
+}
+
+// testRangeBoundaries checks the range is half-open and events overlapping it partially are included.
+func testRangeBoundaries(t *testing.T, s app.Storage, clk *clock.Fake) {
+	begin := clk.Now().Add(24 * time.Hour)
+	end := begin.Add(4 * time.Hour)
+
+	endsAtBegin := create(t, s, newEvent("ends at begin", begin.Add(-time.Hour), time.Hour))
+	startsAtEnd := create(t, s, newEvent("starts at end", end, time.Hour))
+	before := create(t, s, newEvent("before", begin.Add(-3*time.Hour), time.Hour))
+	after := create(t, s, newEvent("after", end.Add(time.Hour), time.Hour))
+
+	crossesBegin := create(t, s, newEvent("crosses begin", begin.Add(-time.Hour), 2*time.Hour))
+	crossesEnd := create(t, s, newEvent("crosses end", end.Add(-time.Hour), 2*time.Hour))
+	covers := create(t, s, newEvent("covers", begin.Add(-time.Hour), 6*time.Hour))
+	startsAtBegin := create(t, s, newEvent("starts at begin", begin, time.Hour))
+	instant := create(t, s, newEvent("instant", begin.Add(time.Hour), 0))
+	withoutEnd := newEvent("without end", begin.Add(2*time.Hour), 0)
+	withoutEnd.EndDate = time.Time{}
+	withoutEnd = create(t, s, withoutEnd)
+
+	events, err := s.GetEventsInRange(context.Background(), begin, end)
+	require.NoError(t, err)
+
+	found := ids(events)
+	require.ElementsMatch(t, []int64{
+		crossesBegin.ID,
+		crossesEnd.ID,
+		covers.ID,
+		startsAtBegin.ID,
+		instant.ID,
+		withoutEnd.ID,
+	}, found)
+
+	for _, excluded := range []storage.Event{endsAtBegin, startsAtEnd, before, after} {
+		require.NotContains(t, found, excluded.ID, excluded.Title)
+	}
+}
+
+// testOwnerRanges checks range queries of a few owners follow the changes of events, in the begin order.
+func testOwnerRanges(t *testing.T, s app.Storage, clk *clock.Fake) {
+	ctx := context.Background()
+	begin := clk.Now().Add(time.Hour)
+
+	owned := func(title string, ownerID int64, at time.Time) storage.Event {
+		event := newEvent(title, at, time.Hour)
+		event.OwnerID = ownerID
+
+		return create(t, s, event)
+	}
+
+	late := owned("late", 1, begin.Add(2*time.Hour))
+	early := owned("early", 3, begin)
+	moved := owned("moved", 1, begin.Add(time.Hour))
+	other := owned("other", 2, begin)
+
+	events, err := s.GetOwnerEventsInRange(ctx, begin, begin.Add(24*time.Hour), 1, 3, 1)
+	require.NoError(t, err)
+	require.Equal(t, []int64{early.ID, moved.ID, late.ID}, ids(events))
+
+	all, err := s.GetEventsInRange(ctx, begin, begin.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+	require.Equal(t, late.ID, all[3].ID)
+
+	none, err := s.GetOwnerEventsInRange(ctx, begin, begin.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, none)
+
+	// Moving an event to another owner and time moves it between the ranges.
+	moved.OwnerID = 2
+	moved.BeginDate = begin.Add(3 * time.Hour)
+	moved.EndDate = moved.BeginDate.Add(time.Hour)
+	_, err = s.UpdateEvent(ctx, moved)
+	require.NoError(t, err)
+
+	events, err = s.GetOwnerEventsInRange(ctx, begin, begin.Add(24*time.Hour), 1)
+	require.NoError(t, err)
+	require.Equal(t, []int64{late.ID}, ids(events))
+
+	events, err = s.GetOwnerEventsInRange(ctx, begin, begin.Add(24*time.Hour), 2)
+	require.NoError(t, err)
+	require.Equal(t, []int64{other.ID, moved.ID}, ids(events))
+
+	// Rolled back changes leave the ranges as they were.
+	err = s.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.RemoveEvent(ctx, late); err != nil {
+			return err
+		}
+
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	require.NoError(t, s.RemoveEvent(ctx, other))
+
+	events, err = s.GetOwnerEventsInRange(ctx, begin, begin.Add(24*time.Hour), 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, []int64{late.ID, moved.ID}, ids(events))
+}
+
+func testAheadEvents(t *testing.T, s app.Storage, clk *clock.Fake) {
+	ctx := context.Background()
+	current := clk.Now()
+
+	past := create(t, s, newEvent("past", current.Add(-2*time.Hour), time.Hour))
+	hour := create(t, s, newEvent("in an hour", current.Add(time.Hour), time.Hour))
+	days := create(t, s, newEvent("in three days", current.Add(3*24*time.Hour), time.Hour))
+	weeks := create(t, s, newEvent("in two weeks", current.Add(14*24*time.Hour), time.Hour))
+	create(t, s, newEvent("in two months", current.AddDate(0, 2, 0), time.Hour))
+
+	day, err := s.GetDayAheadEvents(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []int64{hour.ID}, ids(day))
+
+	week, err := s.GetWeekAheadEvents(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []int64{hour.ID, days.ID}, ids(week))
+
+	month, err := s.GetMonthAheadEvents(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []int64{hour.ID, days.ID, weeks.ID}, ids(month))
+	require.NotContains(t, ids(month), past.ID)
+}
+
+// testReminders checks pending reminders are due until their event begins, snoozed ones regardless,
+// reminders to come are signalled, and reminders go along with their event.
+func testReminders(t *testing.T, s app.Storage, clk *clock.Fake) {
+	ctx := context.Background()
+	current := clk.Now()
+
+	signals, release := s.SubscribeReminderChanges()
+	defer release()
+
+	remind := func(event storage.Event, offset time.Duration, state string) storage.Reminder {
+		t.Helper()
+
+		reminder, err := s.CreateReminder(ctx, storage.Reminder{
+			EventID:  event.ID,
+			OwnerID:  event.OwnerID,
+			Offset:   offset,
+			RemindAt: event.BeginDate.Add(-offset),
+			State:    state,
+		})
+		require.NoError(t, err)
+		require.NotZero(t, reminder.ID)
+
+		return reminder
+	}
+	reminderIDs := func(reminders []storage.Reminder) []int64 {
+		result := make([]int64, 0, len(reminders))
+		for _, reminder := range reminders {
+			result = append(result, reminder.ID)
+		}
+
+		return result
+	}
+
+	coming := create(t, s, newEvent("coming", current.Add(time.Hour), time.Hour))
+	dayBefore := remind(coming, 24*time.Hour, storage.ReminderPending)
+	quarterBefore := remind(coming, 15*time.Minute, storage.ReminderPending)
+	remind(coming, 2*time.Hour, storage.ReminderSent)
+
+	started := create(t, s, newEvent("started", current.Add(-time.Hour), 2*time.Hour))
+	remind(started, 15*time.Minute, storage.ReminderPending)
+	snoozed := remind(started, 2*time.Hour, storage.ReminderSnoozed)
+
+	_, err := s.CreateReminder(ctx, storage.Reminder{EventID: 1000, RemindAt: current, State: storage.ReminderPending})
+	require.ErrorIs(t, err, storage.ErrEventNotFound)
+
+	require.Eventually(t, func() bool {
+		select {
+		case <-signals:
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	due, err := s.GetDueReminders(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int64{dayBefore.ID, snoozed.ID}, reminderIDs(due))
+
+	upcoming, err := s.GetUpcomingReminders(ctx, current.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []int64{dayBefore.ID, snoozed.ID, quarterBefore.ID}, reminderIDs(upcoming))
+
+	reminders, err := s.GetReminders(ctx, coming.ID)
+	require.NoError(t, err)
+	require.Len(t, reminders, 3)
+	require.Equal(t, dayBefore.ID, reminders[0].ID)
+	require.Equal(t, quarterBefore.ID, reminders[2].ID)
+	require.Equal(t, 15*time.Minute, reminders[2].Offset)
+	require.True(t, quarterBefore.RemindAt.Equal(reminders[2].RemindAt))
+
+	dayBefore.State = storage.ReminderSent
+	_, err = s.UpdateReminder(ctx, dayBefore)
+	require.NoError(t, err)
+
+	clk.Advance(50 * time.Minute)
+	due, err = s.GetDueReminders(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int64{snoozed.ID, quarterBefore.ID}, reminderIDs(due))
+
+	stored, err := s.GetReminder(ctx, dayBefore.ID)
+	require.NoError(t, err)
+	require.Equal(t, storage.ReminderSent, stored.State)
+
+	_, err = s.UpdateReminder(ctx, storage.Reminder{ID: 1000})
+	require.ErrorIs(t, err, storage.ErrReminderNotFound)
+
+	// The state and the time only change as long as the reminder is the way it was read.
+	delivered := stored
+	delivered.State = storage.ReminderDelivered
+	updated, err := s.UpdateReminderState(ctx, stored, delivered)
+	require.NoError(t, err)
+	require.Equal(t, storage.ReminderDelivered, updated.State)
+	require.True(t, stored.RemindAt.Equal(updated.RemindAt))
+
+	pending := stored
+	pending.State = storage.ReminderPending
+	_, err = s.UpdateReminderState(ctx, stored, pending)
+	require.ErrorIs(t, err, storage.ErrReminderChanged)
+
+	moved := updated
+	moved.RemindAt = moved.RemindAt.Add(time.Minute)
+	_, err = s.UpdateReminderState(ctx, moved, pending)
+	require.ErrorIs(t, err, storage.ErrReminderChanged)
+
+	retried := updated
+	retried.State = storage.ReminderPending
+	retried.RemindAt = current.Add(2 * time.Hour)
+	updated, err = s.UpdateReminderState(ctx, updated, retried)
+	require.NoError(t, err)
+	require.Equal(t, storage.ReminderPending, updated.State)
+	require.True(t, retried.RemindAt.Equal(updated.RemindAt))
+
+	_, err = s.UpdateReminderState(ctx, storage.Reminder{ID: 1000}, pending)
+	require.ErrorIs(t, err, storage.ErrReminderNotFound)
+
+	require.NoError(t, s.RemoveReminder(ctx, snoozed.ID))
+	require.ErrorIs(t, s.RemoveReminder(ctx, snoozed.ID), storage.ErrReminderNotFound)
+
+	require.NoError(t, s.RemoveEvent(ctx, coming))
+	_, err = s.GetReminder(ctx, quarterBefore.ID)
+	require.ErrorIs(t, err, storage.ErrReminderNotFound)
+
+	reminders, err = s.GetReminders(ctx, coming.ID, started.ID)
+	require.NoError(t, err)
+	require.Len(t, reminders, 1)
+	require.Equal(t, started.ID, reminders[0].EventID)
+}
+
+// testExpiry checks events ended longer than the retention period ago are removed.
+func testExpiry(t *testing.T, s app.Storage, clk *clock.Fake) {
+	ctx := context.Background()
+	current := clk.Now()
+
+	expired := create(t, s, newEvent("expired", current.AddDate(-2, 0, 0), time.Hour))
+	expiredInstant := create(t, s, newEvent("expired instant", current.AddDate(-1, 0, -1), 0))
+	recent := create(t, s, newEvent("recent", current.AddDate(0, -6, 0), time.Hour))
+	// Began long ago, but ended recently.
+	long := newEvent("long", current.AddDate(-2, 0, 0), 0)
+	long.EndDate = current.AddDate(0, -1, 0)
+	long = create(t, s, long)
+	withoutEnd := newEvent("without end", current.AddDate(0, -1, 0), 0)
+	withoutEnd.EndDate = time.Time{}
+	withoutEnd = create(t, s, withoutEnd)
+	future := create(t, s, newEvent("future", current.Add(time.Hour), time.Hour))
+
+	require.NoError(t, s.RemoveExpiredEvents(ctx, 365*24*time.Hour))
+
+	for _, event := range []storage.Event{expired, expiredInstant} {
+		_, err := s.GetEventByID(ctx, event.ID)
+		require.ErrorIs(t, err, storage.ErrEventNotFound, event.Title)
+	}
+
+	for _, event := range []storage.Event{recent, long, withoutEnd, future} {
+		_, err := s.GetEventByID(ctx, event.ID)
+		require.NoError(t, err, event.Title)
+	}
+
+	// A shorter retention removes the recent past too.
+	require.NoError(t, s.RemoveExpiredEvents(ctx, 7*24*time.Hour))
+
+	for _, event := range []storage.Event{recent, long, withoutEnd} {
+		_, err := s.GetEventByID(ctx, event.ID)
+		require.ErrorIs(t, err, storage.ErrEventNotFound, event.Title)
+	}
+
+	_, err := s.GetEventByID(ctx, future.ID)
+	require.NoError(t, err)
+}
+
+// testChanges checks every modification is recorded in order.
+func testChanges(t *testing.T, s app.Storage, clk *clock.Fake) {
+	ctx := context.Background()
+
+	since, err := s.GetChanges(ctx, 0)
+	require.NoError(t, err)
+	require.Empty(t, since)
+
+	event := create(t, s, newEvent("changed", clk.Now().Add(time.Hour), time.Hour))
+	event.Title = "changed again"
+	_, err = s.UpdateEvent(ctx, event)
+	require.NoError(t, err)
+	require.NoError(t, s.RemoveEvent(ctx, event))
+
+	changes, err := s.GetChanges(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+
+	types := []storage.ChangeType{storage.EventCreated, storage.EventUpdated, storage.EventDeleted}
+	for i, change := range changes {
+		require.Equal(t, types[i], change.Type)
+		require.Equal(t, event.ID, change.Event.ID)
+		require.Equal(t, event.OwnerID, change.Event.OwnerID)
+		if i > 0 {
+			require.Greater(t, change.Revision, changes[i-1].Revision)
+		}
+	}
+
+	latest, err := s.GetChanges(ctx, changes[1].Revision)
+	require.NoError(t, err)
+	require.Len(t, latest, 1)
+	require.Equal(t, storage.EventDeleted, latest[0].Type)
+}
+
+// testTransactions checks failed transactions leave no trace and nested calls join the outer one.
+func testTransactions(t *testing.T, s app.Storage, clk *clock.Fake) {
+	ctx := context.Background()
+	begin := clk.Now().Add(time.Hour)
+
+	var rolledBack storage.Event
+	err := s.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		rolledBack, err = s.CreateEvent(ctx, newEvent("rolled back", begin, time.Hour))
+		require.NoError(t, err)
+
+		// Reads in a transaction see its own writes.
+		_, err = s.GetEventByID(ctx, rolledBack.ID)
+		require.NoError(t, err)
+
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	_, err = s.GetEventByID(ctx, rolledBack.ID)
+	require.ErrorIs(t, err, storage.ErrEventNotFound)
+
+	changes, err := s.GetChanges(ctx, 0)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	var committed []storage.Event
+	err = s.WithinTransaction(ctx, func(ctx context.Context) error {
+		event, err := s.CreateEvent(ctx, newEvent("outer", begin, time.Hour))
+		if err != nil {
+			return err
+		}
+		committed = append(committed, event)
+
+		return s.WithinTransaction(ctx, func(ctx context.Context) error {
+			event, err := s.CreateEvent(ctx, newEvent("nested", begin.Add(time.Hour), time.Hour))
+			committed = append(committed, event)
+
+			return err
+		})
+	})
+	require.NoError(t, err)
+
+	events, err := s.GetEventsInRange(ctx, begin, begin.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.ElementsMatch(t, ids(committed), ids(events))
+}
+
+// testConcurrency checks concurrent writers get distinct ids and readers never fail.
+func testConcurrency(t *testing.T, s app.Storage, clk *clock.Fake) {
+	const writers = 20
+
+	ctx := context.Background()
+	begin := clk.Now().Add(time.Hour)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created []int64
+		errs    = make(chan error, 3*writers)
+	)
+
+	for i := 0; i < writers; i++ {
+		wg.Add(2)
+
+		go func(i int) {
+			defer wg.Done()
+
+			event, err := s.CreateEvent(ctx, newEvent("concurrent", begin.Add(time.Duration(i)*time.Minute), time.Minute))
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			event.Description = "updated"
+			if _, err := s.UpdateEvent(ctx, event); err != nil {
+				errs <- err
+				return
+			}
+
+			mu.Lock()
+			created = append(created, event.ID)
+			mu.Unlock()
+		}(i)
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := s.GetEventsInRange(ctx, begin, begin.Add(time.Hour)); err != nil {
+				errs <- err
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	events, err := s.GetEventsInRange(ctx, begin, begin.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, events, writers)
+	require.ElementsMatch(t, created, ids(events))
+
+	for _, event := range events {
+		require.Equal(t, "updated", event.Description)
+	}
+}
+
+// testDigests checks digest subscriptions are upserted per owner and keep the last sent time.
+func testDigests(t *testing.T, s app.Storage, clk *clock.Fake) {
+	ctx := context.Background()
+
+	_, err := s.GetDigestSubscription(ctx, 1)
+	require.ErrorIs(t, err, storage.ErrDigestNotFound)
+
+	sentAt := clk.Now().Add(-time.Hour)
+	daily := storage.DigestSubscription{OwnerID: 2, Period: storage.DigestDaily, Time: "08:00", TimeZone: "Europe/Moscow", LastSentAt: &sentAt}
+	stored, err := s.SetDigestSubscription(ctx, daily)
+	require.NoError(t, err)
+	require.True(t, sentAt.Equal(*stored.LastSentAt))
+
+	weekly := storage.DigestSubscription{OwnerID: 1, Period: storage.DigestWeekly, Time: "09:30", Weekday: 1}
+	_, err = s.SetDigestSubscription(ctx, weekly)
+	require.NoError(t, err)
+
+	// Replacing keeps the last sent time.
+	daily.Time = "07:15"
+	daily.LastSentAt = nil
+	stored, err = s.SetDigestSubscription(ctx, daily)
+	require.NoError(t, err)
+	require.True(t, sentAt.Equal(*stored.LastSentAt))
+
+	stored, err = s.GetDigestSubscription(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, "07:15", stored.Time)
+	require.Equal(t, "Europe/Moscow", stored.TimeZone)
+
+	require.NoError(t, s.MarkDigestSent(ctx, 1, clk.Now()))
+	require.ErrorIs(t, s.MarkDigestSent(ctx, 3, clk.Now()), storage.ErrDigestNotFound)
+
+	subscriptions, err := s.ListDigestSubscriptions(ctx)
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 2)
+	require.Equal(t, int64(1), subscriptions[0].OwnerID)
+	require.Equal(t, storage.DigestWeekly, subscriptions[0].Period)
+	require.Equal(t, 1, subscriptions[0].Weekday)
+	require.True(t, clk.Now().Equal(*subscriptions[0].LastSentAt))
+	require.Equal(t, int64(2), subscriptions[1].OwnerID)
+
+	require.NoError(t, s.RemoveDigestSubscription(ctx, 2))
+	require.ErrorIs(t, s.RemoveDigestSubscription(ctx, 2), storage.ErrDigestNotFound)
+
+	subscriptions, err = s.ListDigestSubscriptions(ctx)
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+}
+
+// testNotifications checks notifications are kept per reminder and channel, filtered, removed along
+// with their event or reminder, and flag their event as they are published and delivered.
+func testNotifications(t *testing.T, s app.Storage, clk *clock.Fake) {
+	ctx := context.Background()
+	now := clk.Now()
+
+	first := create(t, s, newEvent("first", now.Add(time.Hour), time.Hour))
+	second := create(t, s, newEvent("second", now.Add(2*time.Hour), time.Hour))
+	second.OwnerID = 2
+	_, err := s.UpdateEvent(ctx, second)
+	require.NoError(t, err)
+
+	remind := func(event storage.Event) storage.Reminder {
+		t.Helper()
+
+		reminder, err := s.CreateReminder(ctx, storage.Reminder{
+			EventID:  event.ID,
+			OwnerID:  event.OwnerID,
+			Offset:   time.Hour,
+			RemindAt: event.BeginDate.Add(-time.Hour),
+			State:    storage.ReminderPending,
+		})
+		require.NoError(t, err)
+
+		return reminder
+	}
+	firstReminder, secondReminder := remind(first), remind(second)
+
+	notify := func(event storage.Event, reminderID int64, channel string) storage.Notification {
+		t.Helper()
+
+		notification, err := s.CreateNotification(ctx, storage.Notification{
+			EventID:    event.ID,
+			ReminderID: reminderID,
+			OwnerID:    event.OwnerID,
+			Channel:    channel,
+			State:      storage.NotificationPending,
+			Attempts:   1,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+		require.NoError(t, err)
+		require.NotZero(t, notification.ID)
+
+		return notification
+	}
+
+	console := notify(first, firstReminder.ID, storage.ChannelConsole)
+	email := notify(first, firstReminder.ID, "email")
+	other := notify(second, secondReminder.ID, storage.ChannelConsole)
+
+	_, err = s.CreateNotification(ctx, storage.Notification{EventID: 1000, Channel: storage.ChannelConsole})
+	require.ErrorIs(t, err, storage.ErrEventNotFound)
+
+	found, err := s.FindNotification(ctx, first.ID, firstReminder.ID, storage.ChannelConsole)
+	require.NoError(t, err)
+	require.Equal(t, console.ID, found.ID)
+	require.True(t, now.Equal(found.CreatedAt))
+	require.Nil(t, found.PublishedAt)
+
+	_, err = s.FindNotification(ctx, first.ID, secondReminder.ID, storage.ChannelConsole)
+	require.ErrorIs(t, err, storage.ErrNotificationNotFound)
+
+	clk.Advance(time.Minute)
+	delivered := clk.Now()
+	console.State = storage.NotificationDelivered
+	console.Attempts = 2
+	console.LastError = "timeout"
+	console.UpdatedAt = delivered
+	console.PublishedAt = &now
+	console.DeliveredAt = &delivered
+	updated, err := s.UpdateNotification(ctx, console)
+	require.NoError(t, err)
+	require.Equal(t, console.ID, updated.ID)
+	require.Equal(t, firstReminder.ID, updated.ReminderID)
+	require.Equal(t, storage.NotificationDelivered, updated.State)
+
+	stored, err := s.GetNotification(ctx, console.ID)
+	require.NoError(t, err)
+	require.Equal(t, storage.NotificationDelivered, stored.State)
+	require.Equal(t, 2, stored.Attempts)
+	require.Equal(t, "timeout", stored.LastError)
+	require.True(t, delivered.Equal(stored.UpdatedAt))
+	require.True(t, now.Equal(*stored.PublishedAt))
+	require.True(t, delivered.Equal(*stored.DeliveredAt))
+
+	_, err = s.UpdateNotification(ctx, storage.Notification{ID: 1000})
+	require.ErrorIs(t, err, storage.ErrNotificationNotFound)
+
+	// The flags of the event follow its notifications and are not written along with the event.
+	flagged, err := s.GetEventByID(ctx, first.ID)
+	require.NoError(t, err)
+	require.True(t, flagged.NotificationSent)
+	require.True(t, flagged.NotificationReceived)
+
+	flagged.NotificationSent, flagged.NotificationReceived = false, false
+	flagged, err = s.UpdateEvent(ctx, flagged)
+	require.NoError(t, err)
+	require.True(t, flagged.NotificationSent)
+	require.True(t, flagged.NotificationReceived)
+
+	other.State = storage.NotificationPublished
+	other.PublishedAt = &delivered
+	_, err = s.UpdateNotification(ctx, other)
+	require.NoError(t, err)
+
+	flagged, err = s.GetEventByID(ctx, second.ID)
+	require.NoError(t, err)
+	require.True(t, flagged.NotificationSent)
+	require.False(t, flagged.NotificationReceived)
+
+	notificationIDs := func(filter storage.NotificationFilter) []int64 {
+		t.Helper()
+
+		notifications, err := s.ListNotifications(ctx, filter)
+		require.NoError(t, err)
+
+		result := make([]int64, 0, len(notifications))
+		for _, notification := range notifications {
+			result = append(result, notification.ID)
+		}
+
+		return result
+	}
+
+	require.Equal(t, []int64{console.ID, email.ID, other.ID}, notificationIDs(storage.NotificationFilter{}))
+	require.Equal(t, []int64{console.ID, email.ID}, notificationIDs(storage.NotificationFilter{EventID: first.ID}))
+	require.Equal(t, []int64{other.ID}, notificationIDs(storage.NotificationFilter{OwnerID: 2}))
+	require.Equal(t, []int64{email.ID}, notificationIDs(storage.NotificationFilter{
+		OwnerID: 1, State: storage.NotificationPending,
+	}))
+
+	require.NoError(t, s.RemoveEvent(ctx, first))
+	_, err = s.GetNotification(ctx, console.ID)
+	require.ErrorIs(t, err, storage.ErrNotificationNotFound)
+	require.Equal(t, []int64{other.ID}, notificationIDs(storage.NotificationFilter{}))
+
+	require.NoError(t, s.RemoveReminder(ctx, secondReminder.ID))
+	_, err = s.GetNotification(ctx, other.ID)
+	require.ErrorIs(t, err, storage.ErrNotificationNotFound)
+}
+
+func testCategories(t *testing.T, s app.Storage, _ *clock.Fake) {
+	ctx := context.Background()
+
+	work, err := s.CreateCategory(ctx, storage.Category{
+		OwnerID: 1, Name: "work", Color: "#ff0000", Reminders: storage.Offsets{time.Hour, 10 * time.Minute},
+	})
+	require.NoError(t, err)
+	require.NotZero(t, work.ID)
+
+	home, err := s.CreateCategory(ctx, storage.Category{OwnerID: 1, Name: "home"})
+	require.NoError(t, err)
+
+	_, err = s.CreateCategory(ctx, storage.Category{OwnerID: 2, Name: "work"})
+	require.NoError(t, err)
+
+	_, err = s.CreateCategory(ctx, storage.Category{OwnerID: 1, Name: "work"})
+	require.ErrorIs(t, err, storage.ErrCategoryExists)
+
+	stored, err := s.GetCategory(ctx, work.ID)
+	require.NoError(t, err)
+	require.Equal(t, work, stored)
+
+	home.Name = "work"
+	_, err = s.UpdateCategory(ctx, home)
+	require.ErrorIs(t, err, storage.ErrCategoryExists)
+
+	home.Name, home.Color = "family", "#00ff00"
+	_, err = s.UpdateCategory(ctx, home)
+	require.NoError(t, err)
+
+	_, err = s.UpdateCategory(ctx, storage.Category{ID: 1000, OwnerID: 1, Name: "lost"})
+	require.ErrorIs(t, err, storage.ErrCategoryNotFound)
+
+	categories, err := s.ListCategories(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []storage.Category{home, work}, categories)
+
+	event := newEvent("meeting", Reference.Add(time.Hour), time.Hour)
+	event.CategoryID = work.ID
+	event = create(t, s, event)
+	other := create(t, s, newEvent("lunch", Reference.Add(3*time.Hour), time.Hour))
+
+	require.NoError(t, s.SetEventTags(ctx, event.ID, []string{"urgent", "client"}))
+	require.NoError(t, s.SetEventTags(ctx, other.ID, []string{"food"}))
+	require.ErrorIs(t, s.SetEventTags(ctx, 1000, []string{"lost"}), storage.ErrEventNotFound)
+
+	// Changed tags record an update of the event, the same ones record nothing.
+	changes, err := s.GetChanges(ctx, 0)
+	require.NoError(t, err)
+	tagged := changes[len(changes)-1]
+	require.Equal(t, storage.EventUpdated, tagged.Type)
+	require.Equal(t, other.ID, tagged.Event.ID)
+
+	require.NoError(t, s.SetEventTags(ctx, other.ID, []string{"food"}))
+	changes, err = s.GetChanges(ctx, tagged.Revision)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	tags, err := s.GetEventTags(ctx, event.ID, other.ID)
+	require.NoError(t, err)
+	require.Equal(t, map[int64][]string{event.ID: {"client", "urgent"}, other.ID: {"food"}}, tags)
+
+	require.NoError(t, s.SetEventTags(ctx, other.ID, nil))
+	tags, err = s.GetEventTags(ctx, other.ID)
+	require.NoError(t, err)
+	require.Empty(t, tags)
+
+	// Removing a category leaves its events without one.
+	require.NoError(t, s.RemoveCategory(ctx, work.ID))
+	_, err = s.GetCategory(ctx, work.ID)
+	require.ErrorIs(t, err, storage.ErrCategoryNotFound)
+	require.ErrorIs(t, s.RemoveCategory(ctx, work.ID), storage.ErrCategoryNotFound)
+
+	uncategorized, err := s.GetEventByID(ctx, event.ID)
+	require.NoError(t, err)
+	require.Zero(t, uncategorized.CategoryID)
+
+	require.NoError(t, s.RemoveEvent(ctx, event))
+	tags, err = s.GetEventTags(ctx, event.ID)
+	require.NoError(t, err)
+	require.Empty(t, tags)
+}
+
+func testCalendars(t *testing.T, s app.Storage, _ *clock.Fake) {
+	ctx := context.Background()
+
+	_, err := s.GetDefaultCalendar(ctx, 1)
+	require.ErrorIs(t, err, storage.ErrCalendarNotFound)
+
+	personal, err := s.CreateCalendar(ctx, storage.Calendar{OwnerID: 1, Name: "personal", Default: true})
+	require.NoError(t, err)
+	require.NotZero(t, personal.ID)
+
+	_, err = s.CreateCalendar(ctx, storage.Calendar{OwnerID: 1, Name: "other", Default: true})
+	require.ErrorIs(t, err, storage.ErrCalendarExists)
+
+	work, err := s.CreateCalendar(ctx, storage.Calendar{OwnerID: 1, Name: "work"})
+	require.NoError(t, err)
+
+	_, err = s.CreateCalendar(ctx, storage.Calendar{OwnerID: 2, Name: "team", Default: true})
+	require.NoError(t, err)
+
+	stored, err := s.GetDefaultCalendar(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, personal, stored)
+
+	work.Name = "office"
+	_, err = s.UpdateCalendar(ctx, work)
+	require.NoError(t, err)
+
+	work.Default = true
+	_, err = s.UpdateCalendar(ctx, work)
+	require.ErrorIs(t, err, storage.ErrCalendarExists)
+	work.Default = false
+
+	_, err = s.UpdateCalendar(ctx, storage.Calendar{ID: 1000, OwnerID: 1, Name: "lost"})
+	require.ErrorIs(t, err, storage.ErrCalendarNotFound)
+
+	calendars, err := s.ListCalendars(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []storage.Calendar{work, personal}, calendars)
+
+	read := storage.Grant{CalendarID: work.ID, OwnerID: 1, GranteeID: 2, Access: storage.AccessRead}
+	_, err = s.SetGrant(ctx, read)
+	require.NoError(t, err)
+
+	shared := storage.Grant{CalendarID: work.ID, OwnerID: 1, GranteeID: 3, Access: storage.AccessFreeBusy}
+	_, err = s.SetGrant(ctx, shared)
+	require.NoError(t, err)
+
+	// Granting again replaces the access.
+	shared.Access = storage.AccessWrite
+	_, err = s.SetGrant(ctx, shared)
+	require.NoError(t, err)
+
+	_, err = s.SetGrant(ctx, storage.Grant{CalendarID: 1000, OwnerID: 1, GranteeID: 2, Access: storage.AccessRead})
+	require.ErrorIs(t, err, storage.ErrCalendarNotFound)
+
+	grant, err := s.GetGrant(ctx, work.ID, 3)
+	require.NoError(t, err)
+	require.Equal(t, shared, grant)
+
+	_, err = s.GetGrant(ctx, personal.ID, 3)
+	require.ErrorIs(t, err, storage.ErrGrantNotFound)
+
+	grants, err := s.ListGrants(ctx, storage.GrantFilter{CalendarID: work.ID})
+	require.NoError(t, err)
+	require.Equal(t, []storage.Grant{read, shared}, grants)
+
+	grants, err = s.ListGrants(ctx, storage.GrantFilter{GranteeID: 3})
+	require.NoError(t, err)
+	require.Equal(t, []storage.Grant{shared}, grants)
+
+	require.NoError(t, s.RemoveGrant(ctx, work.ID, 2))
+	require.ErrorIs(t, s.RemoveGrant(ctx, work.ID, 2), storage.ErrGrantNotFound)
+
+	// Calendars having events are kept.
+	event := newEvent("meeting", Reference.Add(time.Hour), time.Hour)
+	event.CalendarID = work.ID
+	event = create(t, s, event)
+
+	placed, err := s.GetEventByID(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, work.ID, placed.CalendarID)
+
+	require.ErrorIs(t, s.RemoveCalendar(ctx, work.ID), storage.ErrCalendarNotEmpty)
+
+	require.NoError(t, s.RemoveEvent(ctx, event))
+	require.NoError(t, s.RemoveCalendar(ctx, work.ID))
+	require.ErrorIs(t, s.RemoveCalendar(ctx, work.ID), storage.ErrCalendarNotFound)
+
+	_, err = s.GetCalendar(ctx, work.ID)
+	require.ErrorIs(t, err, storage.ErrCalendarNotFound)
+
+	grants, err = s.ListGrants(ctx, storage.GrantFilter{GranteeID: 3})
+	require.NoError(t, err)
+	require.Empty(t, grants)
+}
@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrTokenNotFound = errors.New("api token not found")
+
+// APIToken is an opaque API token, only the hash of its value is stored.
+type APIToken struct {
+	ID        int64      `db:"id" json:"id"`
+	Name      string     `db:"name" json:"name"`
+	OwnerID   int64      `db:"owner_id" json:"owner_id"`
+	Hash      string     `db:"token_hash" json:"-"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	ExpiresAt *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	RevokedAt *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/spendmail/otus_go_hw/hw12_13_14_15_calendar"
+
+// Supported span exporters.
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+)
+
+var (
+	ErrUnknownExporter = errors.New("unknown tracing exporter")
+	ErrCreateExporter  = errors.New("unable to create tracing exporter")
+	ErrShutdown        = errors.New("unable to shutdown tracing")
+)
+
+type Config interface {
+	GetTracingExporter() string
+	GetTracingEndpoint() string
+	GetTracingFile() string
+}
+
+// Provider exports the spans of a single binary.
+type Provider struct {
+	provider *sdktrace.TracerProvider
+	file     *os.File
+}
+
+// NewProvider sets up the global tracer provider and the W3C trace context propagator.
+// Without an exporter spans are not recorded, but the context is still propagated.
+func NewProvider(ctx context.Context, config Config, service string) (*Provider, error) {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(service),
+		)),
+	}
+
+	p := &Provider{}
+
+	switch config.GetTracingExporter() {
+	case "", ExporterNone:
+	case ExporterOTLP:
+		exporter, err := otlptracegrpc.New(
+			ctx,
+			otlptracegrpc.WithEndpoint(config.GetTracingEndpoint()),
+			otlptracegrpc.WithInsecure(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrCreateExporter, err.Error())
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	case ExporterFile:
+		file, err := os.OpenFile(config.GetTracingFile(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrCreateExporter, err.Error())
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%w: %s", ErrCreateExporter, err.Error())
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+		p.file = file
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownExporter, config.GetTracingExporter())
+	}
+
+	p.provider = sdktrace.NewTracerProvider(options...)
+
+	otel.SetTracerProvider(p.provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return p, nil
+}
+
+// Shutdown flushes the pending spans and releases the exporter.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if err := p.provider.Shutdown(ctx); err != nil {
+		return fmt.Errorf("%w: %s", ErrShutdown, err.Error())
+	}
+
+	if p.file != nil {
+		if err := p.file.Close(); err != nil {
+			return fmt.Errorf("%w: %s", ErrShutdown, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Start starts a span using the global tracer provider.
+func Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, options...)
+}
+
+// Inject writes the trace context of ctx into the carrier.
+func Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+}
+
+// Extract returns ctx with the trace context read from the carrier.
+func Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
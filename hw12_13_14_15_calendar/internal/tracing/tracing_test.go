@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type testConfig struct {
+	exporter string
+	file     string
+}
+
+func (c testConfig) GetTracingExporter() string { return c.exporter }
+func (c testConfig) GetTracingEndpoint() string { return "" }
+func (c testConfig) GetTracingFile() string     { return c.file }
+
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unknown exporter", func(t *testing.T) {
+		_, err := NewProvider(ctx, testConfig{exporter: "zipkin"}, "test")
+		require.ErrorIs(t, err, ErrUnknownExporter)
+	})
+
+	t.Run("propagation into file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "traces.json")
+		provider, err := NewProvider(ctx, testConfig{exporter: ExporterFile, file: file}, "test")
+		require.NoError(t, err)
+
+		parentCtx, parent := Start(ctx, "publish")
+		carrier := propagation.HeaderCarrier(http.Header{})
+		Inject(parentCtx, carrier)
+
+		_, child := Start(Extract(ctx, carrier), "consume")
+		require.Equal(t, parent.SpanContext().TraceID(), child.SpanContext().TraceID())
+		require.Equal(t, parent.SpanContext().SpanID(), trace.SpanContextFromContext(Extract(ctx, carrier)).SpanID())
+
+		child.End()
+		parent.End()
+		require.NoError(t, provider.Shutdown(ctx))
+
+		b, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.True(t, strings.Contains(string(b), `"Name":"publish"`))
+		require.True(t, strings.Contains(string(b), `"Name":"consume"`))
+	})
+}
@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE app_event_change
+(
+    revision   BIGSERIAL                                                NOT NULL,
+    type       VARCHAR(16)                                              NOT NULL,
+    event_id   INT                                                      NOT NULL,
+    owner_id   INT                                                      NOT NULL,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (revision)
+);
+CREATE INDEX IDX_APP_EVENT_CHANGE_CREATED_AT ON app_event_change (created_at);
+
+CREATE FUNCTION app_event_notify_change() RETURNS TRIGGER AS
+$$
+DECLARE
+    changed         app_event;
+    change_type     VARCHAR(16);
+    change_revision BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+        change_type := 'deleted';
+    ELSIF TG_OP = 'UPDATE' THEN
+        changed := NEW;
+        change_type := 'updated';
+    ELSE
+        changed := NEW;
+        change_type := 'created';
+    END IF;
+
+    INSERT INTO app_event_change (type, event_id, owner_id)
+    VALUES (change_type, changed.id, changed.owner_id)
+    RETURNING revision INTO change_revision;
+
+    PERFORM pg_notify('app_event_change', change_revision::TEXT);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER app_event_change
+    AFTER INSERT OR UPDATE OR DELETE
+    ON app_event
+    FOR EACH ROW
+EXECUTE PROCEDURE app_event_notify_change();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS app_event_change ON app_event;
+DROP FUNCTION IF EXISTS app_event_notify_change();
+DROP TABLE IF EXISTS app_event_change;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE app_api_token
+(
+    id         BIGSERIAL                                                NOT NULL,
+    name       VARCHAR(255)                                             NOT NULL,
+    owner_id   INT                                                      NOT NULL,
+    token_hash VARCHAR(64)                                              NOT NULL,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP(0) WITHOUT TIME ZONE DEFAULT NULL,
+    revoked_at TIMESTAMP(0) WITHOUT TIME ZONE DEFAULT NULL,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX UNIQ_APP_API_TOKEN_HASH ON app_api_token (token_hash);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS app_api_token;
+-- +goose StatementEnd
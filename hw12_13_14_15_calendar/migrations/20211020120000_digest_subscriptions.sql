@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE app_digest_subscription
+(
+    owner_id     INT                                     NOT NULL,
+    period       VARCHAR(16)                             NOT NULL,
+    send_time    VARCHAR(5)                              NOT NULL,
+    time_zone    VARCHAR(64)    DEFAULT ''               NOT NULL,
+    weekday      SMALLINT       DEFAULT 0                NOT NULL,
+    last_sent_at TIMESTAMP(0) WITHOUT TIME ZONE DEFAULT NULL,
+    PRIMARY KEY (owner_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS app_digest_subscription;
+-- +goose StatementEnd
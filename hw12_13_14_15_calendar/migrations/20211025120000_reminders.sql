@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE app_reminder
+(
+    id        BIGSERIAL                              NOT NULL,
+    event_id  INT                                    NOT NULL REFERENCES app_event (id) ON DELETE CASCADE,
+    owner_id  INT                                    NOT NULL,
+    offset_ns BIGINT                                 NOT NULL,
+    remind_at TIMESTAMP(0) WITHOUT TIME ZONE         NOT NULL,
+    state     VARCHAR(16)    DEFAULT 'pending'       NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IDX_APP_REMINDER_EVENT ON app_reminder (event_id);
+CREATE INDEX IDX_APP_REMINDER_DUE ON app_reminder (state, remind_at);
+
+-- Existing events keep the single day-ahead reminder they used to get.
+INSERT INTO app_reminder (event_id, owner_id, offset_ns, remind_at, state)
+SELECT id, owner_id, 86400000000000, begin_date - interval '1 day',
+       CASE
+           WHEN notification_received THEN 'delivered'
+           WHEN notification_sent THEN 'sent'
+           ELSE 'pending'
+       END
+FROM app_event;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS app_reminder;
+-- +goose StatementEnd
@@ -0,0 +1,58 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE app_notification
+(
+    id           BIGSERIAL                                                NOT NULL,
+    event_id     INT                                                      NOT NULL REFERENCES app_event (id) ON DELETE CASCADE,
+    reminder_id  BIGINT                                                   NOT NULL REFERENCES app_reminder (id) ON DELETE CASCADE,
+    owner_id     INT                                                      NOT NULL,
+    channel      VARCHAR(32)                                              NOT NULL,
+    state        VARCHAR(16)    DEFAULT 'pending'                         NOT NULL,
+    attempts     INT            DEFAULT 0                                 NOT NULL,
+    last_error   TEXT           DEFAULT ''                                NOT NULL,
+    created_at   TIMESTAMP(0) WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at   TIMESTAMP(0) WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    published_at TIMESTAMP(0) WITHOUT TIME ZONE DEFAULT NULL,
+    delivered_at TIMESTAMP(0) WITHOUT TIME ZONE DEFAULT NULL,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX UNIQ_APP_NOTIFICATION_KEY ON app_notification (event_id, reminder_id, channel);
+CREATE INDEX IDX_APP_NOTIFICATION_OWNER ON app_notification (owner_id);
+CREATE INDEX IDX_APP_NOTIFICATION_REMINDER ON app_notification (reminder_id);
+
+-- The flags of the sent reminders become their records, their times are unknown.
+INSERT INTO app_notification (event_id, reminder_id, owner_id, channel, state, attempts)
+SELECT event_id, id, owner_id, 'console', CASE WHEN state = 'delivered' THEN 'delivered' ELSE 'published' END, 1
+FROM app_reminder
+WHERE state IN ('sent', 'delivered');
+
+-- The flags of the events stay for the clients reading them, kept up by the notification records.
+-- They are set once a notification of the event is published or delivered and never reset.
+-- Events are only updated when a flag changes, which records an update of the event as well.
+CREATE FUNCTION app_notification_mark_event() RETURNS TRIGGER AS
+$$
+BEGIN
+    UPDATE app_event
+    SET notification_sent     = TRUE,
+        notification_received = notification_received OR NEW.state = 'delivered'
+    WHERE id = NEW.event_id
+      AND (NOT notification_sent OR NEW.state = 'delivered' AND NOT notification_received);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER app_notification_mark_event
+    AFTER INSERT OR UPDATE OF state
+    ON app_notification
+    FOR EACH ROW
+    WHEN (NEW.state IN ('published', 'delivered'))
+EXECUTE PROCEDURE app_notification_mark_event();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS app_notification_mark_event ON app_notification;
+DROP FUNCTION IF EXISTS app_notification_mark_event();
+DROP TABLE IF EXISTS app_notification;
+-- +goose StatementEnd
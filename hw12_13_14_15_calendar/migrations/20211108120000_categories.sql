@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE app_category
+(
+    id        BIGSERIAL                       NOT NULL,
+    owner_id  INT                             NOT NULL,
+    name      VARCHAR(64)                     NOT NULL,
+    color     VARCHAR(7)     DEFAULT ''       NOT NULL,
+    -- JSON array of the default reminder offsets in nanoseconds.
+    reminders TEXT           DEFAULT '[]'     NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX UNIQ_APP_CATEGORY_NAME ON app_category (owner_id, name);
+
+-- Events without a category refer to none with NULL, those of a removed category are left without one.
+ALTER TABLE app_event ADD COLUMN category_id BIGINT DEFAULT NULL REFERENCES app_category (id) ON DELETE SET NULL;
+CREATE INDEX IDX_APP_EVENT_CATEGORY ON app_event (category_id);
+
+CREATE TABLE app_event_tag
+(
+    event_id INT         NOT NULL REFERENCES app_event (id) ON DELETE CASCADE,
+    tag      VARCHAR(64) NOT NULL,
+    PRIMARY KEY (event_id, tag)
+);
+CREATE INDEX IDX_APP_EVENT_TAG ON app_event_tag (tag);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS app_event_tag;
+ALTER TABLE app_event DROP COLUMN IF EXISTS category_id;
+DROP TABLE IF EXISTS app_category;
+-- +goose StatementEnd
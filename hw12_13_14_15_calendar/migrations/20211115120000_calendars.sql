@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE app_calendar
+(
+    id         BIGSERIAL                    NOT NULL,
+    owner_id   INT                          NOT NULL,
+    name       VARCHAR(64)                  NOT NULL,
+    is_default BOOLEAN        DEFAULT FALSE NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IDX_APP_CALENDAR_OWNER ON app_calendar (owner_id);
+-- An owner has a single default calendar.
+CREATE UNIQUE INDEX UNIQ_APP_CALENDAR_DEFAULT ON app_calendar (owner_id) WHERE is_default;
+
+CREATE TABLE app_calendar_grant
+(
+    calendar_id BIGINT      NOT NULL REFERENCES app_calendar (id) ON DELETE CASCADE,
+    owner_id    INT         NOT NULL,
+    grantee_id  INT         NOT NULL,
+    access      VARCHAR(16) NOT NULL,
+    PRIMARY KEY (calendar_id, grantee_id)
+);
+CREATE INDEX IDX_APP_CALENDAR_GRANT_GRANTEE ON app_calendar_grant (grantee_id);
+
+-- Events without a calendar refer to none with NULL, calendars still having events are not removed.
+ALTER TABLE app_event ADD COLUMN calendar_id BIGINT DEFAULT NULL REFERENCES app_calendar (id);
+CREATE INDEX IDX_APP_EVENT_CALENDAR ON app_event (calendar_id);
+
+-- Existing events move to the default calendars of their owners.
+INSERT INTO app_calendar (owner_id, name, is_default)
+SELECT DISTINCT owner_id, 'Default', TRUE FROM app_event;
+
+UPDATE app_event SET calendar_id = app_calendar.id
+FROM app_calendar
+WHERE app_calendar.owner_id = app_event.owner_id AND app_calendar.is_default;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE app_event DROP COLUMN IF EXISTS calendar_id;
+DROP TABLE IF EXISTS app_calendar_grant;
+DROP TABLE IF EXISTS app_calendar;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Range queries look up events by their begin dates, of all the owners or of a few.
+CREATE INDEX IDX_APP_EVENT_BEGIN ON app_event (begin_date);
+CREATE INDEX IDX_APP_EVENT_OWNER_BEGIN ON app_event (owner_id, begin_date);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS IDX_APP_EVENT_OWNER_BEGIN;
+DROP INDEX IF EXISTS IDX_APP_EVENT_BEGIN;
+-- +goose StatementEnd
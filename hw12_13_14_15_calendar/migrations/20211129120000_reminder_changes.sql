@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Wakes the scheduler up when a reminder may come due earlier than it knows: added, moved or snoozed.
+-- Notifications of a transaction are merged into one, as their payloads are the same.
+CREATE FUNCTION app_reminder_notify_change() RETURNS TRIGGER AS
+$$
+BEGIN
+    PERFORM pg_notify('app_reminder_change', '');
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER app_reminder_change
+    AFTER INSERT OR UPDATE
+    ON app_reminder
+    FOR EACH ROW
+    WHEN (NEW.state IN ('pending', 'snoozed'))
+EXECUTE PROCEDURE app_reminder_notify_change();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS app_reminder_change ON app_reminder;
+DROP FUNCTION IF EXISTS app_reminder_notify_change();
+-- +goose StatementEnd
@@ -0,0 +1,84 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Revisions handed out on insert become visible in the order the transactions commit, so a watcher
+-- reading past revision N+1 could miss N committed later. Changes are recorded without a revision
+-- instead and numbered by their readers once committed, the readers taking turns, so revisions
+-- become visible in their order while the writers of events never wait for each other.
+ALTER TABLE app_event_change DROP CONSTRAINT app_event_change_pkey;
+ALTER TABLE app_event_change ALTER COLUMN revision DROP NOT NULL, ALTER COLUMN revision DROP DEFAULT;
+ALTER TABLE app_event_change ADD COLUMN id BIGSERIAL NOT NULL PRIMARY KEY;
+CREATE UNIQUE INDEX UNIQ_APP_EVENT_CHANGE_REVISION ON app_event_change (revision);
+CREATE INDEX IDX_APP_EVENT_CHANGE_UNNUMBERED ON app_event_change (id) WHERE revision IS NULL;
+
+CREATE OR REPLACE FUNCTION app_event_notify_change() RETURNS TRIGGER AS
+$$
+DECLARE
+    changed     app_event;
+    change_type VARCHAR(16);
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+        change_type := 'deleted';
+    ELSIF TG_OP = 'UPDATE' THEN
+        changed := NEW;
+        change_type := 'updated';
+    ELSE
+        changed := NEW;
+        change_type := 'created';
+    END IF;
+
+    INSERT INTO app_event_change (type, event_id, owner_id)
+    VALUES (change_type, changed.id, changed.owner_id);
+
+    -- Notifications are delivered on commit, when the change can be numbered.
+    PERFORM pg_notify('app_event_change', '');
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION app_event_notify_change() RETURNS TRIGGER AS
+$$
+DECLARE
+    changed         app_event;
+    change_type     VARCHAR(16);
+    change_revision BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+        change_type := 'deleted';
+    ELSIF TG_OP = 'UPDATE' THEN
+        changed := NEW;
+        change_type := 'updated';
+    ELSE
+        changed := NEW;
+        change_type := 'created';
+    END IF;
+
+    INSERT INTO app_event_change (type, event_id, owner_id)
+    VALUES (change_type, changed.id, changed.owner_id)
+    RETURNING revision INTO change_revision;
+
+    PERFORM pg_notify('app_event_change', change_revision::TEXT);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+UPDATE app_event_change c
+SET revision = n.revision
+FROM (SELECT id, nextval('app_event_change_revision_seq') AS revision
+      FROM (SELECT id FROM app_event_change WHERE revision IS NULL ORDER BY id) unnumbered) n
+WHERE c.id = n.id;
+
+DROP INDEX IF EXISTS IDX_APP_EVENT_CHANGE_UNNUMBERED;
+DROP INDEX IF EXISTS UNIQ_APP_EVENT_CHANGE_REVISION;
+ALTER TABLE app_event_change DROP COLUMN id;
+ALTER TABLE app_event_change
+    ALTER COLUMN revision SET DEFAULT nextval('app_event_change_revision_seq'),
+    ALTER COLUMN revision SET NOT NULL,
+    ADD PRIMARY KEY (revision);
+-- +goose StatementEnd
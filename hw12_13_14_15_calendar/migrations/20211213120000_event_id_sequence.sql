@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Owned by the id column, the sequence is restarted along with the table by TRUNCATE ... RESTART IDENTITY.
+ALTER SEQUENCE app_event_id_seq OWNED BY app_event.id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER SEQUENCE app_event_id_seq OWNED BY NONE;
+-- +goose StatementEnd
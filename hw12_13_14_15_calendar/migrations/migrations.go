@@ -0,0 +1,9 @@
+// Package migrations embeds the goose migrations of the sql storage.
+package migrations
+
+import "embed"
+
+// FS holds the versioned migration files.
+//
+//go:embed [0-9]*.sql
+var FS embed.FS
@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFS(t *testing.T) {
+	files, err := fs.Glob(FS, "*.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		b, err := FS.ReadFile(file)
+		require.NoError(t, err)
+
+		content := string(b)
+		require.Contains(t, content, "-- +goose Up", file)
+		require.Contains(t, content, "-- +goose Down", file)
+		require.True(t, strings.IndexByte("0123456789", file[0]) >= 0, file)
+	}
+}
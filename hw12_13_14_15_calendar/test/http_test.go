@@ -22,14 +22,12 @@ var (
 )
 
 type Event struct {
-	ID                   int64     `db:"id" json:"id"`
-	Title                string    `db:"title" json:"title"`
-	BeginDate            time.Time `db:"begin_date" json:"begin_date"`
-	EndDate              time.Time `db:"end_date" json:"end_date"`
-	Description          string    `db:"description" json:"description"`
-	OwnerID              int64     `db:"owner_id" json:"owner_id"`
-	NotificationSent     bool      `db:"notification_sent" json:"notification_sent"`
-	NotificationReceived bool      `db:"notification_received" json:"notification_received"`
+	ID          int64     `db:"id" json:"id"`
+	Title       string    `db:"title" json:"title"`
+	BeginDate   time.Time `db:"begin_date" json:"begin_date"`
+	EndDate     time.Time `db:"end_date" json:"end_date"`
+	Description string    `db:"description" json:"description"`
+	OwnerID     int64     `db:"owner_id" json:"owner_id"`
 }
 
 func init() {
@@ -42,11 +40,32 @@ func init() {
 	}
 }
 
+// waitReady polls the readiness probe of the calendar, which migrates the schema before serving.
+func waitReady(t *testing.T, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		response, err := http.Get(HTTPHost + "/readyz")
+		if err == nil {
+			response.Body.Close()
+			if response.StatusCode == http.StatusOK {
+				return
+			}
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("calendar is not ready after %s", timeout)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
 //nolint:funlen,gocognit
 func TestHTTP(t *testing.T) {
-	// Waiting for a few seconds in order to create a schema.
-	t.Logf("Waiting for the database preparaion...\n")
-	time.Sleep(5 * time.Second)
+	// Waiting for the calendar to migrate the schema and get ready.
+	t.Logf("Waiting for the calendar readiness...\n")
+	waitReady(t, 30*time.Second)
 
 	// DB connection.
 	db, err := sqlx.ConnectContext(context.Background(), "postgres", PostgresDSN)
@@ -66,14 +85,12 @@ func TestHTTP(t *testing.T) {
 
 		// Initial event.
 		event := Event{
-			ID:                   1,
-			Title:                "Title",
-			BeginDate:            time.Now().Add(time.Hour * 12),
-			EndDate:              time.Now().Add(time.Hour * 12),
-			Description:          "Description",
-			OwnerID:              1,
-			NotificationSent:     false,
-			NotificationReceived: false,
+			ID:          1,
+			Title:       "Title",
+			BeginDate:   time.Now().Add(time.Hour * 12),
+			EndDate:     time.Now().Add(time.Hour * 12),
+			Description: "Description",
+			OwnerID:     1,
 		}
 
 		// Receiving event entities.
@@ -250,12 +267,12 @@ func TestHTTP(t *testing.T) {
 		t.Logf("Waiting for notification receiving...\n")
 		time.Sleep(5 * time.Second)
 
-		// Checking removed rows in database.
-		events = []Event{}
-		err = db.Select(&events, "SELECT * FROM app_event WHERE notification_received is true")
+		// Checking the delivery receipt has been recorded.
+		var delivered int
+		err = db.Get(&delivered, "SELECT COUNT(*) FROM app_notification WHERE state = 'delivered'")
 		t.Logf("Notification receiving checking...\n")
 		require.NoError(t, err, "should be without errors")
-		require.Len(t, events, 1, "event notification is not received")
+		require.Equal(t, 1, delivered, "event notification is not received")
 		require.Equal(t, http.StatusOK, response.StatusCode, "response status code should be ok")
 
 		// REMOVING REQUEST.